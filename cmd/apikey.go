@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/payment-api/config"
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/usecase"
+)
+
+func apiKeyCommand(ctx context.Context, cfg config.Configuration, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: apikey mint|rotate|revoke [flags]")
+	}
+
+	pgRepository, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	apiKeys := usecase.NewAPIKeyUseCase(repository.NewAPIKeyRepository(*pgRepository), auth.NewTenants(cfg.Tenants))
+
+	flags := flag.NewFlagSet("apikey "+args[0], flag.ContinueOnError)
+
+	switch args[0] {
+	case "mint":
+		clientID := flags.String("client", "", "client the key is issued to")
+		scopes := flags.String("scopes", "", "comma separated scopes: accounts:read,accounts:write,accounts:erase,transactions:write,transactions:review,transactions:import,audit:read,webhooks:manage,admin")
+		ttl := flags.Duration("ttl", 0, "key validity, zero never expires")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		parsed, err := auth.ParseScopes(strings.Split(*scopes, ","))
+		if err != nil {
+			return err
+		}
+
+		raw, key, err := apiKeys.Mint(ctx, *clientID, parsed, *ttl)
+		if err != nil {
+			return err
+		}
+
+		printKey(key.Id, raw)
+	case "rotate":
+		id := flags.String("id", "", "id of the key to rotate")
+		overlap := flags.Duration("overlap", 24*time.Hour, "time the previous key keeps working")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		raw, key, err := apiKeys.Rotate(ctx, *id, *overlap)
+		if err != nil {
+			return err
+		}
+
+		printKey(key.Id, raw)
+	case "revoke":
+		id := flags.String("id", "", "id of the key to revoke")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		if err := apiKeys.Revoke(ctx, *id); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stdout, "revoked %s\n", *id)
+	default:
+		return fmt.Errorf("unknown apikey command %q", args[0])
+	}
+
+	return nil
+}
+
+// printKey shows the raw key once, only its hash is stored.
+func printKey(id, raw string) {
+	fmt.Fprintf(os.Stdout, "id:  %s\nkey: %s\n", id, raw)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/payment-api/config"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/usecase"
+)
+
+func auditCommand(ctx context.Context, cfg config.Configuration, args []string) error {
+	if len(args) == 0 || args[0] != "verify" {
+		return errors.New("usage: audit verify")
+	}
+
+	pgRepository, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	verification, err := usecase.NewAuditUseCase(repository.NewAuditRepository(*pgRepository)).Verify(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !verification.Valid {
+		return fmt.Errorf("audit log broken at entry %d: %s", verification.BrokenAt, verification.Reason)
+	}
+
+	fmt.Fprintf(os.Stdout, "audit log valid, %d entries verified\n", verification.Entries)
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/payment-api/config"
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/postgres"
+)
+
+// command is an administrative subcommand run instead of the http server, as
+// in `payment-api apikey mint -client partner -scopes accounts:read`. Commands
+// act for every tenant.
+type command func(ctx context.Context, cfg config.Configuration, args []string) error
+
+var commands = map[string]command{
+	"apikey":  apiKeyCommand,
+	"audit":   auditCommand,
+	"history": historyCommand,
+	"import":  importCommand,
+}
+
+func runCommand(ctx context.Context, cfg config.Configuration, args []string) error {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return fmt.Errorf("unknown command %q, available: %s", args[0], strings.Join(names, ", "))
+	}
+
+	return cmd(auth.WithAnyTenant(ctx), cfg, args[1:])
+}
+
+// connect opens the database of the commands, scoped like the service's.
+func connect(ctx context.Context, cfg config.Configuration) (*postgres.Repository, error) {
+	pgRepository, err := postgres.NewRepository(ctx, cfg.Postgres.Url)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect postgresql: %w", err)
+	}
+	if cfg.Postgres.RowLevelSecurity {
+		pgRepository.Tenant = auth.Tenant
+	}
+
+	return pgRepository, nil
+}
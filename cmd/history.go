@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/payment-api/config"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/domain"
+	"github.com/payment-api/internal/usecase"
+)
+
+func historyCommand(ctx context.Context, cfg config.Configuration, args []string) error {
+	if len(args) == 0 || (args[0] != "diff" && args[0] != "rebuild") {
+		return errors.New("usage: history diff|rebuild")
+	}
+
+	pgRepository, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	history := usecase.NewHistoryUseCase(repository.NewHistoryRepository(*pgRepository))
+
+	if args[0] == "rebuild" {
+		projection, err := history.Rebuild(ctx)
+		if err != nil {
+			return err
+		}
+
+		printProjection(projection)
+		fmt.Fprintf(os.Stdout, "projections rebuilt from %d events\n", projection.Events)
+		return nil
+	}
+
+	projection, err := history.Diff(ctx)
+	if err != nil {
+		return err
+	}
+
+	printProjection(projection)
+	if !projection.InSync() {
+		return fmt.Errorf("projections differ from the history of %d events", projection.Events)
+	}
+
+	fmt.Fprintf(os.Stdout, "projections match the history, %d events replayed\n", projection.Events)
+	return nil
+}
+
+func printProjection(projection domain.Projection) {
+	for _, diff := range projection.Diffs {
+		for _, id := range diff.Missing {
+			fmt.Fprintf(os.Stdout, "%s %s: missing\n", diff.Table, id)
+		}
+		for _, change := range diff.Changed {
+			fmt.Fprintf(os.Stdout, "%s %s: changed %s\n", diff.Table, change.Id, strings.Join(change.Columns, ", "))
+		}
+		for _, id := range diff.Unexpected {
+			fmt.Fprintf(os.Stdout, "%s %s: not in history\n", diff.Table, id)
+		}
+	}
+}
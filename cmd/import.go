@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/payment-api/config"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/adapter/server"
+	"github.com/payment-api/internal/domain"
+	"github.com/payment-api/internal/usecase"
+)
+
+func importCommand(ctx context.Context, cfg config.Configuration, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	path := flags.String("file", "", "csv or ndjson file of transactions")
+	format := flags.String("format", "", "csv or ndjson, inferred from the file extension when empty")
+	report := flags.String("errors", "", "csv file the rejected rows are written to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *path == "" {
+		return errors.New("usage: import -file <path> [-format csv|ndjson] [-errors <report.csv>]")
+	}
+
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*path)), ".")
+	}
+
+	file, err := os.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	pgRepository, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	policies, err := server.TenantPolicies(cfg.Tenants)
+	if err != nil {
+		return err
+	}
+
+	accountRepository := repository.NewAccountRepository(*pgRepository)
+	transactionRepository := repository.NewTransactionRepository(*pgRepository)
+
+	fraudEngine, err := server.FraudEngine(cfg.Fraud, transactionRepository)
+	if err != nil {
+		return err
+	}
+
+	events := usecase.NewEventUseCase(repository.NewEventRepository(*pgRepository), accountRepository, usecase.NewHub(), cfg.Events.Buffer)
+	publisher := usecase.Publishers{events, server.WebhookUseCase(cfg.Webhook, *pgRepository, accountRepository)}
+
+	imports := usecase.NewImportUseCase(
+		repository.NewImportRepository(*pgRepository),
+		accountRepository,
+		transactionRepository,
+		fraudEngine,
+		usecase.NewAuditUseCase(repository.NewAuditRepository(*pgRepository)),
+		publisher,
+		usecase.ImportLimits{BatchSize: cfg.Import.BatchSize},
+		policies,
+	)
+
+	job, err := imports.Run(ctx, domain.ImportFormat(*format), file)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "import %s %s: %d rows, %d imported, %d failed\n", job.Id, job.Status, job.Processed, job.Imported, job.Failed)
+
+	if *report != "" && job.Failed > 0 {
+		if err := writeImportErrors(ctx, imports, job.Id, *report); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "rejected rows written to %s\n", *report)
+	}
+
+	if job.Status == domain.ImportFailed {
+		return fmt.Errorf("import %s failed: %s", job.Id, job.Error)
+	}
+
+	return nil
+}
+
+func writeImportErrors(ctx context.Context, imports usecase.ImportUseCase, id, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	_ = writer.Write([]string{"row", "field", "message", "raw"})
+
+	err = imports.Errors(ctx, id, func(rowError domain.ImportError) error {
+		return writer.Write([]string{strconv.Itoa(rowError.Row), rowError.Field, rowError.Message, rowError.Raw})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/hashicorp/go-multierror"
 
@@ -14,25 +17,39 @@ import (
 func main() {
 	cfg, err := config.LoadAppConfig("./scripts/config")
 	if err != nil {
-		logger.Fatal(logger.FatalError, "unable to load configurations")
+		logger.Fatal(context.Background(), logger.FatalError, "unable to load configurations", logger.Err(err))
+	}
+
+	if err := logger.Configure(cfg.Log.Level, cfg.Log.Format); err != nil {
+		logger.Fatal(context.Background(), logger.ConfigError, "unable to configure logger", logger.Err(err))
+	}
+
+	if len(os.Args) > 1 {
+		if err := runCommand(context.Background(), cfg, os.Args[1:]); err != nil {
+			logger.Fatal(context.Background(), logger.FatalError, "command failed", logger.Err(err))
+		}
+		return
 	}
 
 	var g multierror.Group
 
-	ctx, stop := context.WithCancel(context.Background())
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	ctx = context.WithValue(ctx, "service-name", "payment-api")
 
 	tracer, err := telemetry.NewTraceProvider(ctx, cfg)
 	if err != nil {
-		logger.Fatal(logger.FatalError, "unable to create tracer")
+		logger.Fatal(ctx, logger.FatalError, "unable to create tracer", logger.Err(err))
 	}
 
 	defer func() {
 		if tracer != nil {
-			if err := tracer.Shutdown(ctx); err != nil {
-				logger.Fatal(logger.FatalError, "unable to shutdown tracer")
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+			defer cancel()
+
+			if err := tracer.Shutdown(shutdownCtx); err != nil {
+				logger.Fatal(ctx, logger.FatalError, "unable to shutdown tracer", logger.Err(err))
 			}
 		}
 	}()
@@ -41,6 +58,6 @@ func main() {
 	g.Go(s.Run(ctx, stop))
 
 	if err := g.Wait().ErrorOrNil(); err != nil {
-		logger.Fatal(logger.ServerError, err)
+		logger.Fatal(ctx, logger.ServerError, "server stopped", logger.Err(err))
 	}
 }
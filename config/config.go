@@ -3,6 +3,8 @@ package config
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -11,18 +13,200 @@ type Configuration struct {
 	Server    Server    `mapstructure:"server"`
 	Postgres  Postgres  `mapstructure:"postgres"`
 	Telemetry Telemetry `mapstructure:"telemetry"`
+	Health    Health    `mapstructure:"health"`
+	Log       Log       `mapstructure:"log"`
+	JWT       JWT       `mapstructure:"jwt"`
+	Signing   Signing   `mapstructure:"signing"`
+	RateLimit RateLimit `mapstructure:"rate_limit"`
+	Fraud     Fraud     `mapstructure:"fraud"`
+	Review    Review    `mapstructure:"review"`
+	Webhook   Webhook   `mapstructure:"webhook"`
+	Events    Events    `mapstructure:"events"`
+	Import    Import    `mapstructure:"import"`
+	Statement Statement `mapstructure:"statement"`
+	Erasure   Erasure   `mapstructure:"erasure"`
+	Tenants   Tenants   `mapstructure:"tenants"`
+	Schedule  Schedule  `mapstructure:"schedule"`
+	Cards     Cards     `mapstructure:"cards"`
+}
+
+// Schedule configures the scheduler of scheduled payments, polling due
+// payments every Interval, BatchSize at a time, on the replica holding the
+// scheduler lock. A payment failing to post is retried up to MaxAttempts,
+// waiting BackoffBase doubled on every failure, at most BackoffMax.
+type Schedule struct {
+	Interval    time.Duration `mapstructure:"interval"`
+	BatchSize   int           `mapstructure:"batch_size"`
+	MaxAttempts int           `mapstructure:"max_attempts"`
+	BackoffBase time.Duration `mapstructure:"backoff_base"`
+	BackoffMax  time.Duration `mapstructure:"backoff_max"`
+}
+
+// Cards configures issued cards: their numbers start with BIN and they are
+// valid through the end of the month ValidityMonths after issuance. Cards past
+// their expiry are marked expired every Interval.
+type Cards struct {
+	BIN            string        `mapstructure:"bin"`
+	ValidityMonths int           `mapstructure:"validity_months"`
+	Interval       time.Duration `mapstructure:"interval"`
+}
+
+// Tenants maps the id of each card program sharing the deployment to its
+// configuration.
+type Tenants map[string]Tenant
+
+// Tenant configures a card program: the api clients acting for it, and the
+// operation types and maximum amount allowed to the transactions of its
+// accounts. Empty OperationTypes allow every type, a zero MaxAmount any amount.
+type Tenant struct {
+	Clients        []string `mapstructure:"clients"`
+	OperationTypes []int    `mapstructure:"operation_types"`
+	MaxAmount      float64  `mapstructure:"max_amount"`
+}
+
+// Erasure configures the erasure of closed accounts, Key derives the
+// document number erased accounts keep and the hmacs of the audit log. The
+// server refuses to start while it is empty, set it through ERASURE_KEY.
+type Erasure struct {
+	Key string `mapstructure:"key"`
+}
+
+// Statement configures the exported account statements, amounts are in
+// Currency and OFX files identify the bank by BankID.
+type Statement struct {
+	Currency string `mapstructure:"currency"`
+	BankID   string `mapstructure:"bank_id"`
+}
+
+// Import configures bulk transaction imports. Uploads up to MaxBytes are
+// spooled to SpoolDir, the os temp dir when empty, and inserted BatchSize rows
+// at a time. Jobs not updated for StaleAfter, left by a stopped replica, are
+// failed by a worker running every Interval.
+type Import struct {
+	BatchSize  int           `mapstructure:"batch_size"`
+	MaxBytes   int64         `mapstructure:"max_bytes"`
+	SpoolDir   string        `mapstructure:"spool_dir"`
+	StaleAfter time.Duration `mapstructure:"stale_after"`
+	Interval   time.Duration `mapstructure:"interval"`
+}
+
+// Events configures the account event streams, a comment is sent every
+// Heartbeat to keep idle connections open and a stream more than Buffer
+// events behind is closed for its client to resume.
+type Events struct {
+	Heartbeat time.Duration `mapstructure:"heartbeat"`
+	Buffer    int           `mapstructure:"buffer"`
+}
+
+// Webhook configures the dispatcher polling due deliveries every Interval,
+// BatchSize at a time. A delivery is leased for Lease while attempted and
+// dead-lettered after MaxAttempts, waiting BackoffBase doubled on every
+// failure, at most BackoffMax, between attempts. Insecure, for local
+// development only, accepts http endpoints and internal addresses.
+type Webhook struct {
+	Interval    time.Duration `mapstructure:"interval"`
+	BatchSize   int           `mapstructure:"batch_size"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+	Lease       time.Duration `mapstructure:"lease"`
+	MaxAttempts int           `mapstructure:"max_attempts"`
+	BackoffBase time.Duration `mapstructure:"backoff_base"`
+	BackoffMax  time.Duration `mapstructure:"backoff_max"`
+	Insecure    bool          `mapstructure:"insecure"`
+}
+
+// Review configures the manual review queue, transactions pending longer than
+// Expiry are declined by a worker running every Interval. A zero Expiry keeps
+// them queued until an operator resolves them.
+type Review struct {
+	Expiry   time.Duration `mapstructure:"expiry"`
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+type Fraud struct {
+	Rules []FraudRule `mapstructure:"rules"`
+}
+
+// FraudRule is one declarative rule, Type is velocity, max_amount or
+// first_transaction and Decision is allow, review or deny.
+type FraudRule struct {
+	Name          string        `mapstructure:"name"`
+	Type          string        `mapstructure:"type"`
+	Decision      string        `mapstructure:"decision"`
+	OperationType int           `mapstructure:"operation_type"`
+	MaxAmount     float64       `mapstructure:"max_amount"`
+	MaxCount      int           `mapstructure:"max_count"`
+	Window        time.Duration `mapstructure:"window"`
+}
+
+// RateLimit configures the token buckets of api clients and accounts, a
+// limit with zero rate is disabled. Store is either memory or postgres.
+type RateLimit struct {
+	Store   string           `mapstructure:"store"`
+	Client  Limit            `mapstructure:"client"`
+	Account Limit            `mapstructure:"account"`
+	Clients map[string]Limit `mapstructure:"clients"`
+}
+
+type Limit struct {
+	Rate  float64 `mapstructure:"rate"`
+	Burst int     `mapstructure:"burst"`
+}
+
+// Signing holds the HMAC secret of each partner, keyed by the client id of
+// the partner api key.
+type Signing struct {
+	ReplayWindow time.Duration     `mapstructure:"replay_window"`
+	Partners     map[string]string `mapstructure:"partners"`
+}
+
+// JWT configures bearer tokens for end users, it is disabled while neither
+// JWKSFile nor JWKSURL are set.
+type JWT struct {
+	JWKSFile        string        `mapstructure:"jwks_file"`
+	JWKSURL         string        `mapstructure:"jwks_url"`
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+	Issuer          string        `mapstructure:"issuer"`
+	Audience        string        `mapstructure:"audience"`
+	Leeway          time.Duration `mapstructure:"leeway"`
+	OwnerClaim      string        `mapstructure:"owner_claim"`
+	RolesClaim      string        `mapstructure:"roles_claim"`
+	OperatorRole    string        `mapstructure:"operator_role"`
+	TenantClaim     string        `mapstructure:"tenant_claim"`
+}
+
+func (j JWT) Enabled() bool {
+	return j.JWKSFile != "" || j.JWKSURL != ""
+}
+
+type Log struct {
+	Level  string `mapstructure:"level"`
+	Format string `mapstructure:"format"`
+}
+
+type Health struct {
+	PostgresTimeout  time.Duration `mapstructure:"postgres_timeout"`
+	MigrationTimeout time.Duration `mapstructure:"migration_timeout"`
+	TelemetryTimeout time.Duration `mapstructure:"telemetry_timeout"`
 }
 
 type Telemetry struct {
 	Hostname string `mapstructure:"hostname"`
 }
 
+// Postgres configures the database, RowLevelSecurity makes every statement
+// set app.tenant_id for the row level security policies of the tenant tables.
 type Postgres struct {
-	Url string `mapstructure:"url"`
+	Url              string `mapstructure:"url"`
+	RowLevelSecurity bool   `mapstructure:"row_level_security"`
 }
 
+// Server configures the REST API on Port and the gRPC API on GRPCPort, a zero
+// GRPCPort disables the gRPC API.
 type Server struct {
-	Port int `mapstructure:"port"`
+	Port            int           `mapstructure:"port"`
+	GRPCPort        int           `mapstructure:"grpc_port"`
+	ShutdownDelay   time.Duration `mapstructure:"shutdown_delay"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
 }
 
 func LoadAppConfig(path string) (Configuration, error) {
@@ -33,6 +217,7 @@ func LoadAppConfig(path string) (Configuration, error) {
 	viper.AddConfigPath(path)
 	viper.SetConfigName("local")
 	viper.SetConfigType("yml")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
 	if err := viper.ReadInConfig(); err != nil {
@@ -0,0 +1,16 @@
+package audit
+
+import "context"
+
+type routeKey struct{}
+
+// WithRoute stores the route the mutations of ctx are made through, as in
+// "POST /api/v1/accounts" or "worker:review-expiry".
+func WithRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeKey{}, route)
+}
+
+func RouteFrom(ctx context.Context) string {
+	route, _ := ctx.Value(routeKey{}).(string)
+	return route
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/payment-api/config"
+)
+
+// TokenVerifier validates bearer tokens issued to end users and maps them to a
+// Principal bound to the account document number carried in the owner claim.
+type TokenVerifier struct {
+	keys   *JWKS
+	config config.JWT
+	parser *jwt.Parser
+}
+
+// operatorScopes are only kept on the tokens of operators, their routes are
+// not limited to the accounts of the caller.
+var operatorScopes = map[Scope]bool{
+	ScopeTransactionsReview: true,
+	ScopeAuditRead:          true,
+	ScopeWebhooksManage:     true,
+}
+
+func (v *TokenVerifier) Verify(ctx context.Context, token string) (Principal, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return Principal{}, err
+	}
+
+	subject, _ := claims.GetSubject()
+
+	principal := Principal{
+		ClientID: subject,
+		Subject:  subject,
+		Operator: contains(stringsClaim(claims[v.config.RolesClaim]), v.config.OperatorRole),
+	}
+
+	principal.Owner, _ = claims[v.config.OwnerClaim].(string)
+	if principal.Owner == "" && !principal.Operator {
+		return Principal{}, fmt.Errorf("token without %q claim", v.config.OwnerClaim)
+	}
+
+	if principal.Tenant, _ = claims[v.config.TenantClaim].(string); principal.Tenant == "" {
+		principal.Tenant = DefaultTenant
+	}
+
+	for _, value := range stringsClaim(claims["scope"]) {
+		scope := Scope(value)
+		// end users never hold admin, bulk imports nor erasures, operators are granted
+		// cross account reads, the review queue, the audit log and webhooks by role
+		if operatorScopes[scope] && !principal.Operator {
+			continue
+		}
+		if scopes[scope] && scope != ScopeAdmin && scope != ScopeTransactionsImport && scope != ScopeAccountsErase {
+			principal.Scopes = append(principal.Scopes, scope)
+		}
+	}
+
+	return principal, nil
+}
+
+// stringsClaim reads a claim holding either a json array or a space separated string.
+func stringsClaim(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func NewTokenVerifier(cfg config.JWT) *TokenVerifier {
+	if cfg.OwnerClaim == "" {
+		cfg.OwnerClaim = "document_number"
+	}
+	if cfg.RolesClaim == "" {
+		cfg.RolesClaim = "roles"
+	}
+	if cfg.OperatorRole == "" {
+		cfg.OperatorRole = "operator"
+	}
+	if cfg.TenantClaim == "" {
+		cfg.TenantClaim = "tenant"
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &TokenVerifier{
+		keys:   NewJWKS(cfg.JWKSFile, cfg.JWKSURL, cfg.RefreshInterval),
+		config: cfg,
+		parser: jwt.NewParser(options...),
+	}
+}
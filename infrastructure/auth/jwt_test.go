@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/config"
+)
+
+func writeJWKS(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) string {
+	encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+
+	set := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa-key", "use": "sig", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+			{"kty": "EC", "kid": "ec-key", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+		},
+	}
+
+	raw, err := json.Marshal(set)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, raw, 0o600))
+
+	return path
+}
+
+func Test_TokenVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	verifier := NewTokenVerifier(config.JWT{
+		JWKSFile: writeJWKS(t, rsaKey, ecKey),
+		Issuer:   "https://issuer.test",
+	})
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		assert.NoError(t, err)
+		return signed
+	}
+
+	claims := func(extra jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub":   "user-1",
+			"iss":   "https://issuer.test",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"scope": "accounts:read transactions:write transactions:review transactions:import accounts:erase audit:read webhooks:manage admin",
+		}
+		for k, v := range extra {
+			c[k] = v
+		}
+		return c
+	}
+
+	scenarios := []struct {
+		description    string
+		token          string
+		expectError    bool
+		expectedOwner  string
+		expectedTenant string
+		operator       bool
+	}{
+		{
+			description:   "rsa signed owner token",
+			token:         sign(jwt.SigningMethodRS256, "rsa-key", rsaKey, claims(jwt.MapClaims{"document_number": "12345678900"})),
+			expectedOwner: "12345678900",
+		},
+		{
+			description:    "token of a tenant",
+			token:          sign(jwt.SigningMethodRS256, "rsa-key", rsaKey, claims(jwt.MapClaims{"document_number": "12345678900", "tenant": "program-b"})),
+			expectedOwner:  "12345678900",
+			expectedTenant: "program-b",
+		},
+		{
+			description:   "ec signed owner token",
+			token:         sign(jwt.SigningMethodES256, "ec-key", ecKey, claims(jwt.MapClaims{"document_number": "12345678900"})),
+			expectedOwner: "12345678900",
+		},
+		{
+			description: "operator token without owner",
+			token:       sign(jwt.SigningMethodRS256, "rsa-key", rsaKey, claims(jwt.MapClaims{"roles": []string{"operator"}})),
+			operator:    true,
+		},
+		{
+			description: "token without owner",
+			token:       sign(jwt.SigningMethodRS256, "rsa-key", rsaKey, claims(nil)),
+			expectError: true,
+		},
+		{
+			description: "expired token",
+			token:       sign(jwt.SigningMethodRS256, "rsa-key", rsaKey, claims(jwt.MapClaims{"document_number": "123", "exp": time.Now().Add(-time.Hour).Unix()})),
+			expectError: true,
+		},
+		{
+			description: "wrong issuer",
+			token:       sign(jwt.SigningMethodRS256, "rsa-key", rsaKey, claims(jwt.MapClaims{"document_number": "123", "iss": "https://evil.test"})),
+			expectError: true,
+		},
+		{
+			description: "signed by unknown key",
+			token:       sign(jwt.SigningMethodRS256, "rsa-key", otherKey, claims(jwt.MapClaims{"document_number": "123"})),
+			expectError: true,
+		},
+		{
+			description: "unknown kid",
+			token:       sign(jwt.SigningMethodRS256, "missing-key", rsaKey, claims(jwt.MapClaims{"document_number": "123"})),
+			expectError: true,
+		},
+		{
+			description: "symmetric algorithm",
+			token:       sign(jwt.SigningMethodHS256, "rsa-key", []byte("secret"), claims(jwt.MapClaims{"document_number": "123"})),
+			expectError: true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			principal, err := verifier.Verify(context.Background(), scenario.token)
+
+			if scenario.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "user-1", principal.Subject)
+			assert.Equal(t, scenario.expectedOwner, principal.Owner)
+			if scenario.expectedTenant == "" {
+				scenario.expectedTenant = DefaultTenant
+			}
+			assert.Equal(t, scenario.expectedTenant, principal.Tenant)
+			assert.Equal(t, scenario.operator, principal.Operator)
+			assert.True(t, principal.HasScope(ScopeAccountsRead))
+			assert.False(t, principal.HasScope(ScopeAccountsWrite), "admin scope is never granted to tokens")
+			assert.False(t, principal.HasScope(ScopeTransactionsImport), "bulk imports are never granted to tokens")
+			assert.False(t, principal.HasScope(ScopeAccountsErase), "erasures are never granted to tokens")
+			assert.Equal(t, scenario.operator, principal.HasScope(ScopeTransactionsReview), "only operators review transactions")
+			assert.Equal(t, scenario.operator, principal.HasScope(ScopeAuditRead), "only operators read the audit log")
+			assert.Equal(t, scenario.operator, principal.HasScope(ScopeWebhooksManage), "only operators manage webhooks")
+		})
+	}
+}
+
+func Test_PrincipalCanAccess(t *testing.T) {
+	assert.True(t, Principal{ClientID: "api-client"}.CanAccess("any-document"))
+	assert.True(t, Principal{Owner: "any-document"}.CanAccess("any-document"))
+	assert.False(t, Principal{Owner: "any-document"}.CanAccess("other-document"))
+	assert.True(t, Principal{Owner: "any-document", Operator: true}.CanAccess("other-document"))
+	assert.True(t, CanAccess(context.Background(), "any-document"))
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type Scope string
+
+const (
+	ScopeAccountsRead       Scope = "accounts:read"
+	ScopeAccountsWrite      Scope = "accounts:write"
+	ScopeAccountsErase      Scope = "accounts:erase"
+	ScopeTransactionsWrite  Scope = "transactions:write"
+	ScopeTransactionsReview Scope = "transactions:review"
+	ScopeTransactionsImport Scope = "transactions:import"
+	ScopeAuditRead          Scope = "audit:read"
+	ScopeWebhooksManage     Scope = "webhooks:manage"
+	ScopeAdmin              Scope = "admin"
+)
+
+var scopes = map[Scope]bool{
+	ScopeAccountsRead:       true,
+	ScopeAccountsWrite:      true,
+	ScopeAccountsErase:      true,
+	ScopeTransactionsWrite:  true,
+	ScopeTransactionsReview: true,
+	ScopeTransactionsImport: true,
+	ScopeAuditRead:          true,
+	ScopeWebhooksManage:     true,
+	ScopeAdmin:              true,
+}
+
+func ParseScopes(values []string) ([]Scope, error) {
+	parsed := make([]Scope, 0, len(values))
+
+	for _, value := range values {
+		scope := Scope(strings.TrimSpace(value))
+		if !scopes[scope] {
+			return nil, fmt.Errorf("unknown scope %q", value)
+		}
+		parsed = append(parsed, scope)
+	}
+
+	return parsed, nil
+}
+
+// Principal is the authenticated caller of a request. Api key clients act on
+// any account of their Tenant, end users authenticated by bearer token only on
+// the accounts whose document number is their Owner, unless they hold the
+// operator role.
+type Principal struct {
+	ClientID string
+	KeyID    string
+	Subject  string
+	Owner    string
+	Operator bool
+	Tenant   string
+	Scopes   []Scope
+}
+
+// CanAccess reports whether the principal may act on the account holding documentNumber.
+func (p Principal) CanAccess(documentNumber string) bool {
+	return p.Owner == "" || p.Operator || p.Owner == documentNumber
+}
+
+// CanAccess applies Principal.CanAccess to the caller in ctx, internal callers
+// without a principal are trusted.
+func CanAccess(ctx context.Context, documentNumber string) bool {
+	principal, ok := PrincipalFrom(ctx)
+	return !ok || principal.CanAccess(documentNumber)
+}
+
+// HasScope reports whether the principal was granted scope, admin grants every scope.
+func (p Principal) HasScope(scope Scope) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SystemActor names internal callers, as workers and commands, in audit trails.
+const SystemActor = "system"
+
+// Actor names the caller in ctx: the token subject of end users, the client
+// id of api keys and SystemActor for internal callers.
+func Actor(ctx context.Context) string {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return SystemActor
+	}
+
+	if principal.Subject != "" {
+		return principal.Subject
+	}
+
+	return principal.ClientID
+}
+
+type contextKey struct{}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/payment-api/config"
+)
+
+// DefaultTenant is the tenant of the clients no card program lists, and of
+// the data created before tenants existed.
+const DefaultTenant = "default"
+
+// AnyTenant is the tenant of internal callers let act for every tenant by
+// WithAnyTenant, the scoped queries match it literally.
+const AnyTenant = "*"
+
+// Tenants resolves the tenant an api client acts for.
+type Tenants map[string]string
+
+func NewTenants(cfg config.Tenants) Tenants {
+	tenants := make(Tenants)
+	for tenant, program := range cfg {
+		for _, client := range program.Clients {
+			tenants[strings.ToLower(client)] = tenant
+		}
+	}
+
+	return tenants
+}
+
+// Of returns the tenant of clientID, DefaultTenant when no program lists it.
+func (t Tenants) Of(clientID string) string {
+	if tenant, ok := t[strings.ToLower(clientID)]; ok {
+		return tenant
+	}
+
+	return DefaultTenant
+}
+
+type anyTenantKey struct{}
+
+// WithAnyTenant lets the internal caller in ctx, as workers and commands, act
+// for every tenant. It has no effect on callers holding a principal.
+func WithAnyTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, anyTenantKey{}, true)
+}
+
+// Tenant is the tenant the caller in ctx acts for: the tenant of its
+// principal, AnyTenant for internal callers given WithAnyTenant and "",
+// matching no tenant, for any other. A principal never gets AnyTenant.
+func Tenant(ctx context.Context) string {
+	if principal, ok := PrincipalFrom(ctx); ok {
+		if principal.Tenant == AnyTenant {
+			return ""
+		}
+		return principal.Tenant
+	}
+
+	if bypass, _ := ctx.Value(anyTenantKey{}).(bool); bypass {
+		return AnyTenant
+	}
+
+	return ""
+}
+
+// OwnTenant is the tenant stamped on what the caller in ctx creates,
+// DefaultTenant for internal callers.
+func OwnTenant(ctx context.Context) string {
+	if tenant := Tenant(ctx); tenant != "" && tenant != AnyTenant {
+		return tenant
+	}
+
+	return DefaultTenant
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Tenant(t *testing.T) {
+	scenarios := []struct {
+		description string
+		ctx         context.Context
+		expected    string
+		expectedOwn string
+	}{
+		{
+			description: "principal",
+			ctx:         WithPrincipal(context.Background(), Principal{Tenant: "program-a"}),
+			expected:    "program-a",
+			expectedOwn: "program-a",
+		},
+		{
+			description: "internal caller acting for every tenant",
+			ctx:         WithAnyTenant(context.Background()),
+			expected:    AnyTenant,
+			expectedOwn: DefaultTenant,
+		},
+		{
+			description: "internal caller without bypass",
+			ctx:         context.Background(),
+			expected:    "",
+			expectedOwn: DefaultTenant,
+		},
+		{
+			description: "principal never bypasses",
+			ctx:         WithAnyTenant(WithPrincipal(context.Background(), Principal{Tenant: "program-a"})),
+			expected:    "program-a",
+			expectedOwn: "program-a",
+		},
+		{
+			description: "principal named after the bypass",
+			ctx:         WithPrincipal(context.Background(), Principal{Tenant: AnyTenant}),
+			expected:    "",
+			expectedOwn: DefaultTenant,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			assert.Equal(t, scenario.expected, Tenant(scenario.ctx))
+			assert.Equal(t, scenario.expectedOwn, OwnTenant(scenario.ctx))
+		})
+	}
+}
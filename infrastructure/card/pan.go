@@ -0,0 +1,79 @@
+package card
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+const (
+	panLength   = 16
+	tokenPrefix = "tok_"
+)
+
+// GeneratePAN returns a new random 16 digit card number starting with bin and
+// ending with its Luhn check digit.
+func GeneratePAN(bin string) (string, error) {
+	if len(bin) < 6 || len(bin) >= panLength || !digits(bin) {
+		return "", fmt.Errorf("invalid card bin %q", bin)
+	}
+
+	pan := []byte(bin)
+	for len(pan) < panLength-1 {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		pan = append(pan, byte('0'+n.Int64()))
+	}
+
+	return string(pan) + string(checkDigit(string(pan))), nil
+}
+
+// GenerateToken returns a new random token standing for a card number, tokens
+// are not derived from the number so they reveal nothing about it.
+func GenerateToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return tokenPrefix + hex.EncodeToString(b), nil
+}
+
+// Luhn reports whether pan is a card number with a valid check digit.
+func Luhn(pan string) bool {
+	if len(pan) < 2 || !digits(pan) {
+		return false
+	}
+
+	return checkDigit(pan[:len(pan)-1]) == pan[len(pan)-1]
+}
+
+func checkDigit(payload string) byte {
+	sum := 0
+	for i := len(payload) - 1; i >= 0; i-- {
+		d := int(payload[i] - '0')
+		// the digits doubled are every other one from the check digit
+		if (len(payload)-1-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+
+	return byte('0' + (10-sum%10)%10)
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,45 @@
+package card
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Luhn(t *testing.T) {
+	assert.True(t, Luhn("4111111111111111"))
+	assert.True(t, Luhn("5555555555554444"))
+	assert.False(t, Luhn("4111111111111112"))
+	assert.False(t, Luhn("4111-1111-1111-1111"))
+	assert.False(t, Luhn(""))
+}
+
+func Test_GeneratePAN(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		pan, err := GeneratePAN("400000")
+
+		assert.NoError(t, err)
+		assert.Len(t, pan, 16)
+		assert.True(t, strings.HasPrefix(pan, "400000"))
+		assert.True(t, Luhn(pan), pan)
+	}
+
+	_, err := GeneratePAN("40")
+	assert.Error(t, err)
+
+	_, err = GeneratePAN("4000ab")
+	assert.Error(t, err)
+}
+
+func Test_GenerateToken(t *testing.T) {
+	token, err := GenerateToken()
+	assert.NoError(t, err)
+
+	other, err := GenerateToken()
+	assert.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(token, "tok_"))
+	assert.Len(t, token, 36)
+	assert.NotEqual(t, token, other)
+}
@@ -1,11 +1,88 @@
 package exceptions
 
-import "errors"
+import "net/http"
 
 var (
-	EntityNotFoundError       = errors.New("entity not found")
-	PersistenceError          = errors.New("cannot persist error")
-	InvalidAmountError        = errors.New("invalid amount value")
-	InvalidOperationTypeError = errors.New("invalid operation type value")
-	InvalidParameterError     = errors.New("invalid parameter value")
+	EntityNotFoundError       = New("ENTITY_NOT_FOUND", http.StatusNotFound, "entity not found")
+	PersistenceError          = New("PERSISTENCE_ERROR", http.StatusInternalServerError, "cannot persist entity")
+	InvalidAmountError        = New("INVALID_AMOUNT", http.StatusBadRequest, "invalid amount value")
+	InvalidOperationTypeError = New("INVALID_OPERATION_TYPE", http.StatusBadRequest, "invalid operation type value")
+	InvalidParameterError     = New("INVALID_PARAMETER", http.StatusBadRequest, "invalid parameter value")
+	UnauthorizedError         = New("UNAUTHORIZED", http.StatusUnauthorized, "missing or invalid credentials")
+	InvalidSignatureError     = New("INVALID_SIGNATURE", http.StatusUnauthorized, "invalid request signature")
+	ForbiddenError            = New("FORBIDDEN", http.StatusForbidden, "insufficient scope")
+	TooManyRequestsError      = New("RATE_LIMITED", http.StatusTooManyRequests, "too many requests")
+	ConflictError             = New("CONFLICT", http.StatusConflict, "entity already exists")
+	UnavailableError          = New("SERVICE_UNAVAILABLE", http.StatusServiceUnavailable, "service temporarily unavailable")
+	TransactionDeniedError    = New("TRANSACTION_DENIED", http.StatusUnprocessableEntity, "transaction denied by fraud rules")
+	AccountClosedError        = New("ACCOUNT_CLOSED", http.StatusUnprocessableEntity, "account is closed")
+	InvalidStateError         = New("INVALID_STATE", http.StatusUnprocessableEntity, "entity state does not allow the operation")
+	CardInactiveError         = New("CARD_INACTIVE", http.StatusUnprocessableEntity, "card is blocked, replaced or expired")
+	ValidationError           = New("VALIDATION_ERROR", http.StatusBadRequest, "request validation failed")
+	UnsupportedMediaTypeError = New("UNSUPPORTED_MEDIA_TYPE", http.StatusUnsupportedMediaType, "unsupported content type")
+	PayloadTooLargeError      = New("PAYLOAD_TOO_LARGE", http.StatusRequestEntityTooLarge, "request body too large")
+	PreconditionFailedError   = New("PRECONDITION_FAILED", http.StatusPreconditionFailed, "entity was modified since it was read")
+	PreconditionRequiredError = New("PRECONDITION_REQUIRED", http.StatusPreconditionRequired, "If-Match header required")
+	InternalError             = New("INTERNAL_ERROR", http.StatusInternalServerError, "internal server error")
 )
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error carrying a stable code and the http status it is
+// answered with. Copies made by WithDetail, WithFields and Wrap keep matching
+// the original with errors.Is.
+type Error struct {
+	Code   string
+	Status int
+	Title  string
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+func New(code string, status int, title string) *Error {
+	return &Error{
+		Code:   code,
+		Status: status,
+		Title:  title,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return e.Detail
+	}
+
+	return e.Title
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func (e *Error) WithDetail(detail string) *Error {
+	c := *e
+	c.Detail = detail
+	return &c
+}
+
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	c := *e
+	c.Fields = append(append([]FieldError{}, e.Fields...), fields...)
+	return &c
+}
+
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
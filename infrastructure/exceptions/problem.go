@@ -0,0 +1,42 @@
+package exceptions
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+	problemTypeBase    = "https://payment-api.com/problems/"
+)
+
+// Problem is the RFC 7807 representation of an Error.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// ToProblem maps err to a Problem, errors not raised through this package are
+// reported as InternalError without leaking their message.
+func ToProblem(err error, instance string) Problem {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = InternalError
+	}
+
+	return Problem{
+		Type:     problemTypeBase + strings.ReplaceAll(strings.ToLower(e.Code), "_", "-"),
+		Title:    e.Title,
+		Status:   e.Status,
+		Detail:   e.Detail,
+		Instance: instance,
+		Code:     e.Code,
+		Errors:   e.Fields,
+	}
+}
@@ -0,0 +1,73 @@
+package exceptions
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const errorDomain = "payment-api.com"
+
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusUnauthorized:          codes.Unauthenticated,
+	http.StatusForbidden:             codes.PermissionDenied,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusConflict:              codes.AlreadyExists,
+	http.StatusPreconditionFailed:    codes.Aborted,
+	http.StatusRequestEntityTooLarge: codes.ResourceExhausted,
+	http.StatusUnsupportedMediaType:  codes.InvalidArgument,
+	http.StatusUnprocessableEntity:   codes.FailedPrecondition,
+	http.StatusPreconditionRequired:  codes.FailedPrecondition,
+	http.StatusTooManyRequests:       codes.ResourceExhausted,
+	http.StatusServiceUnavailable:    codes.Unavailable,
+}
+
+// ToStatus maps err to the gRPC status answered for it, the gRPC counterpart
+// of ToProblem. The code of the Error travels as an ErrorInfo reason and its
+// fields as BadRequest violations, errors not raised through this package
+// are reported as InternalError without leaking their message.
+func ToStatus(err error) *status.Status {
+	var e *Error
+	if !errors.As(err, &e) {
+		if s, ok := status.FromError(err); ok {
+			return s
+		}
+
+		switch {
+		case errors.Is(err, context.Canceled):
+			return status.New(codes.Canceled, err.Error())
+		case errors.Is(err, context.DeadlineExceeded):
+			return status.New(codes.DeadlineExceeded, err.Error())
+		}
+
+		e = InternalError
+	}
+
+	code, ok := grpcCodes[e.Status]
+	if !ok {
+		code = codes.Internal
+	}
+
+	s, detailErr := status.New(code, e.Error()).WithDetails(&errdetails.ErrorInfo{Reason: e.Code, Domain: errorDomain})
+	if detailErr != nil {
+		return status.New(code, e.Error())
+	}
+
+	if len(e.Fields) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(e.Fields))
+		for _, field := range e.Fields {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: field.Field, Description: field.Message})
+		}
+
+		if withFields, err := s.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+			s = withFields
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,79 @@
+package exceptions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FromBinding converts the error returned while binding a request body into a
+// ValidationError listing every rejected field.
+func FromBinding(err error) *Error {
+	var (
+		validationErrors validator.ValidationErrors
+		typeError        *json.UnmarshalTypeError
+		syntaxError      *json.SyntaxError
+	)
+
+	switch {
+	case errors.As(err, &validationErrors):
+		fields := make([]FieldError, 0, len(validationErrors))
+		for _, fe := range validationErrors {
+			fields = append(fields, FieldError{Field: fe.Field(), Message: validationMessage(fe)})
+		}
+		return ValidationError.WithFields(fields...).Wrap(err)
+	case errors.As(err, &typeError):
+		return ValidationError.WithFields(FieldError{
+			Field:   typeError.Field,
+			Message: fmt.Sprintf("must be of type %s", typeError.Type.String()),
+		}).Wrap(err)
+	case errors.As(err, &syntaxError), errors.Is(err, io.ErrUnexpectedEOF):
+		return ValidationError.WithDetail("request body is not valid json").Wrap(err)
+	case errors.Is(err, io.EOF):
+		return ValidationError.WithDetail("request body is empty").Wrap(err)
+	default:
+		return ValidationError.Wrap(err)
+	}
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_without":
+		return fmt.Sprintf("is required without %s", snakeCase(fe.Param()))
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "min", "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", fe.Param())
+	case "max", "lte":
+		return fmt.Sprintf("must be less than or equal to %s", fe.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "email":
+		return "must be a valid email"
+	default:
+		return fmt.Sprintf("failed on %s validation", fe.Tag())
+	}
+}
+
+// snakeCase turns the struct field name of a validation parameter into its
+// json name, as CardToken into card_token.
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDown     Status = "down"
+	StatusReady    Status = "ready"
+	StatusNotReady Status = "not_ready"
+)
+
+var ShuttingDownError = errors.New("service is shutting down")
+
+type CheckFunc func(ctx context.Context) error
+
+type Check struct {
+	Name    string
+	Timeout time.Duration
+	Func    CheckFunc
+}
+
+type CheckResult struct {
+	Status   Status `json:"status"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status Status                 `json:"status"`
+	Reason string                 `json:"reason,omitempty"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type Checker struct {
+	checks   []Check
+	draining atomic.Bool
+}
+
+// Drain flips the checker to not-ready, so load balancers stop routing
+// traffic before the http server is shut down.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+func (c *Checker) Draining() bool {
+	return c.draining.Load()
+}
+
+// Ready runs every registered check concurrently, each bounded by its own timeout.
+func (c *Checker) Ready(ctx context.Context) Report {
+	if c.Draining() {
+		return Report{Status: StatusNotReady, Reason: ShuttingDownError.Error()}
+	}
+
+	report := Report{
+		Status: StatusReady,
+		Checks: make(map[string]CheckResult, len(c.checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for _, check := range c.checks {
+		wg.Add(1)
+
+		go func(check Check) {
+			defer wg.Done()
+
+			result := run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			report.Checks[check.Name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusNotReady
+			}
+		}(check)
+	}
+
+	wg.Wait()
+
+	return report
+}
+
+func run(ctx context.Context, check Check) CheckResult {
+	if check.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, check.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+
+	go func() {
+		done <- check.Func(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{
+		Status:   StatusUp,
+		Duration: time.Since(start).String(),
+	}
+
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{checks: checks}
+}
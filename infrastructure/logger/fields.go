@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Field adds a structured value to a log entry.
+type Field func(e *zerolog.Event) *zerolog.Event
+
+// maskedFields holds the keys carrying personal data, their values are
+// masked before being written.
+var maskedFields = map[string]bool{
+	"document_number": true,
+}
+
+func Str(key, value string) Field {
+	return func(e *zerolog.Event) *zerolog.Event {
+		if maskedFields[key] {
+			value = Mask(value)
+		}
+
+		return e.Str(key, value)
+	}
+}
+
+func Int(key string, value int) Field {
+	return func(e *zerolog.Event) *zerolog.Event {
+		return e.Int(key, value)
+	}
+}
+
+func Float(key string, value float64) Field {
+	return func(e *zerolog.Event) *zerolog.Event {
+		return e.Float64(key, value)
+	}
+}
+
+func Duration(key string, value time.Duration) Field {
+	return func(e *zerolog.Event) *zerolog.Event {
+		return e.Dur(key, value)
+	}
+}
+
+func Err(err error) Field {
+	return func(e *zerolog.Event) *zerolog.Event {
+		return e.Err(err)
+	}
+}
+
+// Mask keeps the last four characters of value and replaces the rest with '*'.
+func Mask(value string) string {
+	const visible = 4
+
+	runes := []rune(value)
+	if len(runes) <= visible {
+		return strings.Repeat("*", len(runes))
+	}
+
+	return strings.Repeat("*", len(runes)-visible) + string(runes[len(runes)-visible:])
+}
@@ -1,65 +1,132 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"runtime/debug"
 	"time"
 
 	"github.com/rs/zerolog"
-)
+	"go.opentelemetry.io/otel/trace"
 
-var (
-	log zerolog.Logger
+	"github.com/payment-api/infrastructure/requestid"
+)
 
-	// ServerError ...
-	ServerError = "%v type=server.error"
+// Event categorises a log entry, it is written in the "type" field.
+type Event string
+
+const (
+	ServerError    Event = "server.error"
+	ServerInfo     Event = "server.info"
+	FatalError     Event = "fatal.error"
+	ConfigError    Event = "config.error"
+	HTTPError      Event = "http.error"
+	HTTPWarn       Event = "http.warn"
+	HTTPInfo       Event = "http.info"
+	GRPCError      Event = "grpc.error"
+	GRPCWarn       Event = "grpc.warn"
+	GRPCInfo       Event = "grpc.info"
+	FraudAlert     Event = "fraud.alert"
+	AuditError     Event = "audit.error"
+	WebhookDead    Event = "webhook.dead"
+	ScheduleFailed Event = "schedule.failed"
+)
 
-	// ServerInfo ...
-	ServerInfo = "%v type=server.info"
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
 
-	// FatalError ...
-	FatalError = "%v type=fatal.error"
+var log zerolog.Logger
 
-	// ConfigError ...
-	ConfigError = "%v type=config.error"
+func init() {
+	log = newLogger(os.Stdout, zerolog.InfoLevel)
+}
 
-	// HTTPError ...
-	HTTPError = "%v type=http.error"
+// Configure replaces the default json logger at info level with the level and
+// format from the configuration.
+func Configure(level, format string) error {
+	lvl := zerolog.InfoLevel
+	if level != "" {
+		parsed, err := zerolog.ParseLevel(level)
+		if err != nil {
+			return fmt.Errorf("invalid log level %q: %w", level, err)
+		}
+		lvl = parsed
+	}
+
+	var out io.Writer
+	switch format {
+	case "", FormatJSON:
+		out = os.Stdout
+	case FormatConsole:
+		out = zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
+	default:
+		return fmt.Errorf("invalid log format %q", format)
+	}
+
+	log = newLogger(out, lvl)
+
+	return nil
+}
 
-	// HTTPWarn ...
-	HTTPWarn = "%v type=http.warn"
+func newLogger(out io.Writer, level zerolog.Level) zerolog.Logger {
+	l := zerolog.New(out).
+		Level(level).
+		With().
+		Timestamp().
+		Int("pid", os.Getpid())
 
-	// HTTPInfo ...
-	HTTPInfo = "%v type=http.info"
-)
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		l = l.Str("go_version", buildInfo.GoVersion)
+	}
 
-func init() {
-	buildInfo, _ := debug.ReadBuildInfo()
+	return l.Logger()
+}
 
-	fmt.Println("Initialize Log")
+func Debug(ctx context.Context, event Event, msg string, fields ...Field) {
+	write(ctx, log.Debug(), event, msg, fields)
+}
 
-	log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}).
-		Level(zerolog.TraceLevel).
-		With().
-		Timestamp().
-		Int("pid", os.Getpid()).
-		Str("go_version", buildInfo.GoVersion).
-		Logger()
+func Info(ctx context.Context, event Event, msg string, fields ...Field) {
+	write(ctx, log.Info(), event, msg, fields)
 }
 
-func Info(format string, v ...interface{}) {
-	log.Info().Msgf(format, v...)
+func Warn(ctx context.Context, event Event, msg string, fields ...Field) {
+	write(ctx, log.Warn(), event, msg, fields)
 }
 
-func Warn(format string, v ...interface{}) {
-	log.Warn().Msgf(format, v...)
+func Error(ctx context.Context, event Event, msg string, fields ...Field) {
+	write(ctx, log.Error(), event, msg, fields)
 }
 
-func Error(format string, v ...interface{}) {
-	log.Error().Msgf(format, v...)
+func Fatal(ctx context.Context, event Event, msg string, fields ...Field) {
+	write(ctx, log.Fatal(), event, msg, fields)
 }
 
-func Fatal(format string, v ...interface{}) {
-	log.Fatal().Msgf(format, v...)
+func write(ctx context.Context, e *zerolog.Event, event Event, msg string, fields []Field) {
+	if e == nil {
+		return
+	}
+
+	e = e.Str("type", string(event))
+
+	if ctx != nil {
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+			e = e.Str("trace_id", spanContext.TraceID().String()).
+				Str("span_id", spanContext.SpanID().String())
+		}
+
+		if id := requestid.From(ctx); id != "" {
+			e = e.Str("request_id", id)
+		}
+	}
+
+	for _, field := range fields {
+		e = field(e)
+	}
+
+	e.Msg(msg)
 }
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/payment-api/infrastructure/requestid"
+)
+
+func Test_Mask(t *testing.T) {
+	scenarios := []struct {
+		description string
+		input       string
+		expected    string
+	}{
+		{description: "long value", input: "12345678900", expected: "*******8900"},
+		{description: "short value", input: "123", expected: "***"},
+		{description: "empty value", input: "", expected: ""},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			assert.Equal(t, scenario.expected, Mask(scenario.input))
+		})
+	}
+}
+
+func Test_Write(t *testing.T) {
+	var buf bytes.Buffer
+	log = newLogger(&buf, zerolog.InfoLevel)
+
+	ctx := requestid.With(context.Background(), "any-request-id")
+	ctx, span := trace.NewTracerProvider().Tracer("test").Start(ctx, "span")
+	defer span.End()
+
+	Info(ctx, HTTPInfo, "account created",
+		Str("document_number", "12345678900"),
+		Err(errors.New("any-error")),
+	)
+	Debug(ctx, HTTPInfo, "filtered by level")
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+
+	assert.Equal(t, "http.info", entry["type"])
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "account created", entry["message"])
+	assert.Equal(t, "*******8900", entry["document_number"])
+	assert.Equal(t, "any-error", entry["error"])
+	assert.Equal(t, "any-request-id", entry["request_id"])
+	assert.Equal(t, span.SpanContext().TraceID().String(), entry["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), entry["span_id"])
+}
+
+func Test_Configure(t *testing.T) {
+	assert.NoError(t, Configure("debug", FormatConsole))
+	assert.Error(t, Configure("verbose", FormatJSON))
+	assert.Error(t, Configure("info", "xml"))
+	assert.NoError(t, Configure("", ""))
+}
@@ -0,0 +1,109 @@
+// Package mcc maps ISO 18245 merchant category codes to the spending
+// categories of the api, from the ranges of the embedded categories.csv.
+package mcc
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// Other is the category of codes outside every range of the table.
+	Other = "other"
+	// Uncategorized is the category of purchases without a merchant.
+	Uncategorized = "uncategorized"
+)
+
+//go:embed categories.csv
+var table string
+
+type codeRange struct {
+	from, to string
+	category string
+}
+
+var (
+	ranges     = mustParse(table)
+	categories = names(ranges)
+)
+
+// Category returns the category of code, Uncategorized for an empty code.
+func Category(code string) string {
+	if code == "" {
+		return Uncategorized
+	}
+
+	// ranges are sorted and disjoint, codes compare as strings of 4 digits
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].to >= code })
+	if i < len(ranges) && ranges[i].from <= code {
+		return ranges[i].category
+	}
+
+	return Other
+}
+
+// Categories returns every category in alphabetical order, Other and
+// Uncategorized included.
+func Categories() []string {
+	return append([]string(nil), categories...)
+}
+
+// IsCategory reports whether name is one of Categories.
+func IsCategory(name string) bool {
+	i := sort.SearchStrings(categories, name)
+	return i < len(categories) && categories[i] == name
+}
+
+// Valid reports whether code is a merchant category code, 4 digits.
+func Valid(code string) bool {
+	if len(code) != 4 {
+		return false
+	}
+
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func mustParse(data string) []codeRange {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("mcc: read categories: %v", err))
+	}
+
+	parsed := make([]codeRange, 0, len(records))
+	for i, record := range records[1:] {
+		r := codeRange{from: record[0], to: record[1], category: record[2]}
+		if !Valid(r.from) || !Valid(r.to) || r.from > r.to || r.category == "" {
+			panic(fmt.Sprintf("mcc: invalid range on line %d", i+2))
+		}
+		if len(parsed) > 0 && parsed[len(parsed)-1].to >= r.from {
+			panic(fmt.Sprintf("mcc: range on line %d overlaps or is out of order", i+2))
+		}
+		parsed = append(parsed, r)
+	}
+
+	return parsed
+}
+
+func names(ranges []codeRange) []string {
+	seen := map[string]bool{Other: true, Uncategorized: true}
+	for _, r := range ranges {
+		seen[r.category] = true
+	}
+
+	list := make([]string, 0, len(seen))
+	for name := range seen {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+
+	return list
+}
@@ -0,0 +1,44 @@
+package mcc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Category(t *testing.T) {
+	scenarios := map[string]string{
+		"5411": "groceries",
+		"5812": "restaurants",
+		"5541": "transport",
+		"3000": "travel",
+		"3999": "travel",
+		"4511": "travel",
+		"8062": "health",
+		"6011": "cash",
+		"0001": Other,
+		"9999": Other,
+		"":     Uncategorized,
+	}
+
+	for code, expected := range scenarios {
+		assert.Equal(t, expected, Category(code), code)
+	}
+}
+
+func Test_Categories(t *testing.T) {
+	categories := Categories()
+
+	assert.IsIncreasing(t, categories)
+	assert.Contains(t, categories, Other)
+	assert.Contains(t, categories, Uncategorized)
+	assert.True(t, IsCategory("groceries"))
+	assert.False(t, IsCategory("unknown"))
+}
+
+func Test_Valid(t *testing.T) {
+	assert.True(t, Valid("5411"))
+	assert.False(t, Valid("541"))
+	assert.False(t, Valid("54111"))
+	assert.False(t, Valid("54a1"))
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/lib/pq"
+
+	"github.com/payment-api/infrastructure/exceptions"
+)
+
+// ToDomainError classifies a database/sql or lib/pq error into the exceptions
+// kind the upper layers answer with, keeping the original error wrapped.
+// Failures the request did not cause, a serialization failure or an
+// unexpected constraint, statement or data error, are server errors.
+func ToDomainError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var domainError *exceptions.Error
+	if errors.As(err, &domainError) {
+		return err
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return exceptions.EntityNotFoundError.Wrap(err)
+	}
+
+	if isUnavailable(err) {
+		return exceptions.UnavailableError.Wrap(err)
+	}
+
+	var pqError *pq.Error
+	if errors.As(err, &pqError) {
+		switch pqError.Code.Name() {
+		case "unique_violation":
+			return exceptions.ConflictError.Wrap(err)
+		case "foreign_key_violation":
+			return exceptions.EntityNotFoundError.Wrap(err)
+		}
+	}
+
+	return exceptions.PersistenceError.Wrap(err)
+}
+
+func isUnavailable(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netError net.Error
+	if errors.As(err, &netError) {
+		return true
+	}
+
+	var pqError *pq.Error
+	if errors.As(err, &pqError) {
+		switch pqError.Code.Class() {
+		// connection_exception, transaction_rollback (serialization failures and
+		// deadlocks, worth a retry), insufficient_resources and operator_intervention
+		case "08", "40", "53", "57":
+			return true
+		}
+	}
+
+	return false
+}
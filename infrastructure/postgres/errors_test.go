@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/exceptions"
+)
+
+func Test_ToDomainError(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		input         error
+		expectedError error
+	}{
+		{
+			description:   "no rows",
+			input:         sql.ErrNoRows,
+			expectedError: exceptions.EntityNotFoundError,
+		},
+		{
+			description:   "unique violation",
+			input:         &pq.Error{Code: "23505"},
+			expectedError: exceptions.ConflictError,
+		},
+		{
+			description:   "foreign key violation",
+			input:         &pq.Error{Code: "23503"},
+			expectedError: exceptions.EntityNotFoundError,
+		},
+		{
+			description:   "connection refused",
+			input:         &net.OpError{Op: "dial", Err: errors.New("connection refused")},
+			expectedError: exceptions.UnavailableError,
+		},
+		{
+			description:   "bad connection",
+			input:         driver.ErrBadConn,
+			expectedError: exceptions.UnavailableError,
+		},
+		{
+			description:   "deadline exceeded",
+			input:         context.DeadlineExceeded,
+			expectedError: exceptions.UnavailableError,
+		},
+		{
+			description:   "admin shutdown",
+			input:         &pq.Error{Code: "57P01"},
+			expectedError: exceptions.UnavailableError,
+		},
+		{
+			description:   "serialization failure",
+			input:         &pq.Error{Code: "40001"},
+			expectedError: exceptions.UnavailableError,
+		},
+		{
+			description:   "check violation",
+			input:         &pq.Error{Code: "23514"},
+			expectedError: exceptions.PersistenceError,
+		},
+		{
+			description:   "syntax error",
+			input:         &pq.Error{Code: "42601"},
+			expectedError: exceptions.PersistenceError,
+		},
+		{
+			description:   "already classified",
+			input:         exceptions.EntityNotFoundError,
+			expectedError: exceptions.EntityNotFoundError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			err := ToDomainError(scenario.input)
+
+			assert.ErrorIs(t, err, scenario.expectedError)
+			assert.ErrorIs(t, err, scenario.input)
+
+			var domainError *exceptions.Error
+			if assert.ErrorAs(t, err, &domainError) && scenario.expectedError == exceptions.PersistenceError {
+				assert.Equal(t, http.StatusInternalServerError, domainError.Status, "unclassified failures are not the client's")
+			}
+		})
+	}
+
+	assert.NoError(t, ToDomainError(nil))
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// Leader elects one replica among those sharing the database with a session
+// advisory lock on its name. The lock is held on a dedicated connection for as
+// long as the replica leads, and released by postgres when the connection or
+// the replica dies.
+type Leader struct {
+	db   *sql.DB
+	name string
+
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+// Acquire reports whether this replica leads, taking the lock when it is free.
+// A lost connection gives the leadership up.
+func (l *Leader) Acquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil {
+		if err := l.conn.PingContext(ctx); err != nil {
+			_ = l.conn.Close()
+			l.conn = nil
+			return false, err
+		}
+		return true, nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var acquired bool
+	err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext($1));`, l.name).Scan(&acquired)
+	if err != nil || !acquired {
+		_ = conn.Close()
+		return false, err
+	}
+
+	l.conn = conn
+	return true, nil
+}
+
+// Release gives the leadership up, if held.
+func (l *Leader) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+
+	defer func() { l.conn = nil }()
+
+	if _, err := l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext($1));`, l.name); err != nil {
+		_ = l.conn.Close()
+		return err
+	}
+
+	return l.conn.Close()
+}
+
+func NewLeader(db *sql.DB, name string) *Leader {
+	return &Leader{db: db, name: name}
+}
@@ -3,38 +3,266 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
+	"github.com/payment-api/infrastructure/auth"
 	"github.com/payment-api/infrastructure/exceptions"
 	"github.com/payment-api/infrastructure/logger"
 )
 
+// SchemaVersion is the latest migration under scripts/database/migrations
+// the service expects to be applied.
+const SchemaVersion = 24
+
+// Repository runs the statements of the repositories. When Tenant is set,
+// every statement runs in a transaction setting app.tenant_id to the tenant of
+// its context, for the row level security policies to apply, or
+// app.tenant_bypass for internal callers acting for auth.AnyTenant. Without
+// either the policies match no row.
 type Repository struct {
-	DB *sql.DB
+	DB     *sql.DB
+	Tenant func(ctx context.Context) string
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type txKey struct{}
+
+// Tx is a transaction scoped to the tenant of its context. A Tx joining the
+// transaction of its context leaves committing and rolling back to it.
+type Tx struct {
+	*sql.Tx
+	joined bool
+}
+
+func (t *Tx) Commit() error {
+	if t.joined {
+		return nil
+	}
+
+	return t.Tx.Commit()
+}
+
+func (t *Tx) Rollback() error {
+	if t.joined {
+		return nil
+	}
+
+	return t.Tx.Rollback()
+}
+
+// Transaction starts a transaction scoped to the tenant of ctx and returns it
+// with the context the statements of every repository join it through.
+func (r *Repository) Transaction(ctx context.Context) (context.Context, *Tx, error) {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	return context.WithValue(ctx, txKey{}, tx.Tx), tx, nil
+}
+
+// Snapshot starts a read only repeatable read transaction scoped to the
+// tenant of ctx and returns it with the context the statements of every
+// repository join it through, they all see the database as of the first one.
+func (r *Repository) Snapshot(ctx context.Context) (context.Context, *Tx, error) {
+	tx, err := r.beginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	return context.WithValue(ctx, txKey{}, tx.Tx), tx, nil
 }
 
-func (r *Repository) GetById(query string, id string, args ...interface{}) error {
-	row := r.DB.QueryRow(query, id)
+// Savepoint sets a savepoint in the transaction of ctx and returns the end of
+// it. Ended with rollback, or once a failed statement aborted the transaction,
+// the statements run since are rolled back and the transaction carries on,
+// otherwise they are kept. end reports whether they were rolled back. Outside
+// of a transaction there is nothing to roll back to.
+func (r *Repository) Savepoint(ctx context.Context) (end func(rollback bool) (bool, error), err error) {
+	tx, ok := ctx.Value(txKey{}).(*sql.Tx)
+	if !ok {
+		return func(bool) (bool, error) { return false, nil }, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT mutation;`); err != nil {
+		return nil, err
+	}
+
+	return func(rollback bool) (bool, error) {
+		if !rollback {
+			_, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT mutation;`)
+			var pqError *pq.Error
+			if !errors.As(err, &pqError) || pqError.Code.Name() != "in_failed_sql_transaction" {
+				return false, err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT mutation;`); err != nil {
+			return false, err
+		}
+		_, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT mutation;`)
+		return true, err
+	}, nil
+}
+
+// BeginTx starts a transaction scoped to the tenant of ctx, or joins the one
+// of ctx.
+func (r *Repository) BeginTx(ctx context.Context) (*Tx, error) {
+	return r.beginTx(ctx, nil)
+}
+
+// BeginSnapshotTx starts a repeatable read transaction scoped to the tenant
+// of ctx, its statements all see the database as it was at its first one.
+// Joining the transaction of ctx, they see it as that one does.
+func (r *Repository) BeginSnapshotTx(ctx context.Context) (*Tx, error) {
+	return r.beginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+}
+
+func (r *Repository) beginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return &Tx{Tx: tx, joined: true}, nil
+	}
+
+	tx, err := r.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Tenant == nil {
+		return &Tx{Tx: tx}, nil
+	}
+
+	tenant, bypass := r.Tenant(ctx), "off"
+	if tenant == auth.AnyTenant {
+		tenant, bypass = "", "on"
+	}
 
-	err := row.Scan(args...)
+	_, err = tx.ExecContext(ctx, `SELECT set_config('app.tenant_id', $1, true), set_config('app.tenant_bypass', $2, true);`, tenant, bypass)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	return &Tx{Tx: tx}, nil
+}
+
+// scoped runs fn in the transaction of ctx, or else on the database, or in a
+// transaction scoped to the tenant of ctx when Tenant is set.
+func (r *Repository) scoped(ctx context.Context, fn func(querier) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(tx)
+	}
+
+	if r.Tenant == nil {
+		return fn(r.DB)
+	}
+
+	tx, err := r.BeginTx(ctx)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	return nil
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.DB.PingContext(ctx)
+}
+
+func (r *Repository) MigrationVersion(ctx context.Context) (int, error) {
+	var version sql.NullInt64
+
+	err := r.DB.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations;`).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(version.Int64), nil
 }
 
-func (r *Repository) Push(query string, args ...interface{}) error {
-	row, err := r.DB.Exec(query, args...)
+func (r *Repository) CheckMigrations(ctx context.Context) error {
+	version, err := r.MigrationVersion(ctx)
 	if err != nil {
 		return err
 	}
 
-	count, err := row.RowsAffected()
+	if version < SchemaVersion {
+		return fmt.Errorf("schema version %d is behind expected version %d", version, SchemaVersion)
+	}
+
+	return nil
+}
+
+func (r *Repository) GetById(ctx context.Context, query string, id string, args ...interface{}) error {
+	err := r.scoped(ctx, func(q querier) error {
+		return q.QueryRowContext(ctx, query, id).Scan(args...)
+	})
+	if err != nil {
+		return ToDomainError(err)
+	}
+
+	return nil
+}
+
+// QueryRow scans the single row of query into dest, as in INSERT ... RETURNING.
+func (r *Repository) QueryRow(ctx context.Context, query string, args []interface{}, dest ...interface{}) error {
+	err := r.scoped(ctx, func(q querier) error {
+		return q.QueryRowContext(ctx, query, args...).Scan(dest...)
+	})
 	if err != nil {
+		return ToDomainError(err)
+	}
+
+	return nil
+}
+
+// Query calls scan for every row of query.
+func (r *Repository) Query(ctx context.Context, query string, args []interface{}, scan func(*sql.Rows) error) error {
+	return ToDomainError(r.scoped(ctx, func(q querier) error {
+		rows, err := q.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := scan(rows); err != nil {
+				return err
+			}
+		}
+
+		return rows.Err()
+	}))
+}
+
+func (r *Repository) Push(ctx context.Context, query string, args ...interface{}) error {
+	var count int64
+	err := r.scoped(ctx, func(q querier) error {
+		row, err := q.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		count, err = row.RowsAffected()
 		return err
+	})
+	if err != nil {
+		return ToDomainError(err)
 	}
 
 	if count == 0 {
@@ -47,7 +275,7 @@ func (r *Repository) Push(query string, args ...interface{}) error {
 func connectPostgresDB(ctx context.Context, dsn string) (*sql.DB, error) {
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
-		logger.Error(logger.ConfigError, err)
+		logger.Error(ctx, logger.ConfigError, "cannot open postgres connection", logger.Err(err))
 
 		return nil, err
 	}
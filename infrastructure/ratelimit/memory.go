@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store evicts its idle buckets.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	// fullAt is when the bucket refills up to its burst, from then on it is
+	// the same as a missing bucket and can be evicted.
+	fullAt time.Time
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]bucket
+	sweptAt time.Time
+}
+
+func (m *memoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = bucket{tokens: float64(limit.Burst), updatedAt: now}
+	}
+
+	tokens, result := take(b.tokens, b.updatedAt, now, limit)
+	m.buckets[key] = bucket{tokens: tokens, updatedAt: now, fullAt: now.Add(result.Reset)}
+
+	return result, nil
+}
+
+// sweep evicts the buckets refilled up to their burst, at most once per
+// sweepInterval, so keys taken once do not pile up.
+func (m *memoryStore) sweep(now time.Time) {
+	if now.Sub(m.sweptAt) < sweepInterval {
+		return
+	}
+	m.sweptAt = now
+
+	for key, b := range m.buckets {
+		if !now.Before(b.fullAt) {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{buckets: map[string]bucket{}}
+}
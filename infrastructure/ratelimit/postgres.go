@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+type postgresStore struct {
+	db *sql.DB
+
+	mu      sync.Mutex
+	sweptAt time.Time
+}
+
+// Take locks the bucket row so concurrent replicas consume tokens one at a time.
+func (p *postgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	if err := p.sweep(ctx, now); err != nil {
+		return Result{}, err
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO rate_limit_buckets (key, tokens, updated_at, full_at)
+        VALUES ($1, $2, $3, $3)
+        ON CONFLICT (key) DO NOTHING;
+    `, key, float64(limit.Burst), now)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var (
+		tokens    float64
+		updatedAt time.Time
+	)
+
+	err = tx.QueryRowContext(ctx, `
+	SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE;
+    `, key).Scan(&tokens, &updatedAt)
+	if err != nil {
+		return Result{}, err
+	}
+
+	tokens, result := take(tokens, updatedAt, now, limit)
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3, full_at = $4 WHERE key = $1;
+    `, key, tokens, now, now.Add(result.Reset))
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result, tx.Commit()
+}
+
+// sweep deletes the buckets refilled up to their burst, at most once per
+// sweepInterval on each replica.
+func (p *postgresStore) sweep(ctx context.Context, now time.Time) error {
+	p.mu.Lock()
+	if now.Sub(p.sweptAt) < sweepInterval {
+		p.mu.Unlock()
+		return nil
+	}
+	p.sweptAt = now
+	p.mu.Unlock()
+
+	_, err := p.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE full_at <= $1;`, now)
+
+	return err
+}
+
+func NewPostgresStore(db *sql.DB) Store {
+	return &postgresStore{db: db}
+}
@@ -0,0 +1,170 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/payment-api/config"
+)
+
+// Limit is a token bucket refilled at Rate tokens per second up to Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// Store keeps the buckets, the in memory one limits a single replica and the
+// postgres one is shared by every replica.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// take refills a bucket holding tokens since updatedAt and consumes one token
+// when available, returning the tokens left.
+func take(tokens float64, updatedAt, now time.Time, limit Limit) (float64, Result) {
+	elapsed := now.Sub(updatedAt).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	tokens = math.Min(float64(limit.Burst), tokens+elapsed*limit.Rate)
+
+	result := Result{Limit: limit.Burst}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = seconds((float64(limit.Burst) - tokens) / limit.Rate)
+
+	return tokens, result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s)) * time.Second
+}
+
+type Limiter struct {
+	store   Store
+	client  Limit
+	account Limit
+	clients map[string]Limit
+	now     func() time.Time
+}
+
+// Client takes a token from the bucket of an api client, using its override
+// when one is configured. Overrides are matched case insensitively, as the
+// config loader lowercases their client ids.
+func (l *Limiter) Client(ctx context.Context, clientID string) (Result, bool, error) {
+	limit, ok := l.clients[strings.ToLower(clientID)]
+	if !ok {
+		limit = l.client
+	}
+
+	return l.take(ctx, "client:"+clientID, limit)
+}
+
+// Account takes a token from the bucket of an account for an api client, each
+// client has its own so one cannot exhaust the limit of another.
+func (l *Limiter) Account(ctx context.Context, clientID, accountID string) (Result, bool, error) {
+	return l.take(ctx, "account:"+clientID+":"+accountID, l.account)
+}
+
+// Card limits the requests naming a card token alone with the account limit,
+// as the account of the card is only known once the card is resolved.
+func (l *Limiter) Card(ctx context.Context, clientID, token string) (Result, bool, error) {
+	return l.take(ctx, "card:"+clientID+":"+token, l.account)
+}
+
+// Request takes a token from the bucket of clientID and from the one it holds
+// for accountID, or for cardToken when the request names a card alone, both
+// left empty to skip it. The most restrictive result is reported, allowed
+// only when every bucket allows, and false as second value when every limit
+// is disabled. Store failures let the request through, they are returned for
+// the caller to log.
+func (l *Limiter) Request(ctx context.Context, clientID, accountID, cardToken string) (Result, bool, error) {
+	var results []Result
+	var errs []error
+
+	result, enabled, err := l.Client(ctx, clientID)
+	if err != nil {
+		errs = append(errs, err)
+	} else if enabled {
+		results = append(results, result)
+	}
+
+	result, enabled, err = Result{}, false, nil
+	switch {
+	case accountID != "":
+		result, enabled, err = l.Account(ctx, clientID, accountID)
+	case cardToken != "":
+		result, enabled, err = l.Card(ctx, clientID, cardToken)
+	}
+
+	if err != nil {
+		errs = append(errs, err)
+	} else if enabled {
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		return Result{}, false, errors.Join(errs...)
+	}
+
+	reported := results[0]
+	reported.Allowed = true
+	for _, r := range results {
+		if r.Remaining < reported.Remaining || (!r.Allowed && r.RetryAfter > reported.RetryAfter) {
+			reported.Limit, reported.Remaining, reported.RetryAfter, reported.Reset = r.Limit, r.Remaining, r.RetryAfter, r.Reset
+		}
+		if !r.Allowed {
+			reported.Allowed = false
+		}
+	}
+
+	return reported, true, errors.Join(errs...)
+}
+
+// take reports false as second value when the limit is disabled.
+func (l *Limiter) take(ctx context.Context, key string, limit Limit) (Result, bool, error) {
+	if !limit.Enabled() {
+		return Result{}, false, nil
+	}
+
+	result, err := l.store.Take(ctx, key, limit, l.now())
+
+	return result, true, err
+}
+
+func NewLimiter(cfg config.RateLimit, store Store) *Limiter {
+	clients := make(map[string]Limit, len(cfg.Clients))
+	for clientID, limit := range cfg.Clients {
+		clients[strings.ToLower(clientID)] = Limit{Rate: limit.Rate, Burst: limit.Burst}
+	}
+
+	return &Limiter{
+		store:   store,
+		client:  Limit{Rate: cfg.Client.Rate, Burst: cfg.Client.Burst},
+		account: Limit{Rate: cfg.Account.Rate, Burst: cfg.Account.Burst},
+		clients: clients,
+		now:     time.Now,
+	}
+}
@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/config"
+)
+
+func Test_MemoryStoreTake(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	limit := Limit{Rate: 1, Burst: 2}
+	now := time.Now()
+
+	result, err := store.Take(ctx, "key", limit, now)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+
+	result, _ = store.Take(ctx, "key", limit, now)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, 2*time.Second, result.Reset)
+
+	result, _ = store.Take(ctx, "key", limit, now)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+
+	result, _ = store.Take(ctx, "other-key", limit, now)
+	assert.True(t, result.Allowed, "buckets are independent")
+
+	result, _ = store.Take(ctx, "key", limit, now.Add(time.Second))
+	assert.True(t, result.Allowed, "one token refilled after a second")
+
+	result, _ = store.Take(ctx, "key", limit, now.Add(time.Hour))
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining, "refill is capped at the burst")
+}
+
+func Test_MemoryStoreEviction(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore().(*memoryStore)
+	limit := Limit{Rate: 1, Burst: 2}
+	now := time.Now()
+
+	_, _ = store.Take(ctx, "idle", limit, now)
+	_, _ = store.Take(ctx, "busy", limit, now)
+	_, _ = store.Take(ctx, "busy", limit, now)
+	assert.Len(t, store.buckets, 2)
+
+	_, _ = store.Take(ctx, "busy", limit, now.Add(time.Second))
+	assert.Len(t, store.buckets, 2, "buckets are kept until the next sweep")
+
+	later := now.Add(sweepInterval)
+	result, _ := store.Take(ctx, "other", limit, later)
+	assert.True(t, result.Allowed)
+	assert.NotContains(t, store.buckets, "idle", "refilled buckets are evicted")
+	assert.NotContains(t, store.buckets, "busy")
+	assert.Contains(t, store.buckets, "other")
+
+	_, _ = store.Take(ctx, "slow", Limit{Rate: 0.001, Burst: 1}, later)
+	_, _ = store.Take(ctx, "other", limit, later.Add(sweepInterval))
+	assert.Contains(t, store.buckets, "slow", "buckets still refilling are kept")
+	result, _ = store.Take(ctx, "slow", Limit{Rate: 0.001, Burst: 1}, later.Add(sweepInterval))
+	assert.False(t, result.Allowed)
+}
+
+func Test_LimiterClientOverrideCase(t *testing.T) {
+	dir := t.TempDir()
+	yml := `
+rate_limit:
+  client:
+    rate: 1
+    burst: 5
+  clients:
+    Card-Network:
+      rate: 1
+      burst: 1
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "local.yml"), []byte(yml), 0o600))
+
+	cfg, err := config.LoadAppConfig(dir)
+	assert.NoError(t, err)
+
+	limiter := NewLimiter(cfg.RateLimit, NewMemoryStore())
+	limiter.now = func() time.Time { return time.Unix(0, 0) }
+
+	for _, clientID := range []string{"Card-Network", "card-network"} {
+		limiter.store = NewMemoryStore()
+
+		result, _, err := limiter.Client(context.Background(), clientID)
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+
+		result, _, _ = limiter.Client(context.Background(), clientID)
+		assert.False(t, result.Allowed, "%s gets the override of its client id", clientID)
+	}
+
+	result, _, _ := limiter.Client(context.Background(), "other-client")
+	assert.Equal(t, 4, result.Remaining, "other clients keep the default limit")
+}
@@ -0,0 +1,49 @@
+package signature
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NonceStore remembers nonces for the replay window.
+type NonceStore interface {
+	// Add records nonce for ttl and reports false when it was already recorded.
+	Add(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+type memoryNonceStore struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+	sweepAt time.Time
+}
+
+func (m *memoryNonceStore) Add(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+
+	if now.After(m.sweepAt) {
+		for key, expiresAt := range m.entries {
+			if now.After(expiresAt) {
+				delete(m.entries, key)
+			}
+		}
+		m.sweepAt = now.Add(ttl)
+	}
+
+	if expiresAt, ok := m.entries[nonce]; ok && now.Before(expiresAt) {
+		return false, nil
+	}
+
+	m.entries[nonce] = now.Add(ttl)
+
+	return true, nil
+}
+
+// NewMemoryNonceStore keeps nonces in process, replicas behind a load balancer
+// each keep their own.
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{entries: map[string]time.Time{}}
+}
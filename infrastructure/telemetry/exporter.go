@@ -0,0 +1,49 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+var (
+	ExporterNotInitializedError = errors.New("trace exporter not initialized")
+
+	exporter *healthExporter
+)
+
+// healthExporter keeps the outcome of the last export so readiness can report
+// a collector that stopped accepting spans.
+type healthExporter struct {
+	trace.SpanExporter
+
+	mu  sync.RWMutex
+	err error
+}
+
+func (e *healthExporter) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+
+	e.mu.Lock()
+	e.err = err
+	e.mu.Unlock()
+
+	return err
+}
+
+func (e *healthExporter) lastError() error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.err
+}
+
+func ExporterHealth(_ context.Context) error {
+	if exporter == nil {
+		return ExporterNotInitializedError
+	}
+
+	return exporter.lastError()
+}
@@ -8,8 +8,10 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+const ServiceName = "payment-api"
+
 func Span(ctx context.Context, name string, kind trace.SpanKind) (context.Context, trace.Span) {
-	ctx, span := otel.GetTracerProvider().Tracer(ctx.Value("service-name").(string)).Start(
+	ctx, span := otel.GetTracerProvider().Tracer(serviceName(ctx)).Start(
 		ctx, name, trace.WithSpanKind(kind),
 	)
 
@@ -20,3 +22,13 @@ func ErrorSpan(span trace.Span, err error) {
 	span.SetStatus(codes.Error, err.Error())
 	span.RecordError(err)
 }
+
+// serviceName reads the tracer name set on the root context, request scoped
+// contexts created by gin fall back to ServiceName.
+func serviceName(ctx context.Context) string {
+	if name, ok := ctx.Value("service-name").(string); ok {
+		return name
+	}
+
+	return ServiceName
+}
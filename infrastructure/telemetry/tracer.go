@@ -23,10 +23,12 @@ func NewTraceProvider(ctx context.Context, cfg config.Configuration) (*trace.Tra
 		return nil, err
 	}
 
+	exporter = &healthExporter{SpanExporter: traceExporter}
+
 	traceProvider := trace.NewTracerProvider(
 		trace.WithSampler(trace.AlwaysSample()),
 		trace.WithSpanProcessor(
-			trace.NewBatchSpanProcessor(traceExporter),
+			trace.NewBatchSpanProcessor(exporter),
 		),
 		trace.WithResource(
 			resource.NewWithAttributes(
@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/payment-api/infrastructure/signature"
+)
+
+const (
+	EventHeader    = "X-Webhook-Event"
+	DeliveryHeader = "X-Webhook-Delivery"
+
+	secretPrefix = "whsec_"
+)
+
+// ForbiddenAddressError is returned for endpoints resolving to a loopback,
+// private, link-local or otherwise internal address.
+var ForbiddenAddressError = errors.New("webhook endpoint resolves to a forbidden address")
+
+// sharedAddressSpace is the carrier grade NAT range, internal as the private ones.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// Sender posts webhook payloads signed as partner requests are, with the
+// delivery id as nonce, so receivers verify them with signature.Sign. Unless
+// insecure, for local development, endpoints must be https and are never
+// dialed on an internal address, whatever their host name resolves to.
+type Sender struct {
+	client   *http.Client
+	insecure bool
+	now      func() time.Time
+}
+
+// Send posts payload to endpoint and returns the status code answered, an
+// error only when no response was received.
+func (s *Sender) Send(ctx context.Context, endpoint, secret, deliveryID, eventType string, payload []byte) (int, error) {
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		return 0, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventHeader, eventType)
+	request.Header.Set(DeliveryHeader, deliveryID)
+	request.Header.Set(signature.TimestampHeader, timestamp)
+	request.Header.Set(signature.NonceHeader, deliveryID)
+	request.Header.Set(signature.Header, signature.Sign(secret, http.MethodPost, target.EscapedPath(), timestamp, deliveryID, payload))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	// drain a bounded part of the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	return response.StatusCode, nil
+}
+
+// GenerateSecret returns a new random signing secret for a subscription.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// ValidateURL accepts absolute https endpoints on public hosts, http ones and
+// internal hosts too when the sender is insecure. Host names are checked
+// again once resolved, when dialed.
+func (s *Sender) ValidateURL(endpoint string) error {
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+
+	if s.insecure {
+		if (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return fmt.Errorf("url must be an absolute http or https url")
+		}
+		return nil
+	}
+
+	if target.Scheme != "https" || target.Host == "" {
+		return fmt.Errorf("url must be an absolute https url")
+	}
+
+	host := strings.ToLower(target.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ForbiddenAddressError
+	}
+
+	if ip := net.ParseIP(host); ip != nil && forbidden(ip) {
+		return ForbiddenAddressError
+	}
+
+	return nil
+}
+
+// forbidden reports whether ip is not a public unicast address, cloud
+// metadata endpoints being link-local.
+func forbidden(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// control refuses connections to forbidden addresses, checked on the address
+// actually dialed so redirects and DNS rebinding cannot reach them either.
+func control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || forbidden(ip) {
+		return ForbiddenAddressError
+	}
+
+	return nil
+}
+
+func NewSender(timeout time.Duration, insecure bool) *Sender {
+	if insecure {
+		return &Sender{client: &http.Client{Timeout: timeout}, insecure: true, now: time.Now}
+	}
+
+	dialer := &net.Dialer{Timeout: timeout, Control: control}
+
+	client := &http.Client{
+		Timeout: timeout,
+		// a proxy would be dialed in place of the endpoint
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if request.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to a non https url")
+			}
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+
+	return &Sender{client: client, now: time.Now}
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/signature"
+)
+
+func Test_SenderSignsPayload(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+
+	verifier := signature.NewVerifier(map[string]string{"payment-api": secret}, time.Minute, signature.NewMemoryNonceStore())
+
+	var (
+		verifyErr error
+		received  http.Header
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = r.Header
+
+		verifyErr = verifier.Verify(r.Context(), "payment-api", r.Method, r.URL.Path,
+			r.Header.Get(signature.TimestampHeader), r.Header.Get(signature.NonceHeader), r.Header.Get(signature.Header), body)
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	status, err := NewSender(time.Second, true).Send(context.Background(), receiver.URL+"/hooks/payments", secret, "any-delivery-id", "account.created", []byte(`{"id":"any-event-id"}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, status)
+	assert.NoError(t, verifyErr)
+	assert.Equal(t, "account.created", received.Get(EventHeader))
+	assert.Equal(t, "any-delivery-id", received.Get(DeliveryHeader))
+
+	_, _ = NewSender(time.Second, true).Send(context.Background(), receiver.URL+"/hooks/payments", "other-secret", "other-delivery-id", "account.created", []byte(`{}`))
+	assert.ErrorIs(t, verifyErr, signature.InvalidSignatureError)
+}
+
+func Test_SenderUnreachable(t *testing.T) {
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	receiver.Close()
+
+	status, err := NewSender(time.Second, true).Send(context.Background(), receiver.URL, "any-secret", "any-delivery-id", "account.created", []byte(`{}`))
+
+	assert.Error(t, err)
+	assert.Zero(t, status)
+}
+
+func Test_SenderRefusesInternalAddresses(t *testing.T) {
+	received := false
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	status, err := NewSender(time.Second, false).Send(context.Background(), receiver.URL, "any-secret", "any-delivery-id", "account.created", []byte(`{}`))
+
+	assert.ErrorIs(t, err, ForbiddenAddressError)
+	assert.Zero(t, status)
+	assert.False(t, received)
+}
+
+func Test_ValidateURL(t *testing.T) {
+	sender := NewSender(time.Second, false)
+
+	assert.NoError(t, sender.ValidateURL("https://merchant.test/hooks"))
+	assert.NoError(t, sender.ValidateURL("https://93.184.216.34/hooks"))
+	assert.Error(t, sender.ValidateURL("http://merchant.test/hooks"))
+	assert.Error(t, sender.ValidateURL("ftp://merchant.test/hooks"))
+	assert.Error(t, sender.ValidateURL("/hooks"))
+	assert.Error(t, sender.ValidateURL("://bad"))
+
+	for _, internal := range []string{
+		"https://localhost/hooks",
+		"https://127.0.0.1/hooks",
+		"https://[::1]/hooks",
+		"https://10.0.0.8/hooks",
+		"https://192.168.1.10:8443/hooks",
+		"https://169.254.169.254/latest/meta-data",
+		"https://100.64.0.1/hooks",
+		"https://0.0.0.0/hooks",
+		"https://[fd00:ec2::254]/hooks",
+	} {
+		assert.ErrorIs(t, sender.ValidateURL(internal), ForbiddenAddressError, internal)
+	}
+
+	insecure := NewSender(time.Second, true)
+	assert.NoError(t, insecure.ValidateURL("http://localhost:9000/hooks"))
+	assert.Error(t, insecure.ValidateURL("ftp://merchant.test/hooks"))
+}
@@ -0,0 +1,137 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/payment-api/internal/domain"
+)
+
+const camtHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <GrpHdr>
+      <MsgId>%[1]s</MsgId>
+      <CreDtTm>%[2]s</CreDtTm>
+    </GrpHdr>
+    <Stmt>
+      <Id>%[1]s</Id>
+      <CreDtTm>%[2]s</CreDtTm>
+      <FrToDt>
+        <FrDtTm>%[3]s</FrDtTm>
+        <ToDtTm>%[4]s</ToDtTm>
+      </FrToDt>
+      <Acct>
+        <Id>
+          <Othr>
+            <Id>%[5]s</Id>
+          </Othr>
+        </Id>
+        <Ccy>%[6]s</Ccy>
+      </Acct>
+%[7]s%[8]s`
+
+const camtBalance = `      <Bal>
+        <Tp>
+          <CdOrPrtry>
+            <Cd>%s</Cd>
+          </CdOrPrtry>
+        </Tp>
+        <Amt Ccy="%s">%s</Amt>
+        <CdtDbtInd>%s</CdtDbtInd>
+        <Dt>
+          <DtTm>%s</DtTm>
+        </Dt>
+      </Bal>
+`
+
+const camtEntry = `      <Ntry>
+        <NtryRef>%[1]s</NtryRef>
+        <Amt Ccy="%[2]s">%[3]s</Amt>
+        <CdtDbtInd>%[4]s</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt>
+          <DtTm>%[5]s</DtTm>
+        </BookgDt>
+        <ValDt>
+          <DtTm>%[5]s</DtTm>
+        </ValDt>
+        <BkTxCd>
+          <Prtry>
+            <Cd>%[6]s</Cd>
+          </Prtry>
+        </BkTxCd>
+      </Ntry>
+`
+
+const camtFooter = `    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+// Camt053 writes an ISO 20022 camt.053.001.02 bank to customer statement,
+// with the opening and closing booked balances of the statement.
+type Camt053 struct{}
+
+func (Camt053) ContentType() string {
+	return "application/xml"
+}
+
+func (Camt053) Extension() string {
+	return "xml"
+}
+
+func (Camt053) Begin(w io.Writer, statement domain.Statement) error {
+	currency := escape(statement.Currency)
+
+	_, err := fmt.Fprintf(w, camtHeader,
+		escape(statement.Id),
+		camtDate(statement.CreatedAt),
+		camtDate(statement.From),
+		camtDate(statement.To),
+		escape(statement.Account.Id),
+		currency,
+		camtBal("OPBD", currency, statement.OpeningBalance, statement.From),
+		camtBal("CLBD", currency, statement.ClosingBalance, statement.To),
+	)
+	return err
+}
+
+func (Camt053) Write(w io.Writer, statement domain.Statement, transaction domain.Transaction) error {
+	signed := transaction.SignedAmount()
+
+	_, err := fmt.Fprintf(w, camtEntry,
+		strconv.Itoa(transaction.Id),
+		escape(statement.Currency),
+		amount(math.Abs(signed)),
+		camtIndicator(signed),
+		camtDate(transaction.EventDate),
+		transaction.OperationType.String(),
+	)
+	return err
+}
+
+func (Camt053) End(w io.Writer, _ domain.Statement) error {
+	_, err := io.WriteString(w, camtFooter)
+	return err
+}
+
+func camtBal(code, currency string, balance float64, at time.Time) string {
+	return fmt.Sprintf(camtBalance, code, currency, amount(math.Abs(balance)), camtIndicator(balance), camtDate(at))
+}
+
+// camtIndicator tells credits from debits, amounts being always positive.
+func camtIndicator(value float64) string {
+	if value < 0 {
+		return "DBIT"
+	}
+
+	return "CRDT"
+}
+
+func camtDate(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
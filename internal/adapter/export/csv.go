@@ -0,0 +1,49 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/payment-api/internal/domain"
+)
+
+// CSV writes one row per transaction, amounts are signed, negative for debits.
+type CSV struct{}
+
+func (CSV) ContentType() string {
+	return "text/csv"
+}
+
+func (CSV) Extension() string {
+	return "csv"
+}
+
+func (CSV) Begin(w io.Writer, _ domain.Statement) error {
+	return writeCSV(w, []string{"id", "event_date", "operation_type", "amount", "currency"})
+}
+
+func (CSV) Write(w io.Writer, statement domain.Statement, transaction domain.Transaction) error {
+	return writeCSV(w, []string{
+		strconv.Itoa(transaction.Id),
+		transaction.EventDate.UTC().Format(time.RFC3339),
+		transaction.OperationType.String(),
+		amount(transaction.SignedAmount()),
+		statement.Currency,
+	})
+}
+
+func (CSV) End(io.Writer, domain.Statement) error {
+	return nil
+}
+
+func writeCSV(w io.Writer, record []string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(record); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
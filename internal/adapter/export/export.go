@@ -0,0 +1,54 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/payment-api/internal/domain"
+)
+
+// Exporter writes a statement in one file format. Transactions are written
+// one at a time between Begin and End, so a statement is never held in memory.
+type Exporter interface {
+	ContentType() string
+	Extension() string
+	Begin(w io.Writer, statement domain.Statement) error
+	Write(w io.Writer, statement domain.Statement, transaction domain.Transaction) error
+	End(w io.Writer, statement domain.Statement) error
+}
+
+var exporters = map[string]Exporter{
+	"csv":     CSV{},
+	"ofx":     OFX{},
+	"camt053": Camt053{},
+}
+
+// For returns the exporter of format.
+func For(format string) (Exporter, bool) {
+	exporter, ok := exporters[format]
+	return exporter, ok
+}
+
+// Formats lists the supported formats in alphabetical order.
+func Formats() []string {
+	formats := make([]string, 0, len(exporters))
+	for format := range exporters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+
+	return formats
+}
+
+func amount(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+func escape(value string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files under testdata")
+
+func statement() (domain.Statement, []domain.Transaction) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	return domain.Statement{
+		Id:             "5f0c6a7e9b2d4c1a8e3f7b6d2a1c9e0f",
+		Account:        domain.Account{Id: "8f14e45f-ceea-467f-a8f0-7d1b1c0e2b3a", DocumentNumber: "12345678900"},
+		Currency:       "BRL",
+		BankID:         "0001",
+		From:           from,
+		To:             from.AddDate(0, 1, 0),
+		OpeningBalance: 150,
+		ClosingBalance: -35.25,
+		CreatedAt:      time.Date(2024, 2, 1, 9, 30, 0, 0, time.UTC),
+	}, []domain.Transaction{
+		{Id: 10, OperationType: operation.CASH_PURCHASES, Amount: 50.5, EventDate: from.Add(26 * time.Hour)},
+		{Id: 11, OperationType: operation.INSTALLMENT_PURCHASES, Amount: 23.45, EventDate: from.Add(50 * time.Hour)},
+		{Id: 12, OperationType: operation.WITHDRAW, Amount: 200, EventDate: from.Add(74 * time.Hour)},
+		{Id: 13, OperationType: operation.PAYMENT, Amount: 88.7, EventDate: from.Add(98 * time.Hour)},
+	}
+}
+
+func Test_Exporters(t *testing.T) {
+	for _, format := range Formats() {
+		t.Run(format, func(t *testing.T) {
+			exporter, ok := For(format)
+			assert.True(t, ok)
+
+			s, transactions := statement()
+
+			var out bytes.Buffer
+			assert.NoError(t, exporter.Begin(&out, s))
+			for _, transaction := range transactions {
+				assert.NoError(t, exporter.Write(&out, s, transaction))
+			}
+			assert.NoError(t, exporter.End(&out, s))
+
+			golden := filepath.Join("testdata", "statement."+exporter.Extension())
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, out.Bytes(), 0o644))
+			}
+
+			expected, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), out.String())
+		})
+	}
+}
+
+func Test_ExporterFor(t *testing.T) {
+	_, ok := For("pdf")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"camt053", "csv", "ofx"}, Formats())
+}
+
+func Test_ExportersEscapeText(t *testing.T) {
+	s, _ := statement()
+	s.Account.Id = `a<b>&"c"`
+
+	for _, format := range []string{"ofx", "camt053"} {
+		exporter, _ := For(format)
+
+		var out bytes.Buffer
+		assert.NoError(t, exporter.Begin(&out, s))
+		assert.Contains(t, out.String(), "a&lt;b&gt;&amp;&#34;c&#34;", format)
+	}
+}
@@ -0,0 +1,119 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
+)
+
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <SIGNONMSGSRSV1>
+    <SONRS>
+      <STATUS>
+        <CODE>0</CODE>
+        <SEVERITY>INFO</SEVERITY>
+      </STATUS>
+      <DTSERVER>%[1]s</DTSERVER>
+      <LANGUAGE>ENG</LANGUAGE>
+    </SONRS>
+  </SIGNONMSGSRSV1>
+  <BANKMSGSRSV1>
+    <STMTTRNRS>
+      <TRNUID>%[2]s</TRNUID>
+      <STATUS>
+        <CODE>0</CODE>
+        <SEVERITY>INFO</SEVERITY>
+      </STATUS>
+      <STMTRS>
+        <CURDEF>%[3]s</CURDEF>
+        <BANKACCTFROM>
+          <BANKID>%[4]s</BANKID>
+          <ACCTID>%[5]s</ACCTID>
+          <ACCTTYPE>CHECKING</ACCTTYPE>
+        </BANKACCTFROM>
+        <BANKTRANLIST>
+          <DTSTART>%[6]s</DTSTART>
+          <DTEND>%[7]s</DTEND>
+`
+
+const ofxTransaction = `          <STMTTRN>
+            <TRNTYPE>%s</TRNTYPE>
+            <DTPOSTED>%s</DTPOSTED>
+            <TRNAMT>%s</TRNAMT>
+            <FITID>%s</FITID>
+            <NAME>%s</NAME>
+          </STMTTRN>
+`
+
+const ofxFooter = `        </BANKTRANLIST>
+        <LEDGERBAL>
+          <BALAMT>%s</BALAMT>
+          <DTASOF>%s</DTASOF>
+        </LEDGERBAL>
+      </STMTRS>
+    </STMTTRNRS>
+  </BANKMSGSRSV1>
+</OFX>
+`
+
+// OFX writes an OFX 2.2 bank statement, the ledger balance being the closing
+// balance of the statement.
+type OFX struct{}
+
+func (OFX) ContentType() string {
+	return "application/x-ofx"
+}
+
+func (OFX) Extension() string {
+	return "ofx"
+}
+
+func (OFX) Begin(w io.Writer, statement domain.Statement) error {
+	_, err := fmt.Fprintf(w, ofxHeader,
+		ofxDate(statement.CreatedAt),
+		escape(statement.Id),
+		escape(statement.Currency),
+		escape(statement.BankID),
+		escape(statement.Account.Id),
+		ofxDate(statement.From),
+		ofxDate(statement.To),
+	)
+	return err
+}
+
+func (OFX) Write(w io.Writer, _ domain.Statement, transaction domain.Transaction) error {
+	_, err := fmt.Fprintf(w, ofxTransaction,
+		ofxType(transaction.OperationType),
+		ofxDate(transaction.EventDate),
+		amount(transaction.SignedAmount()),
+		strconv.Itoa(transaction.Id),
+		transaction.OperationType.String(),
+	)
+	return err
+}
+
+func (OFX) End(w io.Writer, statement domain.Statement) error {
+	_, err := fmt.Fprintf(w, ofxFooter, amount(statement.ClosingBalance), ofxDate(statement.To))
+	return err
+}
+
+func ofxType(operationType operation.Type) string {
+	switch operationType {
+	case operation.PAYMENT:
+		return "CREDIT"
+	case operation.WITHDRAW:
+		return "ATM"
+	default:
+		return "POS"
+	}
+}
+
+func ofxDate(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
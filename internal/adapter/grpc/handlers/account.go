@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	paymentv1 "github.com/payment-api/api/payment/v1"
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/domain"
+	"github.com/payment-api/internal/usecase"
+)
+
+// AccountScopes declares the scopes required by each AccountService method.
+var AccountScopes = map[string][]auth.Scope{
+	paymentv1.AccountService_CreateAccount_FullMethodName: {auth.ScopeAccountsWrite},
+	paymentv1.AccountService_GetAccount_FullMethodName:    {auth.ScopeAccountsRead},
+}
+
+type AccountServer struct {
+	paymentv1.UnimplementedAccountServiceServer
+	accountUseCase usecase.AccountUseCase
+}
+
+func (s AccountServer) CreateAccount(ctx context.Context, req *paymentv1.CreateAccountRequest) (*paymentv1.Account, error) {
+	if req.GetDocumentNumber() == "" {
+		return nil, exceptions.ValidationError.WithFields(exceptions.FieldError{Field: "document_number", Message: "is required"})
+	}
+
+	account := domain.NewAccount(uuid.New().String(), req.GetDocumentNumber())
+
+	if err := s.accountUseCase.Create(ctx, account); err != nil {
+		return nil, err
+	}
+
+	return newAccount(account), nil
+}
+
+func (s AccountServer) GetAccount(ctx context.Context, req *paymentv1.GetAccountRequest) (*paymentv1.Account, error) {
+	account, err := s.accountUseCase.Get(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	return newAccount(account), nil
+}
+
+func newAccount(account domain.Account) *paymentv1.Account {
+	return &paymentv1.Account{Id: account.Id, DocumentNumber: account.DocumentNumber}
+}
+
+func NewAccountServer(accountUseCase usecase.AccountUseCase) AccountServer {
+	return AccountServer{accountUseCase: accountUseCase}
+}
@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	paymentv1 "github.com/payment-api/api/payment/v1"
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/adapter/grpc/interceptors"
+	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
+)
+
+type accountUseCaseMock struct {
+	Result domain.Account
+	err    error
+}
+
+func (a accountUseCaseMock) Create(context.Context, domain.Account) error {
+	return a.err
+}
+
+func (a accountUseCaseMock) Get(context.Context, string) (domain.Account, error) {
+	return a.Result, a.err
+}
+
+func (a accountUseCaseMock) Search(context.Context, domain.AccountFilter) ([]domain.Account, error) {
+	return []domain.Account{a.Result}, a.err
+}
+
+func (a accountUseCaseMock) Update(context.Context, string, int, func(*domain.Account) error) (domain.Account, error) {
+	return a.Result, a.err
+}
+
+func (a accountUseCaseMock) Close(context.Context, string, int) (domain.Account, error) {
+	return a.Result, a.err
+}
+
+func (a accountUseCaseMock) Erase(context.Context, string, int) (domain.Account, error) {
+	return a.Result, a.err
+}
+
+func (a accountUseCaseMock) Delete(context.Context, string, int) error {
+	return a.err
+}
+
+type transactionUseCaseMock struct {
+	Result domain.Transaction
+	err    error
+	panics bool
+}
+
+func (t transactionUseCaseMock) Create(_ context.Context, transaction domain.Transaction) (domain.Transaction, error) {
+	if t.panics {
+		panic("unexpected")
+	}
+
+	transaction.Id, transaction.Decision, transaction.Status = 1, domain.DecisionAllow, domain.StatusApproved
+	return transaction, t.err
+}
+
+func (t transactionUseCaseMock) Get(context.Context, int) (domain.Transaction, error) {
+	return t.Result, t.err
+}
+
+// apiKeyUseCaseMock grants the scopes listed after each key.
+type apiKeyUseCaseMock map[string][]auth.Scope
+
+func (a apiKeyUseCaseMock) Authenticate(_ context.Context, key string) (auth.Principal, error) {
+	scopes, ok := a[key]
+	if !ok {
+		return auth.Principal{}, exceptions.UnauthorizedError.WithDetail("unknown api key")
+	}
+	return auth.Principal{ClientID: "internal-service", Scopes: scopes}, nil
+}
+
+func (a apiKeyUseCaseMock) Mint(context.Context, string, []auth.Scope, time.Duration) (string, domain.APIKey, error) {
+	return "", domain.APIKey{}, nil
+}
+
+func (a apiKeyUseCaseMock) Rotate(context.Context, string, time.Duration) (string, domain.APIKey, error) {
+	return "", domain.APIKey{}, nil
+}
+
+func (a apiKeyUseCaseMock) Revoke(context.Context, string) error {
+	return nil
+}
+
+// dial serves the services behind the interceptor chain of the server over
+// an in-memory listener.
+func dial(t *testing.T, accounts accountUseCaseMock, transactions transactionUseCaseMock) *grpc.ClientConn {
+	t.Helper()
+
+	scopes := map[string][]auth.Scope{}
+	for _, declared := range []map[string][]auth.Scope{AccountScopes, TransactionScopes} {
+		for method, required := range declared {
+			scopes[method] = required
+		}
+	}
+
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		interceptors.RequestID(),
+		interceptors.Tracing(),
+		interceptors.Logger(),
+		interceptors.Errors(),
+		interceptors.Recover(),
+		interceptors.AuditRoute(),
+		interceptors.Authenticate(apiKeyUseCaseMock{
+			"writer-key": {auth.ScopeAccountsWrite, auth.ScopeAccountsRead, auth.ScopeTransactionsWrite},
+			"reader-key": {auth.ScopeAccountsRead},
+		}, nil),
+		interceptors.Authorize(scopes),
+	))
+	paymentv1.RegisterAccountServiceServer(server, NewAccountServer(accounts))
+	paymentv1.RegisterTransactionServiceServer(server, NewTransactionServer(transactions))
+
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func withKey(key string) context.Context {
+	if key == "" {
+		return context.Background()
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+}
+
+func reason(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+func Test_AccountService(t *testing.T) {
+	scenarios := []struct {
+		description    string
+		key            string
+		call           func(paymentv1.AccountServiceClient, context.Context) (*paymentv1.Account, error)
+		err            error
+		expectedCode   codes.Code
+		expectedReason string
+	}{
+		{
+			description: "create account",
+			key:         "writer-key",
+			call: func(c paymentv1.AccountServiceClient, ctx context.Context) (*paymentv1.Account, error) {
+				return c.CreateAccount(ctx, &paymentv1.CreateAccountRequest{DocumentNumber: "any-document"})
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			description: "create without document number",
+			key:         "writer-key",
+			call: func(c paymentv1.AccountServiceClient, ctx context.Context) (*paymentv1.Account, error) {
+				return c.CreateAccount(ctx, &paymentv1.CreateAccountRequest{})
+			},
+			expectedCode:   codes.InvalidArgument,
+			expectedReason: "VALIDATION_ERROR",
+		},
+		{
+			description: "document already registered",
+			key:         "writer-key",
+			call: func(c paymentv1.AccountServiceClient, ctx context.Context) (*paymentv1.Account, error) {
+				return c.CreateAccount(ctx, &paymentv1.CreateAccountRequest{DocumentNumber: "any-document"})
+			},
+			err:            exceptions.ConflictError,
+			expectedCode:   codes.AlreadyExists,
+			expectedReason: "CONFLICT",
+		},
+		{
+			description: "create without write scope",
+			key:         "reader-key",
+			call: func(c paymentv1.AccountServiceClient, ctx context.Context) (*paymentv1.Account, error) {
+				return c.CreateAccount(ctx, &paymentv1.CreateAccountRequest{DocumentNumber: "any-document"})
+			},
+			expectedCode:   codes.PermissionDenied,
+			expectedReason: "FORBIDDEN",
+		},
+		{
+			description: "get account",
+			key:         "reader-key",
+			call: func(c paymentv1.AccountServiceClient, ctx context.Context) (*paymentv1.Account, error) {
+				return c.GetAccount(ctx, &paymentv1.GetAccountRequest{Id: "any-account-id"})
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			description: "account not found",
+			key:         "reader-key",
+			call: func(c paymentv1.AccountServiceClient, ctx context.Context) (*paymentv1.Account, error) {
+				return c.GetAccount(ctx, &paymentv1.GetAccountRequest{Id: "any-account-id"})
+			},
+			err:            exceptions.EntityNotFoundError,
+			expectedCode:   codes.NotFound,
+			expectedReason: "ENTITY_NOT_FOUND",
+		},
+		{
+			description: "without credentials",
+			call: func(c paymentv1.AccountServiceClient, ctx context.Context) (*paymentv1.Account, error) {
+				return c.GetAccount(ctx, &paymentv1.GetAccountRequest{Id: "any-account-id"})
+			},
+			expectedCode:   codes.Unauthenticated,
+			expectedReason: "UNAUTHORIZED",
+		},
+		{
+			description: "unknown api key",
+			key:         "revoked-key",
+			call: func(c paymentv1.AccountServiceClient, ctx context.Context) (*paymentv1.Account, error) {
+				return c.GetAccount(ctx, &paymentv1.GetAccountRequest{Id: "any-account-id"})
+			},
+			expectedCode:   codes.Unauthenticated,
+			expectedReason: "UNAUTHORIZED",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			conn := dial(t, accountUseCaseMock{
+				Result: domain.Account{Id: "any-account-id", DocumentNumber: "any-document"},
+				err:    scenario.err,
+			}, transactionUseCaseMock{})
+
+			account, err := scenario.call(paymentv1.NewAccountServiceClient(conn), withKey(scenario.key))
+
+			assert.Equal(t, scenario.expectedCode, status.Code(err))
+			assert.Equal(t, scenario.expectedReason, reason(err))
+			if scenario.expectedCode == codes.OK {
+				assert.NotEmpty(t, account.GetId())
+				assert.Equal(t, "any-document", account.GetDocumentNumber())
+			}
+		})
+	}
+}
+
+func Test_TransactionService(t *testing.T) {
+	reviewedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description    string
+		request        *paymentv1.CreateTransactionRequest
+		useCase        transactionUseCaseMock
+		expectedCode   codes.Code
+		expectedReason string
+		expectedField  string
+	}{
+		{
+			description:  "create transaction",
+			request:      &paymentv1.CreateTransactionRequest{AccountId: "any-account-id", OperationType: paymentv1.OperationType_OPERATION_TYPE_PAYMENT, Amount: 10},
+			expectedCode: codes.OK,
+		},
+		{
+			description:    "unspecified operation type",
+			request:        &paymentv1.CreateTransactionRequest{AccountId: "any-account-id", Amount: 10},
+			expectedCode:   codes.InvalidArgument,
+			expectedReason: "INVALID_OPERATION_TYPE",
+			expectedField:  "operation_type",
+		},
+		{
+			description:    "negative amount",
+			request:        &paymentv1.CreateTransactionRequest{AccountId: "any-account-id", OperationType: paymentv1.OperationType_OPERATION_TYPE_PAYMENT, Amount: -1},
+			expectedCode:   codes.InvalidArgument,
+			expectedReason: "INVALID_AMOUNT",
+			expectedField:  "amount",
+		},
+		{
+			description:    "denied by fraud rules",
+			request:        &paymentv1.CreateTransactionRequest{AccountId: "any-account-id", OperationType: paymentv1.OperationType_OPERATION_TYPE_WITHDRAW, Amount: 5000},
+			useCase:        transactionUseCaseMock{err: exceptions.TransactionDeniedError},
+			expectedCode:   codes.FailedPrecondition,
+			expectedReason: "TRANSACTION_DENIED",
+		},
+		{
+			description:    "database unavailable",
+			request:        &paymentv1.CreateTransactionRequest{AccountId: "any-account-id", OperationType: paymentv1.OperationType_OPERATION_TYPE_PAYMENT, Amount: 10},
+			useCase:        transactionUseCaseMock{err: exceptions.UnavailableError},
+			expectedCode:   codes.Unavailable,
+			expectedReason: "SERVICE_UNAVAILABLE",
+		},
+		{
+			description:    "panic",
+			request:        &paymentv1.CreateTransactionRequest{AccountId: "any-account-id", OperationType: paymentv1.OperationType_OPERATION_TYPE_PAYMENT, Amount: 10},
+			useCase:        transactionUseCaseMock{panics: true},
+			expectedCode:   codes.Internal,
+			expectedReason: "INTERNAL_ERROR",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			conn := dial(t, accountUseCaseMock{}, scenario.useCase)
+
+			transaction, err := paymentv1.NewTransactionServiceClient(conn).CreateTransaction(withKey("writer-key"), scenario.request)
+
+			assert.Equal(t, scenario.expectedCode, status.Code(err))
+			assert.Equal(t, scenario.expectedReason, reason(err))
+
+			if scenario.expectedField != "" {
+				var fields []string
+				for _, detail := range status.Convert(err).Details() {
+					if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+						for _, violation := range badRequest.FieldViolations {
+							fields = append(fields, violation.Field)
+						}
+					}
+				}
+				assert.Equal(t, []string{scenario.expectedField}, fields)
+			}
+
+			if scenario.expectedCode == codes.OK {
+				assert.Equal(t, int64(1), transaction.GetId())
+				assert.Equal(t, scenario.request.OperationType, transaction.GetOperationType())
+				assert.Equal(t, "approved", transaction.GetStatus())
+			}
+		})
+	}
+
+	t.Run("get reviewed transaction", func(t *testing.T) {
+		conn := dial(t, accountUseCaseMock{}, transactionUseCaseMock{Result: domain.Transaction{
+			Id:            7,
+			AccountID:     "any-account-id",
+			OperationType: operation.WITHDRAW,
+			Amount:        2500,
+			Decision:      domain.DecisionReview,
+			Reasons:       []string{"large-withdraw"},
+			Status:        domain.StatusDeclined,
+			ReviewedBy:    "operator",
+			ReviewReason:  "card reported stolen",
+			ReviewedAt:    &reviewedAt,
+		}})
+
+		transaction, err := paymentv1.NewTransactionServiceClient(conn).GetTransaction(withKey("reader-key"), &paymentv1.GetTransactionRequest{Id: 7})
+
+		assert.NoError(t, err)
+		assert.Equal(t, paymentv1.OperationType_OPERATION_TYPE_WITHDRAW, transaction.GetOperationType())
+		assert.Equal(t, []string{"large-withdraw"}, transaction.GetReasons())
+		assert.Equal(t, "declined", transaction.GetStatus())
+		assert.Equal(t, reviewedAt, transaction.GetReviewedAt().AsTime())
+	})
+}
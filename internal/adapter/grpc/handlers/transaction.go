@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	paymentv1 "github.com/payment-api/api/payment/v1"
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/mcc"
+	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
+	"github.com/payment-api/internal/usecase"
+)
+
+// TransactionScopes declares the scopes required by each TransactionService method.
+var TransactionScopes = map[string][]auth.Scope{
+	paymentv1.TransactionService_CreateTransaction_FullMethodName: {auth.ScopeTransactionsWrite},
+	paymentv1.TransactionService_GetTransaction_FullMethodName:    {auth.ScopeAccountsRead},
+}
+
+// TransactionWrites are the TransactionService methods posting transactions,
+// signed by partners and rate limited as POST /api/v1/transactions is.
+var TransactionWrites = map[string]bool{
+	paymentv1.TransactionService_CreateTransaction_FullMethodName: true,
+}
+
+type TransactionServer struct {
+	paymentv1.UnimplementedTransactionServiceServer
+	transactionUseCase usecase.TransactionUseCase
+}
+
+// CreateTransaction validates the request as the REST route does, the fraud
+// rules and account ownership are enforced by the use case.
+func (s TransactionServer) CreateTransaction(ctx context.Context, req *paymentv1.CreateTransactionRequest) (*paymentv1.Transaction, error) {
+	transaction := domain.NewTransaction(req.GetAccountId(), operation.Type(req.GetOperationType()), req.GetAmount())
+	transaction.CardToken = req.GetCardToken()
+	if merchant := req.GetMerchant(); merchant != nil {
+		transaction.Merchant = domain.Merchant{
+			Name:    merchant.GetName(),
+			MCC:     merchant.GetMcc(),
+			City:    merchant.GetCity(),
+			Country: merchant.GetCountry(),
+		}
+	}
+
+	if err := usecase.ValidateTransaction(transaction); err != nil {
+		return nil, err
+	}
+
+	transaction, err := s.transactionUseCase.Create(ctx, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTransaction(transaction), nil
+}
+
+func (s TransactionServer) GetTransaction(ctx context.Context, req *paymentv1.GetTransactionRequest) (*paymentv1.Transaction, error) {
+	transaction, err := s.transactionUseCase.Get(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, err
+	}
+
+	return newTransaction(transaction), nil
+}
+
+func newTransaction(transaction domain.Transaction) *paymentv1.Transaction {
+	response := &paymentv1.Transaction{
+		Id:            int64(transaction.Id),
+		AccountId:     transaction.AccountID,
+		OperationType: paymentv1.OperationType(transaction.OperationType.Index()),
+		Amount:        transaction.Amount,
+		EventDate:     timestamppb.New(transaction.EventDate),
+		Decision:      string(transaction.Decision),
+		Reasons:       transaction.Reasons,
+		Status:        string(transaction.Status),
+		ReviewedBy:    transaction.ReviewedBy,
+		ReviewReason:  transaction.ReviewReason,
+		CardId:        transaction.CardID,
+	}
+
+	if transaction.ReviewedAt != nil {
+		response.ReviewedAt = timestamppb.New(*transaction.ReviewedAt)
+	}
+
+	if !transaction.Merchant.IsZero() {
+		response.Merchant = &paymentv1.Merchant{
+			Name:    transaction.Merchant.Name,
+			Mcc:     transaction.Merchant.MCC,
+			City:    transaction.Merchant.City,
+			Country: transaction.Merchant.Country,
+		}
+		response.Category = mcc.Category(transaction.Merchant.MCC)
+	}
+
+	return response
+}
+
+func NewTransactionServer(transactionUseCase usecase.TransactionUseCase) TransactionServer {
+	return TransactionServer{transactionUseCase: transactionUseCase}
+}
@@ -0,0 +1,17 @@
+package interceptors
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/payment-api/infrastructure/audit"
+)
+
+// AuditRoute stores the called method in the context, so audit entries
+// recorded by the use cases name it as their route.
+func AuditRoute() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(audit.WithRoute(ctx, "grpc "+info.FullMethod), req)
+	}
+}
@@ -0,0 +1,85 @@
+package interceptors
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/internal/usecase"
+)
+
+const (
+	apiKeyKey        = "x-api-key"
+	authorizationKey = "authorization"
+	apiKeyScheme     = "ApiKey "
+	bearerScheme     = "Bearer "
+)
+
+// Authenticate resolves the api key or, when tokens is set, the bearer token
+// in the call metadata into an auth.Principal. Calls without credentials go
+// through anonymously and are rejected by Authorize.
+func Authenticate(apiKeys usecase.APIKeyUseCase, tokens *auth.TokenVerifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		authorization := first(ctx, authorizationKey)
+
+		if key := apiKey(ctx, authorization); key != "" {
+			principal, err := apiKeys.Authenticate(ctx, key)
+			if err != nil {
+				logger.Warn(ctx, logger.GRPCWarn, "api key authentication failed", logger.Err(err))
+				return nil, err
+			}
+
+			ctx = auth.WithPrincipal(ctx, principal)
+		} else if tokens != nil && strings.HasPrefix(authorization, bearerScheme) {
+			principal, err := tokens.Verify(ctx, strings.TrimSpace(strings.TrimPrefix(authorization, bearerScheme)))
+			if err != nil {
+				logger.Warn(ctx, logger.GRPCWarn, "bearer token validation failed", logger.Err(err))
+				return nil, exceptions.UnauthorizedError.WithDetail("invalid bearer token").Wrap(err)
+			}
+
+			ctx = auth.WithPrincipal(ctx, principal)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Authorize requires an authenticated principal holding every scope declared
+// for the called method, methods without declared scopes are refused.
+func Authorize(scopes map[string][]auth.Scope) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		principal, ok := auth.PrincipalFrom(ctx)
+		if !ok {
+			return nil, exceptions.UnauthorizedError
+		}
+
+		required, ok := scopes[info.FullMethod]
+		if !ok {
+			return nil, exceptions.ForbiddenError.WithDetail("method " + info.FullMethod + " is not exposed")
+		}
+
+		for _, scope := range required {
+			if !principal.HasScope(scope) {
+				return nil, exceptions.ForbiddenError.WithDetail("missing scope " + string(scope))
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func apiKey(ctx context.Context, authorization string) string {
+	if key := first(ctx, apiKeyKey); key != "" {
+		return key
+	}
+
+	if strings.HasPrefix(authorization, apiKeyScheme) {
+		return strings.TrimSpace(strings.TrimPrefix(authorization, apiKeyScheme))
+	}
+
+	return ""
+}
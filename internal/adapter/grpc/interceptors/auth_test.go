@@ -0,0 +1,170 @@
+package interceptors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	paymentv1 "github.com/payment-api/api/payment/v1"
+	"github.com/payment-api/config"
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/ratelimit"
+	"github.com/payment-api/infrastructure/requestid"
+	"github.com/payment-api/infrastructure/signature"
+	"github.com/payment-api/internal/domain"
+)
+
+type apiKeyUseCaseMock struct {
+	key *string
+}
+
+func (a apiKeyUseCaseMock) Authenticate(_ context.Context, key string) (auth.Principal, error) {
+	*a.key = key
+	return auth.Principal{ClientID: "internal-service", Scopes: []auth.Scope{auth.ScopeAccountsRead}}, nil
+}
+
+func (a apiKeyUseCaseMock) Mint(context.Context, string, []auth.Scope, time.Duration) (string, domain.APIKey, error) {
+	return "", domain.APIKey{}, nil
+}
+
+func (a apiKeyUseCaseMock) Rotate(context.Context, string, time.Duration) (string, domain.APIKey, error) {
+	return "", domain.APIKey{}, nil
+}
+
+func (a apiKeyUseCaseMock) Revoke(context.Context, string) error {
+	return nil
+}
+
+func Test_AuthenticateInterceptor(t *testing.T) {
+	scenarios := []struct {
+		description       string
+		md                metadata.MD
+		expectedKey       string
+		expectedPrincipal bool
+	}{
+		{
+			description:       "api key metadata",
+			md:                metadata.Pairs("x-api-key", "any-key"),
+			expectedKey:       "any-key",
+			expectedPrincipal: true,
+		},
+		{
+			description:       "api key authorization scheme",
+			md:                metadata.Pairs("authorization", "ApiKey any-key"),
+			expectedKey:       "any-key",
+			expectedPrincipal: true,
+		},
+		{
+			description: "bearer token without verifier",
+			md:          metadata.Pairs("authorization", "Bearer any-token"),
+		},
+		{
+			description: "anonymous",
+			md:          metadata.MD{},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var key string
+			var principal bool
+
+			_, err := Authenticate(apiKeyUseCaseMock{key: &key}, nil)(
+				metadata.NewIncomingContext(context.Background(), scenario.md), nil, &grpc.UnaryServerInfo{},
+				func(ctx context.Context, _ interface{}) (interface{}, error) {
+					_, principal = auth.PrincipalFrom(ctx)
+					return nil, nil
+				},
+			)
+
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.expectedKey, key)
+			assert.Equal(t, scenario.expectedPrincipal, principal)
+		})
+	}
+}
+
+func Test_AuthorizeInterceptor(t *testing.T) {
+	authorize := Authorize(map[string][]auth.Scope{"/payment.v1.AccountService/GetAccount": {auth.ScopeAccountsRead}})
+	handler := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+	reader := auth.WithPrincipal(context.Background(), auth.Principal{Scopes: []auth.Scope{auth.ScopeAccountsRead}})
+
+	resp, err := authorize(reader, nil, &grpc.UnaryServerInfo{FullMethod: "/payment.v1.AccountService/GetAccount"}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+
+	_, err = authorize(reader, nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"}, handler)
+	assert.ErrorIs(t, err, exceptions.ForbiddenError, "methods without declared scopes are refused")
+
+	_, err = authorize(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/payment.v1.AccountService/GetAccount"}, handler)
+	assert.ErrorIs(t, err, exceptions.UnauthorizedError)
+}
+
+func Test_RequestIDInterceptor(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "any-request-id"))
+
+	var id string
+	_, _ = RequestID()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		id = requestid.From(ctx)
+		return nil, nil
+	})
+
+	assert.Equal(t, "any-request-id", id)
+}
+
+func Test_SignatureInterceptor(t *testing.T) {
+	const method = "/payment.v1.TransactionService/CreateTransaction"
+
+	verifier := signature.NewVerifier(map[string]string{"Partner-A": "any-secret"}, time.Minute, signature.NewMemoryNonceStore())
+	intercept := Signature(verifier, map[string]bool{method: true})
+	handler := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+
+	partner := auth.WithPrincipal(context.Background(), auth.Principal{ClientID: "partner-a"})
+	_, err := intercept(partner, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	assert.ErrorIs(t, err, exceptions.InvalidSignatureError, "partners cannot post unsigned over grpc")
+
+	resp, err := intercept(partner, nil, &grpc.UnaryServerInfo{FullMethod: "/payment.v1.TransactionService/GetTransaction"}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+
+	other := auth.WithPrincipal(context.Background(), auth.Principal{ClientID: "internal-service"})
+	resp, err = intercept(other, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+}
+
+func Test_RateLimitInterceptor(t *testing.T) {
+	const method = "/payment.v1.TransactionService/CreateTransaction"
+
+	limiter := ratelimit.NewLimiter(config.RateLimit{
+		Client:  config.Limit{Rate: 0.001, Burst: 10},
+		Account: config.Limit{Rate: 0.001, Burst: 1},
+	}, ratelimit.NewMemoryStore())
+	intercept := RateLimit(limiter, map[string]bool{method: true})
+	handler := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+
+	clientA := auth.WithPrincipal(context.Background(), auth.Principal{ClientID: "client-a"})
+	clientB := auth.WithPrincipal(context.Background(), auth.Principal{ClientID: "client-b"})
+	request := &paymentv1.CreateTransactionRequest{AccountId: "any-account-id"}
+
+	_, err := intercept(clientA, request, info, handler)
+	assert.NoError(t, err)
+
+	_, err = intercept(clientA, request, info, handler)
+	assert.ErrorIs(t, err, exceptions.TooManyRequestsError)
+
+	_, err = intercept(clientB, request, info, handler)
+	assert.NoError(t, err, "each client has its own account bucket")
+
+	_, err = intercept(clientA, &paymentv1.CreateTransactionRequest{AccountId: "other-account-id"}, info, handler)
+	assert.NoError(t, err)
+
+	_, err = intercept(clientA, request, &grpc.UnaryServerInfo{FullMethod: "/payment.v1.TransactionService/GetTransaction"}, handler)
+	assert.NoError(t, err, "reads are not limited")
+}
@@ -0,0 +1,21 @@
+package interceptors
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/payment-api/infrastructure/exceptions"
+)
+
+// Errors answers the error returned by the handler as a gRPC status.
+func Errors() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, exceptions.ToStatus(err).Err()
+		}
+
+		return resp, nil
+	}
+}
@@ -0,0 +1,43 @@
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/payment-api/infrastructure/logger"
+)
+
+// Logger writes a structured entry per call, like the http Logger middleware.
+func Logger() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		fields := []logger.Field{
+			logger.Str("method", info.FullMethod),
+			logger.Str("code", code.String()),
+			logger.Duration("latency", time.Since(start)),
+		}
+
+		if err != nil {
+			fields = append(fields, logger.Err(err))
+		}
+
+		switch code {
+		case codes.OK:
+			logger.Info(ctx, logger.GRPCInfo, "call completed", fields...)
+		case codes.Internal, codes.Unavailable, codes.Unknown, codes.DataLoss:
+			logger.Error(ctx, logger.GRPCError, "call completed", fields...)
+		default:
+			logger.Warn(ctx, logger.GRPCWarn, "call completed", fields...)
+		}
+
+		return resp, err
+	}
+}
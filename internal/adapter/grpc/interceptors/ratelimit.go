@@ -0,0 +1,65 @@
+package interceptors
+
+import (
+	"context"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/ratelimit"
+)
+
+// accountRequest is implemented by the requests naming an account or a card.
+type accountRequest interface {
+	GetAccountId() string
+	GetCardToken() string
+}
+
+// RateLimit takes a token from the bucket of the api client calling one of
+// methods and from the bucket it holds for the account, or card, the request
+// names, as the RateLimit middleware does for the REST API. The most
+// restrictive bucket is reported in the ratelimit-* header metadata. It runs
+// after Signature.
+func RateLimit(limiter *ratelimit.Limiter, methods map[string]bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		principal, ok := auth.PrincipalFrom(ctx)
+		if !ok || !methods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		var accountID, cardToken string
+		if r, ok := req.(accountRequest); ok {
+			accountID, cardToken = r.GetAccountId(), r.GetCardToken()
+		}
+
+		reported, enabled, err := limiter.Request(ctx, principal.ClientID, accountID, cardToken)
+		if err != nil {
+			logger.Error(ctx, logger.ServerError, "rate limit store failed", logger.Err(err))
+		}
+
+		if !enabled {
+			return handler(ctx, req)
+		}
+
+		header := metadata.Pairs(
+			"ratelimit-limit", strconv.Itoa(reported.Limit),
+			"ratelimit-remaining", strconv.Itoa(reported.Remaining),
+			"ratelimit-reset", strconv.Itoa(int(reported.Reset.Seconds())),
+		)
+
+		if !reported.Allowed {
+			logger.Warn(ctx, logger.GRPCWarn, "rate limit exceeded", logger.Str("client_id", principal.ClientID))
+			header.Set("retry-after", strconv.Itoa(int(reported.RetryAfter.Seconds())))
+			_ = grpc.SetHeader(ctx, header)
+			return nil, exceptions.TooManyRequestsError
+		}
+
+		_ = grpc.SetHeader(ctx, header)
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,26 @@
+package interceptors
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+)
+
+// Recover turns a panic into an InternalError, answered by Errors.
+func Recover() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error(ctx, logger.ServerError, "panic recovered", logger.Str("panic", fmt.Sprint(r)))
+
+				resp, err = nil, exceptions.InternalError.Wrap(fmt.Errorf("panic: %v", r))
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,38 @@
+package interceptors
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/payment-api/infrastructure/requestid"
+)
+
+var requestIDKey = strings.ToLower(requestid.Header)
+
+// RequestID reuses the caller x-request-id or generates one, stores it in the
+// call context and sends it back in the response header.
+func RequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id := first(ctx, requestIDKey)
+		if id == "" {
+			id = uuid.New().String()
+		}
+
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+
+		return handler(requestid.With(ctx, id), req)
+	}
+}
+
+// first returns the first value of the incoming metadata key.
+func first(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
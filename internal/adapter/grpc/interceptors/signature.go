@@ -0,0 +1,32 @@
+package interceptors
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/signature"
+)
+
+// Signature refuses the calls to methods of partners that must sign their
+// requests, a decoded message cannot be checked against a signature of its
+// bytes, so they post them signed over the REST API. It must run after
+// Authorize, callers that are not partners go through unchanged.
+func Signature(verifier *signature.Verifier, methods map[string]bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		principal, ok := auth.PrincipalFrom(ctx)
+		if !ok || !methods[info.FullMethod] || !verifier.Required(principal.ClientID) {
+			return handler(ctx, req)
+		}
+
+		logger.Warn(ctx, logger.GRPCWarn, "unsigned partner call refused",
+			logger.Str("client_id", principal.ClientID),
+			logger.Str("method", info.FullMethod),
+		)
+
+		return nil, exceptions.InvalidSignatureError.WithDetail("partners sign their requests over the REST API")
+	}
+}
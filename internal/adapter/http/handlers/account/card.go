@@ -0,0 +1,115 @@
+package account
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/usecase"
+)
+
+func SetCardRoutes(ctx context.Context, r *gin.Engine, s usecase.CardUseCase) {
+	cards := r.Group("/api/v1/accounts/:account_id/cards")
+
+	cards.POST("", middlewares.Authorize(auth.ScopeAccountsWrite), issueCard(ctx, s))
+	cards.GET("", middlewares.Authorize(auth.ScopeAccountsRead), listCards(ctx, s))
+	cards.GET("/:card_id", middlewares.Authorize(auth.ScopeAccountsRead), getCard(ctx, s))
+	cards.POST("/:card_id/block", middlewares.Authorize(auth.ScopeAccountsWrite), blockCard(ctx, s))
+	cards.POST("/:card_id/replace", middlewares.Authorize(auth.ScopeAccountsWrite), replaceCard(ctx, s))
+}
+
+func issueCard(_ context.Context, cardUseCase usecase.CardUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:issueCard", trace.SpanKindServer)
+		defer span.End()
+
+		card, err := cardUseCase.Issue(ctx, c.Param("account_id"))
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed issue card", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, IssuedCardResponse{CardResponse: NewCardResponse(card), PAN: card.PAN})
+	}
+}
+
+func listCards(_ context.Context, cardUseCase usecase.CardUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:listCards", trace.SpanKindServer)
+		defer span.End()
+
+		cards, err := cardUseCase.List(ctx, c.Param("account_id"))
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed list cards", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		items := make([]CardResponse, 0, len(cards))
+		for _, card := range cards {
+			items = append(items, NewCardResponse(card))
+		}
+
+		c.JSON(http.StatusOK, CardListResponse{Items: items})
+	}
+}
+
+func getCard(_ context.Context, cardUseCase usecase.CardUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:getCard", trace.SpanKindServer)
+		defer span.End()
+
+		card, err := cardUseCase.Get(ctx, c.Param("account_id"), c.Param("card_id"))
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed get card", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, NewCardResponse(card))
+	}
+}
+
+func blockCard(_ context.Context, cardUseCase usecase.CardUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:blockCard", trace.SpanKindServer)
+		defer span.End()
+
+		card, err := cardUseCase.Block(ctx, c.Param("account_id"), c.Param("card_id"))
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed block card", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, NewCardResponse(card))
+	}
+}
+
+func replaceCard(_ context.Context, cardUseCase usecase.CardUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:replaceCard", trace.SpanKindServer)
+		defer span.End()
+
+		card, err := cardUseCase.Replace(ctx, c.Param("account_id"), c.Param("card_id"))
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed replace card", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, IssuedCardResponse{CardResponse: NewCardResponse(card), PAN: card.PAN})
+	}
+}
@@ -0,0 +1,170 @@
+package account
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/domain"
+)
+
+type cardUseCaseMock struct {
+	err error
+}
+
+func (c cardUseCaseMock) Issue(_ context.Context, accountID string) (domain.Card, error) {
+	return domain.Card{Id: "any-card-id", AccountID: accountID, Token: "tok_any", LastFour: "1234", Status: domain.CardActive, PAN: "4000001234561234"}, c.err
+}
+
+func (c cardUseCaseMock) Get(_ context.Context, accountID, id string) (domain.Card, error) {
+	return domain.Card{Id: id, AccountID: accountID, Token: "tok_any", LastFour: "1234", Status: domain.CardActive}, c.err
+}
+
+func (c cardUseCaseMock) List(_ context.Context, accountID string) ([]domain.Card, error) {
+	return []domain.Card{{Id: "any-card-id", AccountID: accountID, Token: "tok_any", LastFour: "1234", Status: domain.CardActive}}, c.err
+}
+
+func (c cardUseCaseMock) Block(_ context.Context, accountID, id string) (domain.Card, error) {
+	return domain.Card{Id: id, AccountID: accountID, Token: "tok_any", LastFour: "1234", Status: domain.CardBlocked}, c.err
+}
+
+func (c cardUseCaseMock) Replace(_ context.Context, accountID, _ string) (domain.Card, error) {
+	return domain.Card{Id: "any-card-id", AccountID: accountID, Token: "tok_new", LastFour: "5678", Status: domain.CardActive, PAN: "4000001234565678"}, c.err
+}
+
+func (c cardUseCaseMock) Expire(context.Context) (int, error) {
+	return 0, nil
+}
+
+func Test_CardHandler(t *testing.T) {
+	scenarios := []struct {
+		description        string
+		method             string
+		path               string
+		scopes             []auth.Scope
+		err                error
+		expectedStatus     int
+		expectedCode       string
+		expectedCardStatus string
+		expectPAN          bool
+	}{
+		{
+			description:        "issue",
+			method:             http.MethodPost,
+			path:               "/api/v1/accounts/any-account-id/cards",
+			scopes:             []auth.Scope{auth.ScopeAccountsWrite},
+			expectedStatus:     http.StatusCreated,
+			expectedCardStatus: "active",
+			expectPAN:          true,
+		},
+		{
+			description:    "issue without accounts:write",
+			method:         http.MethodPost,
+			path:           "/api/v1/accounts/any-account-id/cards",
+			scopes:         []auth.Scope{auth.ScopeAccountsRead},
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   "FORBIDDEN",
+		},
+		{
+			description:    "issue on closed account",
+			method:         http.MethodPost,
+			path:           "/api/v1/accounts/any-account-id/cards",
+			scopes:         []auth.Scope{auth.ScopeAccountsWrite},
+			err:            exceptions.AccountClosedError,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   "ACCOUNT_CLOSED",
+		},
+		{
+			description:        "list",
+			method:             http.MethodGet,
+			path:               "/api/v1/accounts/any-account-id/cards",
+			scopes:             []auth.Scope{auth.ScopeAccountsRead},
+			expectedStatus:     http.StatusOK,
+			expectedCardStatus: "active",
+		},
+		{
+			description:        "get",
+			method:             http.MethodGet,
+			path:               "/api/v1/accounts/any-account-id/cards/any-card-id",
+			scopes:             []auth.Scope{auth.ScopeAccountsRead},
+			expectedStatus:     http.StatusOK,
+			expectedCardStatus: "active",
+		},
+		{
+			description:    "get not found",
+			method:         http.MethodGet,
+			path:           "/api/v1/accounts/any-account-id/cards/any-card-id",
+			scopes:         []auth.Scope{auth.ScopeAccountsRead},
+			err:            exceptions.EntityNotFoundError,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "ENTITY_NOT_FOUND",
+		},
+		{
+			description:        "block",
+			method:             http.MethodPost,
+			path:               "/api/v1/accounts/any-account-id/cards/any-card-id/block",
+			scopes:             []auth.Scope{auth.ScopeAccountsWrite},
+			expectedStatus:     http.StatusOK,
+			expectedCardStatus: "blocked",
+		},
+		{
+			description:    "block blocked",
+			method:         http.MethodPost,
+			path:           "/api/v1/accounts/any-account-id/cards/any-card-id/block",
+			scopes:         []auth.Scope{auth.ScopeAccountsWrite},
+			err:            exceptions.InvalidStateError,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   "INVALID_STATE",
+		},
+		{
+			description:        "replace",
+			method:             http.MethodPost,
+			path:               "/api/v1/accounts/any-account-id/cards/any-card-id/replace",
+			scopes:             []auth.Scope{auth.ScopeAccountsWrite},
+			expectedStatus:     http.StatusCreated,
+			expectedCardStatus: "active",
+			expectPAN:          true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router := gin.Default()
+			router.Use(middlewares.Errors(), func(c *gin.Context) {
+				c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), auth.Principal{ClientID: "any-client", Scopes: scenario.scopes}))
+			})
+			SetCardRoutes(context.Background(), router, cardUseCaseMock{err: scenario.err})
+
+			request, _ := http.NewRequest(scenario.method, scenario.path, bytes.NewBuffer(nil))
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+
+			if scenario.expectedCode != "" {
+				var problem exceptions.Problem
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+				assert.Equal(t, scenario.expectedCode, problem.Code)
+				return
+			}
+
+			assert.Contains(t, rr.Body.String(), `"status":"`+scenario.expectedCardStatus+`"`)
+			assert.Contains(t, rr.Body.String(), `"last_four":"`)
+			if scenario.expectPAN {
+				assert.Contains(t, rr.Body.String(), `"pan":"4000001234`)
+			} else {
+				assert.NotContains(t, rr.Body.String(), `"pan"`)
+			}
+		})
+	}
+}
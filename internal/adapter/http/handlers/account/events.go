@@ -0,0 +1,101 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/usecase"
+)
+
+const lastEventIDHeader = "Last-Event-ID"
+
+// SetEventRoutes serves the activity of an account as Server-Sent Events,
+// sending a heartbeat comment every heartbeat while idle.
+func SetEventRoutes(ctx context.Context, r *gin.Engine, s usecase.EventUseCase, heartbeat time.Duration) {
+	r.GET("/api/v1/accounts/:account_id/events", middlewares.Authorize(auth.ScopeAccountsRead), streamEvents(ctx, s, heartbeat))
+}
+
+// streamEvents ends the stream once ctx is cancelled, so open streams do not
+// hold the graceful shutdown of the server.
+func streamEvents(ctx context.Context, eventUseCase usecase.EventUseCase, heartbeat time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestCtx, span := telemetry.Span(c.Request.Context(), "http:handler:streamEvents", trace.SpanKindServer)
+		defer span.End()
+
+		lastEventID, err := parseLastEventID(c.GetHeader(lastEventIDHeader))
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			_ = c.Error(err)
+			return
+		}
+
+		events, err := eventUseCase.Stream(requestCtx, c.Param("account_id"), lastEventID)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(requestCtx, logger.HTTPError, "failed stream account events", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		var heartbeats <-chan time.Time
+		if heartbeat > 0 {
+			ticker := time.NewTicker(heartbeat)
+			defer ticker.Stop()
+			heartbeats = ticker.C
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-requestCtx.Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+
+				c.Render(-1, sse.Event{Id: strconv.FormatInt(event.Seq, 10), Event: string(event.Type), Data: event})
+				c.Writer.Flush()
+			case <-heartbeats:
+				fmt.Fprint(c.Writer, ": heartbeat\n\n")
+				c.Writer.Flush()
+			}
+		}
+	}
+}
+
+func parseLastEventID(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, exceptions.InvalidParameterError.WithFields(exceptions.FieldError{
+			Field:   lastEventIDHeader,
+			Message: "must be the id of a previous event",
+		})
+	}
+
+	return id, nil
+}
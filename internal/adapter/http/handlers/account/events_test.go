@@ -0,0 +1,158 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/domain"
+)
+
+// eventUseCaseMock streams Result, waiting idle before closing the stream.
+type eventUseCaseMock struct {
+	Result      []domain.Event
+	idle        time.Duration
+	lastEventID *int64
+	err         error
+}
+
+func (e eventUseCaseMock) Publish(context.Context, domain.Event) {}
+
+func (e eventUseCaseMock) Stream(_ context.Context, _ string, lastEventID int64) (<-chan domain.Event, error) {
+	*e.lastEventID = lastEventID
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	events := make(chan domain.Event, len(e.Result))
+	for _, event := range e.Result {
+		events <- event
+	}
+
+	go func() {
+		time.Sleep(e.idle)
+		close(events)
+	}()
+
+	return events, nil
+}
+
+func Test_streamEventsHandler(t *testing.T) {
+	scenarios := []struct {
+		description         string
+		lastEventID         string
+		idle                time.Duration
+		err                 error
+		expectedStatus      int
+		expectedCode        string
+		expectedLastEventID int64
+		expectedBody        []string
+	}{
+		{
+			description:         "resume from last event",
+			lastEventID:         "41",
+			expectedStatus:      http.StatusOK,
+			expectedLastEventID: 41,
+			expectedBody: []string{
+				"id:42\nevent:transaction.created\ndata:{\"id\":\"any-event-id\",\"type\":\"transaction.created\",\"account_id\":\"any-account-id\"",
+			},
+		},
+		{
+			description:    "heartbeat while idle",
+			idle:           50 * time.Millisecond,
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{"id:42\n", ": heartbeat\n\n"},
+		},
+		{
+			description:    "invalid last event id",
+			lastEventID:    "latest",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_PARAMETER",
+		},
+		{
+			description:    "account not found",
+			err:            exceptions.EntityNotFoundError,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "ENTITY_NOT_FOUND",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var lastEventID int64
+			useCase := eventUseCaseMock{
+				Result: []domain.Event{{
+					Seq:       42,
+					Id:        "any-event-id",
+					Type:      domain.EventTransactionCreated,
+					AccountID: "any-account-id",
+				}},
+				idle:        scenario.idle,
+				lastEventID: &lastEventID,
+				err:         scenario.err,
+			}
+
+			rr := httptest.NewRecorder()
+			router := gin.Default()
+			router.Use(middlewares.Errors(), authenticated())
+			SetEventRoutes(context.Background(), router, useCase, 10*time.Millisecond)
+
+			request, _ := http.NewRequest(http.MethodGet, "/api/v1/accounts/any-account-id/events", nil)
+			if scenario.lastEventID != "" {
+				request.Header.Set(lastEventIDHeader, scenario.lastEventID)
+			}
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+			assert.Equal(t, scenario.expectedLastEventID, lastEventID)
+
+			if scenario.expectedCode != "" {
+				var problem exceptions.Problem
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+				assert.Equal(t, scenario.expectedCode, problem.Code)
+				return
+			}
+
+			assert.True(t, strings.HasPrefix(rr.Header().Get("Content-Type"), "text/event-stream"))
+			for _, expected := range scenario.expectedBody {
+				assert.Contains(t, rr.Body.String(), expected)
+			}
+		})
+	}
+}
+
+func Test_streamEventsStopsOnShutdown(t *testing.T) {
+	var lastEventID int64
+	useCase := eventUseCaseMock{idle: time.Hour, lastEventID: &lastEventID}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	router := gin.Default()
+	router.Use(middlewares.Errors(), authenticated())
+	SetEventRoutes(ctx, router, useCase, 0)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		request, _ := http.NewRequest(http.MethodGet, "/api/v1/accounts/any-account-id/events", nil)
+		router.ServeHTTP(httptest.NewRecorder(), request)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream still open after shutdown")
+	}
+}
@@ -0,0 +1,152 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/export"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/domain"
+	"github.com/payment-api/internal/usecase"
+)
+
+const (
+	defaultExportFormat = "csv"
+	dateLayout          = "2006-01-02"
+)
+
+// SetExportRoutes serves the statements of an account as downloadable files.
+func SetExportRoutes(ctx context.Context, r *gin.Engine, s usecase.StatementUseCase) {
+	r.GET("/api/v1/accounts/:account_id/transactions/export", middlewares.Authorize(auth.ScopeAccountsRead), exportTransactions(ctx, s))
+}
+
+// exportTransactions streams the statement as its rows are read from the
+// database. Errors found once the file started streaming can only end the
+// response early.
+func exportTransactions(_ context.Context, statementUseCase usecase.StatementUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:exportTransactions", trace.SpanKindServer)
+		defer span.End()
+
+		format := c.DefaultQuery("format", defaultExportFormat)
+		exporter, ok := export.For(format)
+		if !ok {
+			err := exceptions.InvalidParameterError.WithFields(exceptions.FieldError{
+				Field:   "format",
+				Message: "must be one of " + strings.Join(export.Formats(), ", "),
+			})
+			telemetry.ErrorSpan(span, err)
+			_ = c.Error(err)
+			return
+		}
+
+		from, to, err := parsePeriod(c.Query("from"), c.Query("to"), time.Now())
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			_ = c.Error(err)
+			return
+		}
+
+		ctx, end, err := statementUseCase.Begin(ctx)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed begin statement", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+		defer end()
+
+		statement, err := statementUseCase.Get(ctx, c.Param("account_id"), from, to)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed get statement", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.Header("Content-Type", exporter.ContentType())
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName(statement, exporter)))
+		c.Status(http.StatusOK)
+
+		err = exporter.Begin(c.Writer, statement)
+		if err == nil {
+			err = statementUseCase.Walk(ctx, statement, func(transaction domain.Transaction) error {
+				return exporter.Write(c.Writer, statement, transaction)
+			})
+		}
+		if err == nil {
+			err = exporter.End(c.Writer, statement)
+		}
+
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed stream statement",
+				logger.Str("account_id", statement.Account.Id),
+				logger.Str("format", format),
+				logger.Err(err),
+			)
+		}
+	}
+}
+
+// parsePeriod reads from and to as RFC 3339 timestamps or dates, a date to
+// includes the whole day. The period defaults to the month before now.
+func parsePeriod(fromValue, toValue string, now time.Time) (time.Time, time.Time, error) {
+	to := now
+	if toValue != "" {
+		parsed, date, err := parseTime(toValue)
+		if err != nil {
+			return time.Time{}, time.Time{}, periodError("to")
+		}
+		to = parsed
+		if date {
+			to = to.AddDate(0, 0, 1)
+		}
+	}
+
+	from := to.AddDate(0, -1, 0)
+	if fromValue != "" {
+		parsed, _, err := parseTime(fromValue)
+		if err != nil {
+			return time.Time{}, time.Time{}, periodError("from")
+		}
+		from = parsed
+	}
+
+	return from, to, nil
+}
+
+func parseTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(dateLayout, value); err == nil {
+		return t, true, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
+
+func periodError(field string) error {
+	return exceptions.InvalidParameterError.WithFields(exceptions.FieldError{
+		Field:   field,
+		Message: "must be a date or an RFC 3339 timestamp",
+	})
+}
+
+func fileName(statement domain.Statement, exporter export.Exporter) string {
+	return fmt.Sprintf("statement-%s-%s-%s.%s",
+		statement.Account.Id,
+		statement.From.UTC().Format("20060102"),
+		statement.To.UTC().Format("20060102"),
+		exporter.Extension(),
+	)
+}
@@ -0,0 +1,138 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
+)
+
+type statementUseCaseMock struct {
+	Result []domain.Transaction
+	period *[2]time.Time
+	err    error
+}
+
+func (s statementUseCaseMock) Begin(ctx context.Context) (context.Context, func(), error) {
+	return ctx, func() {}, nil
+}
+
+func (s statementUseCaseMock) Get(_ context.Context, accountID string, from, to time.Time) (domain.Statement, error) {
+	*s.period = [2]time.Time{from, to}
+	return domain.Statement{Id: "any-statement-id", Account: domain.Account{Id: accountID}, Currency: "BRL", From: from, To: to}, s.err
+}
+
+func (s statementUseCaseMock) Walk(_ context.Context, _ domain.Statement, fn func(domain.Transaction) error) error {
+	for _, transaction := range s.Result {
+		if err := fn(transaction); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Test_exportTransactionsHandler(t *testing.T) {
+	scenarios := []struct {
+		description         string
+		query               string
+		err                 error
+		expectedStatus      int
+		expectedCode        string
+		expectedContentType string
+		expectedFile        string
+		expectedPeriod      [2]time.Time
+		expectedBody        string
+	}{
+		{
+			description:         "csv by dates",
+			query:               "?from=2024-01-01&to=2024-01-31",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv",
+			expectedFile:        "statement-any-account-id-20240101-20240201.csv",
+			expectedPeriod:      [2]time.Time{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+			expectedBody:        "id,event_date,operation_type,amount,currency\n7,2024-01-02T10:00:00Z,CASH_PURCHASES,-12.30,BRL\n",
+		},
+		{
+			description:         "ofx by timestamps",
+			query:               "?format=ofx&from=2024-01-01T00:00:00Z&to=2024-01-15T12:00:00Z",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/x-ofx",
+			expectedFile:        "statement-any-account-id-20240101-20240115.ofx",
+			expectedPeriod:      [2]time.Time{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)},
+		},
+		{
+			description:         "camt053 of the month before to",
+			query:               "?format=camt053&to=2024-03-15T00:00:00Z",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/xml",
+			expectedFile:        "statement-any-account-id-20240215-20240315.xml",
+			expectedPeriod:      [2]time.Time{time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			description:    "unknown format",
+			query:          "?format=pdf",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_PARAMETER",
+		},
+		{
+			description:    "invalid from",
+			query:          "?from=yesterday",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_PARAMETER",
+		},
+		{
+			description:    "account not found",
+			query:          "?from=2024-01-01&to=2024-01-31",
+			err:            exceptions.EntityNotFoundError,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "ENTITY_NOT_FOUND",
+			expectedPeriod: [2]time.Time{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var period [2]time.Time
+			useCase := statementUseCaseMock{
+				Result: []domain.Transaction{{Id: 7, OperationType: operation.CASH_PURCHASES, Amount: 12.3, EventDate: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)}},
+				period: &period,
+				err:    scenario.err,
+			}
+
+			rr := httptest.NewRecorder()
+			router := gin.Default()
+			router.Use(middlewares.Errors(), authenticated())
+			SetExportRoutes(context.Background(), router, useCase)
+
+			request, _ := http.NewRequest(http.MethodGet, "/api/v1/accounts/any-account-id/transactions/export"+scenario.query, nil)
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+			assert.Equal(t, scenario.expectedPeriod, period)
+
+			if scenario.expectedCode != "" {
+				var problem exceptions.Problem
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+				assert.Equal(t, scenario.expectedCode, problem.Code)
+				return
+			}
+
+			assert.Equal(t, scenario.expectedContentType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, `attachment; filename="`+scenario.expectedFile+`"`, rr.Header().Get("Content-Disposition"))
+			if scenario.expectedBody != "" {
+				assert.Equal(t, scenario.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
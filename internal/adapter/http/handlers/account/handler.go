@@ -2,77 +2,215 @@ package account
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
 	"github.com/payment-api/infrastructure/logger"
 	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/http/handlers/etag"
+	"github.com/payment-api/internal/adapter/http/handlers/pagination"
+	"github.com/payment-api/internal/adapter/http/middlewares"
 	"github.com/payment-api/internal/domain"
 	"github.com/payment-api/internal/usecase"
 )
 
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+
+	mergePatchContentType = "application/merge-patch+json"
+)
+
 func SetAccountRoutes(ctx context.Context, r *gin.Engine, s usecase.AccountUseCase) {
-	r.POST("/api/v1/accounts", createAccount(ctx, s))
-	r.GET("/api/v1/accounts/:account_id", getAccount(ctx, s))
+	r.POST("/api/v1/accounts", middlewares.Authorize(auth.ScopeAccountsWrite), createAccount(ctx, s))
+	r.GET("/api/v1/accounts", middlewares.Authorize(auth.ScopeAccountsRead), searchAccounts(ctx, s))
+	r.GET("/api/v1/accounts/:account_id", middlewares.Authorize(auth.ScopeAccountsRead), getAccount(ctx, s))
+	r.PATCH("/api/v1/accounts/:account_id", middlewares.Authorize(auth.ScopeAccountsWrite), patchAccount(ctx, s))
+	r.DELETE("/api/v1/accounts/:account_id", middlewares.Authorize(auth.ScopeAccountsWrite), deleteAccount(ctx, s))
+	r.POST("/api/v1/accounts/:account_id/close", middlewares.Authorize(auth.ScopeAccountsWrite), closeAccount(ctx, s))
+	r.POST("/api/v1/accounts/:account_id/erasure", middlewares.Authorize(auth.ScopeAccountsErase), eraseAccount(ctx, s))
 }
 
-func getAccount(ctx context.Context, accountUseCase usecase.AccountUseCase) gin.HandlerFunc {
+func getAccount(_ context.Context, accountUseCase usecase.AccountUseCase) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx, span := telemetry.Span(ctx, "http:handler:getAccount", trace.SpanKindServer)
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:getAccount", trace.SpanKindServer)
 		defer span.End()
 
 		persistedAccount, err := accountUseCase.Get(ctx, c.Param("account_id"))
 		if err != nil {
 			telemetry.ErrorSpan(span, err)
-			logger.Error(logger.HTTPError, "ErrorSpan creating account")
-			c.JSON(http.StatusNotFound, map[string]string{
-				"message": "failed get account",
-				"reason":  err.Error(),
-			})
+			logger.Error(ctx, logger.HTTPError, "failed get account", logger.Err(err))
+			_ = c.Error(err)
 			return
 		}
 
-		c.JSON(http.StatusOK, persistedAccount)
+		etag.Set(c, persistedAccount.Version)
+		c.JSON(http.StatusOK, NewResponse(persistedAccount))
 	}
 }
 
-func createAccount(ctx context.Context, accountUseCase usecase.AccountUseCase) gin.HandlerFunc {
+// patchAccount updates the profile of an account with a JSON merge patch
+// (RFC 7396): members set replace the current ones, null members clear them.
+// The If-Match header must carry the ETag of the account being patched.
+func patchAccount(_ context.Context, accountUseCase usecase.AccountUseCase) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx, span := telemetry.Span(ctx, "http:handler:createAccount", trace.SpanKindServer)
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:patchAccount", trace.SpanKindServer)
 		defer span.End()
 
-		var request Request
+		switch c.ContentType() {
+		case mergePatchContentType, gin.MIMEJSON:
+		default:
+			err := exceptions.UnsupportedMediaTypeError.WithDetail("patches must be sent as " + mergePatchContentType)
+			telemetry.ErrorSpan(span, err)
+			_ = c.Error(err)
+			return
+		}
+
+		version, err := etag.IfMatch(c)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			_ = c.Error(err)
+			return
+		}
+
+		var patch map[string]interface{}
+		if err := json.NewDecoder(c.Request.Body).Decode(&patch); err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "cannot unmarshal patch", logger.Err(err))
+			_ = c.Error(exceptions.FromBinding(err))
+			return
+		}
+
+		if err := checkPatch(patch); err != nil {
+			telemetry.ErrorSpan(span, err)
+			_ = c.Error(err)
+			return
+		}
+
+		account, err := accountUseCase.Update(ctx, c.Param("account_id"), version, func(account *domain.Account) error {
+			return applyPatch(account, patch)
+		})
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed patch account", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		etag.Set(c, account.Version)
+		c.JSON(http.StatusOK, NewResponse(account))
+	}
+}
+
+func searchAccounts(_ context.Context, accountUseCase usecase.AccountUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:searchAccounts", trace.SpanKindServer)
+		defer span.End()
+
+		filter, err := parseFilter(c)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			_ = c.Error(err)
+			return
+		}
 
-		if err := c.BindJSON(&request); err != nil {
+		accounts, err := accountUseCase.Search(ctx, filter)
+		if err != nil {
 			telemetry.ErrorSpan(span, err)
-			logger.Error(logger.HTTPError, "cannot marshal body")
+			logger.Error(ctx, logger.HTTPError, "failed search accounts", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		items := make([]Response, 0, len(accounts))
+		for _, account := range accounts {
+			items = append(items, NewResponse(account))
+		}
+
+		c.JSON(http.StatusOK, ListResponse{Items: items, Limit: filter.Limit, Offset: filter.Offset})
+	}
+}
+
+// parseFilter reads the search query parameters, created_from and created_to
+// are dates or RFC 3339 timestamps and a created_to date includes the whole day.
+func parseFilter(c *gin.Context) (domain.AccountFilter, error) {
+	limit, offset, err := pagination.Parse(c, defaultLimit, maxLimit)
+	if err != nil {
+		return domain.AccountFilter{}, err
+	}
+
+	filter := domain.AccountFilter{
+		DocumentNumber: c.Query("document_number"),
+		DocumentPrefix: c.Query("document_prefix"),
+		Status:         domain.AccountStatus(c.Query("status")),
+		Limit:          limit,
+		Offset:         offset,
+	}
+
+	switch filter.Status {
+	case "", domain.AccountActive, domain.AccountClosed:
+	default:
+		return domain.AccountFilter{}, exceptions.InvalidParameterError.WithFields(exceptions.FieldError{
+			Field:   "status",
+			Message: "must be one of active, closed",
+		})
+	}
 
-			c.JSON(http.StatusBadRequest, map[string]string{
-				"message": "invalid parameters",
-			})
+	if value := c.Query("created_from"); value != "" {
+		if filter.CreatedFrom, _, err = parseTime(value); err != nil {
+			return domain.AccountFilter{}, periodError("created_from")
+		}
+	}
+
+	if value := c.Query("created_to"); value != "" {
+		var date bool
+		if filter.CreatedTo, date, err = parseTime(value); err != nil {
+			return domain.AccountFilter{}, periodError("created_to")
+		}
+		if date {
+			filter.CreatedTo = filter.CreatedTo.AddDate(0, 0, 1)
+		}
+	}
+
+	return filter, nil
+}
+
+func createAccount(_ context.Context, accountUseCase usecase.AccountUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:createAccount", trace.SpanKindServer)
+		defer span.End()
+
+		var request Request
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "cannot marshal body", logger.Err(err))
+			_ = c.Error(exceptions.FromBinding(err))
 			return
 		}
 
 		generatedAccountID := uuid.New().String()
 		account := domain.NewAccount(generatedAccountID, request.DocumentNumber)
+		account.AccountProfile = request.Profile.domain()
 
 		err := accountUseCase.Create(ctx, account)
 		if err != nil {
 			telemetry.ErrorSpan(span, err)
-			logger.Error(logger.HTTPError, "ErrorSpan creating account")
-			c.JSON(http.StatusUnprocessableEntity, map[string]string{
-				"message": "failed create account",
-				"reason":  err.Error(),
-			})
-
+			logger.Error(ctx, logger.HTTPError, "failed create account", logger.Err(err))
+			_ = c.Error(err)
 			return
 		}
 
-		logger.Info(logger.ServerInfo, fmt.Sprintf("Account Created %v", account))
+		logger.Info(ctx, logger.HTTPInfo, "account created",
+			logger.Str("account_id", account.Id),
+			logger.Str("document_number", account.DocumentNumber),
+		)
 
 		c.JSON(http.StatusCreated, map[string]string{"success": "created", "id": generatedAccountID})
 	}
@@ -3,23 +3,31 @@ package account
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"go.opentelemetry.io/otel/sdk/trace"
 
+	"github.com/payment-api/infrastructure/auth"
 	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/adapter/http/middlewares"
 	"github.com/payment-api/internal/domain"
 	"github.com/payment-api/internal/usecase"
 )
 
 type accountUseCaseMock struct {
-	Result domain.Account
-	err    error
+	Result   domain.Account
+	Accounts []domain.Account
+	filter   *domain.AccountFilter
+	err      error
 }
 
 func (a accountUseCaseMock) Create(context.Context, domain.Account) error {
@@ -30,6 +38,75 @@ func (a accountUseCaseMock) Get(context.Context, string) (domain.Account, error)
 	return a.Result, a.err
 }
 
+func (a accountUseCaseMock) Update(_ context.Context, _ string, version int, patch func(*domain.Account) error) (domain.Account, error) {
+	if a.err != nil {
+		return domain.Account{}, a.err
+	}
+
+	if version != 0 && version != a.Result.Version {
+		return domain.Account{}, exceptions.PreconditionFailedError
+	}
+
+	account := a.Result
+	if err := patch(&account); err != nil {
+		return domain.Account{}, err
+	}
+	account.Version++
+	return account, nil
+}
+
+func (a accountUseCaseMock) Close(_ context.Context, _ string, version int) (domain.Account, error) {
+	return a.transition(version, func(account *domain.Account) {
+		closedAt := account.UpdatedAt
+		account.Status = domain.AccountClosed
+		account.ClosedAt = &closedAt
+	})
+}
+
+func (a accountUseCaseMock) Erase(_ context.Context, _ string, version int) (domain.Account, error) {
+	return a.transition(version, func(account *domain.Account) {
+		erasedAt := account.UpdatedAt
+		account.DocumentNumber = "erased-document"
+		account.AccountProfile = domain.AccountProfile{}
+		account.ErasedAt = &erasedAt
+	})
+}
+
+func (a accountUseCaseMock) Delete(_ context.Context, _ string, version int) error {
+	_, err := a.transition(version, func(*domain.Account) {})
+	return err
+}
+
+func (a accountUseCaseMock) transition(version int, change func(*domain.Account)) (domain.Account, error) {
+	if a.err != nil {
+		return domain.Account{}, a.err
+	}
+
+	if version != 0 && version != a.Result.Version {
+		return domain.Account{}, exceptions.PreconditionFailedError
+	}
+
+	account := a.Result
+	change(&account)
+	account.Version++
+	return account, nil
+}
+
+func (a accountUseCaseMock) Search(_ context.Context, filter domain.AccountFilter) ([]domain.Account, error) {
+	*a.filter = filter
+	return a.Accounts, a.err
+}
+
+// authenticated stands in for middlewares.Authenticate with an admin principal.
+func authenticated() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), auth.Principal{
+			ClientID: "any-client",
+			Scopes:   []auth.Scope{auth.ScopeAdmin},
+		}))
+	}
+}
+
 func Test_AccountCreateHandler(t *testing.T) {
 	scenarios := []struct {
 		description    string
@@ -49,6 +126,15 @@ func Test_AccountCreateHandler(t *testing.T) {
 			},
 			expectedStatus: http.StatusCreated,
 		},
+		{
+			description: "success with profile",
+			input:       []byte(`{"document_number":"any-document","holder_name":"Ada Lovelace","email":"ada@example.com","address":{"line1":"12 St James's Square","city":"London","country":"GB"},"metadata":{"tier":"gold"}}`),
+			useCase: &accountUseCaseMock{
+				Result: domain.Account{},
+				err:    nil,
+			},
+			expectedStatus: http.StatusCreated,
+		},
 		{
 			description: "persistence error",
 			input:       []byte(`{"document_number":"any-document"}`),
@@ -56,7 +142,25 @@ func Test_AccountCreateHandler(t *testing.T) {
 				Result: domain.Account{},
 				err:    exceptions.PersistenceError,
 			},
-			expectedStatus: http.StatusUnprocessableEntity,
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			description: "document number already registered",
+			input:       []byte(`{"document_number":"any-document"}`),
+			useCase: &accountUseCaseMock{
+				Result: domain.Account{},
+				err:    fmt.Errorf("create account: %w", exceptions.ConflictError),
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			description: "database unavailable",
+			input:       []byte(`{"document_number":"any-document"}`),
+			useCase: &accountUseCaseMock{
+				Result: domain.Account{},
+				err:    fmt.Errorf("create account: %w", exceptions.UnavailableError),
+			},
+			expectedStatus: http.StatusServiceUnavailable,
 		},
 		{
 			description: "request body empty",
@@ -88,6 +192,7 @@ func Test_AccountCreateHandler(t *testing.T) {
 
 			rr := httptest.NewRecorder()
 			router := gin.Default()
+			router.Use(middlewares.Errors(), authenticated())
 			SetAccountRoutes(ctx, router, scenario.useCase)
 
 			request, _ := http.NewRequest(http.MethodPost, "/api/v1/accounts", bytes.NewBuffer(scenario.input))
@@ -105,6 +210,7 @@ func Test_AccountGetHandler(t *testing.T) {
 		input          string
 		useCase        usecase.AccountUseCase
 		expectedStatus int
+		expectedETag   string
 	}{
 		{
 			description: "success",
@@ -113,10 +219,30 @@ func Test_AccountGetHandler(t *testing.T) {
 				Result: domain.Account{
 					Id:             "generated-account-id",
 					DocumentNumber: "any-document",
+					Version:        2,
 				},
 				err: nil,
 			},
 			expectedStatus: http.StatusOK,
+			expectedETag:   `"2"`,
+		},
+		{
+			description: "not found error",
+			input:       "any-valid-account-id",
+			useCase: &accountUseCaseMock{
+				Result: domain.Account{},
+				err:    fmt.Errorf("get account: %w", exceptions.EntityNotFoundError),
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			description: "database unavailable",
+			input:       "any-valid-account-id",
+			useCase: &accountUseCaseMock{
+				Result: domain.Account{},
+				err:    fmt.Errorf("get account: %w", exceptions.UnavailableError),
+			},
+			expectedStatus: http.StatusServiceUnavailable,
 		},
 		{
 			description: "persistence error",
@@ -125,7 +251,16 @@ func Test_AccountGetHandler(t *testing.T) {
 				Result: domain.Account{},
 				err:    exceptions.PersistenceError,
 			},
-			expectedStatus: http.StatusNotFound,
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			description: "unexpected error",
+			input:       "any-valid-account-id",
+			useCase: &accountUseCaseMock{
+				Result: domain.Account{},
+				err:    errors.New("any-error"),
+			},
+			expectedStatus: http.StatusInternalServerError,
 		},
 	}
 
@@ -139,6 +274,7 @@ func Test_AccountGetHandler(t *testing.T) {
 
 			rr := httptest.NewRecorder()
 			router := gin.Default()
+			router.Use(middlewares.Errors(), authenticated())
 			SetAccountRoutes(ctx, router, scenario.useCase)
 
 			request, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/accounts/%s", scenario.input), nil)
@@ -146,6 +282,413 @@ func Test_AccountGetHandler(t *testing.T) {
 			router.ServeHTTP(rr, request)
 
 			assert.Equal(t, scenario.expectedStatus, rr.Code)
+			assert.Equal(t, scenario.expectedETag, rr.Header().Get("ETag"))
+		})
+	}
+}
+
+func Test_AccountSearchHandler(t *testing.T) {
+	scenarios := []struct {
+		description    string
+		query          string
+		err            error
+		expectedStatus int
+		expectedCode   string
+		expectedFilter domain.AccountFilter
+	}{
+		{
+			description:    "every account",
+			expectedStatus: http.StatusOK,
+			expectedFilter: domain.AccountFilter{Limit: 50},
+		},
+		{
+			description:    "exact document and status",
+			query:          "?document_number=12345678900&status=active&limit=10&offset=20",
+			expectedStatus: http.StatusOK,
+			expectedFilter: domain.AccountFilter{DocumentNumber: "12345678900", Status: domain.AccountActive, Limit: 10, Offset: 20},
+		},
+		{
+			description:    "document prefix created within dates",
+			query:          "?document_prefix=123&created_from=2024-01-01&created_to=2024-01-31",
+			expectedStatus: http.StatusOK,
+			expectedFilter: domain.AccountFilter{
+				DocumentPrefix: "123",
+				CreatedFrom:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				CreatedTo:      time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+				Limit:          50,
+			},
+		},
+		{
+			description:    "created before a timestamp",
+			query:          "?created_to=2024-01-15T12:00:00Z",
+			expectedStatus: http.StatusOK,
+			expectedFilter: domain.AccountFilter{CreatedTo: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC), Limit: 50},
+		},
+		{
+			description:    "unknown status",
+			query:          "?status=frozen",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_PARAMETER",
+		},
+		{
+			description:    "invalid created_from",
+			query:          "?created_from=yesterday",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_PARAMETER",
+		},
+		{
+			description:    "limit above maximum",
+			query:          "?limit=1000",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_PARAMETER",
+		},
+		{
+			description:    "database unavailable",
+			err:            exceptions.UnavailableError,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   "SERVICE_UNAVAILABLE",
+			expectedFilter: domain.AccountFilter{Limit: 50},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			filter := domain.AccountFilter{}
+			useCase := &accountUseCaseMock{
+				Accounts: []domain.Account{{
+					Id:             "any-account-id",
+					DocumentNumber: "12345678900",
+					Status:         domain.AccountActive,
+					CreatedAt:      time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC),
+					UpdatedAt:      time.Date(2024, 1, 12, 9, 0, 0, 0, time.UTC),
+				}},
+				filter: &filter,
+				err:    scenario.err,
+			}
+
+			rr := httptest.NewRecorder()
+			router := gin.Default()
+			router.Use(middlewares.Errors(), authenticated())
+			SetAccountRoutes(context.Background(), router, useCase)
+
+			request, _ := http.NewRequest(http.MethodGet, "/api/v1/accounts"+scenario.query, nil)
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+			assert.Equal(t, scenario.expectedFilter, filter)
+
+			if scenario.expectedCode != "" {
+				var problem exceptions.Problem
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+				assert.Equal(t, scenario.expectedCode, problem.Code)
+				return
+			}
+
+			assert.JSONEq(t, `{
+				"items": [{"id": "any-account-id", "document_number": "12345678900", "status": "active", "created_at": "2024-01-10T09:00:00Z", "updated_at": "2024-01-12T09:00:00Z"}],
+				"limit": `+strconv.Itoa(scenario.expectedFilter.Limit)+`,
+				"offset": `+strconv.Itoa(scenario.expectedFilter.Offset)+`
+			}`, rr.Body.String())
+		})
+	}
+}
+
+func Test_AccountPatchHandler(t *testing.T) {
+	persisted := domain.Account{
+		Id:             "any-account-id",
+		DocumentNumber: "12345678900",
+		Status:         domain.AccountActive,
+		AccountProfile: domain.AccountProfile{
+			HolderName: "Ada Lovelace",
+			Address:    &domain.Address{Line1: "12 St James's Square", City: "London", Country: "GB"},
+			Metadata:   map[string]string{"tier": "gold", "segment": "retail"},
+		},
+		Version:   3,
+		CreatedAt: time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 1, 12, 9, 0, 0, 0, time.UTC),
+	}
+
+	scenarios := []struct {
+		description    string
+		contentType    string
+		ifMatch        string
+		input          string
+		err            error
+		expectedStatus int
+		expectedCode   string
+		expectedETag   string
+		expectedBody   string
+	}{
+		{
+			description:    "members are merged",
+			contentType:    "application/merge-patch+json",
+			ifMatch:        `"3"`,
+			input:          `{"email": "ada@example.com", "address": {"city": "Cambridge"}, "metadata": {"segment": null, "channel": "app"}}`,
+			expectedStatus: http.StatusOK,
+			expectedETag:   `"4"`,
+			expectedBody: `{
+				"id": "any-account-id", "document_number": "12345678900", "status": "active",
+				"holder_name": "Ada Lovelace", "email": "ada@example.com",
+				"address": {"line1": "12 St James's Square", "city": "Cambridge", "country": "GB"},
+				"metadata": {"tier": "gold", "channel": "app"},
+				"created_at": "2024-01-10T09:00:00Z", "updated_at": "2024-01-12T09:00:00Z"
+			}`,
+		},
+		{
+			description:    "null members are removed",
+			contentType:    "application/json",
+			ifMatch:        "*",
+			input:          `{"holder_name": null, "address": null, "metadata": null}`,
+			expectedStatus: http.StatusOK,
+			expectedETag:   `"4"`,
+			expectedBody: `{
+				"id": "any-account-id", "document_number": "12345678900", "status": "active",
+				"created_at": "2024-01-10T09:00:00Z", "updated_at": "2024-01-12T09:00:00Z"
+			}`,
+		},
+		{
+			description:    "identity is not patchable",
+			contentType:    "application/merge-patch+json",
+			ifMatch:        `"3"`,
+			input:          `{"document_number": "other-document", "status": "closed"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "VALIDATION_ERROR",
+		},
+		{
+			description:    "unknown address member",
+			contentType:    "application/merge-patch+json",
+			ifMatch:        `"3"`,
+			input:          `{"address": {"zip": "01310-100"}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "VALIDATION_ERROR",
+		},
+		{
+			description:    "metadata values are strings",
+			contentType:    "application/merge-patch+json",
+			ifMatch:        `"3"`,
+			input:          `{"metadata": {"score": 10}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "VALIDATION_ERROR",
+		},
+		{
+			description:    "invalid json",
+			contentType:    "application/merge-patch+json",
+			ifMatch:        `"3"`,
+			input:          `{"email": `,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "VALIDATION_ERROR",
+		},
+		{
+			description:    "stale version",
+			contentType:    "application/merge-patch+json",
+			ifMatch:        `"2"`,
+			input:          `{"email": "ada@example.com"}`,
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   "PRECONDITION_FAILED",
+		},
+		{
+			description:    "weak entity tag",
+			contentType:    "application/merge-patch+json",
+			ifMatch:        `W/"3"`,
+			input:          `{"email": "ada@example.com"}`,
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   "PRECONDITION_FAILED",
+		},
+		{
+			description:    "several entity tags",
+			contentType:    "application/merge-patch+json",
+			ifMatch:        `"2", "3"`,
+			input:          `{"email": "ada@example.com"}`,
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   "PRECONDITION_FAILED",
+		},
+		{
+			description:    "missing If-Match",
+			contentType:    "application/merge-patch+json",
+			input:          `{"email": "ada@example.com"}`,
+			expectedStatus: http.StatusPreconditionRequired,
+			expectedCode:   "PRECONDITION_REQUIRED",
+		},
+		{
+			description:    "unsupported content type",
+			contentType:    "text/plain",
+			ifMatch:        `"3"`,
+			input:          `{"email": "ada@example.com"}`,
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedCode:   "UNSUPPORTED_MEDIA_TYPE",
+		},
+		{
+			description:    "account not found",
+			contentType:    "application/merge-patch+json",
+			ifMatch:        `"3"`,
+			input:          `{"email": "ada@example.com"}`,
+			err:            fmt.Errorf("get account: %w", exceptions.EntityNotFoundError),
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "ENTITY_NOT_FOUND",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			useCase := &accountUseCaseMock{Result: persisted, err: scenario.err}
+
+			rr := httptest.NewRecorder()
+			router := gin.Default()
+			router.Use(middlewares.Errors(), authenticated())
+			SetAccountRoutes(context.Background(), router, useCase)
+
+			request, _ := http.NewRequest(http.MethodPatch, "/api/v1/accounts/any-account-id", bytes.NewBufferString(scenario.input))
+			request.Header.Set("Content-Type", scenario.contentType)
+			if scenario.ifMatch != "" {
+				request.Header.Set("If-Match", scenario.ifMatch)
+			}
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+			assert.Equal(t, scenario.expectedETag, rr.Header().Get("ETag"))
+
+			if scenario.expectedCode != "" {
+				var problem exceptions.Problem
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+				assert.Equal(t, scenario.expectedCode, problem.Code)
+				return
+			}
+
+			assert.JSONEq(t, scenario.expectedBody, rr.Body.String())
+		})
+	}
+}
+
+func Test_AccountLifecycleHandler(t *testing.T) {
+	persisted := domain.Account{
+		Id:             "any-account-id",
+		DocumentNumber: "12345678900",
+		Status:         domain.AccountActive,
+		AccountProfile: domain.AccountProfile{HolderName: "Ada Lovelace"},
+		Version:        3,
+		CreatedAt:      time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC),
+		UpdatedAt:      time.Date(2024, 1, 12, 9, 0, 0, 0, time.UTC),
+	}
+
+	scenarios := []struct {
+		description    string
+		method         string
+		path           string
+		scopes         []auth.Scope
+		ifMatch        string
+		err            error
+		expectedStatus int
+		expectedCode   string
+		expectedETag   string
+		expectedBody   string
+	}{
+		{
+			description:    "close",
+			method:         http.MethodPost,
+			path:           "/api/v1/accounts/any-account-id/close",
+			ifMatch:        `"3"`,
+			expectedStatus: http.StatusOK,
+			expectedETag:   `"4"`,
+			expectedBody: `{
+				"id": "any-account-id", "document_number": "12345678900", "status": "closed", "holder_name": "Ada Lovelace",
+				"created_at": "2024-01-10T09:00:00Z", "updated_at": "2024-01-12T09:00:00Z", "closed_at": "2024-01-12T09:00:00Z"
+			}`,
+		},
+		{
+			description:    "close without If-Match",
+			method:         http.MethodPost,
+			path:           "/api/v1/accounts/any-account-id/close",
+			expectedStatus: http.StatusPreconditionRequired,
+			expectedCode:   "PRECONDITION_REQUIRED",
+		},
+		{
+			description:    "close closed account",
+			method:         http.MethodPost,
+			path:           "/api/v1/accounts/any-account-id/close",
+			ifMatch:        `"3"`,
+			err:            exceptions.InvalidStateError,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   "INVALID_STATE",
+		},
+		{
+			description:    "erase",
+			method:         http.MethodPost,
+			path:           "/api/v1/accounts/any-account-id/erasure",
+			ifMatch:        `"3"`,
+			expectedStatus: http.StatusOK,
+			expectedETag:   `"4"`,
+			expectedBody: `{
+				"id": "any-account-id", "document_number": "erased-document", "status": "active",
+				"created_at": "2024-01-10T09:00:00Z", "updated_at": "2024-01-12T09:00:00Z", "erased_at": "2024-01-12T09:00:00Z"
+			}`,
+		},
+		{
+			description:    "erase without scope",
+			method:         http.MethodPost,
+			path:           "/api/v1/accounts/any-account-id/erasure",
+			scopes:         []auth.Scope{auth.ScopeAccountsWrite},
+			ifMatch:        `"3"`,
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   "FORBIDDEN",
+		},
+		{
+			description:    "delete",
+			method:         http.MethodDelete,
+			path:           "/api/v1/accounts/any-account-id",
+			ifMatch:        `"3"`,
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			description:    "delete stale version",
+			method:         http.MethodDelete,
+			path:           "/api/v1/accounts/any-account-id",
+			ifMatch:        `"2"`,
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   "PRECONDITION_FAILED",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			useCase := &accountUseCaseMock{Result: persisted, err: scenario.err}
+
+			rr := httptest.NewRecorder()
+			router := gin.Default()
+			router.Use(middlewares.Errors(), authenticated())
+			if scenario.scopes != nil {
+				router.Use(func(c *gin.Context) {
+					c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), auth.Principal{
+						ClientID: "any-client",
+						Scopes:   scenario.scopes,
+					}))
+				})
+			}
+			SetAccountRoutes(context.Background(), router, useCase)
+
+			request, _ := http.NewRequest(scenario.method, scenario.path, nil)
+			if scenario.ifMatch != "" {
+				request.Header.Set("If-Match", scenario.ifMatch)
+			}
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+			assert.Equal(t, scenario.expectedETag, rr.Header().Get("ETag"))
+
+			if scenario.expectedCode != "" {
+				var problem exceptions.Problem
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+				assert.Equal(t, scenario.expectedCode, problem.Code)
+				return
+			}
+
+			if scenario.expectedBody == "" {
+				assert.Empty(t, rr.Body.String())
+				return
+			}
+			assert.JSONEq(t, scenario.expectedBody, rr.Body.String())
 		})
 	}
 }
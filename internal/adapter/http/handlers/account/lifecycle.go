@@ -0,0 +1,77 @@
+package account
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/http/handlers/etag"
+	"github.com/payment-api/internal/domain"
+	"github.com/payment-api/internal/usecase"
+)
+
+// closeAccount closes an active account, which then rejects new
+// transactions. The If-Match header must carry the ETag of the account.
+func closeAccount(_ context.Context, accountUseCase usecase.AccountUseCase) gin.HandlerFunc {
+	return transitionAccount("closeAccount", accountUseCase.Close)
+}
+
+// eraseAccount erases the personal data of a closed account. The If-Match
+// header must carry the ETag of the account.
+func eraseAccount(_ context.Context, accountUseCase usecase.AccountUseCase) gin.HandlerFunc {
+	return transitionAccount("eraseAccount", accountUseCase.Erase)
+}
+
+func transitionAccount(name string, transition func(context.Context, string, int) (domain.Account, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:"+name, trace.SpanKindServer)
+		defer span.End()
+
+		version, err := etag.IfMatch(c)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			_ = c.Error(err)
+			return
+		}
+
+		account, err := transition(ctx, c.Param("account_id"), version)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed "+name, logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		etag.Set(c, account.Version)
+		c.JSON(http.StatusOK, NewResponse(account))
+	}
+}
+
+// deleteAccount soft deletes a closed account, which then answers 404. The
+// If-Match header must carry the ETag of the account.
+func deleteAccount(_ context.Context, accountUseCase usecase.AccountUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:deleteAccount", trace.SpanKindServer)
+		defer span.End()
+
+		version, err := etag.IfMatch(c)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			_ = c.Error(err)
+			return
+		}
+
+		if err := accountUseCase.Delete(ctx, c.Param("account_id"), version); err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed delete account", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
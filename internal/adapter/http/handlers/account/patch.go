@@ -0,0 +1,103 @@
+package account
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/domain"
+)
+
+// mergePatch applies an RFC 7396 merge patch to target: members of patch
+// replace those of target, null members remove them and objects are merged
+// recursively. A patch that is not an object replaces target entirely.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{}, len(patchObject))
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+
+	return targetObject
+}
+
+// checkPatch rejects members of patch that are not part of Profile, so typos
+// and attempts to change the identity of an account are not silently ignored.
+func checkPatch(patch map[string]interface{}) error {
+	fields := make([]exceptions.FieldError, 0)
+
+	for name, value := range patch {
+		nested, known := profileFields[name]
+		if !known {
+			fields = append(fields, exceptions.FieldError{Field: name, Message: "cannot be patched"})
+			continue
+		}
+
+		object, ok := value.(map[string]interface{})
+		if !ok || nested == nil {
+			continue
+		}
+
+		for member := range object {
+			if !contains(nested, member) {
+				fields = append(fields, exceptions.FieldError{Field: name + "." + member, Message: "is not a known field"})
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return exceptions.ValidationError.WithFields(fields...)
+}
+
+// applyPatch merges patch into the profile of account.
+func applyPatch(account *domain.Account, patch map[string]interface{}) error {
+	raw, err := json.Marshal(NewProfile(account.AccountProfile))
+	if err != nil {
+		return err
+	}
+
+	var document interface{}
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return err
+	}
+
+	merged, err := json.Marshal(mergePatch(document, patch))
+	if err != nil {
+		return err
+	}
+
+	var profile Profile
+	if err := json.Unmarshal(merged, &profile); err != nil {
+		return exceptions.FromBinding(err)
+	}
+
+	account.AccountProfile = profile.domain()
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
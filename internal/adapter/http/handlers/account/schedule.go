@@ -0,0 +1,106 @@
+package account
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/usecase"
+)
+
+func SetScheduleRoutes(ctx context.Context, r *gin.Engine, s usecase.ScheduleUseCase) {
+	payments := r.Group("/api/v1/accounts/:account_id/scheduled-payments")
+
+	payments.POST("", middlewares.Authorize(auth.ScopeTransactionsWrite), createSchedule(ctx, s))
+	payments.GET("", middlewares.Authorize(auth.ScopeAccountsRead), listSchedules(ctx, s))
+	payments.GET("/:payment_id", middlewares.Authorize(auth.ScopeAccountsRead), getSchedule(ctx, s))
+	payments.DELETE("/:payment_id", middlewares.Authorize(auth.ScopeTransactionsWrite), cancelSchedule(ctx, s))
+}
+
+func createSchedule(_ context.Context, scheduleUseCase usecase.ScheduleUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:createSchedule", trace.SpanKindServer)
+		defer span.End()
+
+		var request ScheduleRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "cannot marshal body", logger.Err(err))
+			_ = c.Error(exceptions.FromBinding(err))
+			return
+		}
+
+		payment, err := scheduleUseCase.Create(ctx, request.ScheduledPayment(c.Param("account_id")))
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed create scheduled payment", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, NewScheduleResponse(payment))
+	}
+}
+
+func listSchedules(_ context.Context, scheduleUseCase usecase.ScheduleUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:listSchedules", trace.SpanKindServer)
+		defer span.End()
+
+		payments, err := scheduleUseCase.List(ctx, c.Param("account_id"))
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed list scheduled payments", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		items := make([]ScheduleResponse, 0, len(payments))
+		for _, payment := range payments {
+			items = append(items, NewScheduleResponse(payment))
+		}
+
+		c.JSON(http.StatusOK, ScheduleListResponse{Items: items})
+	}
+}
+
+func getSchedule(_ context.Context, scheduleUseCase usecase.ScheduleUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:getSchedule", trace.SpanKindServer)
+		defer span.End()
+
+		payment, err := scheduleUseCase.Get(ctx, c.Param("account_id"), c.Param("payment_id"))
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed get scheduled payment", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, NewScheduleResponse(payment))
+	}
+}
+
+func cancelSchedule(_ context.Context, scheduleUseCase usecase.ScheduleUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:cancelSchedule", trace.SpanKindServer)
+		defer span.End()
+
+		if err := scheduleUseCase.Cancel(ctx, c.Param("account_id"), c.Param("payment_id")); err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed cancel scheduled payment", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,186 @@
+package account
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/domain"
+)
+
+type scheduleUseCaseMock struct {
+	created  *domain.ScheduledPayment
+	canceled *[2]string
+	err      error
+}
+
+func (s scheduleUseCaseMock) Create(_ context.Context, payment domain.ScheduledPayment) (domain.ScheduledPayment, error) {
+	*s.created = payment
+	payment.Id, payment.Status = "any-payment-id", domain.ScheduleActive
+	return payment, s.err
+}
+
+func (s scheduleUseCaseMock) Get(_ context.Context, accountID, id string) (domain.ScheduledPayment, error) {
+	return domain.ScheduledPayment{Id: id, AccountID: accountID, Status: domain.ScheduleActive}, s.err
+}
+
+func (s scheduleUseCaseMock) List(_ context.Context, accountID string) ([]domain.ScheduledPayment, error) {
+	return []domain.ScheduledPayment{{Id: "any-payment-id", AccountID: accountID, Status: domain.ScheduleActive}}, s.err
+}
+
+func (s scheduleUseCaseMock) Cancel(_ context.Context, accountID, id string) error {
+	*s.canceled = [2]string{accountID, id}
+	return s.err
+}
+
+func (s scheduleUseCaseMock) Run(context.Context) (int, error) {
+	return 0, nil
+}
+
+func Test_ScheduleHandler(t *testing.T) {
+	runAt := time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description      string
+		method           string
+		path             string
+		body             string
+		scopes           []auth.Scope
+		err              error
+		expectedStatus   int
+		expectedCode     string
+		expectedCreated  domain.ScheduledPayment
+		expectedCanceled [2]string
+	}{
+		{
+			description:    "create one-off",
+			method:         http.MethodPost,
+			path:           "/api/v1/accounts/any-account-id/scheduled-payments",
+			body:           `{"frequency": "once", "run_at": "2024-02-01T06:00:00-03:00", "amount_type": "fixed", "amount": 50}`,
+			scopes:         []auth.Scope{auth.ScopeTransactionsWrite},
+			expectedStatus: http.StatusCreated,
+			expectedCreated: domain.ScheduledPayment{
+				AccountID: "any-account-id", Frequency: domain.ScheduleOnce, NextRunAt: runAt, AmountType: domain.AmountFixed, Amount: 50,
+			},
+		},
+		{
+			description:    "create monthly",
+			method:         http.MethodPost,
+			path:           "/api/v1/accounts/any-account-id/scheduled-payments",
+			body:           `{"frequency": "monthly", "day_of_month": 10, "amount_type": "statement_balance"}`,
+			scopes:         []auth.Scope{auth.ScopeTransactionsWrite},
+			expectedStatus: http.StatusCreated,
+			expectedCreated: domain.ScheduledPayment{
+				AccountID: "any-account-id", Frequency: domain.ScheduleMonthly, DayOfMonth: 10, AmountType: domain.AmountStatementBalance,
+			},
+		},
+		{
+			description:    "create without frequency",
+			method:         http.MethodPost,
+			path:           "/api/v1/accounts/any-account-id/scheduled-payments",
+			body:           `{"amount_type": "fixed", "amount": 50}`,
+			scopes:         []auth.Scope{auth.ScopeTransactionsWrite},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "VALIDATION_ERROR",
+		},
+		{
+			description:    "create without transactions:write",
+			method:         http.MethodPost,
+			path:           "/api/v1/accounts/any-account-id/scheduled-payments",
+			body:           `{"frequency": "monthly", "day_of_month": 10, "amount_type": "statement_balance"}`,
+			scopes:         []auth.Scope{auth.ScopeAccountsRead},
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   "FORBIDDEN",
+		},
+		{
+			description:    "list",
+			method:         http.MethodGet,
+			path:           "/api/v1/accounts/any-account-id/scheduled-payments",
+			scopes:         []auth.Scope{auth.ScopeAccountsRead},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "get",
+			method:         http.MethodGet,
+			path:           "/api/v1/accounts/any-account-id/scheduled-payments/any-payment-id",
+			scopes:         []auth.Scope{auth.ScopeAccountsRead},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "get not found",
+			method:         http.MethodGet,
+			path:           "/api/v1/accounts/any-account-id/scheduled-payments/any-payment-id",
+			scopes:         []auth.Scope{auth.ScopeAccountsRead},
+			err:            exceptions.EntityNotFoundError,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "ENTITY_NOT_FOUND",
+		},
+		{
+			description:      "cancel",
+			method:           http.MethodDelete,
+			path:             "/api/v1/accounts/any-account-id/scheduled-payments/any-payment-id",
+			scopes:           []auth.Scope{auth.ScopeTransactionsWrite},
+			expectedStatus:   http.StatusNoContent,
+			expectedCanceled: [2]string{"any-account-id", "any-payment-id"},
+		},
+		{
+			description:      "cancel cancelled",
+			method:           http.MethodDelete,
+			path:             "/api/v1/accounts/any-account-id/scheduled-payments/any-payment-id",
+			scopes:           []auth.Scope{auth.ScopeTransactionsWrite},
+			err:              exceptions.InvalidStateError,
+			expectedStatus:   http.StatusUnprocessableEntity,
+			expectedCode:     "INVALID_STATE",
+			expectedCanceled: [2]string{"any-account-id", "any-payment-id"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var created domain.ScheduledPayment
+			var canceled [2]string
+			useCase := scheduleUseCaseMock{created: &created, canceled: &canceled, err: scenario.err}
+
+			rr := httptest.NewRecorder()
+			router := gin.Default()
+			router.Use(middlewares.Errors(), func(c *gin.Context) {
+				c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), auth.Principal{ClientID: "any-client", Scopes: scenario.scopes}))
+			})
+			SetScheduleRoutes(context.Background(), router, useCase)
+
+			request, _ := http.NewRequest(scenario.method, scenario.path, bytes.NewBufferString(scenario.body))
+			request.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+			assert.Equal(t, scenario.expectedCreated, created)
+			assert.Equal(t, scenario.expectedCanceled, canceled)
+
+			if scenario.expectedCode != "" {
+				var problem exceptions.Problem
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+				assert.Equal(t, scenario.expectedCode, problem.Code)
+				return
+			}
+
+			if scenario.method == http.MethodDelete {
+				assert.Empty(t, rr.Body.String())
+				return
+			}
+
+			assert.Contains(t, rr.Body.String(), `"id":"any-payment-id"`)
+			assert.Contains(t, rr.Body.String(), `"status":"active"`)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package account
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/usecase"
+)
+
+// SetSpendingRoutes serves the purchases of an account summed by category,
+// and by merchant within a category, for the period of from and to.
+func SetSpendingRoutes(ctx context.Context, r *gin.Engine, s usecase.SpendingUseCase) {
+	spending := r.Group("/api/v1/accounts/:account_id/spending")
+
+	spending.GET("", middlewares.Authorize(auth.ScopeAccountsRead), spendingByCategory(ctx, s))
+	spending.GET("/:category", middlewares.Authorize(auth.ScopeAccountsRead), spendingByMerchant(ctx, s))
+}
+
+func spendingByCategory(_ context.Context, spendingUseCase usecase.SpendingUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:spendingByCategory", trace.SpanKindServer)
+		defer span.End()
+
+		from, to, err := parsePeriod(c.Query("from"), c.Query("to"), time.Now())
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			_ = c.Error(err)
+			return
+		}
+
+		spending, err := spendingUseCase.ByCategory(ctx, c.Param("account_id"), from, to)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed get spending by category", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, NewSpendingResponse(spending))
+	}
+}
+
+func spendingByMerchant(_ context.Context, spendingUseCase usecase.SpendingUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:spendingByMerchant", trace.SpanKindServer)
+		defer span.End()
+
+		from, to, err := parsePeriod(c.Query("from"), c.Query("to"), time.Now())
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			_ = c.Error(err)
+			return
+		}
+
+		spending, err := spendingUseCase.ByMerchant(ctx, c.Param("account_id"), c.Param("category"), from, to)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed get spending by merchant", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, NewSpendingResponse(spending))
+	}
+}
@@ -0,0 +1,125 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/domain"
+)
+
+type spendingUseCaseMock struct {
+	period *[2]time.Time
+	err    error
+}
+
+func (s spendingUseCaseMock) ByCategory(_ context.Context, accountID string, from, to time.Time) (domain.Spending, error) {
+	*s.period = [2]time.Time{from, to}
+	return domain.Spending{
+		AccountID: accountID, From: from, To: to, Total: 150.5, Count: 2,
+		Items: []domain.SpendingItem{{Name: "restaurants", Amount: 150.5, Count: 2}},
+	}, s.err
+}
+
+func (s spendingUseCaseMock) ByMerchant(_ context.Context, accountID, category string, from, to time.Time) (domain.Spending, error) {
+	*s.period = [2]time.Time{from, to}
+	return domain.Spending{
+		AccountID: accountID, From: from, To: to, Category: category, Total: 150.5, Count: 2,
+		Items: []domain.SpendingItem{{Name: "Cantina", MCC: "5812", Amount: 150.5, Count: 2}},
+	}, s.err
+}
+
+func Test_SpendingHandler(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description    string
+		path           string
+		scopes         []auth.Scope
+		err            error
+		expectedStatus int
+		expectedCode   string
+		expectedPeriod [2]time.Time
+		expectedBody   string
+	}{
+		{
+			description:    "by category",
+			path:           "/api/v1/accounts/any-account-id/spending?from=2024-01-01&to=2024-01-31",
+			scopes:         []auth.Scope{auth.ScopeAccountsRead},
+			expectedStatus: http.StatusOK,
+			expectedPeriod: [2]time.Time{from, to},
+			expectedBody:   `"items":[{"category":"restaurants","amount":150.5,"count":2}]`,
+		},
+		{
+			description:    "by merchant",
+			path:           "/api/v1/accounts/any-account-id/spending/restaurants?from=2024-01-01&to=2024-01-31",
+			scopes:         []auth.Scope{auth.ScopeAccountsRead},
+			expectedStatus: http.StatusOK,
+			expectedPeriod: [2]time.Time{from, to},
+			expectedBody:   `"items":[{"merchant":"Cantina","mcc":"5812","amount":150.5,"count":2}]`,
+		},
+		{
+			description:    "invalid period",
+			path:           "/api/v1/accounts/any-account-id/spending?from=yesterday",
+			scopes:         []auth.Scope{auth.ScopeAccountsRead},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_PARAMETER",
+		},
+		{
+			description:    "unknown category",
+			path:           "/api/v1/accounts/any-account-id/spending/unknown?from=2024-01-01&to=2024-01-31",
+			scopes:         []auth.Scope{auth.ScopeAccountsRead},
+			err:            exceptions.InvalidParameterError,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_PARAMETER",
+			expectedPeriod: [2]time.Time{from, to},
+		},
+		{
+			description:    "without accounts:read",
+			path:           "/api/v1/accounts/any-account-id/spending",
+			scopes:         []auth.Scope{auth.ScopeTransactionsWrite},
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   "FORBIDDEN",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var period [2]time.Time
+			useCase := spendingUseCaseMock{period: &period, err: scenario.err}
+
+			rr := httptest.NewRecorder()
+			router := gin.Default()
+			router.Use(middlewares.Errors(), func(c *gin.Context) {
+				c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), auth.Principal{ClientID: "any-client", Scopes: scenario.scopes}))
+			})
+			SetSpendingRoutes(context.Background(), router, useCase)
+
+			request, _ := http.NewRequest(http.MethodGet, scenario.path, nil)
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+			assert.Equal(t, scenario.expectedPeriod, period)
+
+			if scenario.expectedCode != "" {
+				var problem exceptions.Problem
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+				assert.Equal(t, scenario.expectedCode, problem.Code)
+				return
+			}
+
+			assert.Contains(t, rr.Body.String(), scenario.expectedBody)
+		})
+	}
+}
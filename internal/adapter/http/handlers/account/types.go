@@ -1,5 +1,261 @@
 package account
 
+import (
+	"time"
+
+	"github.com/payment-api/internal/domain"
+)
+
 type Request struct {
 	DocumentNumber string `json:"document_number" binding:"required"`
+	Profile
+}
+
+// Profile is the patchable part of an account, the document of the merge
+// patches sent to PATCH /api/v1/accounts/:account_id.
+type Profile struct {
+	HolderName string            `json:"holder_name,omitempty"`
+	Email      string            `json:"email,omitempty"`
+	Phone      string            `json:"phone,omitempty"`
+	Address    *Address          `json:"address,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+type Address struct {
+	Line1      string `json:"line1,omitempty"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city,omitempty"`
+	State      string `json:"state,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	Country    string `json:"country,omitempty"`
+}
+
+type Response struct {
+	Id             string `json:"id"`
+	DocumentNumber string `json:"document_number"`
+	Status         string `json:"status"`
+	Profile
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+	ErasedAt  *time.Time `json:"erased_at,omitempty"`
+}
+
+type ListResponse struct {
+	Items  []Response `json:"items"`
+	Limit  int        `json:"limit"`
+	Offset int        `json:"offset"`
+}
+
+// profileFields lists the members a merge patch may set, with the members of
+// their nested objects.
+var profileFields = map[string][]string{
+	"holder_name": nil,
+	"email":       nil,
+	"phone":       nil,
+	"address":     {"line1", "line2", "city", "state", "postal_code", "country"},
+	"metadata":    nil,
+}
+
+func (p Profile) domain() domain.AccountProfile {
+	profile := domain.AccountProfile{
+		HolderName: p.HolderName,
+		Email:      p.Email,
+		Phone:      p.Phone,
+		Metadata:   p.Metadata,
+	}
+
+	if p.Address != nil {
+		profile.Address = &domain.Address{
+			Line1:      p.Address.Line1,
+			Line2:      p.Address.Line2,
+			City:       p.Address.City,
+			State:      p.Address.State,
+			PostalCode: p.Address.PostalCode,
+			Country:    p.Address.Country,
+		}
+	}
+
+	return profile
+}
+
+func NewProfile(profile domain.AccountProfile) Profile {
+	p := Profile{
+		HolderName: profile.HolderName,
+		Email:      profile.Email,
+		Phone:      profile.Phone,
+		Metadata:   profile.Metadata,
+	}
+
+	if profile.Address != nil {
+		p.Address = &Address{
+			Line1:      profile.Address.Line1,
+			Line2:      profile.Address.Line2,
+			City:       profile.Address.City,
+			State:      profile.Address.State,
+			PostalCode: profile.Address.PostalCode,
+			Country:    profile.Address.Country,
+		}
+	}
+
+	return p
+}
+
+func NewResponse(account domain.Account) Response {
+	return Response{
+		Id:             account.Id,
+		DocumentNumber: account.DocumentNumber,
+		Status:         string(account.Status),
+		Profile:        NewProfile(account.AccountProfile),
+		CreatedAt:      account.CreatedAt,
+		UpdatedAt:      account.UpdatedAt,
+		ClosedAt:       account.ClosedAt,
+		ErasedAt:       account.ErasedAt,
+	}
+}
+
+// ScheduleRequest schedules a payment, once at RunAt or monthly on DayOfMonth.
+type ScheduleRequest struct {
+	Frequency  string     `json:"frequency" binding:"required"`
+	RunAt      *time.Time `json:"run_at"`
+	DayOfMonth int        `json:"day_of_month"`
+	AmountType string     `json:"amount_type" binding:"required"`
+	Amount     float64    `json:"amount"`
+}
+
+type ScheduleResponse struct {
+	Id                string     `json:"id"`
+	AccountID         string     `json:"account_id"`
+	Frequency         string     `json:"frequency"`
+	DayOfMonth        int        `json:"day_of_month,omitempty"`
+	AmountType        string     `json:"amount_type"`
+	Amount            float64    `json:"amount,omitempty"`
+	Status            string     `json:"status"`
+	NextRunAt         time.Time  `json:"next_run_at"`
+	Attempts          int        `json:"attempts"`
+	LastRunAt         *time.Time `json:"last_run_at,omitempty"`
+	LastTransactionID int        `json:"last_transaction_id,omitempty"`
+	LastError         string     `json:"last_error,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+type ScheduleListResponse struct {
+	Items []ScheduleResponse `json:"items"`
+}
+
+func (r ScheduleRequest) ScheduledPayment(accountID string) domain.ScheduledPayment {
+	payment := domain.ScheduledPayment{
+		AccountID:  accountID,
+		Frequency:  domain.ScheduleFrequency(r.Frequency),
+		DayOfMonth: r.DayOfMonth,
+		AmountType: domain.ScheduleAmount(r.AmountType),
+		Amount:     r.Amount,
+	}
+
+	if r.RunAt != nil {
+		payment.NextRunAt = r.RunAt.UTC()
+	}
+
+	return payment
+}
+
+func NewScheduleResponse(payment domain.ScheduledPayment) ScheduleResponse {
+	return ScheduleResponse{
+		Id:                payment.Id,
+		AccountID:         payment.AccountID,
+		Frequency:         string(payment.Frequency),
+		DayOfMonth:        payment.DayOfMonth,
+		AmountType:        string(payment.AmountType),
+		Amount:            payment.Amount,
+		Status:            string(payment.Status),
+		NextRunAt:         payment.NextRunAt,
+		Attempts:          payment.Attempts,
+		LastRunAt:         payment.LastRunAt,
+		LastTransactionID: payment.LastTransactionID,
+		LastError:         payment.LastError,
+		CreatedAt:         payment.CreatedAt,
+		UpdatedAt:         payment.UpdatedAt,
+	}
+}
+
+type CardResponse struct {
+	Id          string    `json:"id"`
+	AccountID   string    `json:"account_id"`
+	Token       string    `json:"token"`
+	LastFour    string    `json:"last_four"`
+	ExpiryMonth int       `json:"expiry_month"`
+	ExpiryYear  int       `json:"expiry_year"`
+	Status      string    `json:"status"`
+	ReplacedBy  string    `json:"replaced_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CardListResponse struct {
+	Items []CardResponse `json:"items"`
+}
+
+// IssuedCardResponse is the only response carrying the card number.
+type IssuedCardResponse struct {
+	CardResponse
+	PAN string `json:"pan"`
+}
+
+func NewCardResponse(card domain.Card) CardResponse {
+	return CardResponse{
+		Id:          card.Id,
+		AccountID:   card.AccountID,
+		Token:       card.Token,
+		LastFour:    card.LastFour,
+		ExpiryMonth: card.ExpiryMonth,
+		ExpiryYear:  card.ExpiryYear,
+		Status:      string(card.Status),
+		ReplacedBy:  card.ReplacedBy,
+		CreatedAt:   card.CreatedAt,
+		UpdatedAt:   card.UpdatedAt,
+	}
+}
+
+// SpendingResponse lists categories, or the merchants of Category when it is
+// set, with the amount and number of their purchases.
+type SpendingResponse struct {
+	AccountID string                 `json:"account_id"`
+	From      time.Time              `json:"from"`
+	To        time.Time              `json:"to"`
+	Category  string                 `json:"category,omitempty"`
+	Total     float64                `json:"total"`
+	Count     int                    `json:"count"`
+	Items     []SpendingItemResponse `json:"items"`
+}
+
+type SpendingItemResponse struct {
+	Category string  `json:"category,omitempty"`
+	Merchant string  `json:"merchant,omitempty"`
+	MCC      string  `json:"mcc,omitempty"`
+	Amount   float64 `json:"amount"`
+	Count    int     `json:"count"`
+}
+
+func NewSpendingResponse(spending domain.Spending) SpendingResponse {
+	items := make([]SpendingItemResponse, 0, len(spending.Items))
+	for _, item := range spending.Items {
+		response := SpendingItemResponse{Amount: item.Amount, Count: item.Count}
+		if spending.Category == "" {
+			response.Category = item.Name
+		} else {
+			response.Merchant, response.MCC = item.Name, item.MCC
+		}
+		items = append(items, response)
+	}
+
+	return SpendingResponse{
+		AccountID: spending.AccountID,
+		From:      spending.From,
+		To:        spending.To,
+		Category:  spending.Category,
+		Total:     spending.Total,
+		Count:     spending.Count,
+		Items:     items,
+	}
 }
@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/http/handlers/pagination"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/domain"
+	"github.com/payment-api/internal/usecase"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+func SetAuditRoutes(ctx context.Context, r *gin.Engine, s usecase.AuditUseCase) {
+	r.GET("/api/v1/audit", middlewares.Authorize(auth.ScopeAuditRead), listAudit(ctx, s))
+}
+
+func listAudit(_ context.Context, auditUseCase usecase.AuditUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:listAudit", trace.SpanKindServer)
+		defer span.End()
+
+		limit, offset, err := pagination.Parse(c, defaultLimit, maxLimit)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			_ = c.Error(err)
+			return
+		}
+
+		filter := domain.AuditFilter{
+			EntityType: domain.AuditEntity(c.Query("entity_type")),
+			EntityID:   c.Query("entity_id"),
+			Limit:      limit,
+			Offset:     offset,
+		}
+
+		switch filter.EntityType {
+		case "", domain.AuditAccount, domain.AuditTransaction, domain.AuditImport, domain.AuditSchedule, domain.AuditCard:
+		default:
+			_ = c.Error(exceptions.InvalidParameterError.WithFields(exceptions.FieldError{
+				Field:   "entity_type",
+				Message: "must be one of account, transaction, import, scheduled_payment, card",
+			}))
+			return
+		}
+
+		if filter.EntityID != "" && filter.EntityType == "" {
+			_ = c.Error(exceptions.InvalidParameterError.WithFields(exceptions.FieldError{
+				Field:   "entity_type",
+				Message: "is required with entity_id",
+			}))
+			return
+		}
+
+		entries, err := auditUseCase.List(ctx, filter)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed list audit entries", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		items := make([]Response, 0, len(entries))
+		for _, entry := range entries {
+			items = append(items, NewResponse(entry))
+		}
+
+		c.JSON(http.StatusOK, ListResponse{Items: items, Limit: limit, Offset: offset})
+	}
+}
@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/domain"
+)
+
+type auditUseCaseMock struct {
+	Result []domain.AuditEntry
+	filter *domain.AuditFilter
+	err    error
+}
+
+func (a auditUseCaseMock) Begin(ctx context.Context) (context.Context, func(error) error, error) {
+	return ctx, func(err error) error { return err }, nil
+}
+
+func (a auditUseCaseMock) Savepoint(ctx context.Context, fn func(context.Context) error) error {
+	return fn(ctx)
+}
+
+func (a auditUseCaseMock) Record(context.Context, domain.AuditChange, error) {}
+
+func (a auditUseCaseMock) List(_ context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	*a.filter = filter
+	return a.Result, a.err
+}
+
+func (a auditUseCaseMock) Verify(context.Context) (domain.AuditVerification, error) {
+	return domain.AuditVerification{}, a.err
+}
+
+func Test_listAuditHandler(t *testing.T) {
+	scenarios := []struct {
+		description    string
+		query          string
+		scopes         []auth.Scope
+		err            error
+		expectedStatus int
+		expectedCode   string
+		expectedFilter domain.AuditFilter
+	}{
+		{
+			description:    "entries of an account",
+			query:          "?entity_type=account&entity_id=any-account-id",
+			scopes:         []auth.Scope{auth.ScopeAuditRead},
+			expectedStatus: http.StatusOK,
+			expectedFilter: domain.AuditFilter{EntityType: domain.AuditAccount, EntityID: "any-account-id", Limit: 50},
+		},
+		{
+			description:    "every entry paginated",
+			query:          "?limit=10&offset=20",
+			scopes:         []auth.Scope{auth.ScopeAdmin},
+			expectedStatus: http.StatusOK,
+			expectedFilter: domain.AuditFilter{Limit: 10, Offset: 20},
+		},
+		{
+			description:    "unknown entity type",
+			query:          "?entity_type=unknown",
+			scopes:         []auth.Scope{auth.ScopeAuditRead},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_PARAMETER",
+		},
+		{
+			description:    "entity id without type",
+			query:          "?entity_id=any-account-id",
+			scopes:         []auth.Scope{auth.ScopeAuditRead},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_PARAMETER",
+		},
+		{
+			description:    "missing audit scope",
+			scopes:         []auth.Scope{auth.ScopeAccountsRead},
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   "FORBIDDEN",
+		},
+		{
+			description:    "database unavailable",
+			scopes:         []auth.Scope{auth.ScopeAuditRead},
+			err:            exceptions.UnavailableError,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   "SERVICE_UNAVAILABLE",
+			expectedFilter: domain.AuditFilter{Limit: 50},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			filter := domain.AuditFilter{}
+			useCase := auditUseCaseMock{
+				Result: []domain.AuditEntry{{Id: 1, EntityType: domain.AuditAccount, EntityID: "any-account-id", After: json.RawMessage(`{"Id":"any-account-id"}`)}},
+				filter: &filter,
+				err:    scenario.err,
+			}
+
+			rr := httptest.NewRecorder()
+			router := gin.Default()
+			router.Use(middlewares.Errors(), func(c *gin.Context) {
+				c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), auth.Principal{ClientID: "compliance", Scopes: scenario.scopes}))
+			})
+			SetAuditRoutes(context.Background(), router, useCase)
+
+			request, _ := http.NewRequest(http.MethodGet, "/api/v1/audit"+scenario.query, nil)
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+			assert.Equal(t, scenario.expectedFilter, filter)
+
+			if scenario.expectedCode != "" {
+				var problem exceptions.Problem
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+				assert.Equal(t, scenario.expectedCode, problem.Code)
+				return
+			}
+
+			var response ListResponse
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+			assert.Len(t, response.Items, 1)
+			assert.Equal(t, "null", string(response.Items[0].Before))
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/payment-api/internal/domain"
+)
+
+type Response struct {
+	Id         int64           `json:"id"`
+	Actor      string          `json:"actor"`
+	Route      string          `json:"route"`
+	RequestID  string          `json:"request_id"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Action     string          `json:"action"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	Outcome    string          `json:"outcome"`
+	ErrorCode  string          `json:"error_code,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+type ListResponse struct {
+	Items  []Response `json:"items"`
+	Limit  int        `json:"limit"`
+	Offset int        `json:"offset"`
+}
+
+func NewResponse(entry domain.AuditEntry) Response {
+	return Response{
+		Id:         entry.Id,
+		Actor:      entry.Actor,
+		Route:      entry.Route,
+		RequestID:  entry.RequestID,
+		EntityType: string(entry.EntityType),
+		EntityID:   entry.EntityID,
+		Action:     entry.Action,
+		Before:     snapshot(entry.Before),
+		After:      snapshot(entry.After),
+		Outcome:    string(entry.Outcome),
+		ErrorCode:  entry.ErrorCode,
+		CreatedAt:  entry.CreatedAt,
+		PrevHash:   entry.PrevHash,
+		Hash:       entry.Hash,
+	}
+}
+
+func snapshot(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return json.RawMessage("null")
+	}
+
+	return raw
+}
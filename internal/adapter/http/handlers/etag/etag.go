@@ -0,0 +1,40 @@
+package etag
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/payment-api/infrastructure/exceptions"
+)
+
+// Set answers version as the strong entity tag of the response.
+func Set(c *gin.Context, version int) {
+	c.Header("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+// IfMatch reads the If-Match header a mutating request must carry as the
+// version it expects to replace, zero for "*". Only a single strong entity tag
+// can match a version, so weak tags and lists fail the precondition.
+func IfMatch(c *gin.Context) (int, error) {
+	value := strings.TrimSpace(c.GetHeader("If-Match"))
+	if value == "" {
+		return 0, exceptions.PreconditionRequiredError.WithDetail("send the ETag of the entity as If-Match")
+	}
+
+	if value == "*" {
+		return 0, nil
+	}
+
+	if len(value) < 2 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		return 0, exceptions.PreconditionFailedError.WithDetail("If-Match must be a single strong entity tag")
+	}
+
+	version, err := strconv.Atoi(value[1 : len(value)-1])
+	if err != nil || version < 1 {
+		return 0, exceptions.PreconditionFailedError.WithDetail("If-Match does not match the entity")
+	}
+
+	return version, nil
+}
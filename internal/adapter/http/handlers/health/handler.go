@@ -0,0 +1,36 @@
+package health
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/payment-api/infrastructure/health"
+	"github.com/payment-api/infrastructure/logger"
+)
+
+func SetHealthRoutes(ctx context.Context, r *gin.Engine, checker *health.Checker) {
+	r.GET("/health/live", live(ctx))
+	r.GET("/health/ready", ready(ctx, checker))
+}
+
+func live(_ context.Context) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, health.Report{Status: health.StatusUp})
+	}
+}
+
+func ready(_ context.Context, checker *health.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := checker.Ready(c.Request.Context())
+
+		if report.Status != health.StatusReady {
+			logger.Warn(c.Request.Context(), logger.HTTPWarn, "service not ready", logger.Str("reason", report.Reason))
+			c.JSON(http.StatusServiceUnavailable, report)
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/health"
+)
+
+func Test_LiveHandler(t *testing.T) {
+	checker := health.NewChecker()
+	checker.Drain()
+
+	rr := httptest.NewRecorder()
+	router := gin.Default()
+	SetHealthRoutes(context.Background(), router, checker)
+
+	request, _ := http.NewRequest(http.MethodGet, "/health/live", nil)
+
+	router.ServeHTTP(rr, request)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func Test_ReadyHandler(t *testing.T) {
+	up := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	scenarios := []struct {
+		description    string
+		checks         []health.Check
+		draining       bool
+		expectedStatus int
+		expectedChecks map[string]health.Status
+	}{
+		{
+			description: "success",
+			checks: []health.Check{
+				{Name: "postgres", Timeout: time.Second, Func: up},
+				{Name: "telemetry", Timeout: time.Second, Func: up},
+			},
+			expectedStatus: http.StatusOK,
+			expectedChecks: map[string]health.Status{
+				"postgres":  health.StatusUp,
+				"telemetry": health.StatusUp,
+			},
+		},
+		{
+			description: "dependency down",
+			checks: []health.Check{
+				{Name: "postgres", Timeout: time.Second, Func: down},
+				{Name: "telemetry", Timeout: time.Second, Func: up},
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedChecks: map[string]health.Status{
+				"postgres":  health.StatusDown,
+				"telemetry": health.StatusUp,
+			},
+		},
+		{
+			description: "check timeout",
+			checks: []health.Check{
+				{Name: "migrations", Timeout: 10 * time.Millisecond, Func: slow},
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedChecks: map[string]health.Status{
+				"migrations": health.StatusDown,
+			},
+		},
+		{
+			description: "shutting down",
+			checks: []health.Check{
+				{Name: "postgres", Timeout: time.Second, Func: up},
+			},
+			draining:       true,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedChecks: map[string]health.Status{},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			checker := health.NewChecker(scenario.checks...)
+			if scenario.draining {
+				checker.Drain()
+			}
+
+			rr := httptest.NewRecorder()
+			router := gin.Default()
+			SetHealthRoutes(context.Background(), router, checker)
+
+			request, _ := http.NewRequest(http.MethodGet, "/health/ready", nil)
+
+			router.ServeHTTP(rr, request)
+
+			var report health.Report
+			_ = json.Unmarshal(rr.Body.Bytes(), &report)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+			assert.Len(t, report.Checks, len(scenario.expectedChecks))
+			for name, status := range scenario.expectedChecks {
+				assert.Equal(t, status, report.Checks[name].Status)
+			}
+		})
+	}
+}
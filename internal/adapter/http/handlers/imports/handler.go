@@ -0,0 +1,121 @@
+package imports
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/domain"
+	"github.com/payment-api/internal/usecase"
+)
+
+// formats maps the accepted upload content types to their import format.
+var formats = map[string]domain.ImportFormat{
+	"text/csv":             domain.ImportCSV,
+	"application/x-ndjson": domain.ImportNDJSON,
+}
+
+func SetImportRoutes(ctx context.Context, r *gin.Engine, s usecase.ImportUseCase) {
+	imports := r.Group("/api/v1/imports", middlewares.Authorize(auth.ScopeTransactionsImport))
+
+	imports.POST("", createImport(ctx, s))
+	imports.GET("/:import_id", getImport(ctx, s))
+	imports.GET("/:import_id/errors", getImportErrors(ctx, s))
+}
+
+func createImport(_ context.Context, importUseCase usecase.ImportUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:createImport", trace.SpanKindServer)
+		defer span.End()
+
+		mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		format, ok := formats[mediaType]
+		if !ok {
+			err := exceptions.UnsupportedMediaTypeError.WithDetail("imports are sent as text/csv or application/x-ndjson")
+			telemetry.ErrorSpan(span, err)
+			logger.Warn(ctx, logger.HTTPWarn, "unsupported import content type", logger.Str("content_type", c.GetHeader("Content-Type")))
+			_ = c.Error(err)
+			return
+		}
+
+		job, err := importUseCase.Start(ctx, format, c.Request.Body)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed start import", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		logger.Info(ctx, logger.HTTPInfo, "import started", logger.Str("import_id", job.Id), logger.Str("format", string(job.Format)))
+
+		c.Header("Location", "/api/v1/imports/"+job.Id)
+		c.JSON(http.StatusAccepted, NewResponse(job))
+	}
+}
+
+func getImport(_ context.Context, importUseCase usecase.ImportUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:getImport", trace.SpanKindServer)
+		defer span.End()
+
+		job, err := importUseCase.Get(ctx, c.Param("import_id"))
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed get import", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, NewResponse(job))
+	}
+}
+
+// getImportErrors downloads the rejected rows of a job as csv. Errors found
+// once the report started streaming can only end the response early.
+func getImportErrors(_ context.Context, importUseCase usecase.ImportUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:getImportErrors", trace.SpanKindServer)
+		defer span.End()
+
+		id := c.Param("import_id")
+
+		job, err := importUseCase.Get(ctx, id)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed get import", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="import-%s-errors.csv"`, job.Id))
+		c.Status(http.StatusOK)
+
+		writer := csv.NewWriter(c.Writer)
+		_ = writer.Write([]string{"row", "field", "message", "raw"})
+
+		err = importUseCase.Errors(ctx, job.Id, func(rowError domain.ImportError) error {
+			return writer.Write([]string{strconv.Itoa(rowError.Row), rowError.Field, rowError.Message, rowError.Raw})
+		})
+		writer.Flush()
+
+		if err == nil {
+			err = writer.Error()
+		}
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed stream import errors", logger.Str("import_id", job.Id), logger.Err(err))
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package imports
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/domain"
+)
+
+type importUseCaseMock struct {
+	Result    domain.ImportJob
+	RowErrors []domain.ImportError
+	format    *domain.ImportFormat
+	body      *string
+	err       error
+}
+
+func (i importUseCaseMock) Start(_ context.Context, format domain.ImportFormat, body io.Reader) (domain.ImportJob, error) {
+	raw, _ := io.ReadAll(body)
+	*i.format, *i.body = format, string(raw)
+	return i.Result, i.err
+}
+
+func (i importUseCaseMock) Run(context.Context, domain.ImportFormat, io.Reader) (domain.ImportJob, error) {
+	return i.Result, i.err
+}
+
+func (i importUseCaseMock) Get(context.Context, string) (domain.ImportJob, error) {
+	return i.Result, i.err
+}
+
+func (i importUseCaseMock) Errors(_ context.Context, _ string, fn func(domain.ImportError) error) error {
+	for _, rowError := range i.RowErrors {
+		if err := fn(rowError); err != nil {
+			return err
+		}
+	}
+	return i.err
+}
+
+func (i importUseCaseMock) Sweep(context.Context) (int, error) {
+	return 0, i.err
+}
+
+func Test_importHandlers(t *testing.T) {
+	scenarios := []struct {
+		description    string
+		method         string
+		path           string
+		contentType    string
+		body           string
+		scopes         []auth.Scope
+		err            error
+		expectedStatus int
+		expectedCode   string
+		expectedFormat domain.ImportFormat
+		expectedBody   string
+	}{
+		{
+			description:    "start csv import",
+			method:         http.MethodPost,
+			path:           "/api/v1/imports",
+			contentType:    "text/csv; charset=utf-8",
+			body:           "account_id,operation_type,amount\naccount-1,1,10\n",
+			scopes:         []auth.Scope{auth.ScopeTransactionsImport},
+			expectedStatus: http.StatusAccepted,
+			expectedFormat: domain.ImportCSV,
+		},
+		{
+			description:    "start ndjson import",
+			method:         http.MethodPost,
+			path:           "/api/v1/imports",
+			contentType:    "application/x-ndjson",
+			body:           `{"account_id":"account-1","operation_type":1,"amount":10}`,
+			scopes:         []auth.Scope{auth.ScopeTransactionsImport},
+			expectedStatus: http.StatusAccepted,
+			expectedFormat: domain.ImportNDJSON,
+		},
+		{
+			description:    "unsupported content type",
+			method:         http.MethodPost,
+			path:           "/api/v1/imports",
+			contentType:    "application/json",
+			body:           `[]`,
+			scopes:         []auth.Scope{auth.ScopeTransactionsImport},
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedCode:   "UNSUPPORTED_MEDIA_TYPE",
+		},
+		{
+			description:    "upload too large",
+			method:         http.MethodPost,
+			path:           "/api/v1/imports",
+			contentType:    "text/csv",
+			scopes:         []auth.Scope{auth.ScopeTransactionsImport},
+			err:            exceptions.PayloadTooLargeError,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   "PAYLOAD_TOO_LARGE",
+			expectedFormat: domain.ImportCSV,
+		},
+		{
+			description:    "get import",
+			method:         http.MethodGet,
+			path:           "/api/v1/imports/any-import-id",
+			scopes:         []auth.Scope{auth.ScopeTransactionsImport},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "import of another client",
+			method:         http.MethodGet,
+			path:           "/api/v1/imports/any-import-id",
+			scopes:         []auth.Scope{auth.ScopeTransactionsImport},
+			err:            exceptions.EntityNotFoundError,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "ENTITY_NOT_FOUND",
+		},
+		{
+			description:    "download error report",
+			method:         http.MethodGet,
+			path:           "/api/v1/imports/any-import-id/errors",
+			scopes:         []auth.Scope{auth.ScopeTransactionsImport},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "row,field,message,raw\n2,amount,must be a number,\"account-1,1,ten\"\n",
+		},
+		{
+			description:    "error report of another client",
+			method:         http.MethodGet,
+			path:           "/api/v1/imports/any-import-id/errors",
+			scopes:         []auth.Scope{auth.ScopeTransactionsImport},
+			err:            exceptions.EntityNotFoundError,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "ENTITY_NOT_FOUND",
+		},
+		{
+			description:    "missing import scope",
+			method:         http.MethodPost,
+			path:           "/api/v1/imports",
+			contentType:    "text/csv",
+			scopes:         []auth.Scope{auth.ScopeTransactionsWrite},
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   "FORBIDDEN",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var format domain.ImportFormat
+			var body string
+			useCase := importUseCaseMock{
+				Result:    domain.ImportJob{Id: "any-import-id", Format: domain.ImportCSV, Status: domain.ImportPending},
+				RowErrors: []domain.ImportError{{JobID: "any-import-id", Row: 2, Field: "amount", Message: "must be a number", Raw: "account-1,1,ten"}},
+				format:    &format,
+				body:      &body,
+				err:       scenario.err,
+			}
+
+			rr := httptest.NewRecorder()
+			router := gin.Default()
+			router.Use(middlewares.Errors(), func(c *gin.Context) {
+				c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), auth.Principal{ClientID: "partner", Scopes: scenario.scopes}))
+			})
+			SetImportRoutes(context.Background(), router, useCase)
+
+			request, _ := http.NewRequest(scenario.method, scenario.path, bytes.NewBufferString(scenario.body))
+			request.Header.Set("Content-Type", scenario.contentType)
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+			assert.Equal(t, scenario.expectedFormat, format)
+
+			if scenario.expectedCode != "" {
+				var problem exceptions.Problem
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+				assert.Equal(t, scenario.expectedCode, problem.Code)
+				return
+			}
+
+			switch {
+			case scenario.expectedStatus == http.StatusAccepted:
+				assert.Equal(t, scenario.body, body, "the upload is streamed to the use case")
+				assert.Equal(t, "/api/v1/imports/any-import-id", rr.Header().Get("Location"))
+			case scenario.expectedBody != "":
+				assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+				assert.Contains(t, rr.Header().Get("Content-Disposition"), "import-any-import-id-errors.csv")
+				assert.Equal(t, scenario.expectedBody, rr.Body.String())
+			default:
+				var response Response
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+				assert.Equal(t, "any-import-id", response.Id)
+			}
+		})
+	}
+}
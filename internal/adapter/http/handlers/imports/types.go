@@ -0,0 +1,35 @@
+package imports
+
+import (
+	"time"
+
+	"github.com/payment-api/internal/domain"
+)
+
+type Response struct {
+	Id         string     `json:"id"`
+	Format     string     `json:"format"`
+	Status     string     `json:"status"`
+	Processed  int        `json:"processed"`
+	Imported   int        `json:"imported"`
+	Failed     int        `json:"failed"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func NewResponse(job domain.ImportJob) Response {
+	return Response{
+		Id:         job.Id,
+		Format:     string(job.Format),
+		Status:     string(job.Status),
+		Processed:  job.Processed,
+		Imported:   job.Imported,
+		Failed:     job.Failed,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		UpdatedAt:  job.UpdatedAt,
+		FinishedAt: job.FinishedAt,
+	}
+}
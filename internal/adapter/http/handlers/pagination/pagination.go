@@ -0,0 +1,46 @@
+package pagination
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/payment-api/infrastructure/exceptions"
+)
+
+// Parse reads the limit and offset query parameters of list routes, limit
+// defaults to defaultLimit and may not exceed maxLimit.
+func Parse(c *gin.Context, defaultLimit, maxLimit int) (limit, offset int, err error) {
+	limit, err = queryInt(c, "limit", defaultLimit, 1, maxLimit)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	offset, err = queryInt(c, "offset", 0, 0, -1)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return limit, offset, nil
+}
+
+// queryInt reads an optional integer query parameter within [min, max], a
+// negative max leaves it unbounded.
+func queryInt(c *gin.Context, name string, fallback, min, max int) (int, error) {
+	value, ok := c.GetQuery(name)
+	if !ok {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < min || (max >= 0 && parsed > max) {
+		message := "must be an integer greater than or equal to " + strconv.Itoa(min)
+		if max >= 0 {
+			message = "must be an integer between " + strconv.Itoa(min) + " and " + strconv.Itoa(max)
+		}
+
+		return 0, exceptions.InvalidParameterError.WithFields(exceptions.FieldError{Field: name, Message: message})
+	}
+
+	return parsed, nil
+}
@@ -2,78 +2,104 @@ package transaction
 
 import (
 	"context"
-	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/payment-api/infrastructure/auth"
 	"github.com/payment-api/infrastructure/exceptions"
 	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/ratelimit"
+	"github.com/payment-api/infrastructure/signature"
 	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/http/middlewares"
 	"github.com/payment-api/internal/domain"
 	"github.com/payment-api/internal/usecase"
 )
 
-func SetTransactionRoutes(ctx context.Context, r *gin.Engine, s usecase.TransactionUseCase) {
-	r.POST("/api/v1/transactions", createTransaction(ctx, s))
+func SetTransactionRoutes(ctx context.Context, r *gin.Engine, s usecase.TransactionUseCase, verifier *signature.Verifier, limiter *ratelimit.Limiter) {
+	r.POST("/api/v1/transactions",
+		middlewares.Authorize(auth.ScopeTransactionsWrite),
+		middlewares.Signature(verifier),
+		middlewares.RateLimit(limiter, true),
+		createTransaction(ctx, s),
+	)
+	r.GET("/api/v1/transactions/:transaction_id", middlewares.Authorize(auth.ScopeAccountsRead), getTransaction(ctx, s))
 }
 
-func createTransaction(ctx context.Context, transactionUseCase usecase.TransactionUseCase) gin.HandlerFunc {
+func getTransaction(_ context.Context, transactionUseCase usecase.TransactionUseCase) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx, span := telemetry.Span(ctx, "http:handler:createTransaction", trace.SpanKindServer)
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:getTransaction", trace.SpanKindServer)
 		defer span.End()
 
-		var request Request
-
-		if err := c.BindJSON(&request); err != nil {
+		id, err := strconv.Atoi(c.Param("transaction_id"))
+		if err != nil {
 			telemetry.ErrorSpan(span, err)
-			logger.Error(logger.HTTPError, "cannot marshal body")
-
-			c.JSON(http.StatusBadRequest, map[string]string{
-				"message": "invalid parameters",
-			})
+			_ = c.Error(exceptions.InvalidParameterError.WithFields(exceptions.FieldError{
+				Field:   "transaction_id",
+				Message: "must be an integer",
+			}))
 			return
 		}
 
-		if !request.Operation.IsValid() {
-			telemetry.ErrorSpan(span, exceptions.InvalidParameterError)
-			logger.Error(logger.HTTPError, "invalid operation parameter")
-
-			c.JSON(http.StatusBadRequest, map[string]string{
-				"message": "invalid operation parameter",
-				"reason":  exceptions.InvalidOperationTypeError.Error(),
-			})
+		transaction, err := transactionUseCase.Get(ctx, id)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed get transaction", logger.Err(err))
+			_ = c.Error(err)
 			return
 		}
 
-		if request.Amount < 0 {
-			telemetry.ErrorSpan(span, exceptions.InvalidParameterError)
-			logger.Error(logger.HTTPError, "invalid amount parameter")
+		c.JSON(http.StatusOK, NewResponse(transaction))
+	}
+}
+
+func createTransaction(_ context.Context, transactionUseCase usecase.TransactionUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:createTransaction", trace.SpanKindServer)
+		defer span.End()
 
-			c.JSON(http.StatusBadRequest, map[string]string{
-				"message": "invalid amount parameter",
-				"reason":  exceptions.InvalidAmountError.Error(),
-			})
+		var request Request
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "cannot marshal body", logger.Err(err))
+			_ = c.Error(exceptions.FromBinding(err))
 			return
 		}
 
 		transaction := domain.NewTransaction(request.AccountID, request.Operation, request.Amount)
+		transaction.CardToken = request.CardToken
+		transaction.Merchant = request.Merchant.merchant()
 
-		err := transactionUseCase.Create(ctx, transaction)
-		if err != nil {
+		if err := usecase.ValidateTransaction(transaction); err != nil {
 			telemetry.ErrorSpan(span, err)
-			logger.Error(logger.HTTPError, "Error creating transaction")
-			c.JSON(http.StatusUnprocessableEntity, map[string]string{
-				"message": "failed create transaction",
-				"reason":  err.Error(),
-			})
+			logger.Error(ctx, logger.HTTPError, "invalid transaction parameters",
+				logger.Int("operation_type", request.Operation.Index()),
+				logger.Float("amount", request.Amount),
+				logger.Err(err),
+			)
+			_ = c.Error(err)
+			return
+		}
 
+		transaction, err := transactionUseCase.Create(ctx, transaction)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed create transaction", logger.Err(err))
+			_ = c.Error(err)
 			return
 		}
 
-		logger.Info(logger.ServerInfo, fmt.Sprintf("Transaction Created %v", transaction))
+		logger.Info(ctx, logger.HTTPInfo, "transaction created",
+			logger.Str("account_id", transaction.AccountID),
+			logger.Str("operation_type", transaction.OperationType.String()),
+			logger.Float("amount", transaction.Amount),
+			logger.Str("decision", string(transaction.Decision)),
+		)
 
-		c.JSON(http.StatusCreated, map[string]string{"success": "created"})
+		c.JSON(http.StatusCreated, CreatedResponse{Success: "created", Response: NewResponse(transaction)})
 	}
 }
@@ -3,16 +3,26 @@ package transaction
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"go.opentelemetry.io/otel/sdk/trace"
 
+	"github.com/payment-api/config"
+	"github.com/payment-api/infrastructure/auth"
 	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/ratelimit"
+	"github.com/payment-api/infrastructure/signature"
+	"github.com/payment-api/internal/adapter/http/middlewares"
 	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
 	"github.com/payment-api/internal/usecase"
 )
 
@@ -21,20 +31,43 @@ type transactionUseCaseMock struct {
 	err    error
 }
 
-func (a transactionUseCaseMock) Create(context.Context, domain.Transaction) error {
-	return a.err
+func (a transactionUseCaseMock) Create(_ context.Context, transaction domain.Transaction) (domain.Transaction, error) {
+	if a.Result.Id == 0 {
+		return transaction, a.err
+	}
+	return a.Result, a.err
 }
 
-func (a transactionUseCaseMock) Get(context.Context, string) (domain.Transaction, error) {
+func (a transactionUseCaseMock) Get(context.Context, int) (domain.Transaction, error) {
 	return a.Result, a.err
 }
 
+// authenticated stands in for middlewares.Authenticate with an admin principal.
+func authenticated() gin.HandlerFunc {
+	return authenticatedAs("any-client")
+}
+
+func authenticatedAs(clientID string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), auth.Principal{
+			ClientID: clientID,
+			Scopes:   []auth.Scope{auth.ScopeAdmin},
+		}))
+	}
+}
+
+func unlimited() *ratelimit.Limiter {
+	return ratelimit.NewLimiter(config.RateLimit{}, ratelimit.NewMemoryStore())
+}
+
 func Test_transactionCreateHandler(t *testing.T) {
 	scenarios := []struct {
 		description    string
 		input          []byte
 		useCase        usecase.TransactionUseCase
 		expectedStatus int
+		expectedCode   string
+		expectedFields []string
 	}{
 		{
 			description: "success",
@@ -45,6 +78,26 @@ func Test_transactionCreateHandler(t *testing.T) {
 			},
 			expectedStatus: http.StatusCreated,
 		},
+		{
+			description: "success with card token",
+			input:       []byte(`{"card_token": "tok_any", "operation_type": 1,"amount": 10.1}`),
+			useCase: &transactionUseCaseMock{
+				Result: domain.Transaction{},
+				err:    nil,
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			description: "inactive card",
+			input:       []byte(`{"card_token": "tok_any", "operation_type": 1,"amount": 10.1}`),
+			useCase: &transactionUseCaseMock{
+				Result: domain.Transaction{},
+				err:    exceptions.CardInactiveError,
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   "CARD_INACTIVE",
+			expectedFields: []string{},
+		},
 		{
 			description: "persistence error",
 			input:       []byte(`{"account_id": "any-account-id", "operation_type": 1,"amount": 10.1}`),
@@ -52,7 +105,38 @@ func Test_transactionCreateHandler(t *testing.T) {
 				Result: domain.Transaction{},
 				err:    exceptions.PersistenceError,
 			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   "PERSISTENCE_ERROR",
+		},
+		{
+			description: "denied by fraud rules",
+			input:       []byte(`{"account_id": "any-account-id", "operation_type": 1,"amount": 10.1}`),
+			useCase: &transactionUseCaseMock{
+				Result: domain.Transaction{Id: 1, Decision: domain.DecisionDeny, Reasons: []string{"velocity"}},
+				err:    exceptions.TransactionDeniedError.WithDetail("transaction 1 denied by rules: velocity"),
+			},
 			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   "TRANSACTION_DENIED",
+		},
+		{
+			description: "account not found",
+			input:       []byte(`{"account_id": "any-account-id", "operation_type": 1,"amount": 10.1}`),
+			useCase: &transactionUseCaseMock{
+				Result: domain.Transaction{},
+				err:    fmt.Errorf("get account: %w", exceptions.EntityNotFoundError),
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "ENTITY_NOT_FOUND",
+		},
+		{
+			description: "database unavailable",
+			input:       []byte(`{"account_id": "any-account-id", "operation_type": 1,"amount": 10.1}`),
+			useCase: &transactionUseCaseMock{
+				Result: domain.Transaction{},
+				err:    fmt.Errorf("create transaction: %w", exceptions.UnavailableError),
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   "SERVICE_UNAVAILABLE",
 		},
 		{
 			description: "request body empty",
@@ -62,6 +146,8 @@ func Test_transactionCreateHandler(t *testing.T) {
 				err:    nil,
 			},
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "VALIDATION_ERROR",
+			expectedFields: []string{"account_id", "operation_type", "amount"},
 		},
 		{
 			description: "request body with any wrong content",
@@ -71,6 +157,19 @@ func Test_transactionCreateHandler(t *testing.T) {
 				err:    nil,
 			},
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "VALIDATION_ERROR",
+			expectedFields: []string{"account_id", "operation_type", "amount"},
+		},
+		{
+			description: "amount with wrong type",
+			input:       []byte(`{"account_id": "any-account-id", "operation_type": 1,"amount": "ten"}`),
+			useCase: &transactionUseCaseMock{
+				Result: domain.Transaction{},
+				err:    nil,
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "VALIDATION_ERROR",
+			expectedFields: []string{"amount"},
 		},
 		{
 			description: "amount negative",
@@ -80,6 +179,8 @@ func Test_transactionCreateHandler(t *testing.T) {
 				err:    nil,
 			},
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_AMOUNT",
+			expectedFields: []string{"amount"},
 		},
 		{
 			description: "invalid operation type",
@@ -89,6 +190,8 @@ func Test_transactionCreateHandler(t *testing.T) {
 				err:    nil,
 			},
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_OPERATION_TYPE",
+			expectedFields: []string{"operation_type"},
 		},
 	}
 
@@ -102,13 +205,331 @@ func Test_transactionCreateHandler(t *testing.T) {
 
 			rr := httptest.NewRecorder()
 			router := gin.Default()
-			SetTransactionRoutes(ctx, router, scenario.useCase)
+			router.Use(middlewares.Errors(), authenticated())
+			SetTransactionRoutes(ctx, router, scenario.useCase, signature.NewVerifier(nil, time.Minute, signature.NewMemoryNonceStore()), unlimited())
 
 			request, _ := http.NewRequest(http.MethodPost, "/api/v1/transactions", bytes.NewBuffer(scenario.input))
 
 			router.ServeHTTP(rr, request)
 
 			assert.Equal(t, scenario.expectedStatus, rr.Code)
+
+			if scenario.expectedCode == "" {
+				return
+			}
+
+			var problem exceptions.Problem
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+			assert.Equal(t, exceptions.ProblemContentType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, scenario.expectedCode, problem.Code)
+			assert.Equal(t, scenario.expectedStatus, problem.Status)
+
+			fields := make([]string, 0, len(problem.Errors))
+			for _, field := range problem.Errors {
+				fields = append(fields, field.Field)
+			}
+			assert.ElementsMatch(t, scenario.expectedFields, fields)
 		})
 	}
 }
+
+func Test_transactionCreateHandlerDecision(t *testing.T) {
+	rr := httptest.NewRecorder()
+	router := gin.Default()
+	router.Use(middlewares.Errors(), authenticated())
+	SetTransactionRoutes(context.Background(), router, &transactionUseCaseMock{
+		Result: domain.Transaction{
+			Id:            7,
+			AccountID:     "any-account-id",
+			OperationType: operation.WITHDRAW,
+			Amount:        5000,
+			Decision:      domain.DecisionReview,
+			Reasons:       []string{"large-withdraw"},
+		},
+	}, signature.NewVerifier(nil, time.Minute, signature.NewMemoryNonceStore()), unlimited())
+
+	request, _ := http.NewRequest(http.MethodPost, "/api/v1/transactions", bytes.NewBufferString(`{"account_id": "any-account-id", "operation_type": 3,"amount": 5000}`))
+
+	router.ServeHTTP(rr, request)
+
+	var response CreatedResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, 7, response.Id)
+	assert.Equal(t, "review", response.Decision)
+	assert.Equal(t, []string{"large-withdraw"}, response.Reasons)
+}
+
+func Test_transactionCreateHandlerMerchant(t *testing.T) {
+	rr := httptest.NewRecorder()
+	router := gin.Default()
+	router.Use(middlewares.Errors(), authenticated())
+	SetTransactionRoutes(context.Background(), router, &transactionUseCaseMock{}, signature.NewVerifier(nil, time.Minute, signature.NewMemoryNonceStore()), unlimited())
+
+	request, _ := http.NewRequest(http.MethodPost, "/api/v1/transactions", bytes.NewBufferString(
+		`{"account_id": "any-account-id", "operation_type": 1, "amount": 42.5, "merchant": {"name": "Cantina", "mcc": "5812", "city": "Recife", "country": "BR"}}`,
+	))
+
+	router.ServeHTTP(rr, request)
+
+	var response CreatedResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, &MerchantPayload{Name: "Cantina", MCC: "5812", City: "Recife", Country: "BR"}, response.Merchant)
+	assert.Equal(t, "restaurants", response.Category)
+}
+
+func Test_transactionGetHandler(t *testing.T) {
+	scenarios := []struct {
+		description    string
+		input          string
+		useCase        usecase.TransactionUseCase
+		expectedStatus int
+	}{
+		{
+			description: "success",
+			input:       "1",
+			useCase: &transactionUseCaseMock{
+				Result: domain.Transaction{Id: 1, AccountID: "any-account-id", OperationType: operation.PAYMENT, Decision: domain.DecisionAllow},
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "invalid id",
+			input:          "not-a-number",
+			useCase:        &transactionUseCaseMock{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			description: "not found",
+			input:       "1",
+			useCase: &transactionUseCaseMock{
+				err: fmt.Errorf("get transaction 1: %w", exceptions.EntityNotFoundError),
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router := gin.Default()
+			router.Use(middlewares.Errors(), authenticated())
+			SetTransactionRoutes(context.Background(), router, scenario.useCase, signature.NewVerifier(nil, time.Minute, signature.NewMemoryNonceStore()), unlimited())
+
+			request, _ := http.NewRequest(http.MethodGet, "/api/v1/transactions/"+scenario.input, nil)
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func Test_transactionCreateHandlerSignature(t *testing.T) {
+	const secret = "partner-secret"
+
+	body := []byte(`{"account_id": "any-account-id", "operation_type": 1,"amount": 10.1}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	scenarios := []struct {
+		description    string
+		timestamp      string
+		nonce          string
+		signature      string
+		expectedStatus int
+	}{
+		{
+			description:    "valid signature",
+			timestamp:      now,
+			nonce:          "nonce-1",
+			signature:      signature.Sign(secret, http.MethodPost, "/api/v1/transactions", now, "nonce-1", body),
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			description:    "replayed nonce",
+			timestamp:      now,
+			nonce:          "nonce-1",
+			signature:      signature.Sign(secret, http.MethodPost, "/api/v1/transactions", now, "nonce-1", body),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "wrong secret",
+			timestamp:      now,
+			nonce:          "nonce-2",
+			signature:      signature.Sign("other-secret", http.MethodPost, "/api/v1/transactions", now, "nonce-2", body),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "tampered path",
+			timestamp:      now,
+			nonce:          "nonce-3",
+			signature:      signature.Sign(secret, http.MethodPost, "/api/v1/accounts", now, "nonce-3", body),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "timestamp outside window",
+			timestamp:      stale,
+			nonce:          "nonce-4",
+			signature:      signature.Sign(secret, http.MethodPost, "/api/v1/transactions", stale, "nonce-4", body),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "missing signature",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	verifier := signature.NewVerifier(map[string]string{"card-network": secret}, 5*time.Minute, signature.NewMemoryNonceStore())
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router := gin.Default()
+			router.Use(middlewares.Errors(), authenticatedAs("card-network"))
+			SetTransactionRoutes(context.Background(), router, &transactionUseCaseMock{}, verifier, unlimited())
+
+			request, _ := http.NewRequest(http.MethodPost, "/api/v1/transactions", bytes.NewBuffer(body))
+			request.Header.Set(signature.TimestampHeader, scenario.timestamp)
+			request.Header.Set(signature.NonceHeader, scenario.nonce)
+			request.Header.Set(signature.Header, scenario.signature)
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func Test_transactionCreateHandlerSignatureClientCase(t *testing.T) {
+	const secret = "partner-secret"
+
+	body := []byte(`{"account_id": "any-account-id", "operation_type": 1,"amount": 10.1}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+
+	scenarios := []struct {
+		description    string
+		partner        string
+		clientID       string
+		signed         bool
+		expectedStatus int
+	}{
+		{
+			description:    "mixed case client must sign",
+			partner:        "card-network",
+			clientID:       "Card-Network",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "mixed case client signed",
+			partner:        "card-network",
+			clientID:       "Card-Network",
+			signed:         true,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			description:    "mixed case partner must sign",
+			partner:        "Card-Network",
+			clientID:       "card-network",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			verifier := signature.NewVerifier(map[string]string{scenario.partner: secret}, 5*time.Minute, signature.NewMemoryNonceStore())
+
+			rr := httptest.NewRecorder()
+			router := gin.Default()
+			router.Use(middlewares.Errors(), authenticatedAs(scenario.clientID))
+			SetTransactionRoutes(context.Background(), router, &transactionUseCaseMock{}, verifier, unlimited())
+
+			request, _ := http.NewRequest(http.MethodPost, "/api/v1/transactions", bytes.NewBuffer(body))
+			if scenario.signed {
+				request.Header.Set(signature.TimestampHeader, now)
+				request.Header.Set(signature.NonceHeader, "nonce-1")
+				request.Header.Set(signature.Header, signature.Sign(secret, http.MethodPost, "/api/v1/transactions", now, "nonce-1", body))
+			}
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func Test_transactionCreateHandlerRateLimit(t *testing.T) {
+	limiter := ratelimit.NewLimiter(config.RateLimit{
+		Client:  config.Limit{Rate: 0.001, Burst: 3},
+		Account: config.Limit{Rate: 0.001, Burst: 2},
+	}, ratelimit.NewMemoryStore())
+
+	router := gin.Default()
+	router.Use(middlewares.Errors(), authenticated())
+	SetTransactionRoutes(context.Background(), router, &transactionUseCaseMock{}, signature.NewVerifier(nil, time.Minute, signature.NewMemoryNonceStore()), limiter)
+
+	send := func(accountID string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		body := []byte(`{"account_id": "` + accountID + `", "operation_type": 1,"amount": 10.1}`)
+		request, _ := http.NewRequest(http.MethodPost, "/api/v1/transactions", bytes.NewBuffer(body))
+		router.ServeHTTP(rr, request)
+		return rr
+	}
+
+	rr := send("account-1")
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rr.Header().Get("RateLimit-Remaining"))
+
+	assert.Equal(t, http.StatusCreated, send("account-1").Code)
+
+	rr = send("account-1")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code, "account bucket exhausted")
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+	assert.Equal(t, exceptions.ProblemContentType, rr.Header().Get("Content-Type"))
+
+	rr = send("account-2")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code, "client bucket exhausted")
+	assert.Equal(t, "3", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+}
+
+func Test_transactionCreateHandlerRateLimitPerClient(t *testing.T) {
+	const secret = "partner-secret"
+
+	limiter := ratelimit.NewLimiter(config.RateLimit{
+		Account: config.Limit{Rate: 0.001, Burst: 1},
+	}, ratelimit.NewMemoryStore())
+	verifier := signature.NewVerifier(map[string]string{"card-network": secret}, 5*time.Minute, signature.NewMemoryNonceStore())
+
+	router := gin.Default()
+	router.Use(middlewares.Errors(), func(c *gin.Context) {
+		authenticatedAs(c.GetHeader("X-Test-Client"))(c)
+	})
+	SetTransactionRoutes(context.Background(), router, &transactionUseCaseMock{}, verifier, limiter)
+
+	body := []byte(`{"account_id": "account-1", "operation_type": 1,"amount": 10.1}`)
+	send := func(clientID, nonce string) int {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/api/v1/transactions", bytes.NewBuffer(body))
+		request.Header.Set("X-Test-Client", clientID)
+		if nonce != "" {
+			now := strconv.FormatInt(time.Now().Unix(), 10)
+			request.Header.Set(signature.TimestampHeader, now)
+			request.Header.Set(signature.NonceHeader, nonce)
+			request.Header.Set(signature.Header, signature.Sign(secret, http.MethodPost, "/api/v1/transactions", now, nonce, body))
+		}
+		router.ServeHTTP(rr, request)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, send("client-a", ""))
+	assert.Equal(t, http.StatusTooManyRequests, send("client-a", ""), "account bucket of client-a exhausted")
+	assert.Equal(t, http.StatusCreated, send("client-b", ""), "client-b has its own bucket for the account")
+
+	assert.Equal(t, http.StatusUnauthorized, send("card-network", ""))
+	assert.Equal(t, http.StatusUnauthorized, send("card-network", ""), "unsigned requests take no token")
+	assert.Equal(t, http.StatusCreated, send("card-network", "nonce-1"))
+	assert.Equal(t, http.StatusTooManyRequests, send("card-network", "nonce-2"))
+}
@@ -0,0 +1,98 @@
+package transaction
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/http/handlers/pagination"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/domain"
+	"github.com/payment-api/internal/usecase"
+)
+
+const (
+	defaultReviewLimit = 50
+	maxReviewLimit     = 200
+)
+
+func SetReviewRoutes(ctx context.Context, r *gin.Engine, s usecase.ReviewUseCase) {
+	reviews := r.Group("/api/v1/reviews", middlewares.Authorize(auth.ScopeTransactionsReview))
+
+	reviews.GET("", listReviews(ctx, s))
+	reviews.POST("/:transaction_id/approve", resolveReview(ctx, s.Approve))
+	reviews.POST("/:transaction_id/decline", resolveReview(ctx, s.Decline))
+	reviews.POST("/:transaction_id/reverse", resolveReview(ctx, s.Reverse))
+}
+
+func listReviews(_ context.Context, reviewUseCase usecase.ReviewUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:listReviews", trace.SpanKindServer)
+		defer span.End()
+
+		limit, offset, err := pagination.Parse(c, defaultReviewLimit, maxReviewLimit)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			_ = c.Error(err)
+			return
+		}
+
+		transactions, err := reviewUseCase.List(ctx, limit, offset)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed list reviews", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		items := make([]Response, 0, len(transactions))
+		for _, transaction := range transactions {
+			items = append(items, NewResponse(transaction))
+		}
+
+		c.JSON(http.StatusOK, ReviewListResponse{Items: items, Limit: limit, Offset: offset})
+	}
+}
+
+func resolveReview(_ context.Context, resolve func(context.Context, int, string) (domain.Transaction, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:resolveReview", trace.SpanKindServer)
+		defer span.End()
+
+		id, err := strconv.Atoi(c.Param("transaction_id"))
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			_ = c.Error(exceptions.InvalidParameterError.WithFields(exceptions.FieldError{
+				Field:   "transaction_id",
+				Message: "must be an integer",
+			}))
+			return
+		}
+
+		var request ReviewRequest
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "cannot marshal body", logger.Err(err))
+			_ = c.Error(exceptions.FromBinding(err))
+			return
+		}
+
+		transaction, err := resolve(ctx, id, request.Reason)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed resolve review", logger.Int("transaction_id", id), logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, NewResponse(transaction))
+	}
+}
@@ -0,0 +1,155 @@
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/domain"
+	"github.com/payment-api/internal/usecase"
+)
+
+type reviewUseCaseMock struct {
+	Result domain.Transaction
+	err    error
+}
+
+func (r reviewUseCaseMock) List(context.Context, int, int) ([]domain.Transaction, error) {
+	return []domain.Transaction{r.Result}, r.err
+}
+
+func (r reviewUseCaseMock) Approve(_ context.Context, id int, reason string) (domain.Transaction, error) {
+	return domain.Transaction{Id: id, Status: domain.StatusApproved, ReviewReason: reason}, r.err
+}
+
+func (r reviewUseCaseMock) Decline(_ context.Context, id int, reason string) (domain.Transaction, error) {
+	return domain.Transaction{Id: id, Status: domain.StatusDeclined, ReviewReason: reason}, r.err
+}
+
+func (r reviewUseCaseMock) Reverse(_ context.Context, id int, reason string) (domain.Transaction, error) {
+	return domain.Transaction{Id: id, Status: domain.StatusReversed, ReviewReason: reason}, r.err
+}
+
+func (r reviewUseCaseMock) Expire(context.Context) (int, error) {
+	return 0, r.err
+}
+
+func Test_reviewHandler(t *testing.T) {
+	scenarios := []struct {
+		description    string
+		method         string
+		path           string
+		input          []byte
+		useCase        usecase.ReviewUseCase
+		principal      auth.Principal
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			description:    "list pending reviews",
+			method:         http.MethodGet,
+			path:           "/api/v1/reviews?limit=10",
+			useCase:        reviewUseCaseMock{Result: domain.Transaction{Id: 1, Status: domain.StatusPendingReview}},
+			principal:      auth.Principal{ClientID: "back-office", Scopes: []auth.Scope{auth.ScopeTransactionsReview}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "invalid limit",
+			method:         http.MethodGet,
+			path:           "/api/v1/reviews?limit=1000",
+			useCase:        reviewUseCaseMock{},
+			principal:      auth.Principal{ClientID: "back-office", Scopes: []auth.Scope{auth.ScopeTransactionsReview}},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_PARAMETER",
+		},
+		{
+			description:    "approve",
+			method:         http.MethodPost,
+			path:           "/api/v1/reviews/1/approve",
+			input:          []byte(`{"reason": "checked with customer"}`),
+			useCase:        reviewUseCaseMock{},
+			principal:      auth.Principal{ClientID: "back-office", Scopes: []auth.Scope{auth.ScopeTransactionsReview}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "decline",
+			method:         http.MethodPost,
+			path:           "/api/v1/reviews/1/decline",
+			input:          []byte(`{"reason": "stolen card"}`),
+			useCase:        reviewUseCaseMock{},
+			principal:      auth.Principal{ClientID: "back-office", Scopes: []auth.Scope{auth.ScopeAdmin}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "reverse",
+			method:         http.MethodPost,
+			path:           "/api/v1/reviews/1/reverse",
+			input:          []byte(`{"reason": "disputed by the holder"}`),
+			useCase:        reviewUseCaseMock{},
+			principal:      auth.Principal{ClientID: "back-office", Scopes: []auth.Scope{auth.ScopeTransactionsReview}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "reason is required",
+			method:         http.MethodPost,
+			path:           "/api/v1/reviews/1/approve",
+			input:          []byte(`{}`),
+			useCase:        reviewUseCaseMock{},
+			principal:      auth.Principal{ClientID: "back-office", Scopes: []auth.Scope{auth.ScopeTransactionsReview}},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "VALIDATION_ERROR",
+		},
+		{
+			description:    "already reviewed",
+			method:         http.MethodPost,
+			path:           "/api/v1/reviews/1/approve",
+			input:          []byte(`{"reason": "checked with customer"}`),
+			useCase:        reviewUseCaseMock{err: exceptions.ConflictError},
+			principal:      auth.Principal{ClientID: "back-office", Scopes: []auth.Scope{auth.ScopeTransactionsReview}},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "CONFLICT",
+		},
+		{
+			description:    "missing review scope",
+			method:         http.MethodPost,
+			path:           "/api/v1/reviews/1/approve",
+			input:          []byte(`{"reason": "checked with customer"}`),
+			useCase:        reviewUseCaseMock{},
+			principal:      auth.Principal{ClientID: "partner", Scopes: []auth.Scope{auth.ScopeTransactionsWrite}},
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   "FORBIDDEN",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router := gin.Default()
+			router.Use(middlewares.Errors(), func(c *gin.Context) {
+				c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), scenario.principal))
+			})
+			SetReviewRoutes(context.Background(), router, scenario.useCase)
+
+			request, _ := http.NewRequest(scenario.method, scenario.path, bytes.NewBuffer(scenario.input))
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+
+			if scenario.expectedCode != "" {
+				var problem exceptions.Problem
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+				assert.Equal(t, scenario.expectedCode, problem.Code)
+			}
+		})
+	}
+}
@@ -1,9 +1,96 @@
 package transaction
 
-import operation "github.com/payment-api/internal/enum"
+import (
+	"time"
 
+	"github.com/payment-api/infrastructure/mcc"
+	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
+)
+
+// Request creates a transaction on AccountID or with the card of CardToken,
+// which resolves the account when AccountID is empty. Purchases may name
+// their Merchant.
 type Request struct {
-	AccountID string         `json:"account_id" binding:"required"`
-	Operation operation.Type `json:"operation_type" binding:"required"`
-	Amount    float64        `json:"amount" binding:"required"`
+	AccountID string           `json:"account_id" binding:"required_without=CardToken"`
+	CardToken string           `json:"card_token"`
+	Operation operation.Type   `json:"operation_type" binding:"required"`
+	Amount    float64          `json:"amount" binding:"required"`
+	Merchant  *MerchantPayload `json:"merchant"`
+}
+
+type MerchantPayload struct {
+	Name    string `json:"name"`
+	MCC     string `json:"mcc"`
+	City    string `json:"city,omitempty"`
+	Country string `json:"country,omitempty"`
+}
+
+type Response struct {
+	Id            int              `json:"id"`
+	AccountID     string           `json:"account_id"`
+	OperationType int              `json:"operation_type"`
+	Amount        float64          `json:"amount"`
+	EventDate     time.Time        `json:"event_date"`
+	Decision      string           `json:"decision"`
+	Reasons       []string         `json:"reasons"`
+	Status        string           `json:"status"`
+	ReviewedBy    string           `json:"reviewed_by,omitempty"`
+	ReviewReason  string           `json:"review_reason,omitempty"`
+	ReviewedAt    *time.Time       `json:"reviewed_at,omitempty"`
+	CardID        string           `json:"card_id,omitempty"`
+	Merchant      *MerchantPayload `json:"merchant,omitempty"`
+	Category      string           `json:"category,omitempty"`
+}
+
+type ReviewRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type ReviewListResponse struct {
+	Items  []Response `json:"items"`
+	Limit  int        `json:"limit"`
+	Offset int        `json:"offset"`
+}
+
+type CreatedResponse struct {
+	Success string `json:"success"`
+	Response
+}
+
+func NewResponse(transaction domain.Transaction) Response {
+	reasons := transaction.Reasons
+	if reasons == nil {
+		reasons = []string{}
+	}
+
+	response := Response{
+		Id:            transaction.Id,
+		AccountID:     transaction.AccountID,
+		OperationType: transaction.OperationType.Index(),
+		Amount:        transaction.Amount,
+		EventDate:     transaction.EventDate,
+		Decision:      string(transaction.Decision),
+		Reasons:       reasons,
+		Status:        string(transaction.Status),
+		ReviewedBy:    transaction.ReviewedBy,
+		ReviewReason:  transaction.ReviewReason,
+		ReviewedAt:    transaction.ReviewedAt,
+		CardID:        transaction.CardID,
+	}
+
+	if !transaction.Merchant.IsZero() {
+		merchant := MerchantPayload(transaction.Merchant)
+		response.Merchant, response.Category = &merchant, mcc.Category(transaction.Merchant.MCC)
+	}
+
+	return response
+}
+
+func (p *MerchantPayload) merchant() domain.Merchant {
+	if p == nil {
+		return domain.Merchant{}
+	}
+
+	return domain.Merchant(*p)
 }
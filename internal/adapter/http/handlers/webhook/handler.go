@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/http/handlers/pagination"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/usecase"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+func SetWebhookRoutes(ctx context.Context, r *gin.Engine, s usecase.WebhookUseCase) {
+	webhooks := r.Group("/api/v1/webhooks", middlewares.Authorize(auth.ScopeWebhooksManage))
+
+	webhooks.POST("", createWebhook(ctx, s))
+	webhooks.GET("", listWebhooks(ctx, s))
+	webhooks.GET("/:webhook_id", getWebhook(ctx, s))
+	webhooks.PUT("/:webhook_id", updateWebhook(ctx, s))
+	webhooks.DELETE("/:webhook_id", deleteWebhook(ctx, s))
+	webhooks.GET("/:webhook_id/deliveries", listDeliveries(ctx, s))
+	webhooks.POST("/:webhook_id/deliveries/:delivery_id/redeliver", redeliver(ctx, s))
+}
+
+func createWebhook(_ context.Context, webhookUseCase usecase.WebhookUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:createWebhook", trace.SpanKindServer)
+		defer span.End()
+
+		var request Request
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "cannot marshal body", logger.Err(err))
+			_ = c.Error(exceptions.FromBinding(err))
+			return
+		}
+
+		subscription, err := webhookUseCase.Create(ctx, request.Subscription(""))
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed create webhook", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, CreatedResponse{Response: NewResponse(subscription), Secret: subscription.Secret})
+	}
+}
+
+func listWebhooks(_ context.Context, webhookUseCase usecase.WebhookUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:listWebhooks", trace.SpanKindServer)
+		defer span.End()
+
+		subscriptions, err := webhookUseCase.List(ctx)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed list webhooks", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		items := make([]Response, 0, len(subscriptions))
+		for _, subscription := range subscriptions {
+			items = append(items, NewResponse(subscription))
+		}
+
+		c.JSON(http.StatusOK, ListResponse{Items: items})
+	}
+}
+
+func getWebhook(_ context.Context, webhookUseCase usecase.WebhookUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:getWebhook", trace.SpanKindServer)
+		defer span.End()
+
+		subscription, err := webhookUseCase.Get(ctx, c.Param("webhook_id"))
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed get webhook", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, NewResponse(subscription))
+	}
+}
+
+func updateWebhook(_ context.Context, webhookUseCase usecase.WebhookUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:updateWebhook", trace.SpanKindServer)
+		defer span.End()
+
+		var request Request
+
+		if err := c.ShouldBindJSON(&request); err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "cannot marshal body", logger.Err(err))
+			_ = c.Error(exceptions.FromBinding(err))
+			return
+		}
+
+		subscription, err := webhookUseCase.Update(ctx, request.Subscription(c.Param("webhook_id")))
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed update webhook", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, NewResponse(subscription))
+	}
+}
+
+func deleteWebhook(_ context.Context, webhookUseCase usecase.WebhookUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:deleteWebhook", trace.SpanKindServer)
+		defer span.End()
+
+		if err := webhookUseCase.Delete(ctx, c.Param("webhook_id")); err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed delete webhook", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func listDeliveries(_ context.Context, webhookUseCase usecase.WebhookUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:listDeliveries", trace.SpanKindServer)
+		defer span.End()
+
+		limit, offset, err := pagination.Parse(c, defaultDeliveryLimit, maxDeliveryLimit)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			_ = c.Error(err)
+			return
+		}
+
+		deliveries, err := webhookUseCase.Deliveries(ctx, c.Param("webhook_id"), limit, offset)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed list webhook deliveries", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		items := make([]DeliveryResponse, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			items = append(items, NewDeliveryResponse(delivery))
+		}
+
+		c.JSON(http.StatusOK, DeliveryListResponse{Items: items, Limit: limit, Offset: offset})
+	}
+}
+
+func redeliver(_ context.Context, webhookUseCase usecase.WebhookUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := telemetry.Span(c.Request.Context(), "http:handler:redeliver", trace.SpanKindServer)
+		defer span.End()
+
+		delivery, err := webhookUseCase.Redeliver(ctx, c.Param("webhook_id"), c.Param("delivery_id"))
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.HTTPError, "failed redeliver webhook", logger.Err(err))
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, NewDeliveryResponse(delivery))
+	}
+}
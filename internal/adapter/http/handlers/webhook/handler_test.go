@@ -0,0 +1,198 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/adapter/http/middlewares"
+	"github.com/payment-api/internal/domain"
+)
+
+type webhookUseCaseMock struct {
+	Result   domain.Subscription
+	Delivery domain.Delivery
+	created  *domain.Subscription
+	err      error
+}
+
+func (w webhookUseCaseMock) Publish(context.Context, domain.Event) {}
+
+func (w webhookUseCaseMock) Create(_ context.Context, subscription domain.Subscription) (domain.Subscription, error) {
+	*w.created = subscription
+	return w.Result, w.err
+}
+
+func (w webhookUseCaseMock) Get(context.Context, string) (domain.Subscription, error) {
+	return w.Result, w.err
+}
+
+func (w webhookUseCaseMock) List(context.Context) ([]domain.Subscription, error) {
+	return []domain.Subscription{w.Result}, w.err
+}
+
+func (w webhookUseCaseMock) Update(_ context.Context, subscription domain.Subscription) (domain.Subscription, error) {
+	*w.created = subscription
+	return w.Result, w.err
+}
+
+func (w webhookUseCaseMock) Delete(context.Context, string) error {
+	return w.err
+}
+
+func (w webhookUseCaseMock) Deliveries(context.Context, string, int, int) ([]domain.Delivery, error) {
+	return []domain.Delivery{w.Delivery}, w.err
+}
+
+func (w webhookUseCaseMock) Redeliver(context.Context, string, string) (domain.Delivery, error) {
+	return w.Delivery, w.err
+}
+
+func (w webhookUseCaseMock) Dispatch(context.Context) (int, error) {
+	return 0, w.err
+}
+
+func Test_webhookHandlers(t *testing.T) {
+	scenarios := []struct {
+		description    string
+		method         string
+		path           string
+		body           string
+		scopes         []auth.Scope
+		err            error
+		expectedStatus int
+		expectedCode   string
+		expectedInput  domain.Subscription
+	}{
+		{
+			description:    "create subscription",
+			method:         http.MethodPost,
+			path:           "/api/v1/webhooks",
+			body:           `{"url":"https://merchant.test/hooks","event_types":["account.created"]}`,
+			scopes:         []auth.Scope{auth.ScopeWebhooksManage},
+			expectedStatus: http.StatusCreated,
+			expectedInput:  domain.Subscription{URL: "https://merchant.test/hooks", EventTypes: []domain.EventType{domain.EventAccountCreated}, Active: true},
+		},
+		{
+			description:    "create without url",
+			method:         http.MethodPost,
+			path:           "/api/v1/webhooks",
+			body:           `{"event_types":["account.created"]}`,
+			scopes:         []auth.Scope{auth.ScopeWebhooksManage},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "VALIDATION_ERROR",
+		},
+		{
+			description:    "update pauses subscription",
+			method:         http.MethodPut,
+			path:           "/api/v1/webhooks/any-webhook-id",
+			body:           `{"url":"https://merchant.test/hooks","event_types":["transaction.created"],"active":false}`,
+			scopes:         []auth.Scope{auth.ScopeAdmin},
+			expectedStatus: http.StatusOK,
+			expectedInput:  domain.Subscription{Id: "any-webhook-id", URL: "https://merchant.test/hooks", EventTypes: []domain.EventType{domain.EventTransactionCreated}},
+		},
+		{
+			description:    "list subscriptions",
+			method:         http.MethodGet,
+			path:           "/api/v1/webhooks",
+			scopes:         []auth.Scope{auth.ScopeWebhooksManage},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "subscription of another client",
+			method:         http.MethodGet,
+			path:           "/api/v1/webhooks/any-webhook-id",
+			scopes:         []auth.Scope{auth.ScopeWebhooksManage},
+			err:            exceptions.EntityNotFoundError,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "ENTITY_NOT_FOUND",
+		},
+		{
+			description:    "delete subscription",
+			method:         http.MethodDelete,
+			path:           "/api/v1/webhooks/any-webhook-id",
+			scopes:         []auth.Scope{auth.ScopeWebhooksManage},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			description:    "list deliveries",
+			method:         http.MethodGet,
+			path:           "/api/v1/webhooks/any-webhook-id/deliveries?limit=10",
+			scopes:         []auth.Scope{auth.ScopeWebhooksManage},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "invalid deliveries pagination",
+			method:         http.MethodGet,
+			path:           "/api/v1/webhooks/any-webhook-id/deliveries?limit=1000",
+			scopes:         []auth.Scope{auth.ScopeWebhooksManage},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_PARAMETER",
+		},
+		{
+			description:    "redeliver",
+			method:         http.MethodPost,
+			path:           "/api/v1/webhooks/any-webhook-id/deliveries/any-delivery-id/redeliver",
+			scopes:         []auth.Scope{auth.ScopeWebhooksManage},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			description:    "missing webhooks scope",
+			method:         http.MethodGet,
+			path:           "/api/v1/webhooks",
+			scopes:         []auth.Scope{auth.ScopeAccountsWrite},
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   "FORBIDDEN",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			input := domain.Subscription{}
+			useCase := webhookUseCaseMock{
+				Result:   domain.Subscription{Id: "any-webhook-id", URL: "https://merchant.test/hooks", Secret: "whsec_any", Active: true},
+				Delivery: domain.Delivery{Id: "any-delivery-id", Status: domain.DeliveryPending},
+				created:  &input,
+				err:      scenario.err,
+			}
+
+			rr := httptest.NewRecorder()
+			router := gin.Default()
+			router.Use(middlewares.Errors(), func(c *gin.Context) {
+				c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), auth.Principal{ClientID: "merchant", Scopes: scenario.scopes}))
+			})
+			SetWebhookRoutes(context.Background(), router, useCase)
+
+			request, _ := http.NewRequest(scenario.method, scenario.path, bytes.NewBufferString(scenario.body))
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+			assert.Equal(t, scenario.expectedInput, input)
+
+			if scenario.expectedCode != "" {
+				var problem exceptions.Problem
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+				assert.Equal(t, scenario.expectedCode, problem.Code)
+				return
+			}
+
+			switch scenario.expectedStatus {
+			case http.StatusCreated:
+				var response CreatedResponse
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+				assert.Equal(t, "whsec_any", response.Secret, "the secret is shown once on create")
+			case http.StatusOK:
+				assert.NotContains(t, rr.Body.String(), "whsec_any")
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/payment-api/internal/domain"
+)
+
+type Request struct {
+	URL        string   `json:"url" binding:"required"`
+	EventTypes []string `json:"event_types" binding:"required"`
+	AccountID  string   `json:"account_id"`
+	Secret     string   `json:"secret"`
+	Active     *bool    `json:"active"`
+}
+
+func (r Request) Subscription(id string) domain.Subscription {
+	eventTypes := make([]domain.EventType, 0, len(r.EventTypes))
+	for _, eventType := range r.EventTypes {
+		eventTypes = append(eventTypes, domain.EventType(eventType))
+	}
+
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+
+	return domain.Subscription{
+		Id:         id,
+		URL:        r.URL,
+		EventTypes: eventTypes,
+		AccountID:  r.AccountID,
+		Secret:     r.Secret,
+		Active:     active,
+	}
+}
+
+type Response struct {
+	Id         string    `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	AccountID  string    `json:"account_id,omitempty"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type ListResponse struct {
+	Items []Response `json:"items"`
+}
+
+// CreatedResponse is the only response carrying the signing secret.
+type CreatedResponse struct {
+	Response
+	Secret string `json:"secret"`
+}
+
+type DeliveryResponse struct {
+	Id             string     `json:"id"`
+	SubscriptionID string     `json:"subscription_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+type DeliveryListResponse struct {
+	Items  []DeliveryResponse `json:"items"`
+	Limit  int                `json:"limit"`
+	Offset int                `json:"offset"`
+}
+
+func NewResponse(subscription domain.Subscription) Response {
+	eventTypes := make([]string, 0, len(subscription.EventTypes))
+	for _, eventType := range subscription.EventTypes {
+		eventTypes = append(eventTypes, string(eventType))
+	}
+
+	return Response{
+		Id:         subscription.Id,
+		URL:        subscription.URL,
+		EventTypes: eventTypes,
+		AccountID:  subscription.AccountID,
+		Active:     subscription.Active,
+		CreatedAt:  subscription.CreatedAt,
+		UpdatedAt:  subscription.UpdatedAt,
+	}
+}
+
+func NewDeliveryResponse(delivery domain.Delivery) DeliveryResponse {
+	return DeliveryResponse{
+		Id:             delivery.Id,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      string(delivery.EventType),
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+}
@@ -0,0 +1,17 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/payment-api/infrastructure/audit"
+)
+
+// AuditRoute stores the matched route in the request context, so audit
+// entries recorded by the use cases name the route of the mutation.
+func AuditRoute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(audit.WithRoute(c.Request.Context(), c.Request.Method+" "+c.FullPath()))
+
+		c.Next()
+	}
+}
@@ -0,0 +1,105 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/internal/usecase"
+)
+
+const (
+	apiKeyHeader        = "X-API-Key"
+	authorizationHeader = "Authorization"
+	apiKeyScheme        = "ApiKey "
+	bearerScheme        = "Bearer "
+)
+
+// Authenticate resolves the api key of the request into an auth.Principal.
+// Requests without credentials go through anonymously and are rejected by
+// Authorize on protected routes.
+func Authenticate(apiKeys usecase.APIKeyUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := apiKey(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		principal, err := apiKeys.Authenticate(c.Request.Context(), key)
+		if err != nil {
+			logger.Warn(c.Request.Context(), logger.HTTPWarn, "api key authentication failed", logger.Err(err))
+			c.Header("WWW-Authenticate", "ApiKey")
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+
+		c.Next()
+	}
+}
+
+// Bearer validates the bearer token of the request into an auth.Principal
+// bound to the account owner, requests without one go through like in Authenticate.
+func Bearer(verifier *auth.TokenVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(authorizationHeader)
+		if !strings.HasPrefix(header, bearerScheme) {
+			c.Next()
+			return
+		}
+
+		principal, err := verifier.Verify(c.Request.Context(), strings.TrimSpace(strings.TrimPrefix(header, bearerScheme)))
+		if err != nil {
+			logger.Warn(c.Request.Context(), logger.HTTPWarn, "bearer token validation failed", logger.Err(err))
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			_ = c.Error(exceptions.UnauthorizedError.WithDetail("invalid bearer token").Wrap(err))
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+
+		c.Next()
+	}
+}
+
+// Authorize requires an authenticated principal holding every scope.
+func Authorize(scopes ...auth.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := auth.PrincipalFrom(c.Request.Context())
+		if !ok {
+			c.Header("WWW-Authenticate", "ApiKey")
+			_ = c.Error(exceptions.UnauthorizedError)
+			c.Abort()
+			return
+		}
+
+		for _, scope := range scopes {
+			if !principal.HasScope(scope) {
+				_ = c.Error(exceptions.ForbiddenError.WithDetail("missing scope " + string(scope)))
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func apiKey(c *gin.Context) string {
+	if key := c.GetHeader(apiKeyHeader); key != "" {
+		return key
+	}
+
+	if header := c.GetHeader(authorizationHeader); strings.HasPrefix(header, apiKeyScheme) {
+		return strings.TrimSpace(strings.TrimPrefix(header, apiKeyScheme))
+	}
+
+	return ""
+}
@@ -0,0 +1,99 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/domain"
+)
+
+type apiKeyUseCaseMock struct {
+	principals map[string]auth.Principal
+}
+
+func (a apiKeyUseCaseMock) Authenticate(_ context.Context, key string) (auth.Principal, error) {
+	principal, ok := a.principals[key]
+	if !ok {
+		return auth.Principal{}, exceptions.UnauthorizedError
+	}
+	return principal, nil
+}
+
+func (a apiKeyUseCaseMock) Mint(context.Context, string, []auth.Scope, time.Duration) (string, domain.APIKey, error) {
+	return "", domain.APIKey{}, nil
+}
+
+func (a apiKeyUseCaseMock) Rotate(context.Context, string, time.Duration) (string, domain.APIKey, error) {
+	return "", domain.APIKey{}, nil
+}
+
+func (a apiKeyUseCaseMock) Revoke(context.Context, string) error {
+	return nil
+}
+
+func Test_Authorize(t *testing.T) {
+	useCase := apiKeyUseCaseMock{principals: map[string]auth.Principal{
+		"reader-key": {ClientID: "reader", Scopes: []auth.Scope{auth.ScopeAccountsRead}},
+		"admin-key":  {ClientID: "admin", Scopes: []auth.Scope{auth.ScopeAdmin}},
+	}}
+
+	scenarios := []struct {
+		description    string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{
+			description:    "anonymous",
+			headers:        map[string]string{},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "unknown key",
+			headers:        map[string]string{"X-API-Key": "unknown-key"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			description:    "missing scope",
+			headers:        map[string]string{"X-API-Key": "reader-key"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			description:    "authorization header",
+			headers:        map[string]string{"Authorization": "ApiKey admin-key"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			description:    "admin scope",
+			headers:        map[string]string{"X-API-Key": "admin-key"},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router := gin.New()
+			router.Use(Errors(), Authenticate(useCase))
+			router.POST("/protected", Authorize(auth.ScopeAccountsWrite), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			request, _ := http.NewRequest(http.MethodPost, "/protected", nil)
+			for key, value := range scenario.headers {
+				request.Header.Set(key, value)
+			}
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, scenario.expectedStatus, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/requestid"
+)
+
+func init() {
+	// report json field names instead of go struct field names in validation errors
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// Errors renders the last error attached with c.Error as application/problem+json.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		problem := exceptions.ToProblem(c.Errors.Last().Err, c.Request.URL.Path)
+		problem.RequestID = requestid.From(c.Request.Context())
+
+		body, err := json.Marshal(problem)
+		if err != nil {
+			c.Status(problem.Status)
+			return
+		}
+
+		c.Data(problem.Status, exceptions.ProblemContentType, body)
+	}
+}
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/payment-api/infrastructure/logger"
+)
+
+// Logger replaces gin default text access log with a structured entry per request.
+func Logger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		fields := []logger.Field{
+			logger.Str("method", c.Request.Method),
+			logger.Str("path", c.FullPath()),
+			logger.Int("status", c.Writer.Status()),
+			logger.Duration("latency", time.Since(start)),
+			logger.Str("client_ip", c.ClientIP()),
+		}
+
+		if last := c.Errors.Last(); last != nil {
+			fields = append(fields, logger.Err(last.Err))
+		}
+
+		switch status := c.Writer.Status(); {
+		case status >= 500:
+			logger.Error(c.Request.Context(), logger.HTTPError, "request completed", fields...)
+		case status >= 400:
+			logger.Warn(c.Request.Context(), logger.HTTPWarn, "request completed", fields...)
+		default:
+			logger.Info(c.Request.Context(), logger.HTTPInfo, "request completed", fields...)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/ratelimit"
+)
+
+// RateLimit takes a token from the bucket of the api client and, when
+// byAccount is set, from the bucket the client holds for the account_id in the
+// json body, or for its card_token when it names a card alone. The most
+// restrictive bucket is reported in the RateLimit-* headers. Store failures
+// let the request through. It runs after Signature, so requests of partners
+// failing it do not take tokens.
+func RateLimit(limiter *ratelimit.Limiter, byAccount bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		clientID := c.ClientIP()
+		if principal, ok := auth.PrincipalFrom(ctx); ok {
+			clientID = principal.ClientID
+		}
+
+		var accountID, cardToken string
+		if byAccount {
+			accountID, cardToken = accountFromBody(c)
+		}
+
+		reported, enabled, err := limiter.Request(ctx, clientID, accountID, cardToken)
+		if err != nil {
+			logger.Error(ctx, logger.ServerError, "rate limit store failed", logger.Err(err))
+		}
+
+		if !enabled {
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(reported.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(reported.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(int(reported.Reset.Seconds())))
+
+		if !reported.Allowed {
+			logger.Warn(ctx, logger.HTTPWarn, "rate limit exceeded", logger.Str("client_id", clientID))
+			c.Header("Retry-After", strconv.Itoa(int(reported.RetryAfter.Seconds())))
+			_ = c.Error(exceptions.TooManyRequestsError)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// accountFromBody peeks the account_id and card_token of a json body and puts
+// the body back.
+func accountFromBody(c *gin.Context) (string, string) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBodyBytes))
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return "", ""
+	}
+
+	var payload struct {
+		AccountID string `json:"account_id"`
+		CardToken string `json:"card_token"`
+	}
+	_ = json.Unmarshal(body, &payload)
+
+	return payload.AccountID, payload.CardToken
+}
@@ -1,23 +1,23 @@
 package middlewares
 
 import (
-	"net/http"
+	"fmt"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
 )
 
+// Recover turns a panic into an InternalError, rendered by Errors.
 func Recover() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				switch t := err.(type) {
-				case error:
-					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-						"error": t,
-					})
-				default:
-					c.JSON(http.StatusInternalServerError, gin.H{})
-				}
+				logger.Error(c.Request.Context(), logger.ServerError, "panic recovered", logger.Str("panic", fmt.Sprint(err)))
+
+				_ = c.Error(exceptions.InternalError.Wrap(fmt.Errorf("panic: %v", err)))
+				c.Abort()
 			}
 		}()
 
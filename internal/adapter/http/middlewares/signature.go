@@ -0,0 +1,57 @@
+package middlewares
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/signature"
+)
+
+const maxBodyBytes = 1 << 20
+
+// Signature verifies the HMAC signature of requests sent by partners and puts
+// the body back for the handler to bind. It must run after Authorize, callers
+// that are not partners go through unchanged.
+func Signature(verifier *signature.Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := auth.PrincipalFrom(c.Request.Context())
+		if !ok || !verifier.Required(principal.ClientID) {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBodyBytes))
+		if err != nil {
+			_ = c.Error(exceptions.ValidationError.WithDetail("cannot read request body").Wrap(err))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		err = verifier.Verify(c.Request.Context(), principal.ClientID,
+			c.Request.Method,
+			c.Request.URL.Path,
+			c.GetHeader(signature.TimestampHeader),
+			c.GetHeader(signature.NonceHeader),
+			c.GetHeader(signature.Header),
+			body,
+		)
+		if err != nil {
+			logger.Warn(c.Request.Context(), logger.HTTPWarn, "request signature rejected",
+				logger.Str("client_id", principal.ClientID),
+				logger.Err(err),
+			)
+			_ = c.Error(exceptions.InvalidSignatureError.WithDetail(err.Error()).Wrap(err))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -2,10 +2,17 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
 	"github.com/payment-api/infrastructure/logger"
 	"github.com/payment-api/infrastructure/postgres"
 	"github.com/payment-api/infrastructure/telemetry"
@@ -15,6 +22,9 @@ import (
 type Account interface {
 	Push(ctx context.Context, entity domain.Account) error
 	Get(ctx context.Context, id string) (domain.Account, error)
+	Lookup(ctx context.Context, ids []string) (map[string]domain.Account, error)
+	Search(ctx context.Context, filter domain.AccountFilter) ([]domain.Account, error)
+	Update(ctx context.Context, entity domain.Account, version int) error
 }
 
 type (
@@ -22,55 +32,257 @@ type (
 		repository postgres.Repository
 	}
 
-	result struct {
-		Id             string
-		DocumentNumber string
-		CreatedAt      time.Time
+	accountResult struct {
+		domain.Account
+		address   []byte
+		metadata  []byte
+		closedAt  sql.NullTime
+		erasedAt  sql.NullTime
+		deletedAt sql.NullTime
 	}
 )
 
+// Every query of accounts skips the soft deleted ones and is scoped to the
+// tenant of its context with a tenant_id filter, internal callers given
+// auth.WithAnyTenant pass auth.AnyTenant and see every tenant, callers
+// without a tenant see none.
+const accountColumns = `id, tenant_id, document_number, status, holder_name, email, phone, address, metadata, version,
+        created_at, updated_at, closed_at, erased_at, deleted_at`
+
+func (r *accountResult) dest() []interface{} {
+	return []interface{}{
+		&r.Id, &r.TenantID, &r.DocumentNumber, &r.Status, &r.HolderName, &r.Email, &r.Phone, &r.address, &r.metadata,
+		&r.Version, &r.CreatedAt, &r.UpdatedAt, &r.closedAt, &r.erasedAt, &r.deletedAt,
+	}
+}
+
+func (r *accountResult) entity() (domain.Account, error) {
+	entity := r.Account
+
+	if r.address != nil {
+		entity.Address = &domain.Address{}
+		if err := json.Unmarshal(r.address, entity.Address); err != nil {
+			return domain.Account{}, err
+		}
+	}
+
+	if err := json.Unmarshal(r.metadata, &entity.Metadata); err != nil {
+		return domain.Account{}, err
+	}
+
+	entity.ClosedAt = nullableTimePtr(r.closedAt)
+	entity.ErasedAt = nullableTimePtr(r.erasedAt)
+	entity.DeletedAt = nullableTimePtr(r.deletedAt)
+
+	return entity, nil
+}
+
+func nullableTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+
+	return &t.Time
+}
+
+// profileArgs encodes the address and metadata of entity as their jsonb columns.
+func profileArgs(entity domain.Account) (address, metadata interface{}, err error) {
+	var raw []byte
+	if entity.Address != nil {
+		if raw, err = json.Marshal(entity.Address); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if entity.Metadata == nil {
+		return nullableJSON(raw), "{}", nil
+	}
+
+	encoded, err := json.Marshal(entity.Metadata)
+	return nullableJSON(raw), string(encoded), err
+}
+
 func (a *accountImpl) Get(ctx context.Context, id string) (domain.Account, error) {
 	ctx, span := telemetry.Span(ctx, "repository:account:Get", trace.SpanKindInternal)
 	defer span.End()
 
 	q := `
-		SELECT * FROM accounts WHERE id = $1;
+		SELECT ` + accountColumns + ` FROM accounts
+        WHERE id = $1 AND deleted_at IS NULL AND ($2 = '*' OR tenant_id = $2);
     `
 
-	var resultPersisted result
-	err := a.repository.GetById(q, id, &resultPersisted.Id, &resultPersisted.DocumentNumber, &resultPersisted.CreatedAt)
+	var r accountResult
+	err := a.repository.QueryRow(ctx, q, []interface{}{id, auth.Tenant(ctx)}, r.dest()...)
+	if err == nil {
+		r.Account, err = r.entity()
+	}
 
 	if err != nil {
-		logger.Error(logger.ServerError, "Error getting account to postgres", err)
+		logger.Error(ctx, logger.ServerError, "error getting account from postgres", logger.Err(err))
 		return domain.Account{}, err
 	}
 
-	return domain.Account{
-		Id:             resultPersisted.Id,
-		DocumentNumber: resultPersisted.DocumentNumber,
-	}, nil
+	return r.Account, nil
 }
 
+// Push opens entity in the history of its account and in the accounts table.
 func (a *accountImpl) Push(ctx context.Context, entity domain.Account) error {
 	ctx, span := telemetry.Span(ctx, "repository:account:Push", trace.SpanKindInternal)
 	defer span.End()
 
-	q := `
-	INSERT INTO accounts (id, document_number, created_at)
-        VALUES ($1, $2, $3)
-        RETURNING id;
-    `
+	err := transact(ctx, a.repository, func(tx *postgres.Tx) error {
+		event := domain.NewAccountHistory(domain.HistoryAccountOpened, entity)
+		if err := liveProjection.apply(ctx, tx, &event); err != nil {
+			return err
+		}
 
-	err := a.repository.Push(q, entity.Id, entity.DocumentNumber, time.Now())
+		return appendHistory(ctx, tx, event)
+	})
 	if err != nil {
+		err = postgres.ToDomainError(err)
 		telemetry.ErrorSpan(span, err)
-		logger.Error(logger.ServerError, "Error pushing account to postgres", err)
+		logger.Error(ctx, logger.ServerError, "error pushing account to postgres", logger.Err(err))
 		return err
 	}
 
 	return nil
 }
 
+// Lookup returns the accounts among ids by id, with only their id, tenant
+// and status set. Deleted accounts and unknown ids are left out.
+func (a *accountImpl) Lookup(ctx context.Context, ids []string) (map[string]domain.Account, error) {
+	ctx, span := telemetry.Span(ctx, "repository:account:Lookup", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT id, tenant_id, status FROM accounts WHERE id = ANY($1) AND deleted_at IS NULL AND ($2 = '*' OR tenant_id = $2);`
+
+	accounts := make(map[string]domain.Account, len(ids))
+	err := a.repository.Query(ctx, q, []interface{}{pq.Array(ids), auth.Tenant(ctx)}, func(rows *sql.Rows) error {
+		var account domain.Account
+		if err := rows.Scan(&account.Id, &account.TenantID, &account.Status); err != nil {
+			return err
+		}
+
+		accounts[account.Id] = account
+		return nil
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error looking up accounts in postgres", logger.Err(err))
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+// Search returns the accounts matching filter, newest first. The document
+// prefix is matched literally, so wildcards in it match themselves.
+func (a *accountImpl) Search(ctx context.Context, filter domain.AccountFilter) ([]domain.Account, error) {
+	ctx, span := telemetry.Span(ctx, "repository:account:Search", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + accountColumns + ` FROM accounts
+        WHERE deleted_at IS NULL AND ($8 = '*' OR tenant_id = $8)
+          AND ($1 = '' OR document_number = $1)
+          AND ($2 = '' OR document_number LIKE $2 || '%')
+          AND ($3 = '' OR status = $3)
+          AND ($4::timestamp IS NULL OR created_at >= $4)
+          AND ($5::timestamp IS NULL OR created_at < $5)
+        ORDER BY created_at DESC, id DESC LIMIT $6 OFFSET $7;`
+
+	args := []interface{}{
+		filter.DocumentNumber, likePrefix(filter.DocumentPrefix), filter.Status,
+		nullableTime(filter.CreatedFrom), nullableTime(filter.CreatedTo), filter.Limit, filter.Offset, auth.Tenant(ctx),
+	}
+
+	accounts := make([]domain.Account, 0)
+	err := a.repository.Query(ctx, q, args, func(rows *sql.Rows) error {
+		var r accountResult
+		if err := rows.Scan(r.dest()...); err != nil {
+			return err
+		}
+
+		account, err := r.entity()
+		if err != nil {
+			return err
+		}
+
+		accounts = append(accounts, account)
+		return nil
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error searching accounts in postgres", logger.Err(err))
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+// Update stores entity if the persisted account is still at version, and
+// stores entity.Version as its new version. The account is locked while it
+// is compared, so of two concurrent writers of a version only one succeeds
+// and the other gets PreconditionFailedError. The change joins the history
+// of the account, an erasure also erases the personal data of its history.
+func (a *accountImpl) Update(ctx context.Context, entity domain.Account, version int) error {
+	ctx, span := telemetry.Span(ctx, "repository:account:Update", trace.SpanKindInternal)
+	defer span.End()
+
+	err := transact(ctx, a.repository, func(tx *postgres.Tx) error {
+		q := `SELECT ` + accountColumns + ` FROM accounts
+            WHERE id = $1 AND deleted_at IS NULL AND ($2 = '*' OR tenant_id = $2) FOR UPDATE;`
+
+		var r accountResult
+		err := tx.QueryRowContext(ctx, q, entity.Id, auth.Tenant(ctx)).Scan(r.dest()...)
+		if err != nil {
+			return err
+		}
+
+		persisted, err := r.entity()
+		if err != nil {
+			return err
+		}
+
+		if persisted.Version != version {
+			return exceptions.PreconditionFailedError.WithDetail(fmt.Sprintf("account %s was modified", entity.Id))
+		}
+
+		event := domain.NewAccountHistory(accountChange(persisted, entity), entity)
+		if err := liveProjection.apply(ctx, tx, &event); err != nil {
+			return err
+		}
+
+		if entity.IsErased() && !persisted.IsErased() {
+			if err := eraseHistory(ctx, tx, entity); err != nil {
+				return err
+			}
+		}
+
+		return appendHistory(ctx, tx, event)
+	})
+	if err != nil {
+		err = postgres.ToDomainError(err)
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error updating account in postgres", logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// likePrefix escapes the LIKE wildcards of prefix.
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+}
+
+func nullableTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+
+	return t
+}
+
 func NewAccountRepository(repository postgres.Repository) Account {
 	return &accountImpl{
 		repository: repository,
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/postgres"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/domain"
+)
+
+type APIKey interface {
+	Push(ctx context.Context, entity domain.APIKey) error
+	Get(ctx context.Context, id string) (domain.APIKey, error)
+	GetByHash(ctx context.Context, hash string) (domain.APIKey, error)
+	Expire(ctx context.Context, id string, at time.Time) error
+	Revoke(ctx context.Context, id string, at time.Time) error
+}
+
+type (
+	apiKeyImpl struct {
+		repository postgres.Repository
+	}
+
+	apiKeyResult struct {
+		Id        string
+		ClientID  string
+		TenantID  string
+		Hash      string
+		Scopes    []string
+		CreatedAt time.Time
+		ExpiresAt sql.NullTime
+		RevokedAt sql.NullTime
+		TTL       int64
+	}
+)
+
+// Keys belong to the tenant of their client and are read scoped to the
+// tenant of the caller, authentication looks them up for every tenant.
+const apiKeyColumns = `id, client_id, tenant_id, key_hash, scopes, created_at, expires_at, revoked_at, ttl_seconds`
+
+func (a *apiKeyImpl) Get(ctx context.Context, id string) (domain.APIKey, error) {
+	ctx, span := telemetry.Span(ctx, "repository:apiKey:Get", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1 AND ($2 = '*' OR tenant_id = $2);`
+
+	return a.get(ctx, span, q, id)
+}
+
+func (a *apiKeyImpl) GetByHash(ctx context.Context, hash string) (domain.APIKey, error) {
+	ctx, span := telemetry.Span(ctx, "repository:apiKey:GetByHash", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1 AND ($2 = '*' OR tenant_id = $2);`
+
+	return a.get(ctx, span, q, hash)
+}
+
+func (a *apiKeyImpl) get(ctx context.Context, span trace.Span, q, param string) (domain.APIKey, error) {
+	var r apiKeyResult
+	err := a.repository.QueryRow(ctx, q, []interface{}{param, auth.Tenant(ctx)},
+		&r.Id, &r.ClientID, &r.TenantID, &r.Hash, pq.Array(&r.Scopes), &r.CreatedAt, &r.ExpiresAt, &r.RevokedAt, &r.TTL,
+	)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error getting api key from postgres", logger.Err(err))
+		return domain.APIKey{}, err
+	}
+
+	key := domain.APIKey{
+		Id:        r.Id,
+		ClientID:  r.ClientID,
+		TenantID:  r.TenantID,
+		Hash:      r.Hash,
+		Scopes:    r.Scopes,
+		CreatedAt: r.CreatedAt,
+		TTL:       time.Duration(r.TTL) * time.Second,
+	}
+	if r.ExpiresAt.Valid {
+		key.ExpiresAt = &r.ExpiresAt.Time
+	}
+	if r.RevokedAt.Valid {
+		key.RevokedAt = &r.RevokedAt.Time
+	}
+
+	return key, nil
+}
+
+func (a *apiKeyImpl) Push(ctx context.Context, entity domain.APIKey) error {
+	ctx, span := telemetry.Span(ctx, "repository:apiKey:Push", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `
+	INSERT INTO api_keys (id, client_id, tenant_id, key_hash, scopes, created_at, expires_at, ttl_seconds)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+    `
+
+	err := a.repository.Push(ctx, q, entity.Id, entity.ClientID, entity.TenantID, entity.Hash, pq.Array(entity.Scopes), entity.CreatedAt, entity.ExpiresAt, int64(entity.TTL/time.Second))
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error pushing api key to postgres", logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// Expire brings the expiry of a key forward to at, it never extends it.
+func (a *apiKeyImpl) Expire(ctx context.Context, id string, at time.Time) error {
+	ctx, span := telemetry.Span(ctx, "repository:apiKey:Expire", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `
+	UPDATE api_keys SET expires_at = LEAST(COALESCE(expires_at, $2), $2)
+        WHERE id = $1 AND revoked_at IS NULL AND ($3 = '*' OR tenant_id = $3);
+    `
+
+	err := a.repository.Push(ctx, q, id, at, auth.Tenant(ctx))
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error expiring api key in postgres", logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (a *apiKeyImpl) Revoke(ctx context.Context, id string, at time.Time) error {
+	ctx, span := telemetry.Span(ctx, "repository:apiKey:Revoke", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `
+	UPDATE api_keys SET revoked_at = $2
+        WHERE id = $1 AND revoked_at IS NULL AND ($3 = '*' OR tenant_id = $3);
+    `
+
+	err := a.repository.Push(ctx, q, id, at, auth.Tenant(ctx))
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error revoking api key in postgres", logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func NewAPIKeyRepository(repository postgres.Repository) APIKey {
+	return &apiKeyImpl{
+		repository: repository,
+	}
+}
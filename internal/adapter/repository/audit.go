@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/postgres"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/domain"
+)
+
+// Tx is a transaction the repositories called with its context join.
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+type Audit interface {
+	Begin(ctx context.Context) (context.Context, Tx, error)
+	Savepoint(ctx context.Context) (func(rollback bool) (bool, error), error)
+	Append(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error)
+	List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error)
+	Walk(ctx context.Context, fn func(domain.AuditEntry) error) error
+}
+
+type auditImpl struct {
+	repository postgres.Repository
+}
+
+// Entries belong to the tenant of their entity, or of the caller when the
+// entity does not exist, and are read scoped to the tenant of the caller. The
+// chain itself spans every tenant.
+const auditColumns = `id, tenant_id, actor, route, request_id, entity_type, entity_id, action, before_snapshot,
+        after_snapshot, outcome, error_code, created_at, prev_hash, hash`
+
+func scanAudit(rows *sql.Rows) (domain.AuditEntry, error) {
+	var e domain.AuditEntry
+	var before, after []byte
+
+	err := rows.Scan(&e.Id, &e.TenantID, &e.Actor, &e.Route, &e.RequestID, &e.EntityType, &e.EntityID, &e.Action, &before, &after,
+		&e.Outcome, &e.ErrorCode, &e.CreatedAt, &e.PrevHash, &e.Hash)
+	e.Before, e.After = before, after
+
+	return e, err
+}
+
+// Begin starts a transaction the statements run with the returned context
+// join, appends included.
+func (a auditImpl) Begin(ctx context.Context) (context.Context, Tx, error) {
+	ctx, tx, err := a.repository.Transaction(ctx)
+	if err != nil {
+		err = postgres.ToDomainError(err)
+		logger.Error(ctx, logger.ServerError, "error beginning audited transaction in postgres", logger.Err(err))
+		return ctx, nil, err
+	}
+
+	return ctx, tx, nil
+}
+
+// Savepoint sets a savepoint in the transaction begun with Begin, see
+// postgres.Repository.Savepoint.
+func (a auditImpl) Savepoint(ctx context.Context) (func(rollback bool) (bool, error), error) {
+	end, err := a.repository.Savepoint(ctx)
+	if err != nil {
+		err = postgres.ToDomainError(err)
+		logger.Error(ctx, logger.ServerError, "error setting savepoint in postgres", logger.Err(err))
+		return nil, err
+	}
+
+	return func(rollback bool) (bool, error) {
+		rolledBack, err := end(rollback)
+		return rolledBack, postgres.ToDomainError(err)
+	}, nil
+}
+
+// Append chains entry to the last one of the log. The advisory lock
+// serialises appends of every replica, so no two entries share a predecessor.
+func (a auditImpl) Append(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error) {
+	ctx, span := telemetry.Span(ctx, "repository:audit:Append", trace.SpanKindInternal)
+	defer span.End()
+
+	entry, err := a.append(ctx, entry)
+	if err != nil {
+		err = postgres.ToDomainError(err)
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error appending audit entry to postgres", logger.Err(err))
+		return domain.AuditEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (a auditImpl) append(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error) {
+	tx, err := a.repository.BeginTx(ctx)
+	if err != nil {
+		return domain.AuditEntry{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('audit_log'));`); err != nil {
+		return domain.AuditEntry{}, err
+	}
+
+	var prevHash string
+	err = tx.QueryRowContext(ctx, `SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1;`).Scan(&prevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return domain.AuditEntry{}, err
+	}
+
+	if err := entry.Seal(prevHash); err != nil {
+		return domain.AuditEntry{}, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+	INSERT INTO audit_log (actor, route, request_id, entity_type, entity_id, action, before_snapshot, after_snapshot,
+                           outcome, error_code, created_at, prev_hash, hash, tenant_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, COALESCE(audit_entity_tenant($4, $5), $14))
+        RETURNING id, tenant_id;
+    `,
+		entry.Actor, entry.Route, entry.RequestID, entry.EntityType, entry.EntityID, entry.Action,
+		nullableJSON(entry.Before), nullableJSON(entry.After),
+		entry.Outcome, entry.ErrorCode, entry.CreatedAt, entry.PrevHash, entry.Hash, auth.OwnTenant(ctx),
+	).Scan(&entry.Id, &entry.TenantID)
+	if err != nil {
+		return domain.AuditEntry{}, err
+	}
+
+	return entry, tx.Commit()
+}
+
+// List returns the entries matching filter, newest first.
+func (a auditImpl) List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	ctx, span := telemetry.Span(ctx, "repository:audit:List", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + auditColumns + ` FROM audit_log
+        WHERE ($1 = '' OR entity_type = $1) AND ($2 = '' OR entity_id = $2) AND ($5 = '*' OR tenant_id = $5)
+        ORDER BY id DESC LIMIT $3 OFFSET $4;`
+
+	args := []interface{}{filter.EntityType, filter.EntityID, filter.Limit, filter.Offset, auth.Tenant(ctx)}
+
+	entries := make([]domain.AuditEntry, 0)
+	err := a.repository.Query(ctx, q, args, func(rows *sql.Rows) error {
+		entry, err := scanAudit(rows)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error listing audit entries from postgres", logger.Err(err))
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Walk streams every entry of the tenant of ctx in chain order to fn, the
+// whole chain for callers acting for every tenant.
+func (a auditImpl) Walk(ctx context.Context, fn func(domain.AuditEntry) error) error {
+	ctx, span := telemetry.Span(ctx, "repository:audit:Walk", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + auditColumns + ` FROM audit_log WHERE ($1 = '*' OR tenant_id = $1) ORDER BY id;`
+
+	err := a.repository.Query(ctx, q, []interface{}{auth.Tenant(ctx)}, func(rows *sql.Rows) error {
+		entry, err := scanAudit(rows)
+		if err != nil {
+			return err
+		}
+
+		return fn(entry)
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error walking audit entries from postgres", logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func nullableJSON(raw []byte) interface{} {
+	if raw == nil {
+		return nil
+	}
+
+	return string(raw)
+}
+
+func NewAuditRepository(repository postgres.Repository) Audit {
+	return auditImpl{repository: repository}
+}
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/postgres"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/domain"
+)
+
+type Card interface {
+	Push(ctx context.Context, entity domain.Card) error
+	Get(ctx context.Context, id string) (domain.Card, error)
+	GetByToken(ctx context.Context, token string) (domain.Card, error)
+	ListByAccount(ctx context.Context, accountID string) ([]domain.Card, error)
+	Update(ctx context.Context, entity domain.Card) error
+	Replace(ctx context.Context, replaced, replacement domain.Card) error
+	Expire(ctx context.Context, now time.Time) ([]domain.Card, error)
+}
+
+type cardImpl struct {
+	repository postgres.Repository
+}
+
+// Every query is scoped to the tenant of its context with a tenant_id
+// filter, the expiry worker acts for auth.AnyTenant and sees every tenant.
+const cardColumns = `id, account_id, tenant_id, token, last_four, expiry_month, expiry_year, status, replaced_by,
+        created_at, updated_at`
+
+func cardDest(c *domain.Card) []interface{} {
+	return []interface{}{
+		&c.Id, &c.AccountID, &c.TenantID, &c.Token, &c.LastFour, &c.ExpiryMonth, &c.ExpiryYear, &c.Status, &c.ReplacedBy,
+		&c.CreatedAt, &c.UpdatedAt,
+	}
+}
+
+const pushCard = `
+	INSERT INTO cards (` + cardColumns + `)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
+    `
+
+func cardArgs(c domain.Card) []interface{} {
+	return []interface{}{
+		c.Id, c.AccountID, c.TenantID, c.Token, c.LastFour, c.ExpiryMonth, c.ExpiryYear, c.Status, c.ReplacedBy,
+		c.CreatedAt, c.UpdatedAt,
+	}
+}
+
+func (c cardImpl) Push(ctx context.Context, entity domain.Card) error {
+	ctx, span := telemetry.Span(ctx, "repository:card:Push", trace.SpanKindInternal)
+	defer span.End()
+
+	if err := c.repository.Push(ctx, pushCard, cardArgs(entity)...); err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error pushing card to postgres", logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (c cardImpl) Get(ctx context.Context, id string) (domain.Card, error) {
+	ctx, span := telemetry.Span(ctx, "repository:card:Get", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + cardColumns + ` FROM cards WHERE id = $1 AND ($2 = '*' OR tenant_id = $2);`
+
+	var card domain.Card
+	err := c.repository.QueryRow(ctx, q, []interface{}{id, auth.Tenant(ctx)}, cardDest(&card)...)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error getting card from postgres", logger.Err(err))
+		return domain.Card{}, err
+	}
+
+	return card, nil
+}
+
+// GetByToken returns the card a transaction is posted with.
+func (c cardImpl) GetByToken(ctx context.Context, token string) (domain.Card, error) {
+	ctx, span := telemetry.Span(ctx, "repository:card:GetByToken", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + cardColumns + ` FROM cards WHERE token = $1 AND ($2 = '*' OR tenant_id = $2);`
+
+	var card domain.Card
+	err := c.repository.QueryRow(ctx, q, []interface{}{token, auth.Tenant(ctx)}, cardDest(&card)...)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error getting card by token from postgres", logger.Err(err))
+		return domain.Card{}, err
+	}
+
+	return card, nil
+}
+
+// ListByAccount returns the cards of accountID, oldest first.
+func (c cardImpl) ListByAccount(ctx context.Context, accountID string) ([]domain.Card, error) {
+	ctx, span := telemetry.Span(ctx, "repository:card:ListByAccount", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + cardColumns + ` FROM cards
+        WHERE account_id = $1 AND ($2 = '*' OR tenant_id = $2) ORDER BY created_at, id;`
+
+	return c.query(ctx, span, q, accountID, auth.Tenant(ctx))
+}
+
+func (c cardImpl) Update(ctx context.Context, entity domain.Card) error {
+	ctx, span := telemetry.Span(ctx, "repository:card:Update", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `UPDATE cards SET status = $2, replaced_by = $3, updated_at = $4 WHERE id = $1 AND ($5 = '*' OR tenant_id = $5);`
+
+	err := c.repository.Push(ctx, q, entity.Id, entity.Status, entity.ReplacedBy, entity.UpdatedAt, auth.Tenant(ctx))
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error updating card in postgres", logger.Str("card_id", entity.Id), logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// Replace updates the replaced card and issues its replacement in a single
+// transaction, so an account is never left without a usable card or with two.
+func (c cardImpl) Replace(ctx context.Context, replaced, replacement domain.Card) error {
+	ctx, span := telemetry.Span(ctx, "repository:card:Replace", trace.SpanKindInternal)
+	defer span.End()
+
+	err := c.replace(ctx, replaced, replacement)
+	if err != nil {
+		err = postgres.ToDomainError(err)
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error replacing card in postgres", logger.Str("card_id", replaced.Id), logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (c cardImpl) replace(ctx context.Context, replaced, replacement domain.Card) error {
+	tx, err := c.repository.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+	UPDATE cards SET status = $2, replaced_by = $3, updated_at = $4
+        WHERE id = $1 AND status IN ('active', 'blocked') AND ($5 = '*' OR tenant_id = $5);
+    `, replaced.Id, replaced.Status, replaced.ReplacedBy, replaced.UpdatedAt, auth.Tenant(ctx))
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// a concurrent block or replace got there first
+	if count == 0 {
+		return exceptions.InvalidStateError.WithDetail(fmt.Sprintf("card %s is no longer active", replaced.Id))
+	}
+
+	if _, err := tx.ExecContext(ctx, pushCard, cardArgs(replacement)...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Expire marks expired the active and blocked cards whose expiry month ended
+// before now and returns them.
+func (c cardImpl) Expire(ctx context.Context, now time.Time) ([]domain.Card, error) {
+	ctx, span := telemetry.Span(ctx, "repository:card:Expire", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `
+	UPDATE cards SET status = 'expired', updated_at = $1
+        WHERE status IN ('active', 'blocked') AND expiry_year * 12 + expiry_month < $2 AND ($3 = '*' OR tenant_id = $3)
+        RETURNING ` + cardColumns + `;`
+
+	now = now.UTC()
+	return c.query(ctx, span, q, now, now.Year()*12+int(now.Month()), auth.Tenant(ctx))
+}
+
+func (c cardImpl) query(ctx context.Context, span trace.Span, q string, args ...interface{}) ([]domain.Card, error) {
+	cards := make([]domain.Card, 0)
+	err := c.repository.Query(ctx, q, args, func(rows *sql.Rows) error {
+		var card domain.Card
+		if err := rows.Scan(cardDest(&card)...); err != nil {
+			return err
+		}
+
+		cards = append(cards, card)
+		return nil
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error listing cards from postgres", logger.Err(err))
+		return nil, err
+	}
+
+	return cards, nil
+}
+
+func NewCardRepository(repository postgres.Repository) Card {
+	return cardImpl{repository: repository}
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/postgres"
+)
+
+// statement is a statement run against the recording driver.
+type statement struct {
+	query string
+	args  []driver.Value
+}
+
+// recordingDriver records the statements it is sent and answers them with no
+// rows, the database itself is left to the integration tests. Statements
+// containing fail fail and abort the transaction as Postgres does: the next
+// statements fail until it is rolled back, to a savepoint or whole. Statements
+// changing rows change affected.
+type recordingDriver struct {
+	mu         sync.Mutex
+	statements []statement
+	fail       string
+	affected   int64
+	aborted    bool
+}
+
+var (
+	registerRecordingDriver sync.Once
+	recordingDrivers        = map[string]*recordingDriver{}
+	recordingDriversMu      sync.Mutex
+)
+
+func newRecordingDB(t *testing.T) (postgres.Repository, *recordingDriver) {
+	registerRecordingDriver.Do(func() { sql.Register("recording", recordingConnector{}) })
+
+	d := &recordingDriver{}
+	recordingDriversMu.Lock()
+	recordingDrivers[t.Name()] = d
+	recordingDriversMu.Unlock()
+
+	db, err := sql.Open("recording", t.Name())
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return postgres.Repository{DB: db}, d
+}
+
+func (d *recordingDriver) record(query string, args []driver.NamedValue) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	values := make([]driver.Value, 0, len(args))
+	for _, arg := range args {
+		values = append(values, arg.Value)
+	}
+	d.statements = append(d.statements, statement{query: query, args: values})
+
+	switch {
+	case strings.HasPrefix(query, "ROLLBACK TO SAVEPOINT"):
+		d.aborted = false
+	case d.aborted:
+		return &pq.Error{Code: "25P02", Message: "current transaction is aborted, commands ignored until end of transaction block"}
+	case d.fail != "" && strings.Contains(query, d.fail):
+		d.aborted = true
+		return &pq.Error{Code: "23502", Message: "null value violates not-null constraint"}
+	}
+
+	return nil
+}
+
+func (d *recordingDriver) end() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.aborted = false
+	return nil
+}
+
+type recordingConnector struct{}
+
+func (recordingConnector) Open(name string) (driver.Conn, error) {
+	recordingDriversMu.Lock()
+	defer recordingDriversMu.Unlock()
+	return recordingConn{recordingDrivers[name]}, nil
+}
+
+type recordingConn struct {
+	d *recordingDriver
+}
+
+func (c recordingConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c recordingConn) Close() error                        { return nil }
+func (c recordingConn) Begin() (driver.Tx, error)           { return recordingTx{c.d}, nil }
+
+type recordingTx struct {
+	d *recordingDriver
+}
+
+func (t recordingTx) Commit() error   { return t.d.end() }
+func (t recordingTx) Rollback() error { return t.d.end() }
+
+func (c recordingConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.d.record(query, args); err != nil {
+		return nil, err
+	}
+	return noRows{}, nil
+}
+
+func (c recordingConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.d.record(query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(c.d.affected), nil
+}
+
+type noRows struct{}
+
+func (noRows) Columns() []string         { return nil }
+func (noRows) Close() error              { return nil }
+func (noRows) Next([]driver.Value) error { return io.EOF }
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/postgres"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/domain"
+)
+
+type Event interface {
+	Push(ctx context.Context, event domain.Event) (domain.Event, error)
+	ListAfter(ctx context.Context, accountID string, after int64, limit int) ([]domain.Event, error)
+}
+
+type eventImpl struct {
+	repository postgres.Repository
+}
+
+// Push appends event to the account event log and returns it with its Seq.
+// The Seq is drawn under a lock on the account held until the transaction
+// publishing the event commits, so the events of an account commit in Seq
+// order and resuming after one never skips another. Events take the tenant of
+// their account, and are read scoped to the tenant of the caller.
+func (e eventImpl) Push(ctx context.Context, event domain.Event) (domain.Event, error) {
+	ctx, span := telemetry.Span(ctx, "repository:event:Push", trace.SpanKindInternal)
+	defer span.End()
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Event{}, err
+	}
+
+	q := `
+	INSERT INTO account_events (id, account_id, type, occurred_at, data, tenant_id)
+        VALUES ($1, $2, $3, $4, $5, COALESCE((SELECT tenant_id FROM accounts WHERE id = $2), $6))
+        RETURNING seq;
+    `
+
+	err = transact(ctx, e.repository, func(tx *postgres.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('account_events:' || $1));`, event.AccountID); err != nil {
+			return err
+		}
+
+		return tx.QueryRowContext(ctx, q, event.Id, event.AccountID, event.Type, event.OccurredAt, data, auth.OwnTenant(ctx)).Scan(&event.Seq)
+	})
+	if err != nil {
+		err = postgres.ToDomainError(err)
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error pushing account event to postgres", logger.Err(err))
+		return domain.Event{}, err
+	}
+
+	return event, nil
+}
+
+// ListAfter returns up to limit events of accountID following the event
+// numbered after, oldest first.
+func (e eventImpl) ListAfter(ctx context.Context, accountID string, after int64, limit int) ([]domain.Event, error) {
+	ctx, span := telemetry.Span(ctx, "repository:event:ListAfter", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT seq, id, account_id, type, occurred_at, data FROM account_events
+        WHERE account_id = $1 AND seq > $2 AND ($4 = '*' OR tenant_id = $4)
+        ORDER BY seq LIMIT $3;`
+
+	events := make([]domain.Event, 0)
+	err := e.repository.Query(ctx, q, []interface{}{accountID, after, limit, auth.Tenant(ctx)}, func(rows *sql.Rows) error {
+		var event domain.Event
+		var data []byte
+
+		if err := rows.Scan(&event.Seq, &event.Id, &event.AccountID, &event.Type, &event.OccurredAt, &data); err != nil {
+			return err
+		}
+
+		if err := json.Unmarshal(data, &event.Data); err != nil {
+			return err
+		}
+
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error listing account events from postgres", logger.Err(err))
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func NewEventRepository(repository postgres.Repository) Event {
+	return eventImpl{repository: repository}
+}
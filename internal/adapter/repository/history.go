@@ -0,0 +1,613 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/postgres"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
+)
+
+// History replays the history of the accounts into copies of the tables it
+// projects to, and compares them with the tables or restores the tables from
+// them when rebuild is set.
+type History interface {
+	Replay(ctx context.Context, rebuild bool) (domain.Projection, error)
+}
+
+type (
+	historyImpl struct {
+		repository postgres.Repository
+	}
+
+	// projection writes history events to the accounts and transactions
+	// tables it names, the ones the service reads or their replayed copies.
+	projection struct {
+		accounts     string
+		transactions string
+	}
+
+	historyAccount struct {
+		Id             string               `json:"id"`
+		TenantID       string               `json:"tenant_id"`
+		DocumentNumber string               `json:"document_number"`
+		Status         domain.AccountStatus `json:"status"`
+		HolderName     string               `json:"holder_name"`
+		Email          string               `json:"email"`
+		Phone          string               `json:"phone"`
+		Address        *domain.Address      `json:"address"`
+		Metadata       map[string]string    `json:"metadata"`
+		Version        int                  `json:"version"`
+		CreatedAt      time.Time            `json:"created_at"`
+		UpdatedAt      time.Time            `json:"updated_at"`
+		ClosedAt       *time.Time           `json:"closed_at"`
+		ErasedAt       *time.Time           `json:"erased_at"`
+		DeletedAt      *time.Time           `json:"deleted_at"`
+	}
+
+	historyTransaction struct {
+		Id            int             `json:"id"`
+		AccountID     string          `json:"account_id"`
+		OperationType int             `json:"operation_type"`
+		Amount        float64         `json:"amount"`
+		EventDate     time.Time       `json:"event_date"`
+		Decision      domain.Decision `json:"decision"`
+		Reasons       []string        `json:"reasons"`
+		Status        domain.Status   `json:"status"`
+		ReviewedBy    string          `json:"reviewed_by"`
+		ReviewReason  string          `json:"review_reason"`
+		ReviewedAt    *time.Time      `json:"reviewed_at"`
+		CardID        string          `json:"card_id"`
+		Merchant      historyMerchant `json:"merchant"`
+	}
+
+	historyMerchant struct {
+		Name    string `json:"name"`
+		MCC     string `json:"mcc"`
+		City    string `json:"city"`
+		Country string `json:"country"`
+	}
+)
+
+const historyReplayBatch = 1000
+
+var (
+	liveProjection     = projection{accounts: "accounts", transactions: "transactions"}
+	replayedProjection = projection{accounts: "history_accounts", transactions: "history_transactions"}
+)
+
+// Events join the history of their account only when the account is visible
+// to the tenant of the context, and take its tenant.
+const appendHistoryEvents = `
+	INSERT INTO history_events (account_id, tenant_id, type, recorded_at, data)
+        SELECT accounts.id, accounts.tenant_id, e.type, e.recorded_at, e.data
+        FROM unnest($1::text[], $2::text[], $3::timestamptz[], $4::jsonb[]) WITH ORDINALITY AS e(account_id, type, recorded_at, data, n)
+        JOIN accounts ON accounts.id = e.account_id
+        WHERE ($5 = '*' OR accounts.tenant_id = $5)
+        ORDER BY e.n;
+    `
+
+// appendHistory appends events, in order, to the history of their accounts.
+func appendHistory(ctx context.Context, tx *postgres.Tx, events ...domain.HistoryEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	accountIDs := make([]string, 0, len(events))
+	types := make([]string, 0, len(events))
+	recordedAt := make([]string, 0, len(events))
+	data := make([]string, 0, len(events))
+
+	for _, event := range events {
+		encoded, err := encodeHistory(event)
+		if err != nil {
+			return err
+		}
+
+		accountIDs = append(accountIDs, event.AccountID)
+		types = append(types, string(event.Type))
+		recordedAt = append(recordedAt, event.RecordedAt.Format(time.RFC3339Nano))
+		data = append(data, string(encoded))
+	}
+
+	_, err := tx.ExecContext(ctx, appendHistoryEvents,
+		pq.Array(accountIDs), pq.Array(types), pq.Array(recordedAt), pq.Array(data), auth.Tenant(ctx))
+	return err
+}
+
+// accountChange is the history type of storing after over before: a status
+// change when the account is closed, erased or deleted, an update otherwise.
+func accountChange(before, after domain.Account) domain.HistoryType {
+	if before.Status != after.Status ||
+		(before.ErasedAt == nil) != (after.ErasedAt == nil) ||
+		(before.DeletedAt == nil) != (after.DeletedAt == nil) {
+		return domain.HistoryAccountStatusChanged
+	}
+
+	return domain.HistoryAccountUpdated
+}
+
+// eraseHistory rewrites the personal data of the account events of an erased
+// account with the erased values, the only edit the history accepts.
+func eraseHistory(ctx context.Context, tx *postgres.Tx, erased domain.Account) error {
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('app.erasure', $1, true);`, erased.Id); err != nil {
+		return err
+	}
+
+	q := `
+	UPDATE history_events
+        SET data = data || jsonb_build_object('document_number', $2::text, 'holder_name', '', 'email', '', 'phone', '',
+                                              'address', NULL, 'metadata', NULL)
+        WHERE account_id = $1 AND type LIKE 'account.%' AND ($3 = '*' OR tenant_id = $3);
+    `
+
+	_, err := tx.ExecContext(ctx, q, erased.Id, erased.DocumentNumber, auth.Tenant(ctx))
+	return err
+}
+
+func encodeHistory(event domain.HistoryEvent) ([]byte, error) {
+	if event.Account != nil {
+		account := event.Account
+		return json.Marshal(historyAccount{
+			Id:             account.Id,
+			TenantID:       account.TenantID,
+			DocumentNumber: account.DocumentNumber,
+			Status:         account.Status,
+			HolderName:     account.HolderName,
+			Email:          account.Email,
+			Phone:          account.Phone,
+			Address:        account.Address,
+			Metadata:       account.Metadata,
+			Version:        account.Version,
+			CreatedAt:      account.CreatedAt,
+			UpdatedAt:      account.UpdatedAt,
+			ClosedAt:       account.ClosedAt,
+			ErasedAt:       account.ErasedAt,
+			DeletedAt:      account.DeletedAt,
+		})
+	}
+
+	if event.Transaction != nil {
+		transaction := event.Transaction
+		return json.Marshal(historyTransaction{
+			Id:            transaction.Id,
+			AccountID:     transaction.AccountID,
+			OperationType: transaction.OperationType.Index(),
+			Amount:        transaction.Amount,
+			EventDate:     transaction.EventDate,
+			Decision:      transaction.Decision,
+			Reasons:       transaction.Reasons,
+			Status:        transaction.Status,
+			ReviewedBy:    transaction.ReviewedBy,
+			ReviewReason:  transaction.ReviewReason,
+			ReviewedAt:    transaction.ReviewedAt,
+			CardID:        transaction.CardID,
+			Merchant:      historyMerchant(transaction.Merchant),
+		})
+	}
+
+	return nil, fmt.Errorf("history event %s of account %s carries no state", event.Type, event.AccountID)
+}
+
+// decodeHistory sets the account or the transaction of event from data,
+// according to its type.
+func decodeHistory(event *domain.HistoryEvent, data []byte) error {
+	switch event.Type {
+	case domain.HistoryAccountOpened, domain.HistoryAccountUpdated, domain.HistoryAccountStatusChanged:
+		var r historyAccount
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+
+		event.Account = &domain.Account{
+			Id:             r.Id,
+			TenantID:       r.TenantID,
+			DocumentNumber: r.DocumentNumber,
+			Status:         r.Status,
+			AccountProfile: domain.AccountProfile{
+				HolderName: r.HolderName,
+				Email:      r.Email,
+				Phone:      r.Phone,
+				Address:    r.Address,
+				Metadata:   r.Metadata,
+			},
+			Version:   r.Version,
+			CreatedAt: r.CreatedAt,
+			UpdatedAt: r.UpdatedAt,
+			ClosedAt:  r.ClosedAt,
+			ErasedAt:  r.ErasedAt,
+			DeletedAt: r.DeletedAt,
+		}
+
+	case domain.HistoryTransactionPosted, domain.HistoryTransactionStatusChanged, domain.HistoryTransactionReversed:
+		var r historyTransaction
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+
+		event.Transaction = &domain.Transaction{
+			Id:            r.Id,
+			AccountID:     r.AccountID,
+			OperationType: operation.Type(r.OperationType),
+			Amount:        r.Amount,
+			EventDate:     r.EventDate,
+			Decision:      r.Decision,
+			Reasons:       r.Reasons,
+			Status:        r.Status,
+			ReviewedBy:    r.ReviewedBy,
+			ReviewReason:  r.ReviewReason,
+			ReviewedAt:    r.ReviewedAt,
+			CardID:        r.CardID,
+			Merchant:      domain.Merchant(r.Merchant),
+		}
+
+	default:
+		return fmt.Errorf("unknown history event type %q", event.Type)
+	}
+
+	return nil
+}
+
+// apply writes event to the tables of the projection, a posted transaction
+// without an id takes the next one and event is updated with it.
+func (p projection) apply(ctx context.Context, tx *postgres.Tx, event *domain.HistoryEvent) error {
+	switch event.Type {
+	case domain.HistoryAccountOpened:
+		return p.openAccount(ctx, tx, *event.Account)
+	case domain.HistoryAccountUpdated, domain.HistoryAccountStatusChanged:
+		return p.updateAccount(ctx, tx, *event.Account)
+	case domain.HistoryTransactionPosted:
+		return p.postTransaction(ctx, tx, event.Transaction)
+	case domain.HistoryTransactionStatusChanged:
+		return p.resolveTransaction(ctx, tx, *event.Transaction, domain.StatusPendingReview)
+	case domain.HistoryTransactionReversed:
+		return p.resolveTransaction(ctx, tx, *event.Transaction, domain.StatusApproved)
+	}
+
+	return fmt.Errorf("unknown history event type %q", event.Type)
+}
+
+func (p projection) openAccount(ctx context.Context, tx *postgres.Tx, account domain.Account) error {
+	q := `INSERT INTO ` + p.accounts + ` (` + accountColumns + `)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15);`
+
+	address, metadata, err := profileArgs(account)
+	if err != nil {
+		return err
+	}
+
+	return execOne(ctx, tx, q, account.Id, account.TenantID, account.DocumentNumber, account.Status, account.HolderName,
+		account.Email, account.Phone, address, metadata, account.Version, account.CreatedAt, account.UpdatedAt,
+		account.ClosedAt, account.ErasedAt, account.DeletedAt)
+}
+
+// updateAccount stores account over an older version of itself, versions
+// only grow so an account already at its version is left alone.
+func (p projection) updateAccount(ctx context.Context, tx *postgres.Tx, account domain.Account) error {
+	q := `
+	UPDATE ` + p.accounts + `
+        SET document_number = $2, status = $3, holder_name = $4, email = $5, phone = $6, address = $7, metadata = $8,
+            version = $9, updated_at = $10, closed_at = $11, erased_at = $12, deleted_at = $13
+        WHERE id = $1 AND version < $9 AND deleted_at IS NULL AND ($14 = '*' OR tenant_id = $14);
+    `
+
+	address, metadata, err := profileArgs(account)
+	if err != nil {
+		return err
+	}
+
+	return execOne(ctx, tx, q, account.Id, account.DocumentNumber, account.Status, account.HolderName, account.Email,
+		account.Phone, address, metadata, account.Version, account.UpdatedAt, account.ClosedAt, account.ErasedAt,
+		account.DeletedAt, auth.Tenant(ctx))
+}
+
+func (p projection) postTransaction(ctx context.Context, tx *postgres.Tx, transaction *domain.Transaction) error {
+	q := `
+	INSERT INTO ` + p.transactions + ` (` + transactionColumns + `, tenant_id) OVERRIDING SYSTEM VALUE
+        SELECT COALESCE(NULLIF($1::int, 0), nextval(pg_get_serial_sequence('` + p.transactions + `', 'id'))),
+               $2::text, $3::int, $4::float8, $5::timestamp, $6::text, $7::text[], $8::text, $9::text, $10::text,
+               $11::timestamp, $12::text, $13::text, $14::text, $15::text, $16::text, tenant_id
+        FROM ` + p.accounts + ` WHERE id = $2 AND ($17 = '*' OR tenant_id = $17)
+        RETURNING id;
+    `
+
+	return tx.QueryRowContext(ctx, q,
+		transaction.Id, transaction.AccountID, transaction.OperationType, transaction.Amount, transaction.EventDate,
+		transaction.Decision, pq.Array(transaction.Reasons), transaction.Status, transaction.ReviewedBy,
+		transaction.ReviewReason, transaction.ReviewedAt, transaction.CardID, transaction.Merchant.Name,
+		transaction.Merchant.MCC, transaction.Merchant.City, transaction.Merchant.Country, auth.Tenant(ctx),
+	).Scan(&transaction.Id)
+}
+
+// resolveTransaction stores the decision of an operator on a transaction
+// still in the status from: a review of a pending one, or the reversal of an
+// approved one.
+func (p projection) resolveTransaction(ctx context.Context, tx *postgres.Tx, transaction domain.Transaction, from domain.Status) error {
+	q := `
+	UPDATE ` + p.transactions + ` SET status = $2, reviewed_by = $3, review_reason = $4, reviewed_at = $5
+        WHERE id = $1 AND status = $6 AND ($7 = '*' OR tenant_id = $7);
+    `
+
+	return execOne(ctx, tx, q, transaction.Id, transaction.Status, transaction.ReviewedBy, transaction.ReviewReason,
+		transaction.ReviewedAt, from, auth.Tenant(ctx))
+}
+
+// execOne runs q in tx and answers EntityNotFoundError when it changes no row.
+func execOne(ctx context.Context, tx *postgres.Tx, q string, args ...interface{}) error {
+	result, err := tx.ExecContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return exceptions.EntityNotFoundError
+	}
+
+	return nil
+}
+
+// transact runs fn in a database transaction scoped to the tenant of ctx,
+// committed once fn succeeds.
+func transact(ctx context.Context, repository postgres.Repository, fn func(*postgres.Tx) error) error {
+	tx, err := repository.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Replay projects the whole history into temporary copies of the accounts
+// and transactions tables and compares them with the tables. When rebuild is set the missing and changed rows are then
+// written back to the tables, the unexpected ones are left as they are.
+func (h historyImpl) Replay(ctx context.Context, rebuild bool) (domain.Projection, error) {
+	ctx, span := telemetry.Span(ctx, "repository:history:Replay", trace.SpanKindInternal)
+	defer span.End()
+
+	result, err := h.snapshot(ctx, rebuild)
+	if err != nil {
+		err = postgres.ToDomainError(err)
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error replaying history from postgres", logger.Err(err))
+		return domain.Projection{}, err
+	}
+
+	return result, nil
+}
+
+// snapshot replays in a repeatable read transaction, so the history and the
+// tables are read as of the same moment while the service keeps writing.
+func (h historyImpl) snapshot(ctx context.Context, rebuild bool) (domain.Projection, error) {
+	tx, err := h.repository.BeginSnapshotTx(ctx)
+	if err != nil {
+		return domain.Projection{}, err
+	}
+	defer tx.Rollback()
+
+	result, err := h.replay(ctx, tx, rebuild)
+	if err != nil {
+		return domain.Projection{}, err
+	}
+
+	return result, tx.Commit()
+}
+
+func (h historyImpl) replay(ctx context.Context, tx *postgres.Tx, rebuild bool) (domain.Projection, error) {
+	for _, table := range []string{liveProjection.accounts, liveProjection.transactions} {
+		q := fmt.Sprintf(`CREATE TEMPORARY TABLE history_%s (LIKE %s INCLUDING ALL) ON COMMIT DROP;`, table, table)
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			return domain.Projection{}, err
+		}
+	}
+
+	var result domain.Projection
+	var after int64
+	for {
+		events, err := h.page(ctx, tx, after)
+		if err != nil {
+			return domain.Projection{}, err
+		}
+
+		for i := range events {
+			if err := replayedProjection.apply(ctx, tx, &events[i]); err != nil {
+				return domain.Projection{}, fmt.Errorf("replay history event %d: %w", events[i].Seq, err)
+			}
+			after = events[i].Seq
+		}
+
+		result.Events += len(events)
+		if len(events) < historyReplayBatch {
+			break
+		}
+	}
+
+	tables := []struct {
+		name    string
+		columns string
+	}{
+		{name: liveProjection.accounts, columns: accountColumns},
+		{name: liveProjection.transactions, columns: transactionColumns + `, tenant_id`},
+	}
+
+	for _, table := range tables {
+		diff, err := compare(ctx, tx, table.name)
+		if err != nil {
+			return domain.Projection{}, err
+		}
+
+		if rebuild {
+			if err := restore(ctx, tx, table.name, table.columns, diff); err != nil {
+				return domain.Projection{}, err
+			}
+		}
+
+		result.Diffs = append(result.Diffs, diff)
+	}
+
+	return result, nil
+}
+
+// page returns the events recorded after the event numbered after.
+func (h historyImpl) page(ctx context.Context, tx *postgres.Tx, after int64) ([]domain.HistoryEvent, error) {
+	q := `SELECT seq, account_id, type, recorded_at, data FROM history_events
+        WHERE seq > $1 AND ($3 = '*' OR tenant_id = $3)
+        ORDER BY seq LIMIT $2;`
+
+	rows, err := tx.QueryContext(ctx, q, after, historyReplayBatch, auth.Tenant(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]domain.HistoryEvent, 0, historyReplayBatch)
+	for rows.Next() {
+		var event domain.HistoryEvent
+		var data []byte
+
+		if err := rows.Scan(&event.Seq, &event.AccountID, &event.Type, &event.RecordedAt, &data); err != nil {
+			return nil, err
+		}
+
+		if err := decodeHistory(&event, data); err != nil {
+			return nil, fmt.Errorf("decode history event %d: %w", event.Seq, err)
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// compare lists the rows of table missing from, unexpected in or differing
+// from its replayed copy, columns compared by their json value.
+func compare(ctx context.Context, tx *postgres.Tx, table string) (domain.ProjectionDiff, error) {
+	diff := domain.ProjectionDiff{Table: table}
+	tenant := auth.Tenant(ctx)
+
+	missing := `SELECT r.id::text FROM history_` + table + ` r
+        WHERE NOT EXISTS (SELECT 1 FROM ` + table + ` t WHERE t.id = r.id) ORDER BY r.id;`
+	if err := queryIDs(ctx, tx, &diff.Missing, missing); err != nil {
+		return domain.ProjectionDiff{}, err
+	}
+
+	unexpected := `SELECT t.id::text FROM ` + table + ` t
+        WHERE NOT EXISTS (SELECT 1 FROM history_` + table + ` r WHERE r.id = t.id) AND ($1 = '*' OR t.tenant_id = $1)
+        ORDER BY t.id;`
+	if err := queryIDs(ctx, tx, &diff.Unexpected, unexpected, tenant); err != nil {
+		return domain.ProjectionDiff{}, err
+	}
+
+	changed := `SELECT r.id::text, array_agg(c.key ORDER BY c.key)
+        FROM history_` + table + ` r
+        JOIN ` + table + ` t ON t.id = r.id
+        CROSS JOIN LATERAL jsonb_each(to_jsonb(r)) c
+        WHERE c.value IS DISTINCT FROM to_jsonb(t) -> c.key
+        GROUP BY r.id ORDER BY r.id;`
+
+	rows, err := tx.QueryContext(ctx, changed)
+	if err != nil {
+		return domain.ProjectionDiff{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var change domain.ProjectionChange
+		if err := rows.Scan(&change.Id, pq.Array(&change.Columns)); err != nil {
+			return domain.ProjectionDiff{}, err
+		}
+
+		diff.Changed = append(diff.Changed, change)
+	}
+
+	return diff, rows.Err()
+}
+
+func queryIDs(ctx context.Context, tx *postgres.Tx, ids *[]string, q string, args ...interface{}) error {
+	rows, err := tx.QueryContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+
+		*ids = append(*ids, id)
+	}
+
+	return rows.Err()
+}
+
+// restore writes the missing and changed rows of diff from the replayed copy
+// of table back to it. Transactions keep their ids, the sequence handing
+// them out is moved past the restored ones.
+func restore(ctx context.Context, tx *postgres.Tx, table, columns string, diff domain.ProjectionDiff) error {
+	ids := append([]string{}, diff.Missing...)
+	for _, change := range diff.Changed {
+		ids = append(ids, change.Id)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	set := make([]string, 0)
+	for _, column := range strings.Split(columns, ",") {
+		if column = strings.TrimSpace(column); column != "id" {
+			set = append(set, column+" = EXCLUDED."+column)
+		}
+	}
+
+	// only transactions have an identity column to override
+	overriding := ""
+	if table == liveProjection.transactions {
+		overriding = "OVERRIDING SYSTEM VALUE"
+	}
+
+	q := `INSERT INTO ` + table + ` (` + columns + `) ` + overriding + `
+        SELECT ` + columns + ` FROM history_` + table + ` WHERE id::text = ANY($1)
+        ON CONFLICT (id) DO UPDATE SET ` + strings.Join(set, ", ") + `;`
+
+	if _, err := tx.ExecContext(ctx, q, pq.Array(ids)); err != nil {
+		return err
+	}
+
+	if table != liveProjection.transactions {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, `
+	SELECT setval(pg_get_serial_sequence('transactions', 'id'),
+                  GREATEST(MAX(id), nextval(pg_get_serial_sequence('transactions', 'id'))))
+        FROM transactions;
+    `)
+	return err
+}
+
+func NewHistoryRepository(repository postgres.Repository) History {
+	return historyImpl{repository: repository}
+}
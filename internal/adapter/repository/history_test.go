@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
+)
+
+func Test_HistoryEncoding(t *testing.T) {
+	createdAt := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	closedAt := createdAt.Add(time.Hour)
+	reviewedAt := createdAt.Add(2 * time.Hour)
+
+	account := domain.Account{
+		Id:             "account-a",
+		TenantID:       "program-a",
+		DocumentNumber: "12345678900",
+		Status:         domain.AccountClosed,
+		AccountProfile: domain.AccountProfile{
+			HolderName: "Maria Silva",
+			Address:    &domain.Address{Line1: "Rua A, 1", City: "Recife", Country: "BR"},
+			Metadata:   map[string]string{"segment": "gold"},
+		},
+		Version:   2,
+		CreatedAt: createdAt,
+		UpdatedAt: closedAt,
+		ClosedAt:  &closedAt,
+	}
+
+	transaction := domain.Transaction{
+		Id:            7,
+		AccountID:     "account-a",
+		OperationType: operation.CASH_PURCHASES,
+		Amount:        42.5,
+		EventDate:     createdAt,
+		Decision:      domain.DecisionReview,
+		Reasons:       []string{"first-transaction"},
+		Status:        domain.StatusApproved,
+		ReviewedBy:    "operator",
+		ReviewReason:  "known customer",
+		ReviewedAt:    &reviewedAt,
+		CardID:        "card-a",
+		Merchant:      domain.Merchant{Name: "Cantina", MCC: "5812", City: "Recife", Country: "BR"},
+	}
+
+	events := []domain.HistoryEvent{
+		domain.NewAccountHistory(domain.HistoryAccountStatusChanged, account),
+		domain.NewTransactionHistory(domain.HistoryTransactionStatusChanged, transaction),
+		domain.NewTransactionHistory(domain.HistoryTransactionReversed, transaction),
+	}
+
+	for _, event := range events {
+		t.Run(string(event.Type), func(t *testing.T) {
+			data, err := encodeHistory(event)
+			assert.NoError(t, err)
+
+			decoded := domain.HistoryEvent{Type: event.Type}
+			assert.NoError(t, decodeHistory(&decoded, data))
+			assert.Equal(t, event.Account, decoded.Account)
+			assert.Equal(t, event.Transaction, decoded.Transaction)
+		})
+	}
+}
+
+// Test_HistoryDecodingMigrated decodes events as the migration creating the
+// history records the existing rows.
+func Test_HistoryDecodingMigrated(t *testing.T) {
+	event := domain.HistoryEvent{Type: domain.HistoryAccountOpened}
+	err := decodeHistory(&event, []byte(`{"id": "account-a", "tenant_id": "default", "document_number": "12345678900",
+		"status": "active", "holder_name": "", "email": "", "phone": "", "address": null, "metadata": {},
+		"version": 1, "created_at": "2024-01-10T09:00:00.123456Z", "updated_at": "2024-01-10T09:00:00.123456Z",
+		"closed_at": null, "erased_at": null, "deleted_at": null}`))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 10, 9, 0, 0, 123456000, time.UTC), event.Account.CreatedAt)
+	assert.Nil(t, event.Account.ClosedAt)
+
+	event = domain.HistoryEvent{Type: domain.HistoryTransactionPosted}
+	err = decodeHistory(&event, []byte(`{"id": 7, "account_id": "account-a", "operation_type": 4, "amount": 10.1,
+		"event_date": "2024-01-10T09:00:00.000000Z", "decision": "allow", "reasons": [], "status": "approved",
+		"reviewed_by": "", "review_reason": "", "reviewed_at": null, "card_id": "",
+		"merchant": {"name": "", "mcc": "", "city": "", "country": ""}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, operation.PAYMENT, event.Transaction.OperationType)
+	assert.Equal(t, []string{}, event.Transaction.Reasons)
+
+	event = domain.HistoryEvent{Type: "account.unknown"}
+	assert.Error(t, decodeHistory(&event, []byte(`{}`)))
+}
+
+// Test_HistoryReplayTransactionStatus replays the status changes of a
+// transaction, each one only applying to the status it moves the transaction
+// from.
+func Test_HistoryReplayTransactionStatus(t *testing.T) {
+	reviewedAt := time.Date(2024, 1, 10, 11, 0, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description   string
+		event         domain.HistoryEvent
+		affected      int64
+		expectedFrom  domain.Status
+		expectedError error
+	}{
+		{
+			description:  "review approved",
+			event:        domain.NewTransactionHistory(domain.HistoryTransactionStatusChanged, domain.Transaction{Id: 7, Status: domain.StatusApproved, ReviewedAt: &reviewedAt}),
+			affected:     1,
+			expectedFrom: domain.StatusPendingReview,
+		},
+		{
+			description:  "transaction reversed",
+			event:        domain.NewTransactionHistory(domain.HistoryTransactionReversed, domain.Transaction{Id: 7, Status: domain.StatusReversed, ReviewedAt: &reviewedAt}),
+			affected:     1,
+			expectedFrom: domain.StatusApproved,
+		},
+		{
+			description:   "reversal of a transaction not approved",
+			event:         domain.NewTransactionHistory(domain.HistoryTransactionReversed, domain.Transaction{Id: 7, Status: domain.StatusReversed, ReviewedAt: &reviewedAt}),
+			expectedFrom:  domain.StatusApproved,
+			expectedError: exceptions.EntityNotFoundError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			db, d := newRecordingDB(t)
+			d.affected = scenario.affected
+
+			ctx := auth.WithAnyTenant(context.Background())
+			tx, err := db.BeginTx(ctx)
+			assert.NoError(t, err)
+			defer tx.Rollback()
+
+			err = replayedProjection.apply(ctx, tx, &scenario.event)
+			assert.ErrorIs(t, err, scenario.expectedError)
+
+			assert.Len(t, d.statements, 1)
+			assert.Contains(t, d.statements[0].query, "UPDATE history_transactions SET status = $2")
+			assert.Equal(t, []driver.Value{int64(7), string(scenario.event.Transaction.Status)}, d.statements[0].args[:2])
+			assert.Equal(t, string(scenario.expectedFrom), d.statements[0].args[5])
+		})
+	}
+}
+
+// Test_TransactionReverse projects a reversal to the transactions table and
+// appends it to the history, which the replay then reads back.
+func Test_TransactionReverse(t *testing.T) {
+	db, d := newRecordingDB(t)
+	d.affected = 1
+
+	reviewedAt := time.Date(2024, 1, 10, 11, 0, 0, 0, time.UTC)
+	reversed := domain.Transaction{Id: 7, AccountID: "account-a", Status: domain.StatusReversed, ReviewedBy: "operator-1",
+		ReviewReason: "disputed by the holder", ReviewedAt: &reviewedAt}
+
+	err := NewTransactionRepository(db).Reverse(tenantContext("program-a"), reversed)
+	assert.NoError(t, err)
+
+	if assert.Len(t, d.statements, 2) {
+		assert.Contains(t, d.statements[0].query, "UPDATE transactions SET status = $2")
+		assert.Equal(t, string(domain.StatusApproved), d.statements[0].args[5])
+
+		assert.Contains(t, d.statements[1].query, "INSERT INTO history_events")
+		assert.Equal(t, `{"transaction.reversed"}`, d.statements[1].args[1])
+
+		data := d.statements[1].args[3].(string)
+		var encoded []string
+		assert.NoError(t, pq.Array(&encoded).Scan(data))
+
+		event := domain.HistoryEvent{Type: domain.HistoryTransactionReversed}
+		assert.NoError(t, decodeHistory(&event, []byte(encoded[0])))
+		assert.Equal(t, &reversed, event.Transaction)
+	}
+}
+
+func Test_HistoryAccountChange(t *testing.T) {
+	now := time.Now()
+	active := domain.Account{Id: "account-a", Status: domain.AccountActive}
+	closed := domain.Account{Id: "account-a", Status: domain.AccountClosed, ClosedAt: &now}
+	erased := closed
+	erased.ErasedAt = &now
+	renamed := active
+	renamed.HolderName = "Maria Silva"
+
+	assert.Equal(t, domain.HistoryAccountUpdated, accountChange(active, renamed))
+	assert.Equal(t, domain.HistoryAccountStatusChanged, accountChange(active, closed))
+	assert.Equal(t, domain.HistoryAccountStatusChanged, accountChange(closed, erased))
+}
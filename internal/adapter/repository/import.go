@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/postgres"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/domain"
+)
+
+type Import interface {
+	PushJob(ctx context.Context, entity domain.ImportJob) error
+	GetJob(ctx context.Context, id string) (domain.ImportJob, error)
+	UpdateJob(ctx context.Context, entity domain.ImportJob) error
+	FailStale(ctx context.Context, before time.Time, reason string) (int, error)
+
+	PushErrors(ctx context.Context, rowErrors []domain.ImportError) error
+	WalkErrors(ctx context.Context, jobID string, fn func(domain.ImportError) error) error
+}
+
+type (
+	importImpl struct {
+		repository postgres.Repository
+	}
+
+	importJobResult struct {
+		domain.ImportJob
+		FinishedAt sql.NullTime
+	}
+)
+
+// Jobs and their errors are read and updated scoped to the tenant of the
+// caller, as accounts are.
+const importJobColumns = `id, client_id, tenant_id, format, status, processed, imported, failed, error, created_at,
+        updated_at, finished_at`
+
+func (r *importJobResult) dest() []interface{} {
+	return []interface{}{
+		&r.Id, &r.ClientID, &r.TenantID, &r.Format, &r.Status, &r.Processed, &r.Imported, &r.Failed, &r.Error, &r.CreatedAt, &r.UpdatedAt,
+		&r.FinishedAt,
+	}
+}
+
+func (r *importJobResult) entity() domain.ImportJob {
+	entity := r.ImportJob
+	if r.FinishedAt.Valid {
+		entity.FinishedAt = &r.FinishedAt.Time
+	}
+
+	return entity
+}
+
+func (i importImpl) PushJob(ctx context.Context, entity domain.ImportJob) error {
+	ctx, span := telemetry.Span(ctx, "repository:import:PushJob", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `
+	INSERT INTO import_jobs (` + importJobColumns + `)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
+    `
+
+	err := i.repository.Push(ctx, q,
+		entity.Id, entity.ClientID, entity.TenantID, entity.Format, entity.Status, entity.Processed, entity.Imported, entity.Failed,
+		entity.Error, entity.CreatedAt, entity.UpdatedAt, entity.FinishedAt,
+	)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error pushing import job to postgres", logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (i importImpl) GetJob(ctx context.Context, id string) (domain.ImportJob, error) {
+	ctx, span := telemetry.Span(ctx, "repository:import:GetJob", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + importJobColumns + ` FROM import_jobs WHERE id = $1 AND ($2 = '*' OR tenant_id = $2);`
+
+	var r importJobResult
+	err := i.repository.QueryRow(ctx, q, []interface{}{id, auth.Tenant(ctx)}, r.dest()...)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error getting import job from postgres", logger.Err(err))
+		return domain.ImportJob{}, err
+	}
+
+	return r.entity(), nil
+}
+
+func (i importImpl) UpdateJob(ctx context.Context, entity domain.ImportJob) error {
+	ctx, span := telemetry.Span(ctx, "repository:import:UpdateJob", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `
+	UPDATE import_jobs
+        SET status = $2, processed = $3, imported = $4, failed = $5, error = $6, updated_at = $7, finished_at = $8
+        WHERE id = $1 AND ($9 = '*' OR tenant_id = $9);
+    `
+
+	err := i.repository.Push(ctx, q,
+		entity.Id, entity.Status, entity.Processed, entity.Imported, entity.Failed, entity.Error, entity.UpdatedAt,
+		entity.FinishedAt, auth.Tenant(ctx),
+	)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error updating import job in postgres", logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// FailStale fails the unfinished jobs not updated since before, left behind
+// by a replica that stopped while importing them.
+func (i importImpl) FailStale(ctx context.Context, before time.Time, reason string) (int, error) {
+	ctx, span := telemetry.Span(ctx, "repository:import:FailStale", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `
+	UPDATE import_jobs
+        SET status = 'failed', error = $2, updated_at = NOW(), finished_at = NOW()
+        WHERE status IN ('pending', 'running') AND updated_at < $1 AND ($3 = '*' OR tenant_id = $3)
+        RETURNING id;
+    `
+
+	failed := 0
+	err := i.repository.Query(ctx, q, []interface{}{before, reason, auth.Tenant(ctx)}, func(*sql.Rows) error {
+		failed++
+		return nil
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error failing stale import jobs in postgres", logger.Err(err))
+		return 0, err
+	}
+
+	return failed, nil
+}
+
+// PushErrors stores the rejected rows of a batch with COPY.
+func (i importImpl) PushErrors(ctx context.Context, rowErrors []domain.ImportError) error {
+	ctx, span := telemetry.Span(ctx, "repository:import:PushErrors", trace.SpanKindInternal)
+	defer span.End()
+
+	if len(rowErrors) == 0 {
+		return nil
+	}
+
+	err := i.pushErrors(ctx, rowErrors)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error copying import errors to postgres", logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (i importImpl) pushErrors(ctx context.Context, rowErrors []domain.ImportError) error {
+	tx, err := i.repository.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("import_errors", "job_id", "row", "field", "message", "raw"))
+	if err != nil {
+		return err
+	}
+
+	for _, rowError := range rowErrors {
+		if _, err := stmt.ExecContext(ctx, rowError.JobID, rowError.Row, rowError.Field, rowError.Message, rowError.Raw); err != nil {
+			_ = stmt.Close()
+			return err
+		}
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		_ = stmt.Close()
+		return err
+	}
+
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// WalkErrors streams the rejected rows of jobID to fn in row order.
+func (i importImpl) WalkErrors(ctx context.Context, jobID string, fn func(domain.ImportError) error) error {
+	ctx, span := telemetry.Span(ctx, "repository:import:WalkErrors", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT job_id, row, field, message, raw FROM import_errors
+        WHERE job_id IN (SELECT id FROM import_jobs WHERE id = $1 AND ($2 = '*' OR tenant_id = $2))
+        ORDER BY row;`
+
+	err := i.repository.Query(ctx, q, []interface{}{jobID, auth.Tenant(ctx)}, func(rows *sql.Rows) error {
+		var rowError domain.ImportError
+		if err := rows.Scan(&rowError.JobID, &rowError.Row, &rowError.Field, &rowError.Message, &rowError.Raw); err != nil {
+			return err
+		}
+
+		return fn(rowError)
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error walking import errors from postgres", logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func NewImportRepository(repository postgres.Repository) Import {
+	return importImpl{repository: repository}
+}
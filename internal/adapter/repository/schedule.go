@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/postgres"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/domain"
+)
+
+type Schedule interface {
+	Push(ctx context.Context, entity domain.ScheduledPayment) error
+	Get(ctx context.Context, id string) (domain.ScheduledPayment, error)
+	ListByAccount(ctx context.Context, accountID string) ([]domain.ScheduledPayment, error)
+	Due(ctx context.Context, now time.Time, limit int) ([]domain.ScheduledPayment, error)
+	Claim(ctx context.Context, id string, now time.Time) (domain.ScheduledPayment, error)
+	Update(ctx context.Context, entity domain.ScheduledPayment, status domain.ScheduleStatus) error
+}
+
+type (
+	scheduleImpl struct {
+		repository postgres.Repository
+	}
+
+	scheduleResult struct {
+		domain.ScheduledPayment
+		LastRunAt sql.NullTime
+	}
+)
+
+// Every query is scoped to the tenant of its context with a tenant_id
+// filter, the scheduler acts for auth.AnyTenant and sees every tenant.
+const scheduleColumns = `id, account_id, tenant_id, client_id, frequency, day_of_month, amount_type, amount, status,
+        next_run_at, attempts, last_run_at, last_transaction_id, last_error, created_at, updated_at`
+
+func (r *scheduleResult) dest() []interface{} {
+	return []interface{}{
+		&r.Id, &r.AccountID, &r.TenantID, &r.ClientID, &r.Frequency, &r.DayOfMonth, &r.AmountType, &r.Amount, &r.Status,
+		&r.NextRunAt, &r.Attempts, &r.LastRunAt, &r.LastTransactionID, &r.LastError, &r.CreatedAt, &r.UpdatedAt,
+	}
+}
+
+func (r *scheduleResult) entity() domain.ScheduledPayment {
+	entity := r.ScheduledPayment
+	if r.LastRunAt.Valid {
+		entity.LastRunAt = &r.LastRunAt.Time
+	}
+
+	return entity
+}
+
+func (s scheduleImpl) Push(ctx context.Context, entity domain.ScheduledPayment) error {
+	ctx, span := telemetry.Span(ctx, "repository:schedule:Push", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `
+	INSERT INTO scheduled_payments (` + scheduleColumns + `)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16);
+    `
+
+	err := s.repository.Push(ctx, q,
+		entity.Id, entity.AccountID, entity.TenantID, entity.ClientID, entity.Frequency, entity.DayOfMonth, entity.AmountType,
+		entity.Amount, entity.Status, entity.NextRunAt, entity.Attempts, entity.LastRunAt, entity.LastTransactionID,
+		entity.LastError, entity.CreatedAt, entity.UpdatedAt,
+	)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error pushing scheduled payment to postgres", logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s scheduleImpl) Get(ctx context.Context, id string) (domain.ScheduledPayment, error) {
+	ctx, span := telemetry.Span(ctx, "repository:schedule:Get", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + scheduleColumns + ` FROM scheduled_payments WHERE id = $1 AND ($2 = '*' OR tenant_id = $2);`
+
+	var r scheduleResult
+	err := s.repository.QueryRow(ctx, q, []interface{}{id, auth.Tenant(ctx)}, r.dest()...)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error getting scheduled payment from postgres", logger.Err(err))
+		return domain.ScheduledPayment{}, err
+	}
+
+	return r.entity(), nil
+}
+
+// ListByAccount returns the scheduled payments of accountID, oldest first.
+func (s scheduleImpl) ListByAccount(ctx context.Context, accountID string) ([]domain.ScheduledPayment, error) {
+	ctx, span := telemetry.Span(ctx, "repository:schedule:ListByAccount", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + scheduleColumns + ` FROM scheduled_payments
+        WHERE account_id = $1 AND ($2 = '*' OR tenant_id = $2) ORDER BY created_at, id;`
+
+	return s.query(ctx, span, q, accountID, auth.Tenant(ctx))
+}
+
+// Due returns up to limit active payments to run at now, the most late first.
+func (s scheduleImpl) Due(ctx context.Context, now time.Time, limit int) ([]domain.ScheduledPayment, error) {
+	ctx, span := telemetry.Span(ctx, "repository:schedule:Due", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + scheduleColumns + ` FROM scheduled_payments
+        WHERE status = 'active' AND next_run_at <= $1 AND ($3 = '*' OR tenant_id = $3)
+        ORDER BY next_run_at, id LIMIT $2;`
+
+	return s.query(ctx, span, q, now, limit, auth.Tenant(ctx))
+}
+
+// Claim locks payment id until the transaction of ctx ends, provided it is
+// still active and due at now. A payment cancelled or run since it was listed
+// is not found, nor is one locked by another run, which is skipped rather
+// than waited for.
+func (s scheduleImpl) Claim(ctx context.Context, id string, now time.Time) (domain.ScheduledPayment, error) {
+	ctx, span := telemetry.Span(ctx, "repository:schedule:Claim", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + scheduleColumns + ` FROM scheduled_payments
+        WHERE id = $1 AND status = 'active' AND next_run_at <= $2 AND ($3 = '*' OR tenant_id = $3)
+        FOR UPDATE SKIP LOCKED;`
+
+	var r scheduleResult
+	err := s.repository.QueryRow(ctx, q, []interface{}{id, now, auth.Tenant(ctx)}, r.dest()...)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		if !errors.Is(err, exceptions.EntityNotFoundError) {
+			logger.Error(ctx, logger.ServerError, "error claiming scheduled payment in postgres", logger.Str("scheduled_payment_id", id), logger.Err(err))
+		}
+		return domain.ScheduledPayment{}, err
+	}
+
+	return r.entity(), nil
+}
+
+func (s scheduleImpl) query(ctx context.Context, span trace.Span, q string, args ...interface{}) ([]domain.ScheduledPayment, error) {
+	payments := make([]domain.ScheduledPayment, 0)
+	err := s.repository.Query(ctx, q, args, func(rows *sql.Rows) error {
+		var r scheduleResult
+		if err := rows.Scan(r.dest()...); err != nil {
+			return err
+		}
+
+		payments = append(payments, r.entity())
+		return nil
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error listing scheduled payments from postgres", logger.Err(err))
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+// Update stores entity if the persisted payment is still in status, so a
+// cancel and a run of the same payment never overwrite each other: the one
+// coming second gets InvalidStateError.
+func (s scheduleImpl) Update(ctx context.Context, entity domain.ScheduledPayment, status domain.ScheduleStatus) error {
+	ctx, span := telemetry.Span(ctx, "repository:schedule:Update", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `
+	UPDATE scheduled_payments SET status = $2, next_run_at = $3, attempts = $4, last_run_at = $5,
+                                  last_transaction_id = $6, last_error = $7, updated_at = $8
+        WHERE id = $1 AND status = $9 AND ($10 = '*' OR tenant_id = $10);
+    `
+
+	err := s.repository.Push(ctx, q,
+		entity.Id, entity.Status, entity.NextRunAt, entity.Attempts, entity.LastRunAt, entity.LastTransactionID,
+		entity.LastError, entity.UpdatedAt, status, auth.Tenant(ctx),
+	)
+	if errors.Is(err, exceptions.EntityNotFoundError) {
+		err = exceptions.InvalidStateError.WithDetail(fmt.Sprintf("scheduled payment %s is no longer %s", entity.Id, status))
+	}
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error updating scheduled payment in postgres", logger.Str("scheduled_payment_id", entity.Id), logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func NewScheduleRepository(repository postgres.Repository) Schedule {
+	return scheduleImpl{repository: repository}
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/domain"
+)
+
+// Test_ScheduleUpdateAfterFailedPush updates a payment in the transaction of
+// its run once posting its transaction failed, as the scheduler does.
+func Test_ScheduleUpdateAfterFailedPush(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		savepoint     bool
+		expectedError error
+	}{
+		{
+			description: "push under a savepoint",
+			savepoint:   true,
+		},
+		{
+			description:   "push without a savepoint",
+			expectedError: exceptions.PersistenceError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			db, d := newRecordingDB(t)
+			d.fail, d.affected = "INSERT INTO transactions", 1
+
+			ctx, tx, err := db.Transaction(auth.WithAnyTenant(context.Background()))
+			assert.NoError(t, err)
+			defer tx.Rollback()
+
+			end := func(bool) (bool, error) { return false, nil }
+			if scenario.savepoint {
+				end, err = NewAuditRepository(db).Savepoint(ctx)
+				assert.NoError(t, err)
+			}
+
+			_, err = NewTransactionRepository(db).Push(ctx, domain.Transaction{AccountID: "account-a", Amount: 50})
+			assert.ErrorIs(t, err, exceptions.PersistenceError)
+
+			rolledBack, err := end(false)
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.savepoint, rolledBack, "a failed push is rolled back to the savepoint")
+
+			err = NewScheduleRepository(db).Update(ctx, domain.ScheduledPayment{Id: "payment-a", Status: domain.ScheduleActive, NextRunAt: time.Now()}, domain.ScheduleActive)
+			assert.ErrorIs(t, err, scenario.expectedError)
+		})
+	}
+}
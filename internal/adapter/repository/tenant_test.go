@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/postgres"
+	"github.com/payment-api/internal/domain"
+)
+
+// tenantPredicate is the filter every scoped statement carries, alone or in
+// the subquery of its parent, both parameters being the last argument, the
+// tenant of the caller or auth.AnyTenant.
+var tenantPredicate = regexp.MustCompile(`(?:\(|WHERE )\$(\d+) = '\*' OR (?:\w+\.)?tenant_id = \$(\d+)\)`)
+
+func tenantContext(tenant string) context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{ClientID: "any-client", Tenant: tenant})
+}
+
+// Test_TenantScopedStatements runs the reads and updates of every repository
+// and checks each statement sent filters on the tenant of its caller.
+func Test_TenantScopedStatements(t *testing.T) {
+	at := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+
+	calls := map[string]func(ctx context.Context, db postgres.Repository){
+		"account get": func(ctx context.Context, db postgres.Repository) {
+			_, _ = NewAccountRepository(db).Get(ctx, "account-a")
+		},
+		"account search": func(ctx context.Context, db postgres.Repository) {
+			_, _ = NewAccountRepository(db).Search(ctx, domain.AccountFilter{Limit: 50})
+		},
+		"account lookup": func(ctx context.Context, db postgres.Repository) {
+			_, _ = NewAccountRepository(db).Lookup(ctx, []string{"account-a"})
+		},
+		"account update": func(ctx context.Context, db postgres.Repository) {
+			_ = NewAccountRepository(db).Update(ctx, domain.Account{Id: "account-a", Version: 2}, 1)
+		},
+		"transaction get": func(ctx context.Context, db postgres.Repository) { _, _ = NewTransactionRepository(db).Get(ctx, 7) },
+		"transaction count": func(ctx context.Context, db postgres.Repository) {
+			_, _ = NewTransactionRepository(db).Count(ctx, "account-a", at)
+		},
+		"transaction list by status": func(ctx context.Context, db postgres.Repository) {
+			_, _ = NewTransactionRepository(db).ListByStatus(ctx, domain.StatusApproved, 50, 0)
+		},
+		"transaction walk": func(ctx context.Context, db postgres.Repository) {
+			_ = NewTransactionRepository(db).Walk(ctx, "account-a", at, at.AddDate(0, 1, 0), func(domain.Transaction) error { return nil })
+		},
+		"transaction balance": func(ctx context.Context, db postgres.Repository) {
+			_, _ = NewTransactionRepository(db).Balance(ctx, "account-a", at)
+		},
+		"transaction resolve": func(ctx context.Context, db postgres.Repository) {
+			_ = NewTransactionRepository(db).Resolve(ctx, domain.Transaction{Id: 7, Status: domain.StatusApproved})
+		},
+		"transaction reverse": func(ctx context.Context, db postgres.Repository) {
+			_ = NewTransactionRepository(db).Reverse(ctx, domain.Transaction{Id: 7, Status: domain.StatusReversed})
+		},
+		"card get": func(ctx context.Context, db postgres.Repository) { _, _ = NewCardRepository(db).Get(ctx, "card-a") },
+		"card get by token": func(ctx context.Context, db postgres.Repository) {
+			_, _ = NewCardRepository(db).GetByToken(ctx, "tok_card-a")
+		},
+		"card list": func(ctx context.Context, db postgres.Repository) {
+			_, _ = NewCardRepository(db).ListByAccount(ctx, "account-a")
+		},
+		"card update": func(ctx context.Context, db postgres.Repository) {
+			_ = NewCardRepository(db).Update(ctx, domain.Card{Id: "card-a", Status: domain.CardBlocked})
+		},
+		"audit list": func(ctx context.Context, db postgres.Repository) {
+			_, _ = NewAuditRepository(db).List(ctx, domain.AuditFilter{Limit: 50})
+		},
+		"import get": func(ctx context.Context, db postgres.Repository) { _, _ = NewImportRepository(db).GetJob(ctx, "job-a") },
+		"import update": func(ctx context.Context, db postgres.Repository) {
+			_ = NewImportRepository(db).UpdateJob(ctx, domain.ImportJob{Id: "job-a"})
+		},
+		"import errors": func(ctx context.Context, db postgres.Repository) {
+			_ = NewImportRepository(db).WalkErrors(ctx, "job-a", func(domain.ImportError) error { return nil })
+		},
+		"subscription get": func(ctx context.Context, db postgres.Repository) {
+			_, _ = NewWebhookRepository(db).GetSubscription(ctx, "subscription-a")
+		},
+		"subscription list": func(ctx context.Context, db postgres.Repository) {
+			_, _ = NewWebhookRepository(db).ListSubscriptions(ctx, "")
+		},
+		"subscription update": func(ctx context.Context, db postgres.Repository) {
+			_ = NewWebhookRepository(db).UpdateSubscription(ctx, domain.Subscription{Id: "subscription-a"})
+		},
+		"subscription delete": func(ctx context.Context, db postgres.Repository) {
+			_ = NewWebhookRepository(db).DeleteSubscription(ctx, "subscription-a")
+		},
+		"delivery get": func(ctx context.Context, db postgres.Repository) {
+			_, _ = NewWebhookRepository(db).GetDelivery(ctx, "delivery-a")
+		},
+		"delivery list": func(ctx context.Context, db postgres.Repository) {
+			_, _ = NewWebhookRepository(db).ListDeliveries(ctx, "subscription-a", 50, 0)
+		},
+		"event list": func(ctx context.Context, db postgres.Repository) {
+			_, _ = NewEventRepository(db).ListAfter(ctx, "account-a", 0, 50)
+		},
+		"api key get": func(ctx context.Context, db postgres.Repository) { _, _ = NewAPIKeyRepository(db).Get(ctx, "key-a") },
+		"api key revoke": func(ctx context.Context, db postgres.Repository) {
+			_ = NewAPIKeyRepository(db).Revoke(ctx, "key-a", at)
+		},
+		"schedule get": func(ctx context.Context, db postgres.Repository) {
+			_, _ = NewScheduleRepository(db).Get(ctx, "payment-a")
+		},
+		"schedule claim": func(ctx context.Context, db postgres.Repository) {
+			_, _ = NewScheduleRepository(db).Claim(ctx, "payment-a", at)
+		},
+	}
+
+	contexts := map[string]context.Context{
+		"tenant":                   tenantContext("program-a"),
+		"internal caller":          auth.WithAnyTenant(context.Background()),
+		"caller without tenant":    context.Background(),
+		"principal without tenant": auth.WithAnyTenant(tenantContext("")),
+	}
+
+	for name, call := range calls {
+		for caller, ctx := range contexts {
+			t.Run(name+"/"+caller, func(t *testing.T) {
+				db, d := newRecordingDB(t)
+
+				call(ctx, db)
+
+				assert.NotEmpty(t, d.statements)
+				for _, statement := range d.statements {
+					match := tenantPredicate.FindStringSubmatch(statement.query)
+					if !assert.NotNil(t, match, fmt.Sprintf("tenant filter of %s", statement.query)) {
+						continue
+					}
+
+					last := fmt.Sprint(len(statement.args))
+					assert.Equal(t, []string{last, last}, match[1:], "the tenant is the last argument")
+					assert.Equal(t, auth.Tenant(ctx), statement.args[len(statement.args)-1])
+				}
+			})
+		}
+	}
+}
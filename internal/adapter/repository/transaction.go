@@ -2,44 +2,454 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
+	"github.com/lib/pq"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/payment-api/infrastructure/auth"
 	"github.com/payment-api/infrastructure/logger"
 	"github.com/payment-api/infrastructure/postgres"
 	"github.com/payment-api/infrastructure/telemetry"
 	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
 )
 
 type Transaction interface {
-	Push(ctx context.Context, entity domain.Transaction) error
+	Push(ctx context.Context, entity domain.Transaction) (domain.Transaction, error)
+	Get(ctx context.Context, id int) (domain.Transaction, error)
+	Count(ctx context.Context, accountID string, since time.Time) (int, error)
+	ListByStatus(ctx context.Context, status domain.Status, limit, offset int) ([]domain.Transaction, error)
+	Resolve(ctx context.Context, entity domain.Transaction) error
+	Reverse(ctx context.Context, entity domain.Transaction) error
+	ExpireReviews(ctx context.Context, before time.Time, reviewer, reason string) ([]domain.Transaction, error)
+	CopyIn(ctx context.Context, entities []domain.Transaction) ([]domain.Transaction, error)
+	Walk(ctx context.Context, accountID string, from, to time.Time, fn func(domain.Transaction) error) error
+	Balance(ctx context.Context, accountID string, before time.Time) (float64, error)
+	SumByMerchant(ctx context.Context, accountID string, from, to time.Time) ([]domain.SpendingItem, error)
+	Snapshot(ctx context.Context) (context.Context, Tx, error)
 }
 
-type transactionImpl struct {
-	repository postgres.Repository
+type (
+	transactionImpl struct {
+		repository postgres.Repository
+	}
+
+	transactionResult struct {
+		domain.Transaction
+		ReviewedAt sql.NullTime
+	}
+)
+
+// Every query is scoped to the tenant of its context with a tenant_id filter,
+// internal callers given auth.WithAnyTenant see every tenant, callers without
+// a tenant see none.
+// Inserts take the tenant of the account from a trigger.
+const transactionColumns = `id, account_id, operation_type_id, amount, event_date, decision, decision_reasons,
+        status, reviewed_by, review_reason, reviewed_at, card_id, merchant_name, merchant_mcc, merchant_city, merchant_country`
+
+func (r *transactionResult) dest() []interface{} {
+	return []interface{}{
+		&r.Id, &r.AccountID, &r.OperationType, &r.Amount, &r.EventDate, &r.Decision, pq.Array(&r.Reasons),
+		&r.Status, &r.ReviewedBy, &r.ReviewReason, &r.ReviewedAt, &r.CardID,
+		&r.Merchant.Name, &r.Merchant.MCC, &r.Merchant.City, &r.Merchant.Country,
+	}
 }
 
-func (t transactionImpl) Push(ctx context.Context, entity domain.Transaction) error {
+func (r *transactionResult) entity() domain.Transaction {
+	entity := r.Transaction
+	if r.ReviewedAt.Valid {
+		entity.ReviewedAt = &r.ReviewedAt.Time
+	}
+
+	return entity
+}
+
+// Push posts entity in the history of its account and in the transactions
+// table, and returns it with its id.
+func (t transactionImpl) Push(ctx context.Context, entity domain.Transaction) (domain.Transaction, error) {
 	ctx, span := telemetry.Span(ctx, "repository:transaction:Push", trace.SpanKindInternal)
 	defer span.End()
 
+	entity.EventDate = time.Now()
+
+	err := transact(ctx, t.repository, func(tx *postgres.Tx) error {
+		event := domain.NewTransactionHistory(domain.HistoryTransactionPosted, entity)
+		if err := liveProjection.apply(ctx, tx, &event); err != nil {
+			return err
+		}
+
+		entity.Id = event.Transaction.Id
+		return appendHistory(ctx, tx, event)
+	})
+	if err != nil {
+		err = postgres.ToDomainError(err)
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error pushing transaction to postgres", logger.Err(err))
+		return domain.Transaction{}, err
+	}
+
+	return entity, nil
+}
+
+func (t transactionImpl) Get(ctx context.Context, id int) (domain.Transaction, error) {
+	ctx, span := telemetry.Span(ctx, "repository:transaction:Get", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + transactionColumns + ` FROM transactions WHERE id = $1 AND ($2 = '*' OR tenant_id = $2);`
+
+	var r transactionResult
+	err := t.repository.QueryRow(ctx, q, []interface{}{id, auth.Tenant(ctx)}, r.dest()...)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error getting transaction from postgres", logger.Err(err))
+		return domain.Transaction{}, err
+	}
+
+	return r.entity(), nil
+}
+
+// Count returns the approved transactions of an account since the given date,
+// transactions waiting for review or declined do not count toward limits.
+func (t transactionImpl) Count(ctx context.Context, accountID string, since time.Time) (int, error) {
+	ctx, span := telemetry.Span(ctx, "repository:transaction:Count", trace.SpanKindInternal)
+	defer span.End()
+
 	q := `
-	INSERT INTO transactions (account_id, operation_type_id, amount, event_date)
-        VALUES ($1, $2, $3, $4)
-        RETURNING id;
+	SELECT COUNT(*) FROM transactions
+        WHERE account_id = $1 AND event_date >= $2 AND status = 'approved' AND ($3 = '*' OR tenant_id = $3);
     `
 
-	err := t.repository.Push(q, entity.AccountID, entity.OperationType, entity.Amount, time.Now())
+	var count int
+	err := t.repository.QueryRow(ctx, q, []interface{}{accountID, since, auth.Tenant(ctx)}, &count)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error counting transactions in postgres", logger.Err(err))
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// ListByStatus returns the transactions in status, oldest first.
+func (t transactionImpl) ListByStatus(ctx context.Context, status domain.Status, limit, offset int) ([]domain.Transaction, error) {
+	ctx, span := telemetry.Span(ctx, "repository:transaction:ListByStatus", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + transactionColumns + ` FROM transactions
+        WHERE status = $1 AND ($4 = '*' OR tenant_id = $4) ORDER BY event_date, id LIMIT $2 OFFSET $3;`
+
+	transactions := make([]domain.Transaction, 0)
+	err := t.repository.Query(ctx, q, []interface{}{status, limit, offset, auth.Tenant(ctx)}, func(rows *sql.Rows) error {
+		var r transactionResult
+		if err := rows.Scan(r.dest()...); err != nil {
+			return err
+		}
+
+		transactions = append(transactions, r.entity())
+		return nil
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error listing transactions from postgres", logger.Err(err))
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// Resolve records the review of a pending transaction, entity being the
+// reviewed transaction, it answers EntityNotFoundError when the transaction is
+// no longer pending.
+func (t transactionImpl) Resolve(ctx context.Context, entity domain.Transaction) error {
+	ctx, span := telemetry.Span(ctx, "repository:transaction:Resolve", trace.SpanKindInternal)
+	defer span.End()
+
+	err := t.project(ctx, domain.NewTransactionHistory(domain.HistoryTransactionStatusChanged, entity))
+	if err != nil {
+		err = postgres.ToDomainError(err)
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error resolving transaction in postgres", logger.Int("transaction_id", entity.Id), logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// Reverse records the reversal of an approved transaction, entity being the
+// reversed transaction, it answers EntityNotFoundError when the transaction is
+// no longer approved.
+func (t transactionImpl) Reverse(ctx context.Context, entity domain.Transaction) error {
+	ctx, span := telemetry.Span(ctx, "repository:transaction:Reverse", trace.SpanKindInternal)
+	defer span.End()
+
+	err := t.project(ctx, domain.NewTransactionHistory(domain.HistoryTransactionReversed, entity))
+	if err != nil {
+		err = postgres.ToDomainError(err)
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error reversing transaction in postgres", logger.Int("transaction_id", entity.Id), logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// project writes event to the transactions table and appends it to the
+// history of its account, in one database transaction.
+func (t transactionImpl) project(ctx context.Context, event domain.HistoryEvent) error {
+	return transact(ctx, t.repository, func(tx *postgres.Tx) error {
+		if err := liveProjection.apply(ctx, tx, &event); err != nil {
+			return err
+		}
+
+		return appendHistory(ctx, tx, event)
+	})
+}
+
+// ExpireReviews declines the transactions pending review posted before the
+// given date and returns them as declined, every decline joins the history
+// of its account.
+func (t transactionImpl) ExpireReviews(ctx context.Context, before time.Time, reviewer, reason string) ([]domain.Transaction, error) {
+	ctx, span := telemetry.Span(ctx, "repository:transaction:ExpireReviews", trace.SpanKindInternal)
+	defer span.End()
+
+	var transactions []domain.Transaction
+	err := transact(ctx, t.repository, func(tx *postgres.Tx) (err error) {
+		if transactions, err = t.expireReviews(ctx, tx, before, reviewer, reason); err != nil {
+			return err
+		}
+
+		events := make([]domain.HistoryEvent, 0, len(transactions))
+		for _, transaction := range transactions {
+			events = append(events, domain.NewTransactionHistory(domain.HistoryTransactionStatusChanged, transaction))
+		}
+
+		return appendHistory(ctx, tx, events...)
+	})
+	if err != nil {
+		err = postgres.ToDomainError(err)
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error expiring reviews in postgres", logger.Err(err))
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+func (t transactionImpl) expireReviews(ctx context.Context, tx *postgres.Tx, before time.Time, reviewer, reason string) ([]domain.Transaction, error) {
+	q := `
+	UPDATE transactions SET status = 'declined', reviewed_by = $2, review_reason = $3, reviewed_at = $4
+        WHERE status = 'pending_review' AND event_date < $1 AND ($5 = '*' OR tenant_id = $5)
+        RETURNING ` + transactionColumns + `;`
+
+	rows, err := tx.QueryContext(ctx, q, before, reviewer, reason, time.Now(), auth.Tenant(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := make([]domain.Transaction, 0)
+	for rows.Next() {
+		var r transactionResult
+		if err := rows.Scan(r.dest()...); err != nil {
+			return nil, err
+		}
+
+		transactions = append(transactions, r.entity())
+	}
+
+	return transactions, rows.Err()
+}
+
+// Snapshot starts a read only repeatable read transaction the statements of
+// the returned context join, so they all read the same state of the tables.
+func (t transactionImpl) Snapshot(ctx context.Context) (context.Context, Tx, error) {
+	ctx, tx, err := t.repository.Snapshot(ctx)
+	if err != nil {
+		err = postgres.ToDomainError(err)
+		logger.Error(ctx, logger.ServerError, "error beginning snapshot in postgres", logger.Err(err))
+		return ctx, nil, err
+	}
+
+	return ctx, tx, nil
+}
+
+// CopyIn inserts entities with COPY in one database transaction, so a batch
+// is either stored whole or not at all, along with their history, and returns
+// them with their ids. The ids are taken from the sequence beforehand for the
+// history to carry them.
+func (t transactionImpl) CopyIn(ctx context.Context, entities []domain.Transaction) ([]domain.Transaction, error) {
+	ctx, span := telemetry.Span(ctx, "repository:transaction:CopyIn", trace.SpanKindInternal)
+	defer span.End()
+
+	stored, err := t.copyIn(ctx, entities)
 	if err != nil {
+		err = postgres.ToDomainError(err)
 		telemetry.ErrorSpan(span, err)
-		logger.Error(logger.ServerError, "Error pushing transaction to postgres", err)
+		logger.Error(ctx, logger.ServerError, "error copying transactions to postgres", logger.Int("count", len(entities)), logger.Err(err))
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+func (t transactionImpl) copyIn(ctx context.Context, entities []domain.Transaction) ([]domain.Transaction, error) {
+	stored := make([]domain.Transaction, 0, len(entities))
+
+	err := transact(ctx, t.repository, func(tx *postgres.Tx) error {
+		ids := make([]int, 0, len(entities))
+		err := queryInts(ctx, tx, &ids, `SELECT nextval(pg_get_serial_sequence('transactions', 'id')) FROM generate_series(1, $1);`, len(entities))
+		if err != nil {
+			return err
+		}
+
+		stmt, err := tx.PrepareContext(ctx, pq.CopyIn("transactions",
+			"id", "account_id", "operation_type_id", "amount", "event_date", "decision", "decision_reasons", "status",
+			"card_id", "merchant_name", "merchant_mcc", "merchant_city", "merchant_country",
+		))
+		if err != nil {
+			return err
+		}
+
+		events := make([]domain.HistoryEvent, 0, len(entities))
+		for i, entity := range entities {
+			entity.Id = ids[i]
+			_, err := stmt.ExecContext(ctx,
+				entity.Id, entity.AccountID, entity.OperationType, entity.Amount, entity.EventDate, entity.Decision, pq.Array(entity.Reasons), entity.Status,
+				entity.CardID, entity.Merchant.Name, entity.Merchant.MCC, entity.Merchant.City, entity.Merchant.Country,
+			)
+			if err != nil {
+				_ = stmt.Close()
+				return err
+			}
+
+			stored = append(stored, entity)
+			events = append(events, domain.NewTransactionHistory(domain.HistoryTransactionPosted, entity))
+		}
+
+		if _, err := stmt.ExecContext(ctx); err != nil {
+			_ = stmt.Close()
+			return err
+		}
+
+		if err := stmt.Close(); err != nil {
+			return err
+		}
+
+		return appendHistory(ctx, tx, events...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+func queryInts(ctx context.Context, tx *postgres.Tx, values *[]int, q string, args ...interface{}) error {
+	rows, err := tx.QueryContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var value int
+		if err := rows.Scan(&value); err != nil {
+			return err
+		}
+
+		*values = append(*values, value)
+	}
+
+	return rows.Err()
+}
+
+// Walk streams the approved transactions of accountID dated from, inclusive,
+// to to, exclusive, to fn in date order as they are read.
+func (t transactionImpl) Walk(ctx context.Context, accountID string, from, to time.Time, fn func(domain.Transaction) error) error {
+	ctx, span := telemetry.Span(ctx, "repository:transaction:Walk", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + transactionColumns + ` FROM transactions
+        WHERE account_id = $1 AND status = 'approved' AND event_date >= $2 AND event_date < $3
+          AND ($4 = '*' OR tenant_id = $4)
+        ORDER BY event_date, id;`
+
+	err := t.repository.Query(ctx, q, []interface{}{accountID, from, to, auth.Tenant(ctx)}, func(rows *sql.Rows) error {
+		var r transactionResult
+		if err := rows.Scan(r.dest()...); err != nil {
+			return err
+		}
+
+		return fn(r.entity())
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error walking transactions from postgres", logger.Str("account_id", accountID), logger.Err(err))
 		return err
 	}
 
 	return nil
 }
 
+// Balance sums the approved transactions of accountID dated before before,
+// payments credit the account and the other operations debit it.
+func (t transactionImpl) Balance(ctx context.Context, accountID string, before time.Time) (float64, error) {
+	ctx, span := telemetry.Span(ctx, "repository:transaction:Balance", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `
+	SELECT COALESCE(SUM(CASE WHEN operation_type_id = $3 THEN amount ELSE -amount END), 0)
+        FROM transactions
+        WHERE account_id = $1 AND status = 'approved' AND event_date < $2 AND ($4 = '*' OR tenant_id = $4);
+    `
+
+	var balance float64
+	err := t.repository.QueryRow(ctx, q, []interface{}{accountID, before, operation.PAYMENT, auth.Tenant(ctx)}, &balance)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error summing transactions in postgres", logger.Str("account_id", accountID), logger.Err(err))
+		return 0, err
+	}
+
+	return balance, nil
+}
+
 func NewTransactionRepository(repository postgres.Repository) Transaction {
 	return transactionImpl{repository: repository}
 }
+
+// SumByMerchant sums the approved purchases of an account between from and to
+// by merchant, purchases without one are summed under an empty merchant.
+func (t transactionImpl) SumByMerchant(ctx context.Context, accountID string, from, to time.Time) ([]domain.SpendingItem, error) {
+	ctx, span := telemetry.Span(ctx, "repository:transaction:SumByMerchant", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `
+	SELECT merchant_name, merchant_mcc, COALESCE(SUM(amount), 0), COUNT(*)
+        FROM transactions
+        WHERE account_id = $1 AND status = 'approved' AND event_date >= $2 AND event_date < $3
+          AND operation_type_id IN ($4, $5) AND ($6 = '*' OR tenant_id = $6)
+        GROUP BY merchant_name, merchant_mcc;
+    `
+
+	items := make([]domain.SpendingItem, 0)
+	args := []interface{}{accountID, from, to, operation.CASH_PURCHASES, operation.INSTALLMENT_PURCHASES, auth.Tenant(ctx)}
+	err := t.repository.Query(ctx, q, args, func(rows *sql.Rows) error {
+		var item domain.SpendingItem
+		if err := rows.Scan(&item.Name, &item.MCC, &item.Amount, &item.Count); err != nil {
+			return err
+		}
+
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error summing purchases in postgres", logger.Str("account_id", accountID), logger.Err(err))
+		return nil, err
+	}
+
+	return items, nil
+}
@@ -0,0 +1,358 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/postgres"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/domain"
+)
+
+type Webhook interface {
+	PushSubscription(ctx context.Context, entity domain.Subscription) error
+	GetSubscription(ctx context.Context, id string) (domain.Subscription, error)
+	ListSubscriptions(ctx context.Context, clientID string) ([]domain.Subscription, error)
+	UpdateSubscription(ctx context.Context, entity domain.Subscription) error
+	DeleteSubscription(ctx context.Context, id string) error
+	MatchSubscriptions(ctx context.Context, event domain.Event) ([]domain.Subscription, error)
+
+	PushDeliveries(ctx context.Context, deliveries []domain.Delivery) error
+	ClaimDeliveries(ctx context.Context, now, until time.Time, limit int) ([]domain.Delivery, error)
+	UpdateDelivery(ctx context.Context, entity domain.Delivery) error
+	GetDelivery(ctx context.Context, id string) (domain.Delivery, error)
+	ListDeliveries(ctx context.Context, subscriptionID string, limit, offset int) ([]domain.Delivery, error)
+}
+
+type (
+	webhookImpl struct {
+		repository postgres.Repository
+	}
+
+	subscriptionResult struct {
+		domain.Subscription
+		EventTypes []string
+	}
+
+	deliveryResult struct {
+		domain.Delivery
+		Payload     string
+		DeliveredAt sql.NullTime
+	}
+)
+
+// Subscriptions, and their deliveries through them, are read and updated
+// scoped to the tenant of the caller.
+const (
+	subscriptionColumns = `id, client_id, tenant_id, url, event_types, account_id, secret, active, created_at, updated_at`
+	deliveryColumns     = `id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+        last_status_code, last_error, created_at, delivered_at`
+)
+
+func (r *subscriptionResult) dest() []interface{} {
+	return []interface{}{
+		&r.Id, &r.ClientID, &r.TenantID, &r.URL, pq.Array(&r.EventTypes), &r.AccountID, &r.Secret, &r.Active, &r.CreatedAt,
+		&r.UpdatedAt,
+	}
+}
+
+func (r *subscriptionResult) entity() domain.Subscription {
+	entity := r.Subscription
+	entity.EventTypes = make([]domain.EventType, 0, len(r.EventTypes))
+	for _, eventType := range r.EventTypes {
+		entity.EventTypes = append(entity.EventTypes, domain.EventType(eventType))
+	}
+
+	return entity
+}
+
+func (r *deliveryResult) dest() []interface{} {
+	return []interface{}{
+		&r.Id, &r.SubscriptionID, &r.EventID, &r.EventType, &r.Payload, &r.Status, &r.Attempts, &r.NextAttemptAt,
+		&r.LastStatusCode, &r.LastError, &r.CreatedAt, &r.DeliveredAt,
+	}
+}
+
+func (r *deliveryResult) entity() domain.Delivery {
+	entity := r.Delivery
+	entity.Payload = []byte(r.Payload)
+	if r.DeliveredAt.Valid {
+		entity.DeliveredAt = &r.DeliveredAt.Time
+	}
+
+	return entity
+}
+
+// tenantSubscriptions selects the ids of the subscriptions of the tenant in
+// parameter n, deliveries are scoped through them.
+func tenantSubscriptions(n int) string {
+	return fmt.Sprintf(`SELECT id FROM webhook_subscriptions WHERE $%d = '*' OR tenant_id = $%d`, n, n)
+}
+
+func eventTypesArray(eventTypes []domain.EventType) interface{} {
+	values := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		values = append(values, string(eventType))
+	}
+
+	return pq.Array(values)
+}
+
+func (w webhookImpl) PushSubscription(ctx context.Context, entity domain.Subscription) error {
+	ctx, span := telemetry.Span(ctx, "repository:webhook:PushSubscription", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `
+	INSERT INTO webhook_subscriptions (` + subscriptionColumns + `)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+    `
+
+	err := w.repository.Push(ctx, q,
+		entity.Id, entity.ClientID, entity.TenantID, entity.URL, eventTypesArray(entity.EventTypes), entity.AccountID, entity.Secret,
+		entity.Active, entity.CreatedAt, entity.UpdatedAt,
+	)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error pushing webhook subscription to postgres", logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (w webhookImpl) GetSubscription(ctx context.Context, id string) (domain.Subscription, error) {
+	ctx, span := telemetry.Span(ctx, "repository:webhook:GetSubscription", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1 AND ($2 = '*' OR tenant_id = $2);`
+
+	var r subscriptionResult
+	err := w.repository.QueryRow(ctx, q, []interface{}{id, auth.Tenant(ctx)}, r.dest()...)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error getting webhook subscription from postgres", logger.Err(err))
+		return domain.Subscription{}, err
+	}
+
+	return r.entity(), nil
+}
+
+// ListSubscriptions returns the subscriptions of clientID, of every client when it is empty.
+func (w webhookImpl) ListSubscriptions(ctx context.Context, clientID string) ([]domain.Subscription, error) {
+	ctx, span := telemetry.Span(ctx, "repository:webhook:ListSubscriptions", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions
+        WHERE ($1 = '' OR client_id = $1) AND ($2 = '*' OR tenant_id = $2) ORDER BY created_at;`
+
+	return w.querySubscriptions(ctx, span, q, clientID, auth.Tenant(ctx))
+}
+
+// MatchSubscriptions returns the active subscriptions notified of event, among
+// those of the tenant of its account.
+func (w webhookImpl) MatchSubscriptions(ctx context.Context, event domain.Event) ([]domain.Subscription, error) {
+	ctx, span := telemetry.Span(ctx, "repository:webhook:MatchSubscriptions", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions
+        WHERE active AND $1 = ANY(event_types) AND (account_id = '' OR account_id = $2)
+          AND tenant_id = (SELECT tenant_id FROM accounts WHERE id = $2) AND ($3 = '*' OR tenant_id = $3);`
+
+	return w.querySubscriptions(ctx, span, q, string(event.Type), event.AccountID, auth.Tenant(ctx))
+}
+
+func (w webhookImpl) querySubscriptions(ctx context.Context, span trace.Span, q string, args ...interface{}) ([]domain.Subscription, error) {
+	subscriptions := make([]domain.Subscription, 0)
+	err := w.repository.Query(ctx, q, args, func(rows *sql.Rows) error {
+		var r subscriptionResult
+		if err := rows.Scan(r.dest()...); err != nil {
+			return err
+		}
+
+		subscriptions = append(subscriptions, r.entity())
+		return nil
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error listing webhook subscriptions from postgres", logger.Err(err))
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func (w webhookImpl) UpdateSubscription(ctx context.Context, entity domain.Subscription) error {
+	ctx, span := telemetry.Span(ctx, "repository:webhook:UpdateSubscription", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `
+	UPDATE webhook_subscriptions SET url = $2, event_types = $3, account_id = $4, active = $5, updated_at = $6
+        WHERE id = $1 AND ($7 = '*' OR tenant_id = $7);
+    `
+
+	err := w.repository.Push(ctx, q,
+		entity.Id, entity.URL, eventTypesArray(entity.EventTypes), entity.AccountID, entity.Active, entity.UpdatedAt,
+		auth.Tenant(ctx),
+	)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error updating webhook subscription in postgres", logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (w webhookImpl) DeleteSubscription(ctx context.Context, id string) error {
+	ctx, span := telemetry.Span(ctx, "repository:webhook:DeleteSubscription", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `DELETE FROM webhook_subscriptions WHERE id = $1 AND ($2 = '*' OR tenant_id = $2);`
+
+	err := w.repository.Push(ctx, q, id, auth.Tenant(ctx))
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error deleting webhook subscription from postgres", logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// PushDeliveries enqueues the deliveries of one event in a single transaction.
+func (w webhookImpl) PushDeliveries(ctx context.Context, deliveries []domain.Delivery) error {
+	ctx, span := telemetry.Span(ctx, "repository:webhook:PushDeliveries", trace.SpanKindInternal)
+	defer span.End()
+
+	err := w.pushDeliveries(ctx, deliveries)
+	if err != nil {
+		err = postgres.ToDomainError(err)
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error pushing webhook deliveries to postgres", logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (w webhookImpl) pushDeliveries(ctx context.Context, deliveries []domain.Delivery) error {
+	tx, err := w.repository.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, d := range deliveries {
+		_, err := tx.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+        `, d.Id, d.SubscriptionID, d.EventID, d.EventType, string(d.Payload), d.Status, d.NextAttemptAt, d.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ClaimDeliveries leases up to limit pending deliveries due at now until the
+// given time, so no other replica attempts them meanwhile.
+func (w webhookImpl) ClaimDeliveries(ctx context.Context, now, until time.Time, limit int) ([]domain.Delivery, error) {
+	ctx, span := telemetry.Span(ctx, "repository:webhook:ClaimDeliveries", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `
+	UPDATE webhook_deliveries SET next_attempt_at = $2
+        WHERE id IN (
+            SELECT id FROM webhook_deliveries
+            WHERE status = 'pending' AND next_attempt_at <= $1 AND subscription_id IN (` + tenantSubscriptions(4) + `)
+            ORDER BY next_attempt_at LIMIT $3
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING ` + deliveryColumns + `;`
+
+	return w.queryDeliveries(ctx, span, q, now, until, limit, auth.Tenant(ctx))
+}
+
+func (w webhookImpl) ListDeliveries(ctx context.Context, subscriptionID string, limit, offset int) ([]domain.Delivery, error) {
+	ctx, span := telemetry.Span(ctx, "repository:webhook:ListDeliveries", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+        WHERE subscription_id = $1 AND subscription_id IN (` + tenantSubscriptions(4) + `)
+        ORDER BY created_at DESC, id LIMIT $2 OFFSET $3;`
+
+	return w.queryDeliveries(ctx, span, q, subscriptionID, limit, offset, auth.Tenant(ctx))
+}
+
+func (w webhookImpl) queryDeliveries(ctx context.Context, span trace.Span, q string, args ...interface{}) ([]domain.Delivery, error) {
+	deliveries := make([]domain.Delivery, 0)
+	err := w.repository.Query(ctx, q, args, func(rows *sql.Rows) error {
+		var r deliveryResult
+		if err := rows.Scan(r.dest()...); err != nil {
+			return err
+		}
+
+		deliveries = append(deliveries, r.entity())
+		return nil
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error listing webhook deliveries from postgres", logger.Err(err))
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (w webhookImpl) GetDelivery(ctx context.Context, id string) (domain.Delivery, error) {
+	ctx, span := telemetry.Span(ctx, "repository:webhook:GetDelivery", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+        WHERE id = $1 AND subscription_id IN (` + tenantSubscriptions(2) + `);`
+
+	var r deliveryResult
+	err := w.repository.QueryRow(ctx, q, []interface{}{id, auth.Tenant(ctx)}, r.dest()...)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error getting webhook delivery from postgres", logger.Err(err))
+		return domain.Delivery{}, err
+	}
+
+	return r.entity(), nil
+}
+
+func (w webhookImpl) UpdateDelivery(ctx context.Context, entity domain.Delivery) error {
+	ctx, span := telemetry.Span(ctx, "repository:webhook:UpdateDelivery", trace.SpanKindInternal)
+	defer span.End()
+
+	q := `
+	UPDATE webhook_deliveries SET status = $2, attempts = $3, next_attempt_at = $4, last_status_code = $5,
+                                  last_error = $6, delivered_at = $7
+        WHERE id = $1 AND subscription_id IN (` + tenantSubscriptions(8) + `);
+    `
+
+	err := w.repository.Push(ctx, q,
+		entity.Id, entity.Status, entity.Attempts, entity.NextAttemptAt, entity.LastStatusCode, entity.LastError, entity.DeliveredAt,
+		auth.Tenant(ctx),
+	)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "error updating webhook delivery in postgres", logger.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func NewWebhookRepository(repository postgres.Repository) Webhook {
+	return webhookImpl{repository: repository}
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"github.com/payment-api/config"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/domain"
+	"github.com/payment-api/internal/usecase"
+)
+
+// FraudEngine builds the fraud rules of cfg, shared by the service and the
+// import command.
+func FraudEngine(cfg config.Fraud, transactionRepository repository.Transaction) (usecase.FraudEngine, error) {
+	return usecase.NewFraudEngine(fraudRules(cfg), transactionRepository)
+}
+
+func fraudRules(cfg config.Fraud) []domain.FraudRule {
+	rules := make([]domain.FraudRule, 0, len(cfg.Rules))
+
+	for _, rule := range cfg.Rules {
+		rules = append(rules, domain.FraudRule{
+			Name:          rule.Name,
+			Type:          domain.RuleType(rule.Type),
+			Decision:      domain.Decision(rule.Decision),
+			OperationType: rule.OperationType,
+			MaxAmount:     rule.MaxAmount,
+			MaxCount:      rule.MaxCount,
+			Window:        rule.Window,
+		})
+	}
+
+	return rules
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+
+	paymentv1 "github.com/payment-api/api/payment/v1"
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/internal/adapter/grpc/handlers"
+	"github.com/payment-api/internal/adapter/grpc/interceptors"
+)
+
+// runGRPC serves the gRPC API on the configured grpc port next to the REST
+// API, it does nothing while the port is zero.
+func (a *Server) runGRPC(ctx context.Context, cancel context.CancelFunc) func() error {
+	return func() error {
+		if a.config.Server.GRPCPort == 0 {
+			return nil
+		}
+
+		defer cancel()
+
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", a.config.Server.GRPCPort))
+		if err != nil {
+			return fmt.Errorf("listen grpc: %w", err)
+		}
+
+		server := a.grpcServer()
+
+		done := make(chan struct{})
+		go a.shutdownGRPC(ctx, server, done)
+
+		if err := server.Serve(listener); err != nil {
+			return err
+		}
+
+		<-done
+		return nil
+	}
+}
+
+func (a *Server) grpcServer() *grpc.Server {
+	scopes := make(map[string][]auth.Scope, len(handlers.AccountScopes)+len(handlers.TransactionScopes))
+	for _, declared := range []map[string][]auth.Scope{handlers.AccountScopes, handlers.TransactionScopes} {
+		for method, required := range declared {
+			scopes[method] = required
+		}
+	}
+
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		interceptors.RequestID(),
+		interceptors.Tracing(),
+		interceptors.Logger(),
+		interceptors.Errors(),
+		interceptors.Recover(),
+		interceptors.AuditRoute(),
+		interceptors.Authenticate(a.services.apiKey, a.tokens),
+		interceptors.Authorize(scopes),
+		interceptors.Signature(a.signature, handlers.TransactionWrites),
+		interceptors.RateLimit(a.limiter, handlers.TransactionWrites),
+	))
+
+	paymentv1.RegisterAccountServiceServer(server, handlers.NewAccountServer(a.services.account))
+	paymentv1.RegisterTransactionServiceServer(server, handlers.NewTransactionServer(a.services.transaction))
+
+	return server
+}
+
+// shutdownGRPC stops the gRPC server once ctx is cancelled, after the same
+// drain delay as the REST API, letting in-flight calls finish within the
+// shutdown timeout.
+func (a *Server) shutdownGRPC(ctx context.Context, server *grpc.Server, done chan<- struct{}) {
+	defer close(done)
+
+	<-ctx.Done()
+	time.Sleep(a.config.Server.ShutdownDelay)
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(a.config.Server.ShutdownTimeout):
+		logger.Error(ctx, logger.ServerError, "cannot shutdown grpc server gracefully")
+		server.Stop()
+	}
+}
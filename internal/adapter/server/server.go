@@ -2,29 +2,58 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hashicorp/go-multierror"
 
 	"github.com/payment-api/config"
+	"github.com/payment-api/infrastructure/audit"
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/card"
+	"github.com/payment-api/infrastructure/health"
 	"github.com/payment-api/infrastructure/logger"
 	"github.com/payment-api/infrastructure/postgres"
+	"github.com/payment-api/infrastructure/ratelimit"
+	"github.com/payment-api/infrastructure/signature"
+	"github.com/payment-api/infrastructure/telemetry"
 	"github.com/payment-api/internal/adapter/http/handlers/account"
+	auditHandler "github.com/payment-api/internal/adapter/http/handlers/audit"
+	healthHandler "github.com/payment-api/internal/adapter/http/handlers/health"
+	"github.com/payment-api/internal/adapter/http/handlers/imports"
 	"github.com/payment-api/internal/adapter/http/handlers/transaction"
+	webhookHandler "github.com/payment-api/internal/adapter/http/handlers/webhook"
 	"github.com/payment-api/internal/adapter/http/middlewares"
 	"github.com/payment-api/internal/adapter/repository"
 	"github.com/payment-api/internal/usecase"
 )
 
 type Server struct {
-	config   config.Configuration
-	services svs
+	config    config.Configuration
+	services  svs
+	health    *health.Checker
+	signature *signature.Verifier
+	limiter   *ratelimit.Limiter
+	tokens    *auth.TokenVerifier
+	scheduler *postgres.Leader
 }
 
 type svs struct {
 	account     usecase.AccountUseCase
 	transaction usecase.TransactionUseCase
+	apiKey      usecase.APIKeyUseCase
+	review      usecase.ReviewUseCase
+	audit       usecase.AuditUseCase
+	webhook     usecase.WebhookUseCase
+	events      usecase.EventUseCase
+	imports     usecase.ImportUseCase
+	statement   usecase.StatementUseCase
+	schedule    usecase.ScheduleUseCase
+	card        usecase.CardUseCase
+	spending    usecase.SpendingUseCase
 }
 
 func New(ctx context.Context, cfg config.Configuration) (a Server) {
@@ -32,41 +61,222 @@ func New(ctx context.Context, cfg config.Configuration) (a Server) {
 
 	pgRepository, err := postgres.NewRepository(ctx, a.config.Postgres.Url)
 	if err != nil {
-		logger.Fatal(logger.ConfigError, fmt.Sprintf("Cannot connect postgresql error: %v", err))
+		logger.Fatal(ctx, logger.ConfigError, "cannot connect postgresql", logger.Err(err))
+	}
+	if a.config.Postgres.RowLevelSecurity {
+		pgRepository.Tenant = auth.Tenant
+	}
+
+	policies, err := TenantPolicies(a.config.Tenants)
+	if err != nil {
+		logger.Fatal(ctx, logger.ConfigError, "invalid tenants", logger.Err(err))
 	}
 
+	a.services.audit = usecase.NewAuditUseCase(repository.NewAuditRepository(*pgRepository))
+
 	accountRepository := repository.NewAccountRepository(*pgRepository)
-	a.services.account = usecase.NewAccountUseCase(accountRepository)
+
+	a.services.webhook = WebhookUseCase(a.config.Webhook, *pgRepository, accountRepository)
+	a.services.events = usecase.NewEventUseCase(repository.NewEventRepository(*pgRepository), accountRepository, usecase.NewHub(), a.config.Events.Buffer)
+	publisher := usecase.Publishers{a.services.events, a.services.webhook}
+
+	if a.config.Erasure.Key == "" {
+		logger.Fatal(ctx, logger.ConfigError, "erasure key is required")
+	}
+	a.services.account = usecase.NewAccountUseCase(accountRepository, a.services.audit, publisher, a.config.Erasure.Key)
+
+	if _, err := card.GeneratePAN(a.config.Cards.BIN); err != nil {
+		logger.Fatal(ctx, logger.ConfigError, "invalid card bin", logger.Err(err))
+	}
+	if a.config.Cards.ValidityMonths <= 0 {
+		logger.Fatal(ctx, logger.ConfigError, "card validity_months must be positive")
+	}
+	cardRepository := repository.NewCardRepository(*pgRepository)
+	a.services.card = usecase.NewCardUseCase(cardRepository, accountRepository, a.services.audit, a.config.Cards.BIN, a.config.Cards.ValidityMonths)
 
 	transactionRepository := repository.NewTransactionRepository(*pgRepository)
-	a.services.transaction = usecase.NewTransactionUseCase(accountRepository, transactionRepository)
+	fraudEngine, err := FraudEngine(a.config.Fraud, transactionRepository)
+	if err != nil {
+		logger.Fatal(ctx, logger.ConfigError, "invalid fraud rules", logger.Err(err))
+	}
+	a.services.transaction = usecase.NewTransactionUseCase(accountRepository, transactionRepository, cardRepository, fraudEngine, a.services.audit, publisher, policies)
+	a.services.review = usecase.NewReviewUseCase(transactionRepository, a.services.audit, publisher, a.config.Review.Expiry)
+	a.services.spending = usecase.NewSpendingUseCase(accountRepository, transactionRepository)
+	a.services.statement = usecase.NewStatementUseCase(accountRepository, transactionRepository, a.config.Statement.Currency, a.config.Statement.BankID)
+	a.services.imports = usecase.NewImportUseCase(
+		repository.NewImportRepository(*pgRepository),
+		accountRepository,
+		transactionRepository,
+		fraudEngine,
+		a.services.audit,
+		publisher,
+		usecase.ImportLimits{
+			BatchSize:  a.config.Import.BatchSize,
+			MaxBytes:   a.config.Import.MaxBytes,
+			SpoolDir:   a.config.Import.SpoolDir,
+			StaleAfter: a.config.Import.StaleAfter,
+		},
+		policies,
+	)
+	a.services.schedule = usecase.NewScheduleUseCase(
+		repository.NewScheduleRepository(*pgRepository),
+		accountRepository,
+		transactionRepository,
+		a.services.transaction,
+		a.services.audit,
+		publisher,
+		usecase.RetryPolicy{
+			MaxAttempts: a.config.Schedule.MaxAttempts,
+			BackoffBase: a.config.Schedule.BackoffBase,
+			BackoffMax:  a.config.Schedule.BackoffMax,
+			BatchSize:   a.config.Schedule.BatchSize,
+		},
+	)
+	a.scheduler = postgres.NewLeader(pgRepository.DB, "scheduled-payments")
+
+	apiKeyRepository := repository.NewAPIKeyRepository(*pgRepository)
+	a.services.apiKey = usecase.NewAPIKeyUseCase(apiKeyRepository, auth.NewTenants(a.config.Tenants))
+
+	if a.config.JWT.Enabled() {
+		a.tokens = auth.NewTokenVerifier(a.config.JWT)
+	}
+
+	a.signature = signature.NewVerifier(a.config.Signing.Partners, a.config.Signing.ReplayWindow, signature.NewMemoryNonceStore())
+
+	rateLimitStore := ratelimit.NewMemoryStore()
+	if a.config.RateLimit.Store == "postgres" {
+		rateLimitStore = ratelimit.NewPostgresStore(pgRepository.DB)
+	}
+	a.limiter = ratelimit.NewLimiter(a.config.RateLimit, rateLimitStore)
+
+	a.health = health.NewChecker(
+		health.Check{Name: "postgres", Timeout: a.config.Health.PostgresTimeout, Func: pgRepository.Ping},
+		health.Check{Name: "migrations", Timeout: a.config.Health.MigrationTimeout, Func: pgRepository.CheckMigrations},
+		health.Check{Name: "telemetry", Timeout: a.config.Health.TelemetryTimeout, Func: telemetry.ExporterHealth},
+	)
 
 	return a
 }
 
+// Run serves the REST and gRPC APIs until ctx is cancelled, either stopping
+// cancels it for the other to shut down too.
 func (a *Server) Run(ctx context.Context, cancel context.CancelFunc) func() error {
+	return func() error {
+		var g multierror.Group
+
+		g.Go(a.runREST(ctx, cancel))
+		g.Go(a.runGRPC(ctx, cancel))
+
+		return g.Wait().ErrorOrNil()
+	}
+}
+
+func (a *Server) runREST(ctx context.Context, cancel context.CancelFunc) func() error {
 	return func() error {
 		defer cancel()
 
-		router := gin.Default()
+		router := gin.New()
 
-		router.Use(middlewares.Recover())
+		router.Use(middlewares.RequestID(), middlewares.Logger(), middlewares.Errors(), middlewares.Recover(), middlewares.AuditRoute())
 
+		router.Use(middlewares.Authenticate(a.services.apiKey))
+		if a.tokens != nil {
+			router.Use(middlewares.Bearer(a.tokens))
+		}
+
+		healthHandler.SetHealthRoutes(ctx, router, a.health)
 		account.SetAccountRoutes(ctx, router, a.services.account)
-		transaction.SetTransactionRoutes(ctx, router, a.services.transaction)
+		account.SetEventRoutes(ctx, router, a.services.events, a.config.Events.Heartbeat)
+		account.SetExportRoutes(ctx, router, a.services.statement)
+		transaction.SetTransactionRoutes(ctx, router, a.services.transaction, a.signature, a.limiter)
+		transaction.SetReviewRoutes(ctx, router, a.services.review)
+		auditHandler.SetAuditRoutes(ctx, router, a.services.audit)
+		webhookHandler.SetWebhookRoutes(ctx, router, a.services.webhook)
+		imports.SetImportRoutes(ctx, router, a.services.imports)
+		account.SetScheduleRoutes(ctx, router, a.services.schedule)
+		account.SetCardRoutes(ctx, router, a.services.card)
+		account.SetSpendingRoutes(ctx, router, a.services.spending)
 
 		server := &http.Server{
 			Addr:    fmt.Sprintf(":%d", a.config.Server.Port),
 			Handler: router,
 		}
 
-		go shutdown(ctx, server)
-		return server.ListenAndServe()
+		done := make(chan struct{})
+		go a.shutdown(ctx, server, done)
+		go a.worker(ctx, "review-expiry", a.config.Review.Interval, a.services.review.Expire)
+		go a.worker(ctx, "webhook-dispatch", a.config.Webhook.Interval, a.services.webhook.Dispatch)
+		go a.worker(ctx, "import-sweep", a.config.Import.Interval, a.services.imports.Sweep)
+		go a.worker(ctx, "card-expiry", a.config.Cards.Interval, a.services.card.Expire)
+		go a.worker(ctx, "scheduled-payments", a.config.Schedule.Interval, a.leading(a.scheduler, a.services.schedule.Run))
+
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+
+		<-done
+
+		if err := a.scheduler.Release(context.Background()); err != nil {
+			logger.Error(ctx, logger.ServerError, "cannot release scheduler lock", logger.Err(err))
+		}
+		return nil
 	}
 }
 
-func shutdown(ctx context.Context, server *http.Server) {
+// shutdown marks the service as not ready as soon as ctx is cancelled and waits
+// the configured delay, giving load balancers time to drain before the server
+// stops accepting connections.
+func (a *Server) shutdown(ctx context.Context, server *http.Server, done chan<- struct{}) {
+	defer close(done)
+
 	<-ctx.Done()
-	logger.Info(logger.ServerInfo, "New we can do an shutdown gracefully")
-	server.Shutdown(ctx)
+	logger.Info(ctx, logger.ServerInfo, "shutdown started, draining traffic")
+
+	a.health.Drain()
+	time.Sleep(a.config.Server.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.config.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot shutdown server gracefully", logger.Err(err))
+	}
+}
+
+// leading runs job only while this replica holds leader, so a job firing
+// payments runs on one replica at a time.
+func (a *Server) leading(leader *postgres.Leader, job func(context.Context) (int, error)) func(context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		leads, err := leader.Acquire(ctx)
+		if err != nil || !leads {
+			return 0, err
+		}
+
+		return job(ctx)
+	}
+}
+
+// worker runs job every interval until ctx is cancelled, a zero interval
+// disables it. Jobs act for every tenant and their mutations are audited
+// under the worker name.
+func (a *Server) worker(ctx context.Context, name string, interval time.Duration, job func(context.Context) (int, error)) {
+	if interval <= 0 {
+		return
+	}
+
+	ctx = auth.WithAnyTenant(audit.WithRoute(ctx, "worker:"+name))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := job(ctx); err != nil {
+				logger.Error(ctx, logger.ServerError, "worker job failed", logger.Str("worker", name), logger.Err(err))
+			}
+		}
+	}
 }
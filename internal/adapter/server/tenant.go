@@ -0,0 +1,38 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/payment-api/config"
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
+	"github.com/payment-api/internal/usecase"
+)
+
+// TenantPolicies reads the transaction policy of each tenant, shared by the
+// service and the import command.
+func TenantPolicies(cfg config.Tenants) (usecase.TenantPolicies, error) {
+	policies := make(usecase.TenantPolicies, len(cfg))
+
+	for tenant, program := range cfg {
+		if tenant == auth.AnyTenant || tenant == "" {
+			return nil, fmt.Errorf("tenant %q is reserved", tenant)
+		}
+		if program.MaxAmount < 0 {
+			return nil, fmt.Errorf("tenant %s: max_amount must be greater than or equal to 0", tenant)
+		}
+
+		policy := domain.TenantPolicy{MaxAmount: program.MaxAmount}
+		for _, value := range program.OperationTypes {
+			operationType := operation.Type(value)
+			if !operationType.IsValid() {
+				return nil, fmt.Errorf("tenant %s: unknown operation type %d", tenant, value)
+			}
+			policy.OperationTypes = append(policy.OperationTypes, operationType)
+		}
+		policies[tenant] = policy
+	}
+
+	return policies, nil
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"github.com/payment-api/config"
+	"github.com/payment-api/infrastructure/postgres"
+	"github.com/payment-api/infrastructure/webhook"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/usecase"
+)
+
+// WebhookUseCase builds the webhooks of cfg, shared by the service and the
+// import command, which only enqueues deliveries.
+func WebhookUseCase(cfg config.Webhook, pgRepository postgres.Repository, accountRepository repository.Account) usecase.WebhookUseCase {
+	return usecase.NewWebhookUseCase(
+		repository.NewWebhookRepository(pgRepository),
+		accountRepository,
+		webhook.NewSender(cfg.Timeout, cfg.Insecure),
+		usecase.RetryPolicy{
+			MaxAttempts: cfg.MaxAttempts,
+			BackoffBase: cfg.BackoffBase,
+			BackoffMax:  cfg.BackoffMax,
+			Lease:       cfg.Lease,
+			BatchSize:   cfg.BatchSize,
+		},
+	)
+}
@@ -1,13 +1,89 @@
 package domain
 
+import "time"
+
+type AccountStatus string
+
+const (
+	AccountActive AccountStatus = "active"
+	AccountClosed AccountStatus = "closed"
+)
+
+// Account is a holder account. Version starts at 1 and grows with every
+// update, so writers can detect they read a stale account.
+//
+// Accounts are never removed: a closed account keeps its transactions, an
+// erased one has its document number pseudonymised and its profile cleared,
+// and a deleted one is hidden from every read.
+//
+// TenantID is the card program the account belongs to, its transactions
+// belong to the same program.
 type Account struct {
 	Id             string
+	TenantID       string
 	DocumentNumber string
+	Status         AccountStatus
+	AccountProfile
+	Version   int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ClosedAt  *time.Time
+	ErasedAt  *time.Time
+	DeletedAt *time.Time
+}
+
+// AccountProfile holds the contact details of the account holder, every
+// field is optional.
+type AccountProfile struct {
+	HolderName string
+	Email      string
+	Phone      string
+	Address    *Address
+	Metadata   map[string]string
+}
+
+// Address is the postal address of the account holder, Country is an ISO
+// 3166-1 alpha-2 code.
+type Address struct {
+	Line1      string
+	Line2      string
+	City       string
+	State      string
+	PostalCode string
+	Country    string
+}
+
+// IsOpen reports whether the account accepts transactions.
+func (a Account) IsOpen() bool {
+	return a.Status != AccountClosed
+}
+
+// IsErased reports whether the personal data of the account was erased.
+func (a Account) IsErased() bool {
+	return a.ErasedAt != nil
+}
+
+// AccountFilter selects accounts for back-office listings, zero fields match
+// every account. CreatedFrom is inclusive and CreatedTo exclusive.
+type AccountFilter struct {
+	DocumentNumber string
+	DocumentPrefix string
+	Status         AccountStatus
+	CreatedFrom    time.Time
+	CreatedTo      time.Time
+	Limit          int
+	Offset         int
 }
 
 func NewAccount(id, documentNumber string) Account {
+	now := time.Now().UTC()
+
 	return Account{
 		Id:             id,
 		DocumentNumber: documentNumber,
+		Status:         AccountActive,
+		Version:        1,
+		CreatedAt:      now,
+		UpdatedAt:      now,
 	}
 }
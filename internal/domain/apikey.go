@@ -0,0 +1,49 @@
+package domain
+
+import "time"
+
+type APIKey struct {
+	Id        string
+	ClientID  string
+	TenantID  string
+	Hash      string
+	Scopes    []string
+	CreatedAt time.Time
+	ExpiresAt *time.Time
+	RevokedAt *time.Time
+	// TTL is the validity the key was minted with, zero never expires. Unlike
+	// ExpiresAt it is kept when a rotation brings the expiry forward.
+	TTL time.Duration
+}
+
+// Active reports whether the key can authenticate at now, a rotated key keeps
+// working until its expiry so clients can switch over.
+func (k APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil && !now.Before(*k.RevokedAt) {
+		return false
+	}
+
+	if k.ExpiresAt != nil && !now.Before(*k.ExpiresAt) {
+		return false
+	}
+
+	return true
+}
+
+func NewAPIKey(id, clientID, hash string, scopes []string, createdAt time.Time, ttl time.Duration) APIKey {
+	key := APIKey{
+		Id:        id,
+		ClientID:  clientID,
+		Hash:      hash,
+		Scopes:    scopes,
+		CreatedAt: createdAt,
+		TTL:       ttl,
+	}
+
+	if ttl > 0 {
+		expiresAt := createdAt.Add(ttl)
+		key.ExpiresAt = &expiresAt
+	}
+
+	return key
+}
@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+type AuditEntity string
+
+const (
+	AuditAccount     AuditEntity = "account"
+	AuditTransaction AuditEntity = "transaction"
+	AuditImport      AuditEntity = "import"
+	AuditSchedule    AuditEntity = "scheduled_payment"
+	AuditCard        AuditEntity = "card"
+)
+
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditClose   = "close"
+	AuditErase   = "erase"
+	AuditDelete  = "delete"
+	AuditApprove = "approve"
+	AuditDecline = "decline"
+	AuditExpire  = "expire"
+	AuditReverse = "reverse"
+	AuditCancel  = "cancel"
+	AuditBlock   = "block"
+	AuditReplace = "replace"
+)
+
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success"
+	AuditFailure AuditOutcome = "failure"
+)
+
+// AuditChange is a mutation reported by a use case, Before and After are the
+// entity snapshots, nil when the entity did not exist before or after it.
+type AuditChange struct {
+	EntityType AuditEntity
+	EntityID   string
+	Action     string
+	Before     interface{}
+	After      interface{}
+}
+
+// AuditEntry is one append-only record of the audit log. Every entry is
+// chained to the previous one by PrevHash, so editing or removing an entry
+// breaks the hash of every entry after it.
+type AuditEntry struct {
+	Id         int64
+	TenantID   string
+	Actor      string
+	Route      string
+	RequestID  string
+	EntityType AuditEntity
+	EntityID   string
+	Action     string
+	Before     json.RawMessage
+	After      json.RawMessage
+	Outcome    AuditOutcome
+	ErrorCode  string
+	CreatedAt  time.Time
+	PrevHash   string
+	Hash       string
+}
+
+// AuditFilter selects entries of an entity type, and of one entity when EntityID is set.
+type AuditFilter struct {
+	EntityType AuditEntity
+	EntityID   string
+	Limit      int
+	Offset     int
+}
+
+// AuditVerification is the outcome of walking the whole chain, BrokenAt is
+// the id of the first entry failing verification.
+type AuditVerification struct {
+	Entries  int
+	Valid    bool
+	BrokenAt int64
+	Reason   string
+}
+
+// Seal chains the entry to prevHash and computes its hash. Snapshots are
+// stored in their canonical form, so the hash survives the jsonb round trip.
+func (e *AuditEntry) Seal(prevHash string) error {
+	var err error
+
+	if e.Before, err = canonicalJSON(e.Before); err != nil {
+		return err
+	}
+	if e.After, err = canonicalJSON(e.After); err != nil {
+		return err
+	}
+
+	e.CreatedAt = e.CreatedAt.UTC().Truncate(time.Microsecond)
+	e.PrevHash = prevHash
+	e.Hash, err = e.ComputeHash()
+
+	return err
+}
+
+// ComputeHash hashes the entry content together with PrevHash.
+func (e AuditEntry) ComputeHash() (string, error) {
+	before, err := canonicalJSON(e.Before)
+	if err != nil {
+		return "", err
+	}
+
+	after, err := canonicalJSON(e.After)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal([]string{
+		e.PrevHash,
+		e.Actor,
+		e.Route,
+		e.RequestID,
+		string(e.EntityType),
+		e.EntityID,
+		e.Action,
+		string(before),
+		string(after),
+		string(e.Outcome),
+		e.ErrorCode,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalJSON re-encodes a snapshot with sorted keys and no whitespace.
+func canonicalJSON(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(value)
+}
@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+type CardStatus string
+
+const (
+	CardActive   CardStatus = "active"
+	CardBlocked  CardStatus = "blocked"
+	CardReplaced CardStatus = "replaced"
+	CardExpired  CardStatus = "expired"
+)
+
+// Card is a payment card of an account. Its PAN is never stored: the card is
+// known by Token, shown by LastFour and valid through the end of its expiry
+// month. PAN is only set on the card returned when it is issued.
+type Card struct {
+	Id          string
+	AccountID   string
+	TenantID    string
+	Token       string
+	LastFour    string
+	ExpiryMonth int
+	ExpiryYear  int
+	Status      CardStatus
+	ReplacedBy  string
+	PAN         string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// ExpiresAt is the first instant the card is no longer valid, the start of the
+// month after its expiry month.
+func (c Card) ExpiresAt() time.Time {
+	return time.Date(c.ExpiryYear, time.Month(c.ExpiryMonth)+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// IsUsable reports whether transactions can be posted with the card at now.
+func (c Card) IsUsable(now time.Time) bool {
+	return c.Status == CardActive && now.Before(c.ExpiresAt())
+}
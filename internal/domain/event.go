@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const (
+	EventAccountCreated         EventType = "account.created"
+	EventAccountClosed          EventType = "account.closed"
+	EventTransactionCreated     EventType = "transaction.created"
+	EventTransactionApproved    EventType = "transaction.approved"
+	EventTransactionDeclined    EventType = "transaction.declined"
+	EventTransactionReversed    EventType = "transaction.reversed"
+	EventScheduledPaymentFailed EventType = "scheduled_payment.failed"
+)
+
+var eventTypes = map[EventType]bool{
+	EventAccountCreated:         true,
+	EventAccountClosed:          true,
+	EventTransactionCreated:     true,
+	EventTransactionApproved:    true,
+	EventTransactionDeclined:    true,
+	EventTransactionReversed:    true,
+	EventScheduledPaymentFailed: true,
+}
+
+func (t EventType) IsValid() bool {
+	return eventTypes[t]
+}
+
+// Event is something that happened to an account, published by the use cases
+// to whoever listens, as webhook subscriptions. Seq orders the events once
+// stored in the account event log, it is zero until then.
+type Event struct {
+	Seq        int64                  `json:"-"`
+	Id         string                 `json:"id"`
+	Type       EventType              `json:"type"`
+	AccountID  string                 `json:"account_id"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	Data       map[string]interface{} `json:"data"`
+}
+
+func NewAccountEvent(eventType EventType, account Account) Event {
+	return newEvent(eventType, account.Id, map[string]interface{}{
+		"id": account.Id,
+	})
+}
+
+func NewTransactionEvent(eventType EventType, transaction Transaction) Event {
+	return newEvent(eventType, transaction.AccountID, map[string]interface{}{
+		"id":             transaction.Id,
+		"account_id":     transaction.AccountID,
+		"operation_type": transaction.OperationType.Index(),
+		"amount":         transaction.Amount,
+		"event_date":     transaction.EventDate,
+		"decision":       transaction.Decision,
+		"status":         transaction.Status,
+	})
+}
+
+// NewScheduledPaymentEvent notifies that payment could not be posted, Error
+// tells why.
+func NewScheduledPaymentEvent(eventType EventType, payment ScheduledPayment) Event {
+	return newEvent(eventType, payment.AccountID, map[string]interface{}{
+		"id":          payment.Id,
+		"account_id":  payment.AccountID,
+		"amount_type": payment.AmountType,
+		"amount":      payment.Amount,
+		"attempts":    payment.Attempts,
+		"status":      payment.Status,
+		"error":       payment.LastError,
+	})
+}
+
+func newEvent(eventType EventType, accountID string, data map[string]interface{}) Event {
+	return Event{
+		Id:         uuid.New().String(),
+		Type:       eventType,
+		AccountID:  accountID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
@@ -0,0 +1,69 @@
+package domain
+
+import "time"
+
+type Decision string
+
+const (
+	DecisionAllow  Decision = "allow"
+	DecisionReview Decision = "review"
+	DecisionDeny   Decision = "deny"
+)
+
+// weight orders decisions so the strictest one wins.
+func (d Decision) weight() int {
+	switch d {
+	case DecisionDeny:
+		return 2
+	case DecisionReview:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (d Decision) IsValid() bool {
+	return d == DecisionAllow || d == DecisionReview || d == DecisionDeny
+}
+
+type RuleType string
+
+const (
+	// RuleVelocity limits the transactions of an account within Window to MaxCount.
+	RuleVelocity RuleType = "velocity"
+	// RuleMaxAmount limits the amount of an operation type, any type when OperationType is zero.
+	RuleMaxAmount RuleType = "max_amount"
+	// RuleFirstTransaction limits the amount of the first transaction of an account.
+	RuleFirstTransaction RuleType = "first_transaction"
+)
+
+// FraudRule is the declarative definition of a rule, Decision is applied to
+// transactions breaking it.
+type FraudRule struct {
+	Name          string
+	Type          RuleType
+	Decision      Decision
+	OperationType int
+	MaxAmount     float64
+	MaxCount      int
+	Window        time.Duration
+}
+
+// Assessment is the outcome of every rule evaluated for a transaction.
+type Assessment struct {
+	Decision Decision
+	Reasons  []string
+}
+
+// Apply records a broken rule, keeping the strictest decision.
+func (a *Assessment) Apply(rule FraudRule) {
+	if rule.Decision.weight() > a.Decision.weight() {
+		a.Decision = rule.Decision
+	}
+
+	a.Reasons = append(a.Reasons, rule.Name)
+}
+
+func NewAssessment() Assessment {
+	return Assessment{Decision: DecisionAllow, Reasons: []string{}}
+}
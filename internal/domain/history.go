@@ -0,0 +1,83 @@
+package domain
+
+import "time"
+
+type HistoryType string
+
+const (
+	HistoryAccountOpened            HistoryType = "account.opened"
+	HistoryAccountUpdated           HistoryType = "account.updated"
+	HistoryAccountStatusChanged     HistoryType = "account.status_changed"
+	HistoryTransactionPosted        HistoryType = "transaction.posted"
+	HistoryTransactionStatusChanged HistoryType = "transaction.status_changed"
+	HistoryTransactionReversed      HistoryType = "transaction.reversed"
+)
+
+// HistoryEvent is an entry of the append-only history of an account, the
+// source the accounts and transactions tables are projected from. It carries
+// the state of the account, or of one of its transactions, once changed, so
+// replaying the history of an account in Seq order rebuilds its rows.
+type HistoryEvent struct {
+	Seq         int64
+	AccountID   string
+	Type        HistoryType
+	RecordedAt  time.Time
+	Account     *Account
+	Transaction *Transaction
+}
+
+func NewAccountHistory(historyType HistoryType, account Account) HistoryEvent {
+	return HistoryEvent{
+		AccountID:  account.Id,
+		Type:       historyType,
+		RecordedAt: time.Now().UTC(),
+		Account:    &account,
+	}
+}
+
+func NewTransactionHistory(historyType HistoryType, transaction Transaction) HistoryEvent {
+	return HistoryEvent{
+		AccountID:   transaction.AccountID,
+		Type:        historyType,
+		RecordedAt:  time.Now().UTC(),
+		Transaction: &transaction,
+	}
+}
+
+// Projection compares the tables with their projection replayed from the
+// history, Events is the number of events replayed.
+type Projection struct {
+	Events int
+	Diffs  []ProjectionDiff
+}
+
+// ProjectionDiff lists the rows of Table that differ from the history:
+// Missing rows were projected but are absent from the table, Unexpected rows
+// are in the table but no event accounts for them.
+type ProjectionDiff struct {
+	Table      string
+	Missing    []string
+	Unexpected []string
+	Changed    []ProjectionChange
+}
+
+// ProjectionChange is a row whose Columns hold other values than projected.
+type ProjectionChange struct {
+	Id      string
+	Columns []string
+}
+
+func (d ProjectionDiff) IsEmpty() bool {
+	return len(d.Missing) == 0 && len(d.Unexpected) == 0 && len(d.Changed) == 0
+}
+
+// InSync reports whether every table matches its projection.
+func (p Projection) InSync() bool {
+	for _, diff := range p.Diffs {
+		if !diff.IsEmpty() {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+type ImportFormat string
+
+const (
+	ImportCSV    ImportFormat = "csv"
+	ImportNDJSON ImportFormat = "ndjson"
+)
+
+type ImportStatus string
+
+const (
+	ImportPending   ImportStatus = "pending"
+	ImportRunning   ImportStatus = "running"
+	ImportCompleted ImportStatus = "completed"
+	ImportFailed    ImportStatus = "failed"
+)
+
+// ImportJob loads a file of transactions in the background. Processed counts
+// the rows read so far, each either Imported or Failed with an ImportError.
+type ImportJob struct {
+	Id         string
+	ClientID   string
+	TenantID   string
+	Format     ImportFormat
+	Status     ImportStatus
+	Processed  int
+	Imported   int
+	Failed     int
+	Error      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	FinishedAt *time.Time
+}
+
+// ImportError tells why a row was not imported, Row is the record number
+// after the header of csv files and the line number of ndjson files.
+type ImportError struct {
+	JobID   string
+	Row     int
+	Field   string
+	Message string
+	Raw     string
+}
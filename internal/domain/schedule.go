@@ -0,0 +1,72 @@
+package domain
+
+import "time"
+
+type ScheduleFrequency string
+
+const (
+	ScheduleOnce    ScheduleFrequency = "once"
+	ScheduleMonthly ScheduleFrequency = "monthly"
+)
+
+type ScheduleAmount string
+
+const (
+	AmountFixed            ScheduleAmount = "fixed"
+	AmountStatementBalance ScheduleAmount = "statement_balance"
+)
+
+type ScheduleStatus string
+
+const (
+	ScheduleActive    ScheduleStatus = "active"
+	ScheduleCompleted ScheduleStatus = "completed"
+	ScheduleCancelled ScheduleStatus = "cancelled"
+	ScheduleFailed    ScheduleStatus = "failed"
+)
+
+// ScheduledPayment is a payment instruction of an account, posted once at
+// NextRunAt or every month on DayOfMonth, for Amount or for the balance owed
+// by the account when it runs. Attempts counts the failed attempts of the
+// current run.
+type ScheduledPayment struct {
+	Id                string
+	AccountID         string
+	TenantID          string
+	ClientID          string
+	Frequency         ScheduleFrequency
+	DayOfMonth        int
+	AmountType        ScheduleAmount
+	Amount            float64
+	Status            ScheduleStatus
+	NextRunAt         time.Time
+	Attempts          int
+	LastRunAt         *time.Time
+	LastTransactionID int
+	LastError         string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// NextMonthlyRun returns the first midnight UTC on day of a month after
+// after, on the last day of the months shorter than day.
+func NextMonthlyRun(day int, after time.Time) time.Time {
+	after = after.UTC()
+
+	for month := after.Month(); ; month++ {
+		next := time.Date(after.Year(), month, monthDay(after.Year(), month, day), 0, 0, 0, 0, time.UTC)
+		if next.After(after) {
+			return next
+		}
+	}
+}
+
+func monthDay(year int, month time.Month, day int) int {
+	// day 0 of the next month is the last day of month
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day > last {
+		return last
+	}
+
+	return day
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// Spending sums the approved purchases of an account between From and To,
+// by category, or by merchant within Category when it is set. Items are
+// ordered by amount, the largest first.
+type Spending struct {
+	AccountID string
+	From      time.Time
+	To        time.Time
+	Category  string
+	Total     float64
+	Count     int
+	Items     []SpendingItem
+}
+
+// SpendingItem is the Amount and Count of the purchases of one category, or
+// of one merchant named Name with code MCC.
+type SpendingItem struct {
+	Name   string
+	MCC    string
+	Amount float64
+	Count  int
+}
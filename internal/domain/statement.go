@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// Statement is the activity of an account between From, inclusive, and To,
+// exclusive. Only approved transactions are part of it, the balances are the
+// sums of the approved transactions before From and before To.
+type Statement struct {
+	Id             string
+	Account        Account
+	Currency       string
+	BankID         string
+	From           time.Time
+	To             time.Time
+	OpeningBalance float64
+	ClosingBalance float64
+	CreatedAt      time.Time
+}
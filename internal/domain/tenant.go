@@ -0,0 +1,30 @@
+package domain
+
+import operation "github.com/payment-api/internal/enum"
+
+// TenantPolicy restricts the transactions of the accounts of a tenant, empty
+// OperationTypes allow every operation type and a zero MaxAmount any amount.
+type TenantPolicy struct {
+	OperationTypes []operation.Type
+	MaxAmount      float64
+}
+
+// AllowsOperation reports whether the policy allows operationType.
+func (p TenantPolicy) AllowsOperation(operationType operation.Type) bool {
+	if len(p.OperationTypes) == 0 {
+		return true
+	}
+
+	for _, allowed := range p.OperationTypes {
+		if allowed == operationType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowsAmount reports whether the policy allows amount.
+func (p TenantPolicy) AllowsAmount(amount float64) bool {
+	return p.MaxAmount == 0 || amount <= p.MaxAmount
+}
@@ -1,11 +1,64 @@
 package domain
 
-import "github.com/payment-api/internal/enum"
+import (
+	"time"
 
+	"github.com/payment-api/internal/enum"
+)
+
+type Status string
+
+const (
+	StatusApproved      Status = "approved"
+	StatusPendingReview Status = "pending_review"
+	StatusDeclined      Status = "declined"
+	StatusReversed      Status = "reversed"
+)
+
+// StatusFor is the status a transaction is posted with for a fraud decision,
+// transactions to review wait in the queue until an operator resolves them.
+func StatusFor(decision Decision) Status {
+	switch decision {
+	case DecisionDeny:
+		return StatusDeclined
+	case DecisionReview:
+		return StatusPendingReview
+	default:
+		return StatusApproved
+	}
+}
+
+// Transaction is a movement of an account, posted with one of its cards when
+// CardID is set. CardToken is the token a transaction is requested with, it
+// resolves the card and is not stored. Purchases may name their Merchant.
 type Transaction struct {
+	Id            int
 	AccountID     string
 	OperationType operation.Type
 	Amount        float64
+	EventDate     time.Time
+	Decision      Decision
+	Reasons       []string
+	Status        Status
+	ReviewedBy    string
+	ReviewReason  string
+	ReviewedAt    *time.Time
+	CardID        string
+	CardToken     string
+	Merchant      Merchant
+}
+
+// Merchant is where a purchase was made, MCC is its ISO 18245 merchant
+// category code and Country its ISO 3166 alpha-2 code.
+type Merchant struct {
+	Name    string
+	MCC     string
+	City    string
+	Country string
+}
+
+func (m Merchant) IsZero() bool {
+	return m == Merchant{}
 }
 
 func NewTransaction(accountId string, operationType operation.Type, amount float64) Transaction {
@@ -15,3 +68,13 @@ func NewTransaction(accountId string, operationType operation.Type, amount float
 		Amount:        amount,
 	}
 }
+
+// SignedAmount is the amount as it moves the balance of the account,
+// negative for debits.
+func (t Transaction) SignedAmount() float64 {
+	if t.OperationType.IsCredit() {
+		return t.Amount
+	}
+
+	return -t.Amount
+}
@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+// Subscription is a webhook endpoint of an api client, notified of the events
+// of EventTypes, of one account when AccountID is set.
+type Subscription struct {
+	Id         string
+	ClientID   string
+	TenantID   string
+	URL        string
+	EventTypes []EventType
+	AccountID  string
+	Secret     string
+	Active     bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+// Delivery is one event sent to a subscription, retried until the endpoint
+// answers 2xx or it is dead-lettered after the maximum attempts.
+type Delivery struct {
+	Id             string
+	SubscriptionID string
+	EventID        string
+	EventType      EventType
+	Payload        []byte
+	Status         DeliveryStatus
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
@@ -10,6 +10,10 @@ const (
 )
 
 func (t Type) String() string {
+	if !t.IsValid() {
+		return "UNKNOWN"
+	}
+
 	return [...]string{"CASH_PURCHASES", "INSTALLMENT_PURCHASES", "WITHDRAW", "PAYMENT"}[t-1]
 }
 
@@ -24,3 +28,15 @@ func (t Type) IsValid() bool {
 
 	return true
 }
+
+// IsPurchase reports whether the operation is a purchase, cash or in
+// installments.
+func (t Type) IsPurchase() bool {
+	return t == CASH_PURCHASES || t == INSTALLMENT_PURCHASES
+}
+
+// IsCredit reports whether the operation credits the account, payments do
+// while purchases and withdraws debit it.
+func (t Type) IsCredit() bool {
+	return t == PAYMENT
+}
@@ -2,10 +2,19 @@ package usecase
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net/mail"
+	"regexp"
+	"sort"
+	"time"
+	"unicode/utf8"
 
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/payment-api/infrastructure/auth"
 	"github.com/payment-api/infrastructure/exceptions"
 	"github.com/payment-api/infrastructure/logger"
 	"github.com/payment-api/infrastructure/telemetry"
@@ -16,12 +25,35 @@ import (
 type AccountUseCase interface {
 	Create(context.Context, domain.Account) error
 	Get(context.Context, string) (domain.Account, error)
+	Search(context.Context, domain.AccountFilter) ([]domain.Account, error)
+	Update(ctx context.Context, id string, version int, patch func(*domain.Account) error) (domain.Account, error)
+	Close(ctx context.Context, id string, version int) (domain.Account, error)
+	Erase(ctx context.Context, id string, version int) (domain.Account, error)
+	Delete(ctx context.Context, id string, version int) error
 }
 
 type AccountUcImpl struct {
 	accountRepository repository.Account
+	auditor           Auditor
+	publisher         Publisher
+	now               func() time.Time
+	erasureKey        []byte
 }
 
+const (
+	maxHolderName    = 200
+	maxAddressLine   = 200
+	maxPostalCode    = 20
+	maxMetadataKeys  = 50
+	maxMetadataKey   = 40
+	maxMetadataValue = 500
+)
+
+var (
+	phonePattern   = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+	countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
 func (a *AccountUcImpl) Get(ctx context.Context, id string) (domain.Account, error) {
 	ctx, span := telemetry.Span(ctx, "useCase:account:Get", trace.SpanKindInternal)
 	defer span.End()
@@ -29,27 +61,365 @@ func (a *AccountUcImpl) Get(ctx context.Context, id string) (domain.Account, err
 	persistedAccount, err := a.accountRepository.Get(ctx, id)
 	if err != nil {
 		telemetry.ErrorSpan(span, err)
-		logger.Error(logger.ServerError, fmt.Sprintf("account not found error: %v", err.Error()))
-		return domain.Account{}, exceptions.EntityNotFoundError
+		logger.Error(ctx, logger.ServerError, "cannot get account", logger.Str("account_id", id), logger.Err(err))
+		return domain.Account{}, fmt.Errorf("get account %s: %w", id, err)
+	}
+
+	if !auth.CanAccess(ctx, persistedAccount.DocumentNumber) {
+		telemetry.ErrorSpan(span, exceptions.EntityNotFoundError)
+		logger.Warn(ctx, logger.ServerError, "account owned by another caller", logger.Str("account_id", id))
+		return domain.Account{}, exceptions.EntityNotFoundError.WithDetail(fmt.Sprintf("account %s not found", id))
 	}
 
 	return persistedAccount, nil
 }
 
-func (a *AccountUcImpl) Create(ctx context.Context, account domain.Account) error {
+// Search lists the accounts matching filter, bearer token owners only find
+// their own account.
+func (a *AccountUcImpl) Search(ctx context.Context, filter domain.AccountFilter) ([]domain.Account, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:account:Search", trace.SpanKindInternal)
+	defer span.End()
+
+	if principal, ok := auth.PrincipalFrom(ctx); ok && principal.Owner != "" && !principal.Operator {
+		if filter.DocumentNumber != "" && filter.DocumentNumber != principal.Owner {
+			return []domain.Account{}, nil
+		}
+		filter.DocumentNumber = principal.Owner
+	}
+
+	accounts, err := a.accountRepository.Search(ctx, filter)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot search accounts", logger.Err(err))
+		return nil, fmt.Errorf("search accounts: %w", err)
+	}
+
+	return accounts, nil
+}
+
+func (a *AccountUcImpl) Create(ctx context.Context, account domain.Account) (err error) {
 	ctx, span := telemetry.Span(ctx, "useCase:account:Create", trace.SpanKindInternal)
 	defer span.End()
 
-	err := a.accountRepository.Push(ctx, account)
+	account.TenantID = auth.OwnTenant(ctx)
+
+	ctx, finish, err := a.auditor.Begin(ctx)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return err
+	}
+	defer func() { err = finish(err) }()
+
+	defer func() {
+		change := domain.AuditChange{EntityType: domain.AuditAccount, EntityID: account.Id, Action: domain.AuditCreate}
+		if err == nil {
+			change.After = a.audited(account)
+		}
+		a.auditor.Record(ctx, change, err)
+	}()
+
+	if err = ValidateProfile(account.AccountProfile); err != nil {
+		telemetry.ErrorSpan(span, err)
+		return err
+	}
+
+	err = a.accountRepository.Push(ctx, account)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot create account", logger.Err(err))
+		return fmt.Errorf("create account: %w", err)
+	}
+
+	a.publisher.Publish(ctx, domain.NewAccountEvent(domain.EventAccountCreated, account))
+
+	return nil
+}
+
+// Update applies patch to the profile of the account and stores it once
+// validated, provided the account is still at version; a zero version
+// updates whichever version is current. Only the profile survives patch, the
+// identity and status of the account are kept as persisted.
+func (a *AccountUcImpl) Update(ctx context.Context, id string, version int, patch func(*domain.Account) error) (domain.Account, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:account:Update", trace.SpanKindInternal)
+	defer span.End()
+
+	updated, err := a.transition(ctx, id, version, domain.AuditUpdate, func(account *domain.Account) error {
+		if account.IsErased() {
+			return exceptions.InvalidStateError.WithDetail(fmt.Sprintf("account %s is erased", id))
+		}
+
+		patched := *account
+		if err := patch(&patched); err != nil {
+			return err
+		}
+
+		if err := ValidateProfile(patched.AccountProfile); err != nil {
+			return err
+		}
+
+		account.AccountProfile = patched.AccountProfile
+		return nil
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Account{}, err
+	}
+
+	return updated, nil
+}
+
+// Close closes an active account, which then rejects new transactions but
+// keeps its history readable.
+func (a *AccountUcImpl) Close(ctx context.Context, id string, version int) (domain.Account, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:account:Close", trace.SpanKindInternal)
+	defer span.End()
+
+	closed, err := a.transition(ctx, id, version, domain.AuditClose, func(account *domain.Account) error {
+		if !account.IsOpen() {
+			return exceptions.InvalidStateError.WithDetail(fmt.Sprintf("account %s is already closed", id))
+		}
+
+		closedAt := a.now().UTC()
+		account.Status = domain.AccountClosed
+		account.ClosedAt = &closedAt
+		return nil
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Account{}, err
+	}
+
+	a.publisher.Publish(ctx, domain.NewAccountEvent(domain.EventAccountClosed, closed))
+
+	return closed, nil
+}
+
+// Erase replaces the personal data of a closed account: the profile is
+// cleared and the document number becomes a keyed hash of itself, so the
+// transactions stay attached to an account nobody can be identified by.
+func (a *AccountUcImpl) Erase(ctx context.Context, id string, version int) (domain.Account, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:account:Erase", trace.SpanKindInternal)
+	defer span.End()
+
+	if len(a.erasureKey) == 0 {
+		err := exceptions.UnavailableError.WithDetail("account erasure is not configured")
+		telemetry.ErrorSpan(span, err)
+		return domain.Account{}, err
+	}
+
+	erased, err := a.transition(ctx, id, version, domain.AuditErase, func(account *domain.Account) error {
+		if account.IsOpen() {
+			return exceptions.InvalidStateError.WithDetail(fmt.Sprintf("account %s must be closed before erasure", id))
+		}
+		if account.IsErased() {
+			return exceptions.InvalidStateError.WithDetail(fmt.Sprintf("account %s is already erased", id))
+		}
+
+		erasedAt := a.now().UTC()
+		account.DocumentNumber = a.pseudonym(account.DocumentNumber)
+		account.AccountProfile = domain.AccountProfile{}
+		account.ErasedAt = &erasedAt
+		return nil
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Account{}, err
+	}
+
+	return erased, nil
+}
+
+// Delete soft deletes a closed account, hiding it from every read while its
+// row keeps the transactions referencing it valid.
+func (a *AccountUcImpl) Delete(ctx context.Context, id string, version int) error {
+	ctx, span := telemetry.Span(ctx, "useCase:account:Delete", trace.SpanKindInternal)
+	defer span.End()
+
+	_, err := a.transition(ctx, id, version, domain.AuditDelete, func(account *domain.Account) error {
+		if account.IsOpen() {
+			return exceptions.InvalidStateError.WithDetail(fmt.Sprintf("account %s must be closed before deletion", id))
+		}
+
+		deletedAt := a.now().UTC()
+		account.DeletedAt = &deletedAt
+		return nil
+	})
 	if err != nil {
 		telemetry.ErrorSpan(span, err)
-		logger.Error(logger.ServerError, fmt.Sprintf("cannot create account error: %v", err.Error()))
-		return exceptions.PersistenceError
+		return err
 	}
 
 	return nil
 }
 
-func NewAccountUseCase(accountRepository repository.Account) AccountUseCase {
-	return &AccountUcImpl{accountRepository: accountRepository}
+// transition loads the account, applies change to a copy and stores it as
+// the next version, provided the account is still at version.
+func (a *AccountUcImpl) transition(ctx context.Context, id string, version int, action string, change func(*domain.Account) error) (updated domain.Account, err error) {
+	ctx, finish, err := a.auditor.Begin(ctx)
+	if err != nil {
+		return domain.Account{}, err
+	}
+	defer func() {
+		if err = finish(err); err != nil {
+			updated = domain.Account{}
+		}
+	}()
+
+	persisted, err := a.Get(ctx, id)
+	if err != nil {
+		return domain.Account{}, err
+	}
+
+	defer func() {
+		entry := domain.AuditChange{EntityType: domain.AuditAccount, EntityID: id, Action: action, Before: a.audited(persisted)}
+		if err == nil {
+			entry.After = a.audited(updated)
+		}
+		a.auditor.Record(ctx, entry, err)
+	}()
+
+	if version != 0 && version != persisted.Version {
+		return domain.Account{}, exceptions.PreconditionFailedError.WithDetail(fmt.Sprintf("account %s is at version %d", id, persisted.Version))
+	}
+
+	updated = persisted
+	if err = change(&updated); err != nil {
+		return domain.Account{}, err
+	}
+
+	updated.Id = persisted.Id
+	updated.Version = persisted.Version + 1
+	updated.UpdatedAt = a.now().UTC()
+
+	if err = a.accountRepository.Update(ctx, updated, persisted.Version); err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot update account", logger.Str("account_id", id), logger.Str("action", action), logger.Err(err))
+		return domain.Account{}, fmt.Errorf("%s account %s: %w", action, id, err)
+	}
+
+	return updated, nil
+}
+
+// pseudonym derives the document number an erased account keeps, stable for
+// a document so erasures stay unique, unrecoverable without the key.
+func (a *AccountUcImpl) pseudonym(documentNumber string) string {
+	return "erased-" + a.digest(documentNumber)
+}
+
+// audited is the snapshot of account kept by the audit log. The log cannot be
+// edited, so it never holds personal data: the document number is kept as the
+// pseudonym an erasure gives it and the profile as keyed hashes, which still
+// tell its changes apart. Nothing left in the log identifies the holder once
+// the account is erased.
+func (a *AccountUcImpl) audited(account domain.Account) domain.Account {
+	if !account.IsErased() {
+		account.DocumentNumber = a.pseudonym(account.DocumentNumber)
+	}
+
+	account.HolderName = a.reference(account.HolderName)
+	account.Email = a.reference(account.Email)
+	account.Phone = a.reference(account.Phone)
+
+	if account.Address != nil {
+		account.Address = &domain.Address{
+			Line1:      a.reference(account.Address.Line1),
+			Line2:      a.reference(account.Address.Line2),
+			City:       a.reference(account.Address.City),
+			State:      a.reference(account.Address.State),
+			PostalCode: a.reference(account.Address.PostalCode),
+			Country:    a.reference(account.Address.Country),
+		}
+	}
+
+	if account.Metadata != nil {
+		metadata := make(map[string]string, len(account.Metadata))
+		for key, value := range account.Metadata {
+			metadata[key] = a.reference(value)
+		}
+		account.Metadata = metadata
+	}
+
+	return account
+}
+
+// reference is the keyed hash of a personal value, empty values stay empty.
+func (a *AccountUcImpl) reference(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return a.digest(value)
+}
+
+func (a *AccountUcImpl) digest(value string) string {
+	mac := hmac.New(sha256.New, a.erasureKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// ValidateProfile applies the rules shared by account creation and updates,
+// reporting every rejected field at once.
+func ValidateProfile(profile domain.AccountProfile) error {
+	fields := make([]exceptions.FieldError, 0)
+	reject := func(field, message string) {
+		fields = append(fields, exceptions.FieldError{Field: field, Message: message})
+	}
+
+	if utf8.RuneCountInString(profile.HolderName) > maxHolderName {
+		reject("holder_name", fmt.Sprintf("must be at most %d characters", maxHolderName))
+	}
+
+	if profile.Email != "" {
+		if address, err := mail.ParseAddress(profile.Email); err != nil || address.Address != profile.Email {
+			reject("email", "must be a valid email")
+		}
+	}
+
+	if profile.Phone != "" && !phonePattern.MatchString(profile.Phone) {
+		reject("phone", "must be an E.164 number such as +5511912345678")
+	}
+
+	if address := profile.Address; address != nil {
+		for field, value := range map[string]string{"line1": address.Line1, "city": address.City, "country": address.Country} {
+			if value == "" {
+				reject("address."+field, "is required")
+			}
+		}
+		for field, value := range map[string]string{"line1": address.Line1, "line2": address.Line2, "city": address.City, "state": address.State} {
+			if utf8.RuneCountInString(value) > maxAddressLine {
+				reject("address."+field, fmt.Sprintf("must be at most %d characters", maxAddressLine))
+			}
+		}
+		if utf8.RuneCountInString(address.PostalCode) > maxPostalCode {
+			reject("address.postal_code", fmt.Sprintf("must be at most %d characters", maxPostalCode))
+		}
+		if address.Country != "" && !countryPattern.MatchString(address.Country) {
+			reject("address.country", "must be an ISO 3166-1 alpha-2 code")
+		}
+	}
+
+	if len(profile.Metadata) > maxMetadataKeys {
+		reject("metadata", fmt.Sprintf("must have at most %d keys", maxMetadataKeys))
+	}
+	for key, value := range profile.Metadata {
+		if key == "" || utf8.RuneCountInString(key) > maxMetadataKey {
+			reject("metadata", fmt.Sprintf("keys must have between 1 and %d characters", maxMetadataKey))
+		}
+		if utf8.RuneCountInString(value) > maxMetadataValue {
+			reject("metadata."+key, fmt.Sprintf("must be at most %d characters", maxMetadataValue))
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return exceptions.ValidationError.WithFields(fields...)
+}
+
+func NewAccountUseCase(accountRepository repository.Account, auditor Auditor, publisher Publisher, erasureKey string) AccountUseCase {
+	return &AccountUcImpl{
+		accountRepository: accountRepository, auditor: auditor, publisher: publisher, now: time.Now,
+		erasureKey: []byte(erasureKey),
+	}
 }
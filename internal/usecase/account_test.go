@@ -3,29 +3,72 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.opentelemetry.io/otel/sdk/trace"
 
+	"github.com/payment-api/infrastructure/auth"
 	"github.com/payment-api/infrastructure/exceptions"
 	"github.com/payment-api/internal/adapter/repository"
 	"github.com/payment-api/internal/domain"
 )
 
 type accountRepositoryMock struct {
-	Result domain.Account
-	err    error
+	Result   domain.Account
+	err      error
+	missing  map[string]bool
+	closed   map[string]bool
+	tenants  map[string]string
+	searched *domain.AccountFilter
+	updated  *domain.Account
+	pushed   *domain.Account
+	replaced int
+
+	updateErr error
 }
 
 func (r *accountRepositoryMock) Get(_ context.Context, _ string) (domain.Account, error) {
 	return r.Result, r.err
 }
 
-func (r *accountRepositoryMock) Push(_ context.Context, _ domain.Account) error {
+func (r *accountRepositoryMock) Push(_ context.Context, entity domain.Account) error {
+	r.pushed = &entity
 	return r.err
 }
 
+func (r *accountRepositoryMock) Update(_ context.Context, entity domain.Account, version int) error {
+	r.updated = &entity
+	r.replaced = version
+	return r.updateErr
+}
+
+func (r *accountRepositoryMock) Lookup(_ context.Context, ids []string) (map[string]domain.Account, error) {
+	accounts := make(map[string]domain.Account, len(ids))
+	for _, id := range ids {
+		if r.missing[id] {
+			continue
+		}
+		account := domain.Account{Id: id, TenantID: auth.DefaultTenant, Status: domain.AccountActive}
+		if r.closed[id] {
+			account.Status = domain.AccountClosed
+		}
+		if tenant, ok := r.tenants[id]; ok {
+			account.TenantID = tenant
+		}
+		accounts[id] = account
+	}
+	return accounts, r.err
+}
+
+func (r *accountRepositoryMock) Search(_ context.Context, filter domain.AccountFilter) ([]domain.Account, error) {
+	r.searched = &filter
+	return []domain.Account{r.Result}, r.err
+}
+
 func Test_AccountCreateUseCase(t *testing.T) {
 	scenarios := []struct {
 		description   string
@@ -56,10 +99,34 @@ func Test_AccountCreateUseCase(t *testing.T) {
 			},
 			repository: &accountRepositoryMock{
 				Result: domain.Account{},
-				err:    errors.New("any-error"),
+				err:    exceptions.PersistenceError.Wrap(errors.New("any-error")),
 			},
 			expectedError: exceptions.PersistenceError,
 		},
+		{
+			description: "duplicated-document-error",
+			input: domain.Account{
+				Id:             "generated-account-id",
+				DocumentNumber: "any-document",
+			},
+			repository: &accountRepositoryMock{
+				Result: domain.Account{},
+				err:    exceptions.ConflictError.Wrap(errors.New("unique violation")),
+			},
+			expectedError: exceptions.ConflictError,
+		},
+		{
+			description: "database-unavailable-error",
+			input: domain.Account{
+				Id:             "generated-account-id",
+				DocumentNumber: "any-document",
+			},
+			repository: &accountRepositoryMock{
+				Result: domain.Account{},
+				err:    exceptions.UnavailableError.Wrap(errors.New("connection refused")),
+			},
+			expectedError: exceptions.UnavailableError,
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -70,11 +137,15 @@ func Test_AccountCreateUseCase(t *testing.T) {
 			traceProvider := trace.NewTracerProvider(trace.WithSampler(trace.AlwaysSample()))
 			traceProvider.Tracer(ctx.Value("service-name").(string))
 
-			accountUseCase := NewAccountUseCase(scenario.repository)
+			accountUseCase := NewAccountUseCase(scenario.repository, &auditorMock{}, &publisherMock{}, "")
 
 			err := accountUseCase.Create(ctx, scenario.input)
 
-			assert.Equal(t, scenario.expectedError, err)
+			if scenario.expectedError == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, scenario.expectedError)
 		})
 	}
 }
@@ -108,11 +179,21 @@ func Test_AccountGetUseCase(t *testing.T) {
 			input:       "generated-account-id",
 			repository: &accountRepositoryMock{
 				Result: domain.Account{},
-				err:    errors.New("any-error"),
+				err:    exceptions.EntityNotFoundError.Wrap(errors.New("no rows")),
 			},
 			expectedOutput: domain.Account{},
 			expectedError:  exceptions.EntityNotFoundError,
 		},
+		{
+			description: "database-unavailable-error",
+			input:       "generated-account-id",
+			repository: &accountRepositoryMock{
+				Result: domain.Account{},
+				err:    exceptions.UnavailableError.Wrap(errors.New("connection refused")),
+			},
+			expectedOutput: domain.Account{},
+			expectedError:  exceptions.UnavailableError,
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -123,12 +204,593 @@ func Test_AccountGetUseCase(t *testing.T) {
 			traceProvider := trace.NewTracerProvider(trace.WithSampler(trace.AlwaysSample()))
 			traceProvider.Tracer(ctx.Value("service-name").(string))
 
-			accountUseCase := NewAccountUseCase(scenario.repository)
+			accountUseCase := NewAccountUseCase(scenario.repository, &auditorMock{}, &publisherMock{}, "")
 
 			output, err := accountUseCase.Get(ctx, scenario.input)
 
 			assert.Equal(t, scenario.expectedOutput, output)
-			assert.Equal(t, scenario.expectedError, err)
+			if scenario.expectedError == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, scenario.expectedError)
+		})
+	}
+}
+
+func Test_AccountGetOwnershipUseCase(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		principal     auth.Principal
+		expectedError error
+	}{
+		{
+			description: "owner",
+			principal:   auth.Principal{Subject: "user-1", Owner: "any-document"},
+		},
+		{
+			description:   "another-owner",
+			principal:     auth.Principal{Subject: "user-2", Owner: "other-document"},
+			expectedError: exceptions.EntityNotFoundError,
+		},
+		{
+			description: "operator",
+			principal:   auth.Principal{Subject: "operator-1", Operator: true},
+		},
+		{
+			description: "api-key-client",
+			principal:   auth.Principal{ClientID: "partner", KeyID: "any-key"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			ctx := auth.WithPrincipal(context.Background(), scenario.principal)
+
+			accountUseCase := NewAccountUseCase(&accountRepositoryMock{
+				Result: domain.Account{Id: "generated-account-id", DocumentNumber: "any-document"},
+			}, &auditorMock{}, &publisherMock{}, "")
+
+			_, err := accountUseCase.Get(ctx, "generated-account-id")
+
+			if scenario.expectedError == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, scenario.expectedError)
+		})
+	}
+}
+
+func Test_AccountSearchUseCase(t *testing.T) {
+	scenarios := []struct {
+		description    string
+		principal      auth.Principal
+		filter         domain.AccountFilter
+		err            error
+		expectedFilter *domain.AccountFilter
+		expectedLen    int
+		expectedError  error
+	}{
+		{
+			description:    "operator",
+			principal:      auth.Principal{Subject: "operator-1", Operator: true},
+			filter:         domain.AccountFilter{DocumentPrefix: "123", Limit: 50},
+			expectedFilter: &domain.AccountFilter{DocumentPrefix: "123", Limit: 50},
+			expectedLen:    1,
+		},
+		{
+			description:    "api-key-client",
+			principal:      auth.Principal{ClientID: "back-office", KeyID: "any-key"},
+			filter:         domain.AccountFilter{Status: domain.AccountActive, Limit: 50},
+			expectedFilter: &domain.AccountFilter{Status: domain.AccountActive, Limit: 50},
+			expectedLen:    1,
+		},
+		{
+			description:    "owner",
+			principal:      auth.Principal{Subject: "user-1", Owner: "any-document"},
+			filter:         domain.AccountFilter{DocumentPrefix: "any", Limit: 50},
+			expectedFilter: &domain.AccountFilter{DocumentNumber: "any-document", DocumentPrefix: "any", Limit: 50},
+			expectedLen:    1,
+		},
+		{
+			description: "owner searching another document",
+			principal:   auth.Principal{Subject: "user-1", Owner: "any-document"},
+			filter:      domain.AccountFilter{DocumentNumber: "other-document", Limit: 50},
+		},
+		{
+			description:    "database unavailable",
+			principal:      auth.Principal{ClientID: "back-office", KeyID: "any-key"},
+			filter:         domain.AccountFilter{Limit: 50},
+			err:            exceptions.UnavailableError,
+			expectedFilter: &domain.AccountFilter{Limit: 50},
+			expectedError:  exceptions.UnavailableError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			ctx := auth.WithPrincipal(context.Background(), scenario.principal)
+
+			repository := &accountRepositoryMock{
+				Result: domain.Account{Id: "generated-account-id", DocumentNumber: "any-document"},
+				err:    scenario.err,
+			}
+			accountUseCase := NewAccountUseCase(repository, &auditorMock{}, &publisherMock{}, "")
+
+			accounts, err := accountUseCase.Search(ctx, scenario.filter)
+
+			assert.ErrorIs(t, err, scenario.expectedError)
+			assert.Equal(t, scenario.expectedFilter, repository.searched)
+			assert.Len(t, accounts, scenario.expectedLen)
+		})
+	}
+}
+
+func Test_AccountUpdateUseCase(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	persisted := domain.Account{
+		Id:             "generated-account-id",
+		DocumentNumber: "any-document",
+		Status:         domain.AccountActive,
+		AccountProfile: domain.AccountProfile{HolderName: "Ada Lovelace"},
+		Version:        3,
+		CreatedAt:      now.AddDate(0, -1, 0),
+		UpdatedAt:      now.AddDate(0, -1, 0),
+	}
+
+	scenarios := []struct {
+		description    string
+		principal      auth.Principal
+		repository     *accountRepositoryMock
+		version        int
+		patch          func(*domain.Account) error
+		expectedError  error
+		expectedFields []string
+	}{
+		{
+			description: "success",
+			repository:  &accountRepositoryMock{Result: persisted},
+			version:     3,
+			patch: func(account *domain.Account) error {
+				account.Email = "ada@example.com"
+				account.Address = &domain.Address{Line1: "12 St James's Square", City: "London", Country: "GB"}
+				return nil
+			},
+		},
+		{
+			description: "identity is not patchable",
+			repository:  &accountRepositoryMock{Result: persisted},
+			patch: func(account *domain.Account) error {
+				account.Id = "other-account-id"
+				account.DocumentNumber = "other-document"
+				account.Status = domain.AccountClosed
+				account.Email = "ada@example.com"
+				return nil
+			},
+		},
+		{
+			description: "any version",
+			repository:  &accountRepositoryMock{Result: persisted},
+			patch: func(account *domain.Account) error {
+				account.Email = "ada@example.com"
+				return nil
+			},
+		},
+		{
+			description:   "stale version",
+			repository:    &accountRepositoryMock{Result: persisted},
+			version:       2,
+			patch:         func(account *domain.Account) error { account.Email = "ada@example.com"; return nil },
+			expectedError: exceptions.PreconditionFailedError,
+		},
+		{
+			description:   "updated concurrently",
+			repository:    &accountRepositoryMock{Result: persisted, updateErr: exceptions.PreconditionFailedError},
+			version:       3,
+			patch:         func(account *domain.Account) error { account.Email = "ada@example.com"; return nil },
+			expectedError: exceptions.PreconditionFailedError,
+		},
+		{
+			description: "invalid profile",
+			repository:  &accountRepositoryMock{Result: persisted},
+			patch: func(account *domain.Account) error {
+				account.Email = "not an email"
+				account.Address = &domain.Address{City: "London", Country: "gb"}
+				return nil
+			},
+			expectedError:  exceptions.ValidationError,
+			expectedFields: []string{"address.country", "address.line1", "email"},
+		},
+		{
+			description: "patch rejected",
+			repository:  &accountRepositoryMock{Result: persisted},
+			patch: func(*domain.Account) error {
+				return exceptions.ValidationError
+			},
+			expectedError: exceptions.ValidationError,
+		},
+		{
+			description:   "account of another owner",
+			principal:     auth.Principal{Subject: "user-2", Owner: "other-document"},
+			repository:    &accountRepositoryMock{Result: persisted},
+			patch:         func(*domain.Account) error { return nil },
+			expectedError: exceptions.EntityNotFoundError,
+		},
+		{
+			description:   "account not found",
+			repository:    &accountRepositoryMock{err: exceptions.EntityNotFoundError},
+			patch:         func(*domain.Account) error { return nil },
+			expectedError: exceptions.EntityNotFoundError,
+		},
+		{
+			description:   "database unavailable",
+			repository:    &accountRepositoryMock{Result: persisted, updateErr: exceptions.UnavailableError},
+			patch:         func(account *domain.Account) error { account.Phone = "+5511912345678"; return nil },
+			expectedError: exceptions.UnavailableError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			ctx := context.Background()
+			if scenario.principal.Subject != "" {
+				ctx = auth.WithPrincipal(ctx, scenario.principal)
+			}
+
+			auditor := &auditorMock{}
+			accountUseCase := &AccountUcImpl{
+				accountRepository: scenario.repository,
+				auditor:           auditor,
+				publisher:         &publisherMock{},
+				now:               func() time.Time { return now },
+			}
+
+			updated, err := accountUseCase.Update(ctx, "generated-account-id", scenario.version, scenario.patch)
+
+			assert.ErrorIs(t, err, scenario.expectedError)
+			if scenario.expectedFields != nil {
+				var e *exceptions.Error
+				assert.ErrorAs(t, err, &e)
+				fields := make([]string, 0, len(e.Fields))
+				for _, field := range e.Fields {
+					fields = append(fields, field.Field)
+				}
+				assert.Equal(t, scenario.expectedFields, fields)
+			}
+
+			if errors.Is(scenario.expectedError, exceptions.EntityNotFoundError) {
+				assert.Empty(t, auditor.changes)
+				return
+			}
+
+			assert.Len(t, auditor.changes, 1)
+			assert.Equal(t, domain.AuditUpdate, auditor.changes[0].Action)
+			assert.Equal(t, accountUseCase.audited(persisted), auditor.changes[0].Before)
+
+			if scenario.expectedError != nil {
+				assert.Nil(t, auditor.changes[0].After)
+				return
+			}
+
+			assert.Equal(t, "generated-account-id", updated.Id)
+			assert.Equal(t, "any-document", updated.DocumentNumber)
+			assert.Equal(t, domain.AccountActive, updated.Status)
+			assert.Equal(t, "Ada Lovelace", updated.HolderName)
+			assert.Equal(t, "ada@example.com", updated.Email)
+			assert.Equal(t, persisted.CreatedAt, updated.CreatedAt)
+			assert.Equal(t, now, updated.UpdatedAt)
+			assert.Equal(t, 4, updated.Version)
+			assert.Equal(t, 3, scenario.repository.replaced)
+			assert.Equal(t, &updated, scenario.repository.updated)
+			assert.Equal(t, accountUseCase.audited(updated), auditor.changes[0].After)
+		})
+	}
+}
+
+func Test_AccountLifecycleUseCase(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	closedAt := now.AddDate(0, 0, -1)
+	erasedAt := now.AddDate(0, 0, -1)
+	active := domain.Account{
+		Id:             "generated-account-id",
+		DocumentNumber: "any-document",
+		Status:         domain.AccountActive,
+		AccountProfile: domain.AccountProfile{HolderName: "Ada Lovelace", Email: "ada@example.com"},
+		Version:        3,
+	}
+	closed := active
+	closed.Status = domain.AccountClosed
+	closed.ClosedAt = &closedAt
+	erased := closed
+	erased.DocumentNumber = "erased-document"
+	erased.AccountProfile = domain.AccountProfile{}
+	erased.ErasedAt = &erasedAt
+
+	scenarios := []struct {
+		description   string
+		action        string
+		persisted     domain.Account
+		version       int
+		erasureKey    string
+		expectedError error
+		expected      func(domain.Account) domain.Account
+		expectedEvent domain.EventType
+	}{
+		{
+			description: "close",
+			action:      domain.AuditClose,
+			persisted:   active,
+			version:     3,
+			expected: func(account domain.Account) domain.Account {
+				account.Status = domain.AccountClosed
+				account.ClosedAt = &now
+				return account
+			},
+			expectedEvent: domain.EventAccountClosed,
+		},
+		{
+			description:   "close closed account",
+			action:        domain.AuditClose,
+			persisted:     closed,
+			expectedError: exceptions.InvalidStateError,
+		},
+		{
+			description:   "close stale version",
+			action:        domain.AuditClose,
+			persisted:     active,
+			version:       2,
+			expectedError: exceptions.PreconditionFailedError,
+		},
+		{
+			description: "erase",
+			action:      domain.AuditErase,
+			persisted:   closed,
+			erasureKey:  "any-key",
+			expected: func(account domain.Account) domain.Account {
+				account.DocumentNumber = "erased-302af9328ada2a62e81d6d340e2a37c9"
+				account.AccountProfile = domain.AccountProfile{}
+				account.ErasedAt = &now
+				return account
+			},
+		},
+		{
+			description:   "erase active account",
+			action:        domain.AuditErase,
+			persisted:     active,
+			erasureKey:    "any-key",
+			expectedError: exceptions.InvalidStateError,
+		},
+		{
+			description:   "erase erased account",
+			action:        domain.AuditErase,
+			persisted:     erased,
+			erasureKey:    "any-key",
+			expectedError: exceptions.InvalidStateError,
+		},
+		{
+			description:   "erasure not configured",
+			action:        domain.AuditErase,
+			persisted:     closed,
+			expectedError: exceptions.UnavailableError,
+		},
+		{
+			description: "delete",
+			action:      domain.AuditDelete,
+			persisted:   erased,
+			expected: func(account domain.Account) domain.Account {
+				account.DeletedAt = &now
+				return account
+			},
+		},
+		{
+			description:   "delete active account",
+			action:        domain.AuditDelete,
+			persisted:     active,
+			expectedError: exceptions.InvalidStateError,
+		},
+		{
+			description:   "update erased account",
+			action:        domain.AuditUpdate,
+			persisted:     erased,
+			expectedError: exceptions.InvalidStateError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			repository := &accountRepositoryMock{Result: scenario.persisted}
+			auditor := &auditorMock{}
+			publisher := &publisherMock{}
+			accountUseCase := &AccountUcImpl{
+				accountRepository: repository,
+				auditor:           auditor,
+				publisher:         publisher,
+				now:               func() time.Time { return now },
+				erasureKey:        []byte(scenario.erasureKey),
+			}
+
+			var updated domain.Account
+			var err error
+			switch scenario.action {
+			case domain.AuditClose:
+				updated, err = accountUseCase.Close(context.Background(), "generated-account-id", scenario.version)
+			case domain.AuditErase:
+				updated, err = accountUseCase.Erase(context.Background(), "generated-account-id", scenario.version)
+			case domain.AuditDelete:
+				err = accountUseCase.Delete(context.Background(), "generated-account-id", scenario.version)
+			case domain.AuditUpdate:
+				updated, err = accountUseCase.Update(context.Background(), "generated-account-id", scenario.version,
+					func(account *domain.Account) error { account.Email = "ada@example.com"; return nil })
+			}
+
+			assert.ErrorIs(t, err, scenario.expectedError)
+			if errors.Is(scenario.expectedError, exceptions.UnavailableError) {
+				assert.Empty(t, auditor.changes)
+				return
+			}
+
+			assert.Len(t, auditor.changes, 1)
+			assert.Equal(t, scenario.action, auditor.changes[0].Action)
+			assert.Equal(t, accountUseCase.audited(scenario.persisted), auditor.changes[0].Before)
+
+			if scenario.expectedError != nil {
+				assert.Nil(t, repository.updated)
+				assert.Empty(t, publisher.events)
+				return
+			}
+
+			expected := scenario.expected(scenario.persisted)
+			expected.Version = 4
+			expected.UpdatedAt = now
+			assert.Equal(t, &expected, repository.updated)
+			assert.Equal(t, 3, repository.replaced)
+			if scenario.action != domain.AuditDelete {
+				assert.Equal(t, expected, updated)
+			}
+
+			if scenario.expectedEvent == "" {
+				assert.Empty(t, publisher.events)
+				return
+			}
+			assert.Len(t, publisher.events, 1)
+			assert.Equal(t, scenario.expectedEvent, publisher.events[0].Type)
+		})
+	}
+}
+
+func Test_AccountErasureAuditUseCase(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repository := &accountRepositoryMock{}
+	auditor := &auditorMock{}
+	accountUseCase := &AccountUcImpl{
+		accountRepository: repository,
+		auditor:           auditor,
+		publisher:         &publisherMock{},
+		now:               func() time.Time { return now },
+		erasureKey:        []byte("any-key"),
+	}
+	ctx := context.Background()
+
+	err := accountUseCase.Create(ctx, domain.Account{
+		Id:             "generated-account-id",
+		DocumentNumber: "12345678900",
+		Status:         domain.AccountActive,
+		AccountProfile: domain.AccountProfile{HolderName: "Ada Lovelace", Email: "ada@example.com"},
+		Version:        1,
+	})
+	assert.NoError(t, err)
+	repository.Result = *repository.pushed
+
+	updated, err := accountUseCase.Update(ctx, "generated-account-id", 1, func(account *domain.Account) error {
+		account.Phone = "+5511912345678"
+		account.Address = &domain.Address{Line1: "12 St James's Square", City: "London", Country: "GB"}
+		account.Metadata = map[string]string{"nickname": "Ada"}
+		return nil
+	})
+	assert.NoError(t, err)
+	repository.Result = updated
+
+	closed, err := accountUseCase.Close(ctx, "generated-account-id", 0)
+	assert.NoError(t, err)
+	repository.Result = closed
+
+	erased, err := accountUseCase.Erase(ctx, "generated-account-id", 0)
+	assert.NoError(t, err)
+
+	if assert.Len(t, auditor.changes, 4) {
+		assert.Equal(t, erased.DocumentNumber, auditor.changes[0].After.(domain.Account).DocumentNumber,
+			"entries reference the document by the pseudonym it is erased to")
+		assert.NotEqual(t, auditor.changes[1].Before, auditor.changes[1].After, "hashed profiles still tell changes apart")
+	}
+
+	for _, change := range auditor.changes {
+		for _, entity := range []interface{}{change.Before, change.After} {
+			raw, err := snapshot(entity)
+			assert.NoError(t, err)
+			for _, personal := range []string{"12345678900", "Ada", "ada@example.com", "+5511912345678", "St James", "London"} {
+				assert.NotContains(t, string(raw), personal, "%s entry keeps personal data", change.Action)
+			}
+		}
+	}
+}
+
+func Test_ValidateProfile(t *testing.T) {
+	tooLong := func(n int) string { return strings.Repeat("a", n+1) }
+
+	manyKeys := make(map[string]string, 51)
+	for i := 0; i < 51; i++ {
+		manyKeys[fmt.Sprintf("key-%d", i)] = "value"
+	}
+
+	scenarios := []struct {
+		description    string
+		profile        domain.AccountProfile
+		expectedFields []string
+	}{
+		{
+			description: "empty profile",
+		},
+		{
+			description: "complete profile",
+			profile: domain.AccountProfile{
+				HolderName: "Ada Lovelace",
+				Email:      "ada@example.com",
+				Phone:      "+5511912345678",
+				Address:    &domain.Address{Line1: "Av. Paulista, 1000", City: "São Paulo", State: "SP", PostalCode: "01310-100", Country: "BR"},
+				Metadata:   map[string]string{"segment": "premium"},
+			},
+		},
+		{
+			description:    "holder name too long",
+			profile:        domain.AccountProfile{HolderName: tooLong(200)},
+			expectedFields: []string{"holder_name"},
+		},
+		{
+			description:    "email with display name",
+			profile:        domain.AccountProfile{Email: "Ada <ada@example.com>"},
+			expectedFields: []string{"email"},
+		},
+		{
+			description:    "phone without country code",
+			profile:        domain.AccountProfile{Phone: "11912345678"},
+			expectedFields: []string{"phone"},
+		},
+		{
+			description:    "incomplete address",
+			profile:        domain.AccountProfile{Address: &domain.Address{PostalCode: tooLong(20), Country: "Brazil"}},
+			expectedFields: []string{"address.city", "address.country", "address.line1", "address.postal_code"},
+		},
+		{
+			description:    "too many metadata keys",
+			profile:        domain.AccountProfile{Metadata: manyKeys},
+			expectedFields: []string{"metadata"},
+		},
+		{
+			description:    "metadata value too long",
+			profile:        domain.AccountProfile{Metadata: map[string]string{"note": tooLong(500)}},
+			expectedFields: []string{"metadata.note"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			err := ValidateProfile(scenario.profile)
+
+			if scenario.expectedFields == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var e *exceptions.Error
+			assert.ErrorAs(t, err, &e)
+			assert.ErrorIs(t, err, exceptions.ValidationError)
+
+			fields := make([]string, 0, len(e.Fields))
+			for _, field := range e.Fields {
+				fields = append(fields, field.Field)
+			}
+			assert.Equal(t, scenario.expectedFields, fields)
 		})
 	}
 }
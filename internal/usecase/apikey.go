@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/domain"
+)
+
+type APIKeyUseCase interface {
+	Authenticate(ctx context.Context, key string) (auth.Principal, error)
+	Mint(ctx context.Context, clientID string, scopes []auth.Scope, ttl time.Duration) (string, domain.APIKey, error)
+	Rotate(ctx context.Context, id string, overlap time.Duration) (string, domain.APIKey, error)
+	Revoke(ctx context.Context, id string) error
+}
+
+type APIKeyUcImpl struct {
+	apiKeyRepository repository.APIKey
+	tenants          auth.Tenants
+	now              func() time.Time
+}
+
+func (a *APIKeyUcImpl) Authenticate(ctx context.Context, key string) (auth.Principal, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:apiKey:Authenticate", trace.SpanKindInternal)
+	defer span.End()
+
+	// no tenant is known before the key is, the hash alone finds it
+	persistedKey, err := a.apiKeyRepository.GetByHash(auth.WithAnyTenant(ctx), auth.HashAPIKey(key))
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		if errors.Is(err, exceptions.EntityNotFoundError) {
+			return auth.Principal{}, exceptions.UnauthorizedError.WithDetail("unknown api key")
+		}
+		return auth.Principal{}, fmt.Errorf("authenticate api key: %w", err)
+	}
+
+	if !persistedKey.Active(a.now()) {
+		telemetry.ErrorSpan(span, exceptions.UnauthorizedError)
+		logger.Warn(ctx, logger.ServerError, "inactive api key used", logger.Str("key_id", persistedKey.Id))
+		return auth.Principal{}, exceptions.UnauthorizedError.WithDetail("api key expired or revoked")
+	}
+
+	scopes, err := auth.ParseScopes(persistedKey.Scopes)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return auth.Principal{}, exceptions.UnauthorizedError.Wrap(err)
+	}
+
+	return auth.Principal{
+		ClientID: persistedKey.ClientID,
+		KeyID:    persistedKey.Id,
+		Tenant:   a.tenants.Of(persistedKey.ClientID),
+		Scopes:   scopes,
+	}, nil
+}
+
+func (a *APIKeyUcImpl) Mint(ctx context.Context, clientID string, scopes []auth.Scope, ttl time.Duration) (string, domain.APIKey, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:apiKey:Mint", trace.SpanKindInternal)
+	defer span.End()
+
+	if clientID == "" || len(scopes) == 0 {
+		telemetry.ErrorSpan(span, exceptions.InvalidParameterError)
+		return "", domain.APIKey{}, exceptions.InvalidParameterError.WithDetail("client id and at least one scope are required")
+	}
+
+	raw, err := auth.GenerateAPIKey()
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return "", domain.APIKey{}, fmt.Errorf("generate api key: %w", err)
+	}
+
+	values := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		values = append(values, string(scope))
+	}
+
+	key := domain.NewAPIKey(uuid.New().String(), clientID, auth.HashAPIKey(raw), values, a.now(), ttl)
+	key.TenantID = a.tenants.Of(clientID)
+
+	if err := a.apiKeyRepository.Push(ctx, key); err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot create api key", logger.Err(err))
+		return "", domain.APIKey{}, fmt.Errorf("create api key: %w", err)
+	}
+
+	return raw, key, nil
+}
+
+// Rotate mints a key with the same client, scopes and validity, the previous
+// key keeps authenticating for the overlap so callers can roll the new one out.
+func (a *APIKeyUcImpl) Rotate(ctx context.Context, id string, overlap time.Duration) (string, domain.APIKey, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:apiKey:Rotate", trace.SpanKindInternal)
+	defer span.End()
+
+	previous, err := a.apiKeyRepository.Get(ctx, id)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return "", domain.APIKey{}, fmt.Errorf("get api key %s: %w", id, err)
+	}
+
+	if !previous.Active(a.now()) {
+		telemetry.ErrorSpan(span, exceptions.InvalidParameterError)
+		return "", domain.APIKey{}, exceptions.InvalidParameterError.WithDetail("api key expired or revoked")
+	}
+
+	scopes, err := auth.ParseScopes(previous.Scopes)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return "", domain.APIKey{}, exceptions.InvalidParameterError.Wrap(err)
+	}
+
+	raw, key, err := a.Mint(ctx, previous.ClientID, scopes, previous.TTL)
+	if err != nil {
+		return "", domain.APIKey{}, err
+	}
+
+	if err := a.apiKeyRepository.Expire(ctx, previous.Id, a.now().Add(overlap)); err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot expire rotated api key", logger.Str("key_id", previous.Id), logger.Err(err))
+		return "", domain.APIKey{}, fmt.Errorf("expire api key %s: %w", previous.Id, err)
+	}
+
+	return raw, key, nil
+}
+
+func (a *APIKeyUcImpl) Revoke(ctx context.Context, id string) error {
+	ctx, span := telemetry.Span(ctx, "useCase:apiKey:Revoke", trace.SpanKindInternal)
+	defer span.End()
+
+	if err := a.apiKeyRepository.Revoke(ctx, id, a.now()); err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot revoke api key", logger.Str("key_id", id), logger.Err(err))
+		return fmt.Errorf("revoke api key %s: %w", id, err)
+	}
+
+	return nil
+}
+
+func NewAPIKeyUseCase(apiKeyRepository repository.APIKey, tenants auth.Tenants) APIKeyUseCase {
+	return &APIKeyUcImpl{
+		apiKeyRepository: apiKeyRepository,
+		tenants:          tenants,
+		now:              time.Now,
+	}
+}
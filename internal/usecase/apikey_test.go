@@ -0,0 +1,190 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/config"
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/domain"
+)
+
+type apiKeyRepositoryMock struct {
+	keys map[string]domain.APIKey
+	err  error
+}
+
+func (r *apiKeyRepositoryMock) Push(_ context.Context, entity domain.APIKey) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.keys[entity.Id] = entity
+	return nil
+}
+
+func (r *apiKeyRepositoryMock) Get(_ context.Context, id string) (domain.APIKey, error) {
+	key, ok := r.keys[id]
+	if !ok {
+		return domain.APIKey{}, exceptions.EntityNotFoundError
+	}
+	return key, r.err
+}
+
+func (r *apiKeyRepositoryMock) GetByHash(_ context.Context, hash string) (domain.APIKey, error) {
+	if r.err != nil {
+		return domain.APIKey{}, r.err
+	}
+	for _, key := range r.keys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+	return domain.APIKey{}, exceptions.EntityNotFoundError
+}
+
+func (r *apiKeyRepositoryMock) Expire(_ context.Context, id string, at time.Time) error {
+	key := r.keys[id]
+	key.ExpiresAt = &at
+	r.keys[id] = key
+	return r.err
+}
+
+func (r *apiKeyRepositoryMock) Revoke(_ context.Context, id string, at time.Time) error {
+	key := r.keys[id]
+	key.RevokedAt = &at
+	r.keys[id] = key
+	return r.err
+}
+
+func Test_APIKeyAuthenticateUseCase(t *testing.T) {
+	scenarios := []struct {
+		description    string
+		revoke         bool
+		key            string
+		clientID       string
+		repositoryErr  error
+		expectedTenant string
+		expectedError  error
+	}{
+		{
+			description:    "success",
+			clientID:       "any-client",
+			expectedTenant: auth.DefaultTenant,
+		},
+		{
+			description:    "client of a card program",
+			clientID:       "Program-B-Partner",
+			expectedTenant: "program-b",
+		},
+		{
+			description:   "unknown-key",
+			key:           "pk_unknown",
+			expectedError: exceptions.UnauthorizedError,
+		},
+		{
+			description:   "revoked-key",
+			revoke:        true,
+			expectedError: exceptions.UnauthorizedError,
+		},
+		{
+			description:   "database-unavailable-error",
+			repositoryErr: exceptions.UnavailableError.Wrap(errors.New("connection refused")),
+			expectedError: exceptions.UnavailableError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			ctx := context.Background()
+			repository := &apiKeyRepositoryMock{keys: map[string]domain.APIKey{}}
+			apiKeyUseCase := NewAPIKeyUseCase(repository, auth.NewTenants(config.Tenants{
+				"program-b": {Clients: []string{"program-b-partner"}},
+			}))
+
+			if scenario.clientID == "" {
+				scenario.clientID = "any-client"
+			}
+			raw, key, err := apiKeyUseCase.Mint(ctx, scenario.clientID, []auth.Scope{auth.ScopeAccountsRead}, 0)
+			assert.NoError(t, err)
+
+			if scenario.revoke {
+				assert.NoError(t, apiKeyUseCase.Revoke(ctx, key.Id))
+			}
+			if scenario.key != "" {
+				raw = scenario.key
+			}
+			repository.err = scenario.repositoryErr
+
+			principal, err := apiKeyUseCase.Authenticate(ctx, raw)
+
+			if scenario.expectedError != nil {
+				assert.ErrorIs(t, err, scenario.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.clientID, principal.ClientID)
+			assert.Equal(t, scenario.expectedTenant, principal.Tenant)
+			assert.True(t, principal.HasScope(auth.ScopeAccountsRead))
+			assert.False(t, principal.HasScope(auth.ScopeAccountsWrite))
+		})
+	}
+}
+
+func Test_APIKeyRotateUseCase(t *testing.T) {
+	ctx := context.Background()
+	repository := &apiKeyRepositoryMock{keys: map[string]domain.APIKey{}}
+	apiKeyUseCase := NewAPIKeyUseCase(repository, auth.Tenants{})
+
+	previousRaw, previous, err := apiKeyUseCase.Mint(ctx, "any-client", []auth.Scope{auth.ScopeTransactionsWrite}, 0)
+	assert.NoError(t, err)
+
+	rotatedRaw, rotated, err := apiKeyUseCase.Rotate(ctx, previous.Id, time.Hour)
+	assert.NoError(t, err)
+	assert.NotEqual(t, previousRaw, rotatedRaw)
+	assert.Equal(t, previous.Scopes, rotated.Scopes)
+
+	_, err = apiKeyUseCase.Authenticate(ctx, previousRaw)
+	assert.NoError(t, err, "previous key keeps working during the overlap")
+	_, err = apiKeyUseCase.Authenticate(ctx, rotatedRaw)
+	assert.NoError(t, err)
+
+	apiKeyUseCase.(*APIKeyUcImpl).now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	_, err = apiKeyUseCase.Authenticate(ctx, previousRaw)
+	assert.ErrorIs(t, err, exceptions.UnauthorizedError)
+	_, err = apiKeyUseCase.Authenticate(ctx, rotatedRaw)
+	assert.NoError(t, err)
+}
+
+func Test_APIKeyRotateKeepsTTL(t *testing.T) {
+	ctx := context.Background()
+	repository := &apiKeyRepositoryMock{keys: map[string]domain.APIKey{}}
+	apiKeyUseCase := NewAPIKeyUseCase(repository, auth.Tenants{})
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	apiKeyUseCase.(*APIKeyUcImpl).now = func() time.Time { return now }
+
+	_, previous, err := apiKeyUseCase.Mint(ctx, "any-client", []auth.Scope{auth.ScopeTransactionsWrite}, 720*time.Hour)
+	assert.NoError(t, err)
+
+	now = now.Add(24 * time.Hour)
+	_, rotated, err := apiKeyUseCase.Rotate(ctx, previous.Id, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 720*time.Hour, rotated.TTL)
+	if assert.NotNil(t, rotated.ExpiresAt) {
+		assert.Equal(t, now.Add(720*time.Hour), *rotated.ExpiresAt)
+	}
+
+	// rotating again during the overlap keeps the minted ttl, not the overlap
+	_, again, err := apiKeyUseCase.Rotate(ctx, rotated.Id, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 720*time.Hour, again.TTL)
+	if assert.NotNil(t, again.ExpiresAt) {
+		assert.Equal(t, now.Add(720*time.Hour), *again.ExpiresAt)
+	}
+}
@@ -0,0 +1,291 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/audit"
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/requestid"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/domain"
+)
+
+// Auditor records the mutations made by the use cases. A mutation begun with
+// Begin runs in a transaction its changes are recorded in, so it commits with
+// its audit entries or not at all. Savepoint runs a part of a mutation whose
+// failure the mutation outlives.
+type Auditor interface {
+	Begin(ctx context.Context) (context.Context, func(err error) error, error)
+	Savepoint(ctx context.Context, fn func(ctx context.Context) error) error
+	Record(ctx context.Context, change domain.AuditChange, err error)
+}
+
+type auditTxKey struct{}
+
+// auditTx is the transaction of a mutation, the changes recorded in it and
+// what runs once it commits.
+type auditTx struct {
+	tx        repository.Tx
+	changes   []domain.AuditChange
+	committed []func()
+	err       error
+}
+
+// afterCommit runs fn once the mutation of ctx commits, dropping it when the
+// mutation rolls back. Outside of a mutation fn runs right away.
+func afterCommit(ctx context.Context, fn func()) {
+	state, ok := ctx.Value(auditTxKey{}).(*auditTx)
+	if !ok {
+		fn()
+		return
+	}
+
+	state.committed = append(state.committed, fn)
+}
+
+type AuditUseCase interface {
+	Auditor
+	List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error)
+	Verify(ctx context.Context) (domain.AuditVerification, error)
+}
+
+type AuditUcImpl struct {
+	auditRepository repository.Audit
+	now             func() time.Time
+}
+
+// Begin starts the transaction of a mutation, its statements and Record join
+// it through the returned context. The returned finish, called with the
+// outcome of the mutation, commits it, the changes recorded included. When
+// they cannot be, everything is rolled back and the changes are recorded as
+// failed on their own. Mutations begun within another one join it.
+func (a AuditUcImpl) Begin(ctx context.Context) (context.Context, func(err error) error, error) {
+	if _, ok := ctx.Value(auditTxKey{}).(*auditTx); ok {
+		return ctx, func(err error) error { return err }, nil
+	}
+
+	txCtx, tx, err := a.auditRepository.Begin(ctx)
+	if err != nil {
+		return ctx, nil, fmt.Errorf("begin audited transaction: %w", err)
+	}
+
+	state := &auditTx{tx: tx}
+	finish := func(err error) error {
+		if state.err == nil {
+			if state.err = tx.Commit(); state.err == nil {
+				for _, fn := range state.committed {
+					fn()
+				}
+				return err
+			}
+		}
+		_ = tx.Rollback()
+
+		cause := err
+		if cause == nil {
+			cause = fmt.Errorf("record audit entries: %w", state.err)
+		}
+
+		for _, change := range state.changes {
+			a.Record(ctx, change, cause)
+		}
+
+		return cause
+	}
+
+	return context.WithValue(txCtx, auditTxKey{}, state), finish, nil
+}
+
+// Savepoint runs fn within the mutation of ctx and returns its error. When fn
+// leaves the transaction unusable, a statement or an audit entry having
+// failed, its statements are rolled back, the changes it recorded are recorded
+// again as failed and the mutation carries on. Outside of a mutation fn runs
+// on its own.
+func (a AuditUcImpl) Savepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	state, ok := ctx.Value(auditTxKey{}).(*auditTx)
+	if !ok || state.err != nil {
+		return fn(ctx)
+	}
+
+	end, err := a.auditRepository.Savepoint(ctx)
+	if err != nil {
+		state.err = err
+		return fmt.Errorf("set savepoint: %w", err)
+	}
+
+	changes, committed := len(state.changes), len(state.committed)
+	err = fn(ctx)
+
+	rolledBack, endErr := end(state.err != nil)
+	if endErr != nil {
+		state.err = endErr
+		return errors.Join(err, fmt.Errorf("end savepoint: %w", endErr))
+	}
+	if !rolledBack {
+		return err
+	}
+
+	cause := err
+	if cause == nil {
+		cause = fmt.Errorf("record audit entries: %w", state.err)
+	}
+
+	undone := state.changes[changes:]
+	state.changes, state.committed, state.err = state.changes[:changes], state.committed[:committed], nil
+	for _, change := range undone {
+		a.Record(ctx, change, cause)
+	}
+
+	return cause
+}
+
+// Record appends change to the audit log with the caller, route and request
+// of ctx, err being the outcome of the mutation. Within a mutation begun with
+// Begin, a failure to append rolls the mutation back. Otherwise the mutation
+// is already committed at this point, so the failure is logged, not returned.
+func (a AuditUcImpl) Record(ctx context.Context, change domain.AuditChange, err error) {
+	ctx, span := telemetry.Span(ctx, "useCase:audit:Record", trace.SpanKindInternal)
+	defer span.End()
+
+	state, _ := ctx.Value(auditTxKey{}).(*auditTx)
+	if state != nil {
+		state.changes = append(state.changes, change)
+		if state.err != nil {
+			return
+		}
+	}
+
+	entry := domain.AuditEntry{
+		Actor:      auth.Actor(ctx),
+		Route:      audit.RouteFrom(ctx),
+		RequestID:  requestid.From(ctx),
+		EntityType: change.EntityType,
+		EntityID:   change.EntityID,
+		Action:     change.Action,
+		Outcome:    domain.AuditSuccess,
+		CreatedAt:  a.now(),
+	}
+
+	if err != nil {
+		entry.Outcome = domain.AuditFailure
+		entry.ErrorCode = exceptions.InternalError.Code
+
+		var domainError *exceptions.Error
+		if errors.As(err, &domainError) {
+			entry.ErrorCode = domainError.Code
+		}
+	}
+
+	var snapshotErr error
+	entry.Before, snapshotErr = snapshot(change.Before)
+	if snapshotErr == nil {
+		entry.After, snapshotErr = snapshot(change.After)
+	}
+
+	if snapshotErr == nil {
+		_, snapshotErr = a.auditRepository.Append(ctx, entry)
+	}
+
+	if snapshotErr != nil && state != nil {
+		state.err = snapshotErr
+		telemetry.ErrorSpan(span, snapshotErr)
+		return
+	}
+
+	if snapshotErr != nil {
+		telemetry.ErrorSpan(span, snapshotErr)
+		logger.Error(ctx, logger.AuditError, "cannot record audit entry",
+			logger.Str("entity_type", string(change.EntityType)),
+			logger.Str("entity_id", change.EntityID),
+			logger.Str("action", change.Action),
+			logger.Err(snapshotErr),
+		)
+	}
+}
+
+func (a AuditUcImpl) List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:audit:List", trace.SpanKindInternal)
+	defer span.End()
+
+	entries, err := a.auditRepository.List(ctx, filter)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot list audit entries", logger.Err(err))
+		return nil, fmt.Errorf("list audit entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Verify walks the chain from its first entry and reports the first entry
+// whose hash or link to its predecessor does not match.
+func (a AuditUcImpl) Verify(ctx context.Context) (domain.AuditVerification, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:audit:Verify", trace.SpanKindInternal)
+	defer span.End()
+
+	verification := domain.AuditVerification{Valid: true}
+	prevHash := ""
+
+	err := a.auditRepository.Walk(ctx, func(entry domain.AuditEntry) error {
+		if !verification.Valid {
+			return nil
+		}
+
+		verification.Entries++
+
+		if entry.PrevHash != prevHash {
+			verification.Valid, verification.BrokenAt = false, entry.Id
+			verification.Reason = "previous hash does not match the preceding entry"
+			return nil
+		}
+
+		hash, err := entry.ComputeHash()
+		if err != nil {
+			return err
+		}
+
+		if hash != entry.Hash {
+			verification.Valid, verification.BrokenAt = false, entry.Id
+			verification.Reason = "entry content does not match its hash"
+			return nil
+		}
+
+		prevHash = entry.Hash
+		return nil
+	})
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot verify audit log", logger.Err(err))
+		return domain.AuditVerification{}, fmt.Errorf("verify audit log: %w", err)
+	}
+
+	if !verification.Valid {
+		logger.Error(ctx, logger.AuditError, "audit log chain broken",
+			logger.Int("entry_id", int(verification.BrokenAt)),
+			logger.Str("reason", verification.Reason),
+		)
+	}
+
+	return verification, nil
+}
+
+func snapshot(entity interface{}) (json.RawMessage, error) {
+	if entity == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(entity)
+}
+
+func NewAuditUseCase(auditRepository repository.Audit) AuditUseCase {
+	return AuditUcImpl{auditRepository: auditRepository, now: time.Now}
+}
@@ -0,0 +1,363 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/audit"
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/requestid"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/domain"
+)
+
+// auditRepositoryMock chains entries in memory as the postgres repository does.
+// Entries appended in a transaction are only chained once it commits. Once
+// aborted, as by a failed statement, appends in the transaction fail until it
+// is rolled back to a savepoint.
+type auditRepositoryMock struct {
+	entries   []domain.AuditEntry
+	err       error
+	commitErr error
+	tx        *auditTxMock
+	aborted   bool
+}
+
+type auditTxMock struct {
+	repository *auditRepositoryMock
+	pending    []domain.AuditEntry
+	committed  bool
+	rolledBack bool
+}
+
+func (t *auditTxMock) Commit() error {
+	if t.repository.commitErr != nil {
+		return t.repository.commitErr
+	}
+
+	t.committed = true
+	for _, entry := range t.pending {
+		_, _ = t.repository.chain(entry)
+	}
+
+	return nil
+}
+
+func (t *auditTxMock) Rollback() error {
+	if !t.committed {
+		t.rolledBack = true
+	}
+
+	return nil
+}
+
+type auditTxMockKey struct{}
+
+func (r *auditRepositoryMock) Begin(ctx context.Context) (context.Context, repository.Tx, error) {
+	if r.err != nil {
+		return ctx, nil, r.err
+	}
+
+	r.tx = &auditTxMock{repository: r}
+	return context.WithValue(ctx, auditTxMockKey{}, r.tx), r.tx, nil
+}
+
+func (r *auditRepositoryMock) Append(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error) {
+	if r.err != nil {
+		return domain.AuditEntry{}, r.err
+	}
+
+	if tx, ok := ctx.Value(auditTxMockKey{}).(*auditTxMock); ok {
+		if r.aborted {
+			return domain.AuditEntry{}, errors.New("current transaction is aborted")
+		}
+		tx.pending = append(tx.pending, entry)
+		return entry, nil
+	}
+
+	return r.chain(entry)
+}
+
+func (r *auditRepositoryMock) Savepoint(ctx context.Context) (func(rollback bool) (bool, error), error) {
+	tx, ok := ctx.Value(auditTxMockKey{}).(*auditTxMock)
+	if !ok {
+		return func(bool) (bool, error) { return false, nil }, nil
+	}
+
+	pending := len(tx.pending)
+	return func(rollback bool) (bool, error) {
+		if !rollback && !r.aborted {
+			return false, nil
+		}
+		tx.pending, r.aborted = tx.pending[:pending], false
+		return true, nil
+	}, nil
+}
+
+func (r *auditRepositoryMock) chain(entry domain.AuditEntry) (domain.AuditEntry, error) {
+
+	prevHash := ""
+	if len(r.entries) > 0 {
+		prevHash = r.entries[len(r.entries)-1].Hash
+	}
+
+	if err := entry.Seal(prevHash); err != nil {
+		return domain.AuditEntry{}, err
+	}
+
+	entry.Id = int64(len(r.entries) + 1)
+	r.entries = append(r.entries, entry)
+
+	return entry, nil
+}
+
+func (r *auditRepositoryMock) List(context.Context, domain.AuditFilter) ([]domain.AuditEntry, error) {
+	return r.entries, r.err
+}
+
+func (r *auditRepositoryMock) Walk(_ context.Context, fn func(domain.AuditEntry) error) error {
+	for _, entry := range r.entries {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	return r.err
+}
+
+func Test_AuditRecordUseCase(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repository := &auditRepositoryMock{}
+	auditUseCase := AuditUcImpl{auditRepository: repository, now: func() time.Time { return now }}
+
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{ClientID: "back-office"})
+	ctx = requestid.With(ctx, "any-request-id")
+	ctx = audit.WithRoute(ctx, "POST /api/v1/accounts")
+
+	account := domain.NewAccount("any-account-id", "any-document")
+	auditUseCase.Record(ctx, domain.AuditChange{EntityType: domain.AuditAccount, EntityID: account.Id, Action: domain.AuditCreate, After: account}, nil)
+	auditUseCase.Record(ctx, domain.AuditChange{EntityType: domain.AuditAccount, EntityID: account.Id, Action: domain.AuditCreate}, exceptions.ConflictError.Wrap(errors.New("duplicate key")))
+	auditUseCase.Record(ctx, domain.AuditChange{EntityType: domain.AuditAccount, EntityID: account.Id, Action: domain.AuditCreate}, errors.New("any-error"))
+
+	assert.Len(t, repository.entries, 3)
+
+	created := repository.entries[0]
+	assert.Equal(t, "back-office", created.Actor)
+	assert.Equal(t, "POST /api/v1/accounts", created.Route)
+	assert.Equal(t, "any-request-id", created.RequestID)
+	assert.Equal(t, domain.AuditSuccess, created.Outcome)
+	assert.Nil(t, created.Before)
+	snapshot, _ := json.Marshal(account)
+	assert.JSONEq(t, string(snapshot), string(created.After))
+	assert.Equal(t, "", created.PrevHash)
+
+	assert.Equal(t, domain.AuditFailure, repository.entries[1].Outcome)
+	assert.Equal(t, "CONFLICT", repository.entries[1].ErrorCode)
+	assert.Equal(t, created.Hash, repository.entries[1].PrevHash)
+	assert.Equal(t, "INTERNAL_ERROR", repository.entries[2].ErrorCode)
+}
+
+func Test_AuditRecordFailureIsNotFatal(t *testing.T) {
+	auditUseCase := NewAuditUseCase(&auditRepositoryMock{err: exceptions.UnavailableError})
+
+	assert.NotPanics(t, func() {
+		auditUseCase.Record(context.Background(), domain.AuditChange{EntityType: domain.AuditAccount, EntityID: "any-account-id"}, nil)
+	})
+}
+
+func Test_AuditBeginUseCase(t *testing.T) {
+	scenarios := []struct {
+		description      string
+		mutationErr      error
+		commitErr        error
+		expectedErr      string
+		expectedOutcome  domain.AuditOutcome
+		expectedCommit   bool
+		expectedRollback bool
+	}{
+		{
+			description:     "mutation committed with its entry",
+			expectedOutcome: domain.AuditSuccess,
+			expectedCommit:  true,
+		},
+		{
+			description:     "failed mutation committed with its failure",
+			mutationErr:     exceptions.InvalidStateError,
+			expectedErr:     exceptions.InvalidStateError.Error(),
+			expectedOutcome: domain.AuditFailure,
+			expectedCommit:  true,
+		},
+		{
+			description:      "mutation rolled back when its entry cannot commit",
+			commitErr:        errors.New("connection reset"),
+			expectedErr:      "record audit entries: connection reset",
+			expectedOutcome:  domain.AuditFailure,
+			expectedRollback: true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			repository := &auditRepositoryMock{commitErr: scenario.commitErr}
+			auditUseCase := NewAuditUseCase(repository)
+
+			ctx, finish, err := auditUseCase.Begin(context.Background())
+			assert.NoError(t, err)
+
+			nestedCtx, nestedFinish, err := auditUseCase.Begin(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, ctx, nestedCtx)
+			assert.NoError(t, nestedFinish(nil))
+
+			auditUseCase.Record(ctx, domain.AuditChange{EntityType: domain.AuditAccount, EntityID: "any-account-id", Action: domain.AuditCreate}, scenario.mutationErr)
+			assert.Empty(t, repository.entries)
+
+			err = finish(scenario.mutationErr)
+			if scenario.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, scenario.expectedErr)
+			}
+
+			assert.Equal(t, scenario.expectedCommit, repository.tx.committed)
+			assert.Equal(t, scenario.expectedRollback, repository.tx.rolledBack)
+			assert.Len(t, repository.entries, 1)
+			assert.Equal(t, scenario.expectedOutcome, repository.entries[0].Outcome)
+		})
+	}
+}
+
+func Test_AuditSavepointUseCase(t *testing.T) {
+	scenarios := []struct {
+		description      string
+		err              error
+		abort            bool
+		expectedErr      error
+		expectedOutcomes []domain.AuditOutcome
+	}{
+		{
+			description:      "part kept",
+			expectedOutcomes: []domain.AuditOutcome{domain.AuditSuccess, domain.AuditSuccess},
+		},
+		{
+			description:      "failed part kept while the transaction is usable",
+			err:              exceptions.TransactionDeniedError,
+			expectedErr:      exceptions.TransactionDeniedError,
+			expectedOutcomes: []domain.AuditOutcome{domain.AuditFailure, domain.AuditSuccess},
+		},
+		{
+			description:      "part rolled back once it aborted the transaction",
+			err:              exceptions.PersistenceError,
+			abort:            true,
+			expectedErr:      exceptions.PersistenceError,
+			expectedOutcomes: []domain.AuditOutcome{domain.AuditFailure, domain.AuditSuccess},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			repository := &auditRepositoryMock{}
+			auditUseCase := NewAuditUseCase(repository)
+
+			ctx, finish, err := auditUseCase.Begin(context.Background())
+			assert.NoError(t, err)
+
+			err = auditUseCase.Savepoint(ctx, func(ctx context.Context) error {
+				repository.aborted = scenario.abort
+				auditUseCase.Record(ctx, domain.AuditChange{EntityType: domain.AuditTransaction, EntityID: "1", Action: domain.AuditCreate}, scenario.err)
+				return scenario.err
+			})
+			assert.ErrorIs(t, err, scenario.expectedErr)
+
+			auditUseCase.Record(ctx, domain.AuditChange{EntityType: domain.AuditSchedule, EntityID: "any-payment", Action: domain.AuditUpdate}, nil)
+			assert.NoError(t, finish(nil), "the mutation outlives its part")
+
+			assert.True(t, repository.tx.committed)
+			outcomes := make([]domain.AuditOutcome, 0, len(repository.entries))
+			for _, entry := range repository.entries {
+				outcomes = append(outcomes, entry.Outcome)
+			}
+			assert.Equal(t, scenario.expectedOutcomes, outcomes)
+		})
+	}
+}
+
+func Test_AuditVerifyUseCase(t *testing.T) {
+	chain := func() *auditRepositoryMock {
+		repository := &auditRepositoryMock{}
+		auditUseCase := NewAuditUseCase(repository)
+
+		for _, id := range []string{"1", "2", "3"} {
+			auditUseCase.Record(context.Background(), domain.AuditChange{
+				EntityType: domain.AuditTransaction,
+				EntityID:   id,
+				Action:     domain.AuditCreate,
+				After:      domain.Transaction{Amount: 10.5, Status: domain.StatusApproved},
+			}, nil)
+		}
+
+		return repository
+	}
+
+	scenarios := []struct {
+		description      string
+		tamper           func(*auditRepositoryMock)
+		expectedValid    bool
+		expectedBrokenAt int64
+	}{
+		{
+			description:   "untouched chain",
+			tamper:        func(*auditRepositoryMock) {},
+			expectedValid: true,
+		},
+		{
+			description: "snapshot reformatted by jsonb",
+			tamper: func(r *auditRepositoryMock) {
+				var value map[string]interface{}
+				_ = json.Unmarshal(r.entries[1].After, &value)
+				r.entries[1].After, _ = json.MarshalIndent(value, "", "  ")
+			},
+			expectedValid: true,
+		},
+		{
+			description: "edited snapshot",
+			tamper: func(r *auditRepositoryMock) {
+				r.entries[1].After = json.RawMessage(`{"Amount": 9999}`)
+			},
+			expectedBrokenAt: 2,
+		},
+		{
+			description: "edited actor",
+			tamper: func(r *auditRepositoryMock) {
+				r.entries[0].Actor = "someone-else"
+			},
+			expectedBrokenAt: 1,
+		},
+		{
+			description: "removed entry",
+			tamper: func(r *auditRepositoryMock) {
+				r.entries = append(r.entries[:1], r.entries[2:]...)
+			},
+			expectedBrokenAt: 3,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			repository := chain()
+			scenario.tamper(repository)
+
+			verification, err := NewAuditUseCase(repository).Verify(context.Background())
+
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.expectedValid, verification.Valid)
+			assert.Equal(t, scenario.expectedBrokenAt, verification.BrokenAt)
+		})
+	}
+}
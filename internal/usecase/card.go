@@ -0,0 +1,361 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/card"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/domain"
+)
+
+type CardUseCase interface {
+	Issue(ctx context.Context, accountID string) (domain.Card, error)
+	Get(ctx context.Context, accountID, id string) (domain.Card, error)
+	List(ctx context.Context, accountID string) ([]domain.Card, error)
+	Block(ctx context.Context, accountID, id string) (domain.Card, error)
+	Replace(ctx context.Context, accountID, id string) (domain.Card, error)
+	Expire(ctx context.Context) (int, error)
+}
+
+type CardUcImpl struct {
+	cardRepository    repository.Card
+	accountRepository repository.Account
+	auditor           Auditor
+	bin               string
+	validityMonths    int
+	now               func() time.Time
+}
+
+// Issue issues a new card to an open account of the caller. The card number
+// is only returned here, in PAN, the card is stored by token.
+func (c CardUcImpl) Issue(ctx context.Context, accountID string) (issued domain.Card, err error) {
+	ctx, span := telemetry.Span(ctx, "useCase:card:Issue", trace.SpanKindInternal)
+	defer span.End()
+
+	ctx, finish, err := c.auditor.Begin(ctx)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Card{}, err
+	}
+	defer func() { err = finish(err) }()
+
+	change := domain.AuditChange{EntityType: domain.AuditCard, Action: domain.AuditCreate}
+	defer func() { c.auditor.Record(ctx, change, err) }()
+
+	account, err := c.account(ctx, accountID)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Card{}, err
+	}
+
+	if !account.IsOpen() {
+		err = exceptions.AccountClosedError.WithDetail(fmt.Sprintf("account %s is closed", account.Id))
+		telemetry.ErrorSpan(span, err)
+		return domain.Card{}, err
+	}
+
+	issued, err = c.newCard(account)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Card{}, err
+	}
+
+	if err = c.cardRepository.Push(ctx, withoutPAN(issued)); err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot issue card", logger.Str("account_id", accountID), logger.Err(err))
+		return domain.Card{}, fmt.Errorf("issue card: %w", err)
+	}
+	change.EntityID, change.After = issued.Id, audited(issued)
+
+	return issued, nil
+}
+
+func (c CardUcImpl) Get(ctx context.Context, accountID, id string) (domain.Card, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:card:Get", trace.SpanKindInternal)
+	defer span.End()
+
+	issued, err := c.card(ctx, accountID, id)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+	}
+
+	return issued, err
+}
+
+// List returns the cards of an account of the caller, replaced and expired
+// ones included.
+func (c CardUcImpl) List(ctx context.Context, accountID string) ([]domain.Card, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:card:List", trace.SpanKindInternal)
+	defer span.End()
+
+	if _, err := c.account(ctx, accountID); err != nil {
+		telemetry.ErrorSpan(span, err)
+		return nil, err
+	}
+
+	cards, err := c.cardRepository.ListByAccount(ctx, accountID)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot list cards", logger.Str("account_id", accountID), logger.Err(err))
+		return nil, fmt.Errorf("list cards of account %s: %w", accountID, err)
+	}
+
+	return cards, nil
+}
+
+// Block stops an active card, transactions with it are rejected from then on.
+// Blocking is final, a blocked card can only be replaced.
+func (c CardUcImpl) Block(ctx context.Context, accountID, id string) (blocked domain.Card, err error) {
+	ctx, span := telemetry.Span(ctx, "useCase:card:Block", trace.SpanKindInternal)
+	defer span.End()
+
+	ctx, finish, err := c.auditor.Begin(ctx)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Card{}, err
+	}
+	defer func() { err = finish(err) }()
+
+	change := domain.AuditChange{EntityType: domain.AuditCard, EntityID: id, Action: domain.AuditBlock}
+	defer func() { c.auditor.Record(ctx, change, err) }()
+
+	blocked, err = c.card(ctx, accountID, id)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Card{}, err
+	}
+	change.Before = audited(blocked)
+
+	if blocked.Status != domain.CardActive {
+		err = exceptions.InvalidStateError.WithDetail(fmt.Sprintf("card %s is %s", id, blocked.Status))
+		telemetry.ErrorSpan(span, err)
+		return domain.Card{}, err
+	}
+
+	blocked.Status = domain.CardBlocked
+	blocked.UpdatedAt = c.now()
+
+	if err = c.cardRepository.Update(ctx, blocked); err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot block card", logger.Str("card_id", id), logger.Err(err))
+		return domain.Card{}, fmt.Errorf("block card %s: %w", id, err)
+	}
+	change.After = audited(blocked)
+
+	return blocked, nil
+}
+
+// Replace issues a new card in place of an active or blocked one, which is
+// marked replaced and points to its replacement. The replacement is returned
+// with its PAN, as issued cards are.
+func (c CardUcImpl) Replace(ctx context.Context, accountID, id string) (replacement domain.Card, err error) {
+	ctx, span := telemetry.Span(ctx, "useCase:card:Replace", trace.SpanKindInternal)
+	defer span.End()
+
+	ctx, finish, err := c.auditor.Begin(ctx)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Card{}, err
+	}
+	defer func() { err = finish(err) }()
+
+	change := domain.AuditChange{EntityType: domain.AuditCard, EntityID: id, Action: domain.AuditReplace}
+	defer func() { c.auditor.Record(ctx, change, err) }()
+
+	account, err := c.account(ctx, accountID)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Card{}, err
+	}
+
+	replaced, err := c.find(ctx, accountID, id)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Card{}, err
+	}
+	change.Before = audited(replaced)
+
+	if replaced.Status != domain.CardActive && replaced.Status != domain.CardBlocked {
+		err = exceptions.InvalidStateError.WithDetail(fmt.Sprintf("card %s is %s", id, replaced.Status))
+		telemetry.ErrorSpan(span, err)
+		return domain.Card{}, err
+	}
+
+	if !account.IsOpen() {
+		err = exceptions.AccountClosedError.WithDetail(fmt.Sprintf("account %s is closed", account.Id))
+		telemetry.ErrorSpan(span, err)
+		return domain.Card{}, err
+	}
+
+	replacement, err = c.newCard(account)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Card{}, err
+	}
+
+	replaced.Status = domain.CardReplaced
+	replaced.ReplacedBy = replacement.Id
+	replaced.UpdatedAt = replacement.CreatedAt
+
+	if err = c.cardRepository.Replace(ctx, replaced, withoutPAN(replacement)); err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot replace card", logger.Str("card_id", id), logger.Err(err))
+		if errors.Is(err, exceptions.InvalidStateError) {
+			return domain.Card{}, err
+		}
+		return domain.Card{}, fmt.Errorf("replace card %s: %w", id, err)
+	}
+	change.After = audited(replaced)
+
+	c.auditor.Record(ctx, domain.AuditChange{
+		EntityType: domain.AuditCard,
+		EntityID:   replacement.Id,
+		Action:     domain.AuditCreate,
+		After:      audited(replacement),
+	}, nil)
+
+	return replacement, nil
+}
+
+// Expire marks expired the cards past the end of their expiry month and
+// returns how many were.
+func (c CardUcImpl) Expire(ctx context.Context) (_ int, err error) {
+	ctx, span := telemetry.Span(ctx, "useCase:card:Expire", trace.SpanKindInternal)
+	defer span.End()
+
+	ctx, finish, err := c.auditor.Begin(ctx)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return 0, err
+	}
+	defer func() { err = finish(err) }()
+
+	expired, err := c.cardRepository.Expire(ctx, c.now())
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot expire cards", logger.Err(err))
+		return 0, fmt.Errorf("expire cards: %w", err)
+	}
+
+	for _, card := range expired {
+		c.auditor.Record(ctx, domain.AuditChange{
+			EntityType: domain.AuditCard,
+			EntityID:   card.Id,
+			Action:     domain.AuditExpire,
+			After:      audited(card),
+		}, nil)
+	}
+
+	if len(expired) > 0 {
+		logger.Info(ctx, logger.ServerInfo, "cards expired", logger.Int("count", len(expired)))
+	}
+
+	return len(expired), nil
+}
+
+// newCard generates the number and token of a new active card of account,
+// valid through the end of the month validityMonths from now.
+func (c CardUcImpl) newCard(account domain.Account) (domain.Card, error) {
+	pan, err := card.GeneratePAN(c.bin)
+	if err != nil {
+		return domain.Card{}, fmt.Errorf("generate card number: %w", err)
+	}
+
+	token, err := card.GenerateToken()
+	if err != nil {
+		return domain.Card{}, fmt.Errorf("generate card token: %w", err)
+	}
+
+	now := c.now()
+	expiry := time.Date(now.Year(), now.Month()+time.Month(c.validityMonths), 1, 0, 0, 0, 0, time.UTC)
+
+	return domain.Card{
+		Id:          uuid.New().String(),
+		AccountID:   account.Id,
+		TenantID:    account.TenantID,
+		Token:       token,
+		LastFour:    pan[len(pan)-4:],
+		ExpiryMonth: int(expiry.Month()),
+		ExpiryYear:  expiry.Year(),
+		Status:      domain.CardActive,
+		PAN:         pan,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, nil
+}
+
+func withoutPAN(issued domain.Card) domain.Card {
+	issued.PAN = ""
+	return issued
+}
+
+// audited returns card as the audit log keeps it, without its number nor its
+// token, either of which pays with the card.
+func audited(card domain.Card) domain.Card {
+	card.PAN, card.Token = "", ""
+	return card
+}
+
+// account returns an account of the caller, accounts of other owners are not
+// found.
+func (c CardUcImpl) account(ctx context.Context, accountID string) (domain.Account, error) {
+	account, err := c.accountRepository.Get(ctx, accountID)
+	if err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot get card account", logger.Str("account_id", accountID), logger.Err(err))
+		return domain.Account{}, fmt.Errorf("get account %s: %w", accountID, err)
+	}
+
+	if !auth.CanAccess(ctx, account.DocumentNumber) {
+		logger.Warn(ctx, logger.ServerError, "account owned by another caller", logger.Str("account_id", accountID))
+		return domain.Account{}, exceptions.EntityNotFoundError.WithDetail(fmt.Sprintf("account %s not found", accountID))
+	}
+
+	return account, nil
+}
+
+// card returns a card of an account of the caller.
+func (c CardUcImpl) card(ctx context.Context, accountID, id string) (domain.Card, error) {
+	if _, err := c.account(ctx, accountID); err != nil {
+		return domain.Card{}, err
+	}
+
+	return c.find(ctx, accountID, id)
+}
+
+// find returns the card id of accountID, cards of other accounts are not found.
+func (c CardUcImpl) find(ctx context.Context, accountID, id string) (domain.Card, error) {
+	issued, err := c.cardRepository.Get(ctx, id)
+	if err == nil && issued.AccountID != accountID {
+		err = exceptions.EntityNotFoundError
+	}
+	if err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot get card", logger.Str("card_id", id), logger.Err(err))
+		if errors.Is(err, exceptions.EntityNotFoundError) {
+			return domain.Card{}, exceptions.EntityNotFoundError.WithDetail(fmt.Sprintf("card %s not found", id))
+		}
+		return domain.Card{}, fmt.Errorf("get card %s: %w", id, err)
+	}
+
+	return issued, nil
+}
+
+func NewCardUseCase(cardRepository repository.Card, accountRepository repository.Account, auditor Auditor, bin string, validityMonths int) CardUseCase {
+	return CardUcImpl{
+		cardRepository:    cardRepository,
+		accountRepository: accountRepository,
+		auditor:           auditor,
+		bin:               bin,
+		validityMonths:    validityMonths,
+		now:               time.Now,
+	}
+}
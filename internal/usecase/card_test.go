@@ -0,0 +1,251 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/card"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/domain"
+)
+
+type cardRepositoryMock struct {
+	cards    map[string]domain.Card
+	pushed   []domain.Card
+	updated  []domain.Card
+	expired  []domain.Card
+	expireAt time.Time
+	err      error
+}
+
+func (r *cardRepositoryMock) Push(_ context.Context, entity domain.Card) error {
+	r.pushed = append(r.pushed, entity)
+	return r.err
+}
+
+func (r *cardRepositoryMock) Get(_ context.Context, id string) (domain.Card, error) {
+	if c, ok := r.cards[id]; ok {
+		return c, r.err
+	}
+	return domain.Card{}, exceptions.EntityNotFoundError
+}
+
+func (r *cardRepositoryMock) GetByToken(_ context.Context, token string) (domain.Card, error) {
+	for _, c := range r.cards {
+		if c.Token == token {
+			return c, r.err
+		}
+	}
+	return domain.Card{}, exceptions.EntityNotFoundError
+}
+
+func (r *cardRepositoryMock) ListByAccount(_ context.Context, accountID string) ([]domain.Card, error) {
+	cards := make([]domain.Card, 0)
+	for _, c := range r.cards {
+		if c.AccountID == accountID {
+			cards = append(cards, c)
+		}
+	}
+	return cards, r.err
+}
+
+func (r *cardRepositoryMock) Update(_ context.Context, entity domain.Card) error {
+	r.updated = append(r.updated, entity)
+	return r.err
+}
+
+func (r *cardRepositoryMock) Replace(_ context.Context, replaced, replacement domain.Card) error {
+	r.updated = append(r.updated, replaced)
+	r.pushed = append(r.pushed, replacement)
+	return r.err
+}
+
+func (r *cardRepositoryMock) Expire(_ context.Context, now time.Time) ([]domain.Card, error) {
+	r.expireAt = now
+	return r.expired, r.err
+}
+
+var cardNow = time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+func newCardUseCase(cards *cardRepositoryMock, account domain.Account, auditor *auditorMock) CardUcImpl {
+	return CardUcImpl{
+		cardRepository:    cards,
+		accountRepository: &accountRepositoryMock{Result: account},
+		auditor:           auditor,
+		bin:               "400000",
+		validityMonths:    48,
+		now:               func() time.Time { return cardNow },
+	}
+}
+
+func Test_CardIssueUseCase(t *testing.T) {
+	owner := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "user-1", Owner: "any-document"})
+	other := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "user-2", Owner: "other-document"})
+
+	scenarios := []struct {
+		description   string
+		ctx           context.Context
+		account       domain.Account
+		expectedError error
+	}{
+		{
+			description: "issued",
+			ctx:         owner,
+			account:     domain.Account{Id: "any-account", DocumentNumber: "any-document", TenantID: "program-a"},
+		},
+		{
+			description:   "account of another owner",
+			ctx:           other,
+			account:       domain.Account{Id: "any-account", DocumentNumber: "any-document"},
+			expectedError: exceptions.EntityNotFoundError,
+		},
+		{
+			description:   "closed account",
+			ctx:           owner,
+			account:       domain.Account{Id: "any-account", DocumentNumber: "any-document", Status: domain.AccountClosed},
+			expectedError: exceptions.AccountClosedError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			cards := &cardRepositoryMock{}
+			auditor := &auditorMock{}
+
+			issued, err := newCardUseCase(cards, scenario.account, auditor).Issue(scenario.ctx, "any-account")
+
+			assert.Len(t, auditor.changes, 1)
+			if scenario.expectedError != nil {
+				assert.ErrorIs(t, err, scenario.expectedError)
+				assert.Empty(t, cards.pushed)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, card.Luhn(issued.PAN))
+			assert.True(t, strings.HasPrefix(issued.PAN, "400000"))
+			assert.Equal(t, issued.PAN[12:], issued.LastFour)
+			assert.True(t, strings.HasPrefix(issued.Token, "tok_"))
+			assert.Equal(t, domain.CardActive, issued.Status)
+			assert.Equal(t, "program-a", issued.TenantID)
+			assert.Equal(t, 1, issued.ExpiryMonth)
+			assert.Equal(t, 2028, issued.ExpiryYear)
+
+			assert.Len(t, cards.pushed, 1)
+			assert.Empty(t, cards.pushed[0].PAN, "the card number is never stored")
+			assert.Empty(t, auditor.changes[0].After.(domain.Card).PAN, "the card number is never audited")
+			assert.Empty(t, auditor.changes[0].After.(domain.Card).Token, "nor is its token")
+		})
+	}
+}
+
+func Test_CardBlockUseCase(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "user-1", Owner: "any-document"})
+	account := domain.Account{Id: "any-account", DocumentNumber: "any-document"}
+
+	scenarios := []struct {
+		description   string
+		card          domain.Card
+		expectedError error
+	}{
+		{
+			description: "active",
+			card:        domain.Card{Id: "any-card", AccountID: "any-account", Status: domain.CardActive},
+		},
+		{
+			description:   "already blocked",
+			card:          domain.Card{Id: "any-card", AccountID: "any-account", Status: domain.CardBlocked},
+			expectedError: exceptions.InvalidStateError,
+		},
+		{
+			description:   "card of another account",
+			card:          domain.Card{Id: "any-card", AccountID: "other-account", Status: domain.CardActive},
+			expectedError: exceptions.EntityNotFoundError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			cards := &cardRepositoryMock{cards: map[string]domain.Card{"any-card": scenario.card}}
+
+			blocked, err := newCardUseCase(cards, account, &auditorMock{}).Block(ctx, "any-account", "any-card")
+
+			if scenario.expectedError != nil {
+				assert.ErrorIs(t, err, scenario.expectedError)
+				assert.Empty(t, cards.updated)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, domain.CardBlocked, blocked.Status)
+			assert.Equal(t, cardNow, blocked.UpdatedAt)
+			assert.Equal(t, []domain.Card{blocked}, cards.updated)
+		})
+	}
+}
+
+func Test_CardReplaceUseCase(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "user-1", Owner: "any-document"})
+	account := domain.Account{Id: "any-account", DocumentNumber: "any-document"}
+
+	scenarios := []struct {
+		description   string
+		status        domain.CardStatus
+		expectedError error
+	}{
+		{description: "active", status: domain.CardActive},
+		{description: "blocked", status: domain.CardBlocked},
+		{description: "already replaced", status: domain.CardReplaced, expectedError: exceptions.InvalidStateError},
+		{description: "expired", status: domain.CardExpired, expectedError: exceptions.InvalidStateError},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			cards := &cardRepositoryMock{cards: map[string]domain.Card{
+				"any-card": {Id: "any-card", AccountID: "any-account", Token: "tok_old", LastFour: "1111", Status: scenario.status},
+			}}
+			auditor := &auditorMock{}
+
+			replacement, err := newCardUseCase(cards, account, auditor).Replace(ctx, "any-account", "any-card")
+
+			if scenario.expectedError != nil {
+				assert.ErrorIs(t, err, scenario.expectedError)
+				assert.Empty(t, cards.pushed)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotEmpty(t, replacement.PAN)
+			assert.NotEqual(t, "tok_old", replacement.Token)
+			assert.Equal(t, domain.CardActive, replacement.Status)
+
+			assert.Len(t, cards.updated, 1)
+			assert.Equal(t, domain.CardReplaced, cards.updated[0].Status)
+			assert.Equal(t, replacement.Id, cards.updated[0].ReplacedBy)
+
+			assert.Len(t, auditor.changes, 2)
+			assert.Equal(t, domain.AuditReplace, auditor.changes[1].Action)
+			assert.Equal(t, domain.AuditCreate, auditor.changes[0].Action)
+			assert.Equal(t, replacement.Id, auditor.changes[0].EntityID)
+			assert.Empty(t, auditor.changes[1].Before.(domain.Card).Token, "the replaced token is not audited")
+		})
+	}
+}
+
+func Test_CardExpireUseCase(t *testing.T) {
+	cards := &cardRepositoryMock{expired: []domain.Card{{Id: "card-1", Status: domain.CardExpired}, {Id: "card-2", Status: domain.CardExpired}}}
+	auditor := &auditorMock{}
+
+	count, err := newCardUseCase(cards, domain.Account{}, auditor).Expire(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, cardNow, cards.expireAt)
+	assert.Len(t, auditor.changes, 2)
+	assert.Equal(t, domain.AuditExpire, auditor.changes[0].Action)
+}
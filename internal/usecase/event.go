@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/domain"
+)
+
+const replayBatchSize = 500
+
+// Publishers publishes every event to each of its publishers in turn.
+type Publishers []Publisher
+
+func (p Publishers) Publish(ctx context.Context, event domain.Event) {
+	for _, publisher := range p {
+		publisher.Publish(ctx, event)
+	}
+}
+
+// EventUseCase keeps the event log of the accounts and streams it live.
+type EventUseCase interface {
+	Publisher
+	Stream(ctx context.Context, accountID string, lastEventID int64) (<-chan domain.Event, error)
+}
+
+type EventUcImpl struct {
+	eventRepository   repository.Event
+	accountRepository repository.Account
+	hub               *Hub
+	buffer            int
+}
+
+// Publish stores event in the log of its account before notifying the open
+// streams, so they only see events they can resume from. Within a mutation
+// the streams are notified once it commits.
+func (e EventUcImpl) Publish(ctx context.Context, event domain.Event) {
+	ctx, span := telemetry.Span(ctx, "useCase:event:Publish", trace.SpanKindInternal)
+	defer span.End()
+
+	stored, err := e.eventRepository.Push(ctx, event)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot store account event",
+			logger.Str("event_id", event.Id),
+			logger.Str("event_type", string(event.Type)),
+			logger.Err(err),
+		)
+		return
+	}
+
+	afterCommit(ctx, func() { e.hub.Publish(stored) })
+}
+
+// Stream returns the events of accountID published from now on, preceded by
+// the stored ones following lastEventID when it is set. The channel is closed
+// when ctx is done or the stream falls behind, the caller then resumes from
+// the last event received.
+func (e EventUcImpl) Stream(ctx context.Context, accountID string, lastEventID int64) (<-chan domain.Event, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:event:Stream", trace.SpanKindInternal)
+	defer span.End()
+
+	account, err := e.accountRepository.Get(ctx, accountID)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot get account to stream", logger.Str("account_id", accountID), logger.Err(err))
+		return nil, fmt.Errorf("get account %s: %w", accountID, err)
+	}
+
+	if !auth.CanAccess(ctx, account.DocumentNumber) {
+		telemetry.ErrorSpan(span, exceptions.EntityNotFoundError)
+		logger.Warn(ctx, logger.ServerError, "account owned by another caller", logger.Str("account_id", accountID))
+		return nil, exceptions.EntityNotFoundError.WithDetail(fmt.Sprintf("account %s not found", accountID))
+	}
+
+	// subscribing before the replay loses no event published in between,
+	// the live ones already replayed are skipped.
+	live, unsubscribe := e.hub.Subscribe(accountID, e.buffer)
+
+	events := make(chan domain.Event)
+	go e.forward(ctx, accountID, lastEventID, live, unsubscribe, events)
+
+	return events, nil
+}
+
+func (e EventUcImpl) forward(ctx context.Context, accountID string, lastEventID int64, live <-chan domain.Event, unsubscribe func(), events chan<- domain.Event) {
+	defer close(events)
+	defer unsubscribe()
+
+	send := func(event domain.Event) bool {
+		select {
+		case events <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	replayed := lastEventID
+	for lastEventID > 0 {
+		stored, err := e.eventRepository.ListAfter(ctx, accountID, replayed, replayBatchSize)
+		if err != nil {
+			logger.Error(ctx, logger.ServerError, "cannot replay account events", logger.Str("account_id", accountID), logger.Err(err))
+			return
+		}
+
+		for _, event := range stored {
+			if !send(event) {
+				return
+			}
+			replayed = event.Seq
+		}
+
+		if len(stored) < replayBatchSize {
+			break
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-live:
+			if !ok {
+				return
+			}
+			if event.Seq > replayed && !send(event) {
+				return
+			}
+		}
+	}
+}
+
+func NewEventUseCase(eventRepository repository.Event, accountRepository repository.Account, hub *Hub, buffer int) EventUseCase {
+	return EventUcImpl{
+		eventRepository:   eventRepository,
+		accountRepository: accountRepository,
+		hub:               hub,
+		buffer:            buffer,
+	}
+}
@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/domain"
+)
+
+// eventRepositoryMock numbers the pushed events as the account event log.
+type eventRepositoryMock struct {
+	events []domain.Event
+	err    error
+}
+
+func (r *eventRepositoryMock) Push(_ context.Context, event domain.Event) (domain.Event, error) {
+	if r.err != nil {
+		return domain.Event{}, r.err
+	}
+
+	event.Seq = int64(len(r.events) + 1)
+	r.events = append(r.events, event)
+	return event, nil
+}
+
+func (r *eventRepositoryMock) ListAfter(_ context.Context, accountID string, after int64, limit int) ([]domain.Event, error) {
+	events := make([]domain.Event, 0)
+	for _, event := range r.events {
+		if event.AccountID == accountID && event.Seq > after && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, r.err
+}
+
+func accountEvent(accountID string) domain.Event {
+	return domain.NewTransactionEvent(domain.EventTransactionCreated, domain.Transaction{AccountID: accountID})
+}
+
+func receive(t *testing.T, events <-chan domain.Event) domain.Event {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return domain.Event{}
+	}
+}
+
+func Test_HubFanOut(t *testing.T) {
+	hub := NewHub()
+
+	first, unsubscribeFirst := hub.Subscribe("any-account-id", 1)
+	second, unsubscribeSecond := hub.Subscribe("any-account-id", 1)
+	other, unsubscribeOther := hub.Subscribe("other-account-id", 1)
+	defer unsubscribeSecond()
+	defer unsubscribeOther()
+
+	hub.Publish(domain.Event{Seq: 1, AccountID: "any-account-id"})
+
+	assert.Equal(t, int64(1), (<-first).Seq)
+	assert.Equal(t, int64(1), (<-second).Seq)
+	assert.Empty(t, other)
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+	_, open := <-first
+	assert.False(t, open, "unsubscribe closes the stream once")
+
+	hub.Publish(domain.Event{Seq: 2, AccountID: "any-account-id"})
+	hub.Publish(domain.Event{Seq: 3, AccountID: "any-account-id"})
+
+	assert.Equal(t, int64(2), (<-second).Seq)
+	_, open = <-second
+	assert.False(t, open, "a stream behind its buffer is closed")
+}
+
+func Test_EventStreamUseCase(t *testing.T) {
+	events := &eventRepositoryMock{}
+	accounts := &accountRepositoryMock{Result: domain.Account{Id: "any-account-id", DocumentNumber: "any-document"}}
+	useCase := NewEventUseCase(events, accounts, NewHub(), 8)
+
+	useCase.Publish(context.Background(), accountEvent("any-account-id"))
+	useCase.Publish(context.Background(), accountEvent("other-account-id"))
+	useCase.Publish(context.Background(), accountEvent("any-account-id"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := useCase.Stream(ctx, "any-account-id", 1)
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(3), receive(t, stream).Seq, "stored events after Last-Event-ID are replayed")
+
+	useCase.Publish(context.Background(), accountEvent("any-account-id"))
+	assert.Equal(t, int64(4), receive(t, stream).Seq, "then the live ones")
+
+	cancel()
+	for range stream {
+	}
+
+	live, err := useCase.Stream(context.Background(), "any-account-id", 0)
+	assert.NoError(t, err)
+
+	useCase.Publish(context.Background(), accountEvent("any-account-id"))
+	assert.Equal(t, int64(5), receive(t, live).Seq, "without Last-Event-ID only new events are streamed")
+}
+
+func Test_EventStreamUseCaseErrors(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		principal     auth.Principal
+		accounts      *accountRepositoryMock
+		expectedError error
+	}{
+		{
+			description:   "account not found",
+			accounts:      &accountRepositoryMock{err: exceptions.EntityNotFoundError},
+			expectedError: exceptions.EntityNotFoundError,
+		},
+		{
+			description:   "account of another customer",
+			principal:     auth.Principal{Owner: "other-document"},
+			accounts:      &accountRepositoryMock{Result: domain.Account{Id: "any-account-id", DocumentNumber: "any-document"}},
+			expectedError: exceptions.EntityNotFoundError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			useCase := NewEventUseCase(&eventRepositoryMock{}, scenario.accounts, NewHub(), 8)
+
+			_, err := useCase.Stream(auth.WithPrincipal(context.Background(), scenario.principal), "any-account-id", 0)
+
+			assert.ErrorIs(t, err, scenario.expectedError)
+		})
+	}
+}
+
+func Test_EventPublishNotStored(t *testing.T) {
+	hub := NewHub()
+	useCase := NewEventUseCase(&eventRepositoryMock{err: errors.New("connection refused")}, &accountRepositoryMock{}, hub, 8)
+
+	live, unsubscribe := hub.Subscribe("any-account-id", 1)
+	defer unsubscribe()
+
+	useCase.Publish(context.Background(), accountEvent("any-account-id"))
+
+	assert.Empty(t, live, "events that cannot be resumed are not streamed")
+}
+
+func Test_EventPublishAfterCommit(t *testing.T) {
+	scenarios := []struct {
+		description string
+		commitErr   error
+		expected    int
+	}{
+		{description: "streamed once the mutation commits", expected: 1},
+		{description: "not streamed when the mutation rolls back", commitErr: errors.New("connection reset")},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			hub := NewHub()
+			useCase := NewEventUseCase(&eventRepositoryMock{}, &accountRepositoryMock{}, hub, 8)
+			auditor := NewAuditUseCase(&auditRepositoryMock{commitErr: scenario.commitErr})
+
+			live, unsubscribe := hub.Subscribe("any-account-id", 1)
+			defer unsubscribe()
+
+			ctx, finish, err := auditor.Begin(context.Background())
+			assert.NoError(t, err)
+
+			useCase.Publish(ctx, accountEvent("any-account-id"))
+			assert.Empty(t, live, "events are not streamed before they commit")
+
+			_ = finish(nil)
+			assert.Len(t, live, scenario.expected)
+		})
+	}
+}
+
+func Test_Publishers(t *testing.T) {
+	first, second := &publisherMock{}, &publisherMock{}
+
+	Publishers{first, second}.Publish(context.Background(), accountEvent("any-account-id"))
+
+	assert.Len(t, first.events, 1)
+	assert.Len(t, second.events, 1)
+}
@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/domain"
+)
+
+// FraudEngine evaluates the configured rules against a transaction before it
+// is posted. Pending are the transactions accepted before it and not stored
+// yet, counted by the velocity and first transaction rules as stored ones.
+type FraudEngine interface {
+	Evaluate(ctx context.Context, transaction domain.Transaction, pending ...domain.Transaction) (domain.Assessment, error)
+}
+
+type FraudEngineImpl struct {
+	rules                 []domain.FraudRule
+	transactionRepository repository.Transaction
+	now                   func() time.Time
+}
+
+func (f *FraudEngineImpl) Evaluate(ctx context.Context, transaction domain.Transaction, pending ...domain.Transaction) (domain.Assessment, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:fraud:Evaluate", trace.SpanKindInternal)
+	defer span.End()
+
+	assessment := domain.NewAssessment()
+
+	for _, rule := range f.rules {
+		broken, err := f.breaks(ctx, rule, transaction, pending)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			return domain.Assessment{}, fmt.Errorf("evaluate rule %s: %w", rule.Name, err)
+		}
+
+		if broken {
+			assessment.Apply(rule)
+		}
+	}
+
+	return assessment, nil
+}
+
+func (f *FraudEngineImpl) breaks(ctx context.Context, rule domain.FraudRule, transaction domain.Transaction, pending []domain.Transaction) (bool, error) {
+	switch rule.Type {
+	case domain.RuleMaxAmount:
+		if rule.OperationType != 0 && rule.OperationType != transaction.OperationType.Index() {
+			return false, nil
+		}
+		return transaction.Amount > rule.MaxAmount, nil
+	case domain.RuleVelocity:
+		since := f.now().Add(-rule.Window)
+		count, err := f.transactionRepository.Count(ctx, transaction.AccountID, since)
+		if err != nil {
+			return false, err
+		}
+		return count+countPending(pending, transaction.AccountID, since)+1 > rule.MaxCount, nil
+	case domain.RuleFirstTransaction:
+		if transaction.Amount <= rule.MaxAmount {
+			return false, nil
+		}
+		count, err := f.transactionRepository.Count(ctx, transaction.AccountID, time.Time{})
+		if err != nil {
+			return false, err
+		}
+		return count+countPending(pending, transaction.AccountID, time.Time{}) == 0, nil
+	default:
+		return false, fmt.Errorf("unknown rule type %q", rule.Type)
+	}
+}
+
+// countPending counts the pending transactions Count would, the approved ones
+// of accountID dated from since.
+func countPending(pending []domain.Transaction, accountID string, since time.Time) int {
+	count := 0
+	for _, transaction := range pending {
+		if transaction.AccountID == accountID && transaction.Status == domain.StatusApproved && !transaction.EventDate.Before(since) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func validateRule(rule domain.FraudRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("rule without name")
+	}
+
+	if !rule.Decision.IsValid() {
+		return fmt.Errorf("rule %s: invalid decision %q", rule.Name, rule.Decision)
+	}
+
+	switch rule.Type {
+	case domain.RuleMaxAmount, domain.RuleFirstTransaction:
+		if rule.MaxAmount <= 0 {
+			return fmt.Errorf("rule %s: max amount must be positive", rule.Name)
+		}
+	case domain.RuleVelocity:
+		if rule.MaxCount <= 0 || rule.Window <= 0 {
+			return fmt.Errorf("rule %s: max count and window must be positive", rule.Name)
+		}
+	default:
+		return fmt.Errorf("rule %s: unknown type %q", rule.Name, rule.Type)
+	}
+
+	return nil
+}
+
+func NewFraudEngine(rules []domain.FraudRule, transactionRepository repository.Transaction) (FraudEngine, error) {
+	for _, rule := range rules {
+		if err := validateRule(rule); err != nil {
+			return nil, err
+		}
+	}
+
+	return &FraudEngineImpl{
+		rules:                 rules,
+		transactionRepository: transactionRepository,
+		now:                   time.Now,
+	}, nil
+}
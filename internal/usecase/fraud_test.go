@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/internal/domain"
+	"github.com/payment-api/internal/enum"
+)
+
+func Test_FraudEngineEvaluate(t *testing.T) {
+	rules := []domain.FraudRule{
+		{Name: "velocity", Type: domain.RuleVelocity, MaxCount: 3, Window: time.Hour, Decision: domain.DecisionDeny},
+		{Name: "max-cash-purchase", Type: domain.RuleMaxAmount, OperationType: operation.CASH_PURCHASES.Index(), MaxAmount: 1000, Decision: domain.DecisionDeny},
+		{Name: "first-transaction", Type: domain.RuleFirstTransaction, MaxAmount: 100, Decision: domain.DecisionReview},
+		{Name: "large-withdraw", Type: domain.RuleMaxAmount, OperationType: operation.WITHDRAW.Index(), MaxAmount: 500, Decision: domain.DecisionReview},
+	}
+
+	scenarios := []struct {
+		description      string
+		input            domain.Transaction
+		count            int
+		expectedDecision domain.Decision
+		expectedReasons  []string
+	}{
+		{
+			description:      "allow",
+			input:            domain.NewTransaction("any-account-id", operation.CASH_PURCHASES, 50),
+			count:            1,
+			expectedDecision: domain.DecisionAllow,
+			expectedReasons:  []string{},
+		},
+		{
+			description:      "velocity exceeded",
+			input:            domain.NewTransaction("any-account-id", operation.PAYMENT, 50),
+			count:            3,
+			expectedDecision: domain.DecisionDeny,
+			expectedReasons:  []string{"velocity"},
+		},
+		{
+			description:      "max amount for operation type",
+			input:            domain.NewTransaction("any-account-id", operation.CASH_PURCHASES, 1500),
+			count:            1,
+			expectedDecision: domain.DecisionDeny,
+			expectedReasons:  []string{"max-cash-purchase"},
+		},
+		{
+			description:      "max amount of another operation type",
+			input:            domain.NewTransaction("any-account-id", operation.PAYMENT, 1500),
+			count:            1,
+			expectedDecision: domain.DecisionAllow,
+			expectedReasons:  []string{},
+		},
+		{
+			description:      "first transaction of a new account",
+			input:            domain.NewTransaction("any-account-id", operation.PAYMENT, 150),
+			count:            0,
+			expectedDecision: domain.DecisionReview,
+			expectedReasons:  []string{"first-transaction"},
+		},
+		{
+			description:      "large withdraw alert",
+			input:            domain.NewTransaction("any-account-id", operation.WITHDRAW, 800),
+			count:            1,
+			expectedDecision: domain.DecisionReview,
+			expectedReasons:  []string{"large-withdraw"},
+		},
+		{
+			description:      "strictest decision wins",
+			input:            domain.NewTransaction("any-account-id", operation.CASH_PURCHASES, 1500),
+			count:            0,
+			expectedDecision: domain.DecisionDeny,
+			expectedReasons:  []string{"max-cash-purchase", "first-transaction"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			engine, err := NewFraudEngine(rules, &transactionRepositoryMock{count: scenario.count})
+			assert.NoError(t, err)
+
+			assessment, err := engine.Evaluate(context.Background(), scenario.input)
+
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.expectedDecision, assessment.Decision)
+			assert.Equal(t, scenario.expectedReasons, assessment.Reasons)
+		})
+	}
+}
+
+func Test_NewFraudEngineInvalidRules(t *testing.T) {
+	invalid := []domain.FraudRule{
+		{Type: domain.RuleMaxAmount, MaxAmount: 10, Decision: domain.DecisionDeny},
+		{Name: "no-decision", Type: domain.RuleMaxAmount, MaxAmount: 10},
+		{Name: "no-amount", Type: domain.RuleMaxAmount, Decision: domain.DecisionDeny},
+		{Name: "no-window", Type: domain.RuleVelocity, MaxCount: 1, Decision: domain.DecisionDeny},
+		{Name: "unknown", Type: "geo", Decision: domain.DecisionDeny},
+	}
+
+	for _, rule := range invalid {
+		_, err := NewFraudEngine([]domain.FraudRule{rule}, &transactionRepositoryMock{})
+		assert.Error(t, err, rule.Name)
+	}
+}
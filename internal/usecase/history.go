@@ -0,0 +1,70 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/domain"
+)
+
+// HistoryUseCase checks the accounts and transactions tables against the
+// history of the accounts they are projected from, and rebuilds them from it.
+type HistoryUseCase interface {
+	Diff(ctx context.Context) (domain.Projection, error)
+	Rebuild(ctx context.Context) (domain.Projection, error)
+}
+
+type HistoryUcImpl struct {
+	historyRepository repository.History
+}
+
+// Diff replays the history and reports the rows of the tables that differ
+// from it, without changing them.
+func (h HistoryUcImpl) Diff(ctx context.Context) (domain.Projection, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:history:Diff", trace.SpanKindInternal)
+	defer span.End()
+
+	projection, err := h.historyRepository.Replay(ctx, false)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot replay history", logger.Err(err))
+		return domain.Projection{}, fmt.Errorf("replay history: %w", err)
+	}
+
+	return projection, nil
+}
+
+// Rebuild replays the history and writes the missing and changed rows back
+// to the tables, it returns the differences found before they were fixed.
+// Rows no event accounts for are reported but kept, as accounts are never
+// removed and a transaction may still be referenced.
+func (h HistoryUcImpl) Rebuild(ctx context.Context) (domain.Projection, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:history:Rebuild", trace.SpanKindInternal)
+	defer span.End()
+
+	projection, err := h.historyRepository.Replay(ctx, true)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot rebuild projections from history", logger.Err(err))
+		return domain.Projection{}, fmt.Errorf("rebuild projections: %w", err)
+	}
+
+	for _, diff := range projection.Diffs {
+		logger.Info(ctx, logger.ServerInfo, "projection rebuilt from history",
+			logger.Str("table", diff.Table),
+			logger.Int("restored", len(diff.Missing)+len(diff.Changed)),
+			logger.Int("unexpected", len(diff.Unexpected)),
+		)
+	}
+
+	return projection, nil
+}
+
+func NewHistoryUseCase(historyRepository repository.History) HistoryUseCase {
+	return HistoryUcImpl{historyRepository: historyRepository}
+}
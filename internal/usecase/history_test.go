@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/domain"
+)
+
+type historyRepositoryMock struct {
+	projection domain.Projection
+	rebuilt    *bool
+	err        error
+}
+
+func (h historyRepositoryMock) Replay(_ context.Context, rebuild bool) (domain.Projection, error) {
+	*h.rebuilt = rebuild
+	return h.projection, h.err
+}
+
+func Test_HistoryUseCase(t *testing.T) {
+	outOfSync := domain.Projection{Events: 12, Diffs: []domain.ProjectionDiff{
+		{Table: "accounts"},
+		{Table: "transactions", Missing: []string{"7"}, Changed: []domain.ProjectionChange{{Id: "3", Columns: []string{"status"}}}},
+	}}
+
+	scenarios := []struct {
+		description    string
+		rebuild        bool
+		projection     domain.Projection
+		err            error
+		expectedInSync bool
+		expectedErr    error
+	}{
+		{
+			description:    "diff in sync",
+			projection:     domain.Projection{Events: 12, Diffs: []domain.ProjectionDiff{{Table: "accounts"}, {Table: "transactions"}}},
+			expectedInSync: true,
+		},
+		{
+			description: "diff out of sync",
+			projection:  outOfSync,
+		},
+		{
+			description: "rebuild",
+			rebuild:     true,
+			projection:  outOfSync,
+		},
+		{
+			description: "replay failure",
+			err:         exceptions.PersistenceError,
+			expectedErr: exceptions.PersistenceError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			var rebuilt bool
+			historyUseCase := NewHistoryUseCase(historyRepositoryMock{projection: scenario.projection, rebuilt: &rebuilt, err: scenario.err})
+
+			replay := historyUseCase.Diff
+			if scenario.rebuild {
+				replay = historyUseCase.Rebuild
+			}
+
+			projection, err := replay(context.Background())
+
+			assert.ErrorIs(t, err, scenario.expectedErr)
+			assert.Equal(t, scenario.rebuild, rebuilt)
+			if scenario.expectedErr != nil {
+				return
+			}
+
+			assert.Equal(t, scenario.projection, projection)
+			assert.Equal(t, scenario.expectedInSync, projection.InSync())
+		})
+	}
+}
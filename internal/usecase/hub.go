@@ -0,0 +1,66 @@
+package usecase
+
+import (
+	"sync"
+
+	"github.com/payment-api/internal/domain"
+)
+
+// Hub fans the events out, in process, to the streams of their account. A
+// stream falling behind by more than its buffer is closed rather than
+// blocking the publisher, its client resumes from the event log.
+type Hub struct {
+	mu      sync.Mutex
+	streams map[string]map[chan domain.Event]struct{}
+}
+
+// Subscribe opens a stream of the events of accountID, unsubscribe closes it.
+func (h *Hub) Subscribe(accountID string, buffer int) (events <-chan domain.Event, unsubscribe func()) {
+	stream := make(chan domain.Event, buffer)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.streams[accountID] == nil {
+		h.streams[accountID] = make(map[chan domain.Event]struct{})
+	}
+	h.streams[accountID][stream] = struct{}{}
+
+	return stream, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		h.close(accountID, stream)
+	}
+}
+
+func (h *Hub) Publish(event domain.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for stream := range h.streams[event.AccountID] {
+		select {
+		case stream <- event:
+		default:
+			h.close(event.AccountID, stream)
+		}
+	}
+}
+
+// close must hold mu, closing a stream twice does nothing.
+func (h *Hub) close(accountID string, stream chan domain.Event) {
+	if _, ok := h.streams[accountID][stream]; !ok {
+		return
+	}
+
+	delete(h.streams[accountID], stream)
+	if len(h.streams[accountID]) == 0 {
+		delete(h.streams, accountID)
+	}
+
+	close(stream)
+}
+
+func NewHub() *Hub {
+	return &Hub{streams: make(map[string]map[chan domain.Event]struct{})}
+}
@@ -0,0 +1,465 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/domain"
+)
+
+const staleImportReason = "import interrupted"
+
+// ImportLimits bounds the bulk imports, uploads larger than MaxBytes are
+// refused and rows are inserted BatchSize at a time.
+type ImportLimits struct {
+	BatchSize  int
+	MaxBytes   int64
+	SpoolDir   string
+	StaleAfter time.Duration
+}
+
+// ImportUseCase loads files of transactions. Rows are validated and assessed
+// by the fraud rules as single transactions are, the rejected ones are kept
+// in the error report of the job.
+type ImportUseCase interface {
+	Start(ctx context.Context, format domain.ImportFormat, body io.Reader) (domain.ImportJob, error)
+	Run(ctx context.Context, format domain.ImportFormat, body io.Reader) (domain.ImportJob, error)
+	Get(ctx context.Context, id string) (domain.ImportJob, error)
+	Errors(ctx context.Context, id string, fn func(domain.ImportError) error) error
+	Sweep(ctx context.Context) (int, error)
+}
+
+type ImportUcImpl struct {
+	importRepository      repository.Import
+	accountRepository     repository.Account
+	transactionRepository repository.Transaction
+	fraudEngine           FraudEngine
+	auditor               Auditor
+	publisher             Publisher
+	limits                ImportLimits
+	policies              TenantPolicies
+	now                   func() time.Time
+}
+
+// Start spools body to disk and imports it in the background, the returned
+// job is pending and polled with Get.
+func (i ImportUcImpl) Start(ctx context.Context, format domain.ImportFormat, body io.Reader) (domain.ImportJob, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:import:Start", trace.SpanKindInternal)
+	defer span.End()
+
+	if err := validateImportFormat(format); err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.ImportJob{}, err
+	}
+
+	file, err := i.spool(body)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot spool import", logger.Err(err))
+		return domain.ImportJob{}, err
+	}
+
+	job, err := i.create(ctx, format)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		closeSpool(file)
+		return domain.ImportJob{}, err
+	}
+
+	go func() {
+		defer closeSpool(file)
+		i.process(context.WithoutCancel(ctx), job, file)
+	}()
+
+	return job, nil
+}
+
+// Run imports body before returning the finished job, for the command line.
+func (i ImportUcImpl) Run(ctx context.Context, format domain.ImportFormat, body io.Reader) (domain.ImportJob, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:import:Run", trace.SpanKindInternal)
+	defer span.End()
+
+	if err := validateImportFormat(format); err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.ImportJob{}, err
+	}
+
+	job, err := i.create(ctx, format)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.ImportJob{}, err
+	}
+
+	return i.process(ctx, job, body), nil
+}
+
+func (i ImportUcImpl) Get(ctx context.Context, id string) (domain.ImportJob, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:import:Get", trace.SpanKindInternal)
+	defer span.End()
+
+	job, err := i.job(ctx, id)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+	}
+
+	return job, err
+}
+
+// Errors streams the rejected rows of a job to fn in row order.
+func (i ImportUcImpl) Errors(ctx context.Context, id string, fn func(domain.ImportError) error) error {
+	ctx, span := telemetry.Span(ctx, "useCase:import:Errors", trace.SpanKindInternal)
+	defer span.End()
+
+	if _, err := i.job(ctx, id); err != nil {
+		telemetry.ErrorSpan(span, err)
+		return err
+	}
+
+	if err := i.importRepository.WalkErrors(ctx, id, fn); err != nil {
+		telemetry.ErrorSpan(span, err)
+		return fmt.Errorf("walk import errors %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// Sweep fails the jobs no replica updated within the stale delay, their
+// imported rows are kept.
+func (i ImportUcImpl) Sweep(ctx context.Context) (int, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:import:Sweep", trace.SpanKindInternal)
+	defer span.End()
+
+	failed, err := i.importRepository.FailStale(ctx, i.now().Add(-i.limits.StaleAfter), staleImportReason)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot fail stale imports", logger.Err(err))
+		return 0, fmt.Errorf("fail stale imports: %w", err)
+	}
+
+	if failed > 0 {
+		logger.Warn(ctx, logger.ServerError, "stale imports failed", logger.Int("count", failed))
+	}
+
+	return failed, nil
+}
+
+func (i ImportUcImpl) create(ctx context.Context, format domain.ImportFormat) (domain.ImportJob, error) {
+	now := i.now()
+	job := domain.ImportJob{
+		Id:        uuid.New().String(),
+		ClientID:  auth.Actor(ctx),
+		TenantID:  auth.OwnTenant(ctx),
+		Format:    format,
+		Status:    domain.ImportPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := i.importRepository.PushJob(ctx, job); err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot create import job", logger.Err(err))
+		return domain.ImportJob{}, fmt.Errorf("create import job: %w", err)
+	}
+
+	return job, nil
+}
+
+// process reads body batch by batch, the job fails on the first batch that
+// cannot be stored, keeping the batches stored before it.
+func (i ImportUcImpl) process(ctx context.Context, job domain.ImportJob, body io.Reader) domain.ImportJob {
+	ctx, span := telemetry.Span(ctx, "useCase:import:process", trace.SpanKindInternal)
+	defer span.End()
+
+	job.Status = domain.ImportRunning
+	err := i.update(ctx, &job)
+
+	if err == nil {
+		err = i.read(ctx, &job, body)
+	}
+
+	job.Status = domain.ImportCompleted
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "import failed", logger.Str("import_id", job.Id), logger.Err(err))
+		job.Status, job.Error = domain.ImportFailed, err.Error()
+	}
+
+	finishedAt := i.now()
+	job.FinishedAt = &finishedAt
+	if updateErr := i.update(ctx, &job); updateErr != nil && err == nil {
+		err = updateErr
+	}
+
+	i.auditor.Record(ctx, domain.AuditChange{EntityType: domain.AuditImport, EntityID: job.Id, Action: domain.AuditCreate, After: job}, err)
+
+	logger.Info(ctx, logger.ServerInfo, "import finished",
+		logger.Str("import_id", job.Id),
+		logger.Str("status", string(job.Status)),
+		logger.Int("imported", job.Imported),
+		logger.Int("failed", job.Failed),
+	)
+
+	return job
+}
+
+func (i ImportUcImpl) read(ctx context.Context, job *domain.ImportJob, body io.Reader) error {
+	reader, err := newRowReader(job.Format, body)
+	if err != nil {
+		return err
+	}
+
+	batch := make([]importRow, 0, i.limits.BatchSize)
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read import: %w", err)
+		}
+
+		batch = append(batch, row)
+		if len(batch) < i.limits.BatchSize {
+			continue
+		}
+
+		if err := i.store(ctx, job, batch); err != nil {
+			return err
+		}
+		batch = batch[:0]
+	}
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	return i.store(ctx, job, batch)
+}
+
+// store inserts the valid rows of batch and records why the others were
+// rejected. Rows are assessed by the fraud rules against the transactions
+// stored before them, the accepted rows of the batch included, denied ones are
+// stored as declined. The batch is stored with the audit entries of its
+// transactions, its events are published along.
+func (i ImportUcImpl) store(ctx context.Context, job *domain.ImportJob, batch []importRow) (err error) {
+	accepted := make([]importRow, 0, len(batch))
+	rowErrors := make([]domain.ImportError, 0)
+
+	reject := func(row importRow, field exceptions.FieldError) {
+		rowErrors = append(rowErrors, domain.ImportError{JobID: job.Id, Row: row.number, Field: field.Field, Message: field.Message, Raw: row.raw})
+	}
+
+	for _, row := range batch {
+		if row.err != nil {
+			reject(row, *row.err)
+			continue
+		}
+
+		if err := ValidateTransaction(row.transaction); err != nil {
+			reject(row, fieldError(err))
+			continue
+		}
+
+		accepted = append(accepted, row)
+	}
+
+	ids := make([]string, 0, len(accepted))
+	for _, row := range accepted {
+		ids = append(ids, row.transaction.AccountID)
+	}
+
+	accounts, err := i.accountRepository.Lookup(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("check import accounts: %w", err)
+	}
+
+	now := i.now()
+	valid := make([]domain.Transaction, 0, len(accepted))
+	for _, row := range accepted {
+		account, ok := accounts[row.transaction.AccountID]
+		switch {
+		case !ok:
+			reject(row, exceptions.FieldError{Field: "account_id", Message: "account not found"})
+			continue
+		case account.Status == domain.AccountClosed:
+			reject(row, exceptions.FieldError{Field: "account_id", Message: "account is closed"})
+			continue
+		}
+
+		if err := i.policies.Check(account.TenantID, row.transaction); err != nil {
+			reject(row, fieldError(err))
+			continue
+		}
+
+		transaction := row.transaction
+		if transaction.EventDate.IsZero() {
+			transaction.EventDate = now
+		}
+
+		assessment, err := i.fraudEngine.Evaluate(ctx, transaction, valid...)
+		if err != nil {
+			return fmt.Errorf("assess import transactions: %w", err)
+		}
+
+		transaction.Decision = assessment.Decision
+		transaction.Reasons = assessment.Reasons
+		transaction.Status = domain.StatusFor(assessment.Decision)
+		valid = append(valid, transaction)
+	}
+
+	ctx, finish, err := i.auditor.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin import batch: %w", err)
+	}
+
+	updated := *job
+	defer func() {
+		if err = finish(err); err == nil {
+			*job = updated
+		}
+	}()
+
+	if len(valid) > 0 {
+		stored, err := i.transactionRepository.CopyIn(ctx, valid)
+		if err != nil {
+			return fmt.Errorf("copy import transactions: %w", err)
+		}
+
+		for _, transaction := range stored {
+			i.auditor.Record(ctx, domain.AuditChange{EntityType: domain.AuditTransaction, EntityID: strconv.Itoa(transaction.Id), Action: domain.AuditCreate, After: transaction}, nil)
+		}
+
+		i.publish(ctx, stored)
+	}
+
+	if err := i.importRepository.PushErrors(ctx, rowErrors); err != nil {
+		return fmt.Errorf("store import errors: %w", err)
+	}
+
+	updated.Processed += len(batch)
+	updated.Imported += len(valid)
+	updated.Failed += len(rowErrors)
+
+	return i.update(ctx, &updated)
+}
+
+// publish announces the stored transactions of a batch, as Create does.
+func (i ImportUcImpl) publish(ctx context.Context, transactions []domain.Transaction) {
+	for _, transaction := range transactions {
+		eventType := domain.EventTransactionCreated
+		if transaction.Status == domain.StatusDeclined {
+			eventType = domain.EventTransactionDeclined
+		}
+		i.publisher.Publish(ctx, domain.NewTransactionEvent(eventType, transaction))
+
+		if transaction.Decision != domain.DecisionAllow {
+			logger.Warn(ctx, logger.FraudAlert, "imported transaction flagged by fraud rules",
+				logger.Int("transaction_id", transaction.Id),
+				logger.Str("account_id", transaction.AccountID),
+				logger.Str("decision", string(transaction.Decision)),
+				logger.Str("reasons", strings.Join(transaction.Reasons, ",")),
+			)
+		}
+	}
+}
+
+func (i ImportUcImpl) update(ctx context.Context, job *domain.ImportJob) error {
+	job.UpdatedAt = i.now()
+
+	if err := i.importRepository.UpdateJob(ctx, *job); err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot update import job", logger.Str("import_id", job.Id), logger.Err(err))
+		return fmt.Errorf("update import job %s: %w", job.Id, err)
+	}
+
+	return nil
+}
+
+func (i ImportUcImpl) job(ctx context.Context, id string) (domain.ImportJob, error) {
+	job, err := i.importRepository.GetJob(ctx, id)
+	if err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot get import job", logger.Str("import_id", id), logger.Err(err))
+		return domain.ImportJob{}, fmt.Errorf("get import job %s: %w", id, err)
+	}
+
+	principal, ok := auth.PrincipalFrom(ctx)
+	if ok && !principal.HasScope(auth.ScopeAdmin) && auth.Actor(ctx) != job.ClientID {
+		return domain.ImportJob{}, exceptions.EntityNotFoundError.WithDetail(fmt.Sprintf("import %s not found", id))
+	}
+
+	return job, nil
+}
+
+// spool copies body to a temporary file, so the upload is acknowledged
+// before it is imported.
+func (i ImportUcImpl) spool(body io.Reader) (*os.File, error) {
+	file, err := os.CreateTemp(i.limits.SpoolDir, "import-*")
+	if err != nil {
+		return nil, fmt.Errorf("create import spool: %w", err)
+	}
+
+	written, err := io.Copy(file, io.LimitReader(body, i.limits.MaxBytes+1))
+	if err == nil && written > i.limits.MaxBytes {
+		err = exceptions.PayloadTooLargeError.WithDetail(fmt.Sprintf("imports are limited to %d bytes", i.limits.MaxBytes))
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+
+	if err != nil {
+		closeSpool(file)
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func closeSpool(file *os.File) {
+	_ = file.Close()
+	_ = os.Remove(file.Name())
+}
+
+func validateImportFormat(format domain.ImportFormat) error {
+	switch format {
+	case domain.ImportCSV, domain.ImportNDJSON:
+		return nil
+	default:
+		return exceptions.UnsupportedMediaTypeError.WithDetail(fmt.Sprintf("unsupported import format %q", format))
+	}
+}
+
+// fieldError tells the first rejected field of a validation error.
+func fieldError(err error) exceptions.FieldError {
+	var domainError *exceptions.Error
+	if errors.As(err, &domainError) && len(domainError.Fields) > 0 {
+		return domainError.Fields[0]
+	}
+
+	return exceptions.FieldError{Message: err.Error()}
+}
+
+func NewImportUseCase(importRepository repository.Import, accountRepository repository.Account, transactionRepository repository.Transaction, fraudEngine FraudEngine, auditor Auditor, publisher Publisher, limits ImportLimits, policies TenantPolicies) ImportUseCase {
+	return ImportUcImpl{
+		importRepository:      importRepository,
+		accountRepository:     accountRepository,
+		transactionRepository: transactionRepository,
+		fraudEngine:           fraudEngine,
+		auditor:               auditor,
+		publisher:             publisher,
+		limits:                limits,
+		policies:              policies,
+		now:                   time.Now,
+	}
+}
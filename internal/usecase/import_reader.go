@@ -0,0 +1,202 @@
+package usecase
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
+)
+
+const maxImportLine = 1 << 20
+
+// importRow is one parsed row of an import file, err is set when the row
+// could not be parsed into a transaction.
+type importRow struct {
+	number      int
+	raw         string
+	transaction domain.Transaction
+	err         *exceptions.FieldError
+}
+
+// rowReader streams the rows of an import file, it returns io.EOF after the
+// last row and any other error when the file cannot be read further.
+type rowReader interface {
+	Read() (importRow, error)
+}
+
+func newRowReader(format domain.ImportFormat, r io.Reader) (rowReader, error) {
+	switch format {
+	case domain.ImportCSV:
+		return newCSVReader(r)
+	case domain.ImportNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+		return &ndjsonReader{scanner: scanner}, nil
+	default:
+		return nil, exceptions.UnsupportedMediaTypeError.WithDetail(fmt.Sprintf("unsupported import format %q", format))
+	}
+}
+
+// csvReader reads csv files starting with a header naming the account_id,
+// operation_type and amount columns, event_date and the merchant_name,
+// merchant_mcc, merchant_city and merchant_country of purchases are optional.
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	rows    int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, exceptions.ValidationError.WithDetail("csv file is empty")
+	}
+	if err != nil {
+		return nil, exceptions.ValidationError.WithDetail(fmt.Sprintf("cannot read csv header: %s", err))
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	for _, name := range []string{"account_id", "operation_type", "amount"} {
+		if _, ok := columns[name]; !ok {
+			return nil, exceptions.ValidationError.WithDetail(fmt.Sprintf("csv header misses column %s", name))
+		}
+	}
+
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+func (c *csvReader) Read() (importRow, error) {
+	record, err := c.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return importRow{}, io.EOF
+	}
+
+	c.rows++
+	row := importRow{number: c.rows, raw: strings.Join(record, ",")}
+
+	var parseError *csv.ParseError
+	if errors.As(err, &parseError) {
+		row.err = &exceptions.FieldError{Message: parseError.Err.Error()}
+		return row, nil
+	}
+	if err != nil {
+		return importRow{}, err
+	}
+
+	value := func(name string) string {
+		i, ok := c.columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row.transaction, row.err = parseRow(value("account_id"), value("operation_type"), value("amount"), value("event_date"))
+	row.transaction.Merchant = domain.Merchant{
+		Name:    value("merchant_name"),
+		MCC:     value("merchant_mcc"),
+		City:    value("merchant_city"),
+		Country: value("merchant_country"),
+	}
+
+	return row, nil
+}
+
+// ndjsonReader reads one json object per line, with the keys of the csv
+// columns and the merchant as an object, as in the transaction requests.
+// Rows are numbered by line, blank lines are skipped.
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	lines   int
+}
+
+type ndjsonRow struct {
+	AccountID     string     `json:"account_id"`
+	OperationType int        `json:"operation_type"`
+	Amount        float64    `json:"amount"`
+	EventDate     *time.Time `json:"event_date"`
+	Merchant      struct {
+		Name    string `json:"name"`
+		MCC     string `json:"mcc"`
+		City    string `json:"city"`
+		Country string `json:"country"`
+	} `json:"merchant"`
+}
+
+func (n *ndjsonReader) Read() (importRow, error) {
+	for n.scanner.Scan() {
+		n.lines++
+
+		line := strings.TrimSpace(n.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		row := importRow{number: n.lines, raw: line}
+
+		var parsed ndjsonRow
+		if err := json.Unmarshal([]byte(line), &parsed); err != nil {
+			var typeError *json.UnmarshalTypeError
+			if errors.As(err, &typeError) {
+				row.err = &exceptions.FieldError{Field: typeError.Field, Message: fmt.Sprintf("must be of type %s", typeError.Type.String())}
+			} else {
+				row.err = &exceptions.FieldError{Message: "line is not valid json"}
+			}
+			return row, nil
+		}
+
+		row.transaction = domain.NewTransaction(parsed.AccountID, operation.Type(parsed.OperationType), parsed.Amount)
+		if parsed.EventDate != nil {
+			row.transaction.EventDate = *parsed.EventDate
+		}
+		row.transaction.Merchant = domain.Merchant(parsed.Merchant)
+
+		return row, nil
+	}
+
+	if err := n.scanner.Err(); err != nil {
+		return importRow{}, fmt.Errorf("line %d: %w", n.lines+1, err)
+	}
+
+	return importRow{}, io.EOF
+}
+
+func parseRow(accountID, operationType, amount, eventDate string) (domain.Transaction, *exceptions.FieldError) {
+	op, err := strconv.Atoi(operationType)
+	if operationType != "" && err != nil {
+		return domain.Transaction{}, &exceptions.FieldError{Field: "operation_type", Message: "must be an integer"}
+	}
+
+	value, err := strconv.ParseFloat(amount, 64)
+	if amount != "" && (err != nil || math.IsNaN(value) || math.IsInf(value, 0)) {
+		return domain.Transaction{}, &exceptions.FieldError{Field: "amount", Message: "must be a number"}
+	}
+
+	transaction := domain.NewTransaction(accountID, operation.Type(op), value)
+
+	if eventDate != "" {
+		transaction.EventDate, err = time.Parse(time.RFC3339, eventDate)
+		if err != nil {
+			return domain.Transaction{}, &exceptions.FieldError{Field: "event_date", Message: "must be an RFC 3339 timestamp"}
+		}
+	}
+
+	return transaction, nil
+}
@@ -0,0 +1,448 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
+)
+
+// importRepositoryMock keeps jobs and row errors in memory, it is shared with
+// the goroutine of the imports started in the background.
+type importRepositoryMock struct {
+	mu         sync.Mutex
+	jobs       map[string]domain.ImportJob
+	rowErrors  []domain.ImportError
+	staleFrom  time.Time
+	staleCount int
+}
+
+func newImportRepositoryMock(jobs ...domain.ImportJob) *importRepositoryMock {
+	r := &importRepositoryMock{jobs: map[string]domain.ImportJob{}}
+	for _, job := range jobs {
+		r.jobs[job.Id] = job
+	}
+	return r
+}
+
+func (r *importRepositoryMock) PushJob(_ context.Context, entity domain.ImportJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[entity.Id] = entity
+	return nil
+}
+
+func (r *importRepositoryMock) GetJob(_ context.Context, id string) (domain.ImportJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return domain.ImportJob{}, exceptions.EntityNotFoundError
+	}
+	return job, nil
+}
+
+func (r *importRepositoryMock) UpdateJob(ctx context.Context, entity domain.ImportJob) error {
+	return r.PushJob(ctx, entity)
+}
+
+func (r *importRepositoryMock) FailStale(_ context.Context, before time.Time, _ string) (int, error) {
+	r.staleFrom = before
+	return r.staleCount, nil
+}
+
+func (r *importRepositoryMock) PushErrors(_ context.Context, rowErrors []domain.ImportError) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rowErrors = append(r.rowErrors, rowErrors...)
+	return nil
+}
+
+func (r *importRepositoryMock) WalkErrors(_ context.Context, jobID string, fn func(domain.ImportError) error) error {
+	r.mu.Lock()
+	rowErrors := make([]domain.ImportError, 0)
+	for _, rowError := range r.rowErrors {
+		if rowError.JobID == jobID {
+			rowErrors = append(rowErrors, rowError)
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Slice(rowErrors, func(i, j int) bool { return rowErrors[i].Row < rowErrors[j].Row })
+	for _, rowError := range rowErrors {
+		if err := fn(rowError); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newImportUseCase(imports *importRepositoryMock, transactions *transactionRepositoryMock, auditor *auditorMock, limits ImportLimits) ImportUcImpl {
+	return ImportUcImpl{
+		importRepository: imports,
+		accountRepository: &accountRepositoryMock{
+			missing: map[string]bool{"missing-account": true},
+			closed:  map[string]bool{"closed-account": true},
+			tenants: map[string]string{"program-b-account": "program-b"},
+		},
+		transactionRepository: transactions,
+		fraudEngine:           fraudEngineMock{},
+		auditor:               auditor,
+		publisher:             &publisherMock{},
+		limits:                limits,
+		policies:              TenantPolicies{"program-b": {OperationTypes: []operation.Type{operation.PAYMENT}, MaxAmount: 100}},
+		now:                   func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC) },
+	}
+}
+
+func reportOf(t *testing.T, uc ImportUcImpl, id string) []domain.ImportError {
+	rowErrors := make([]domain.ImportError, 0)
+	assert.NoError(t, uc.Errors(context.Background(), id, func(rowError domain.ImportError) error {
+		rowErrors = append(rowErrors, rowError)
+		return nil
+	}))
+	return rowErrors
+}
+
+func Test_ImportRunCSV(t *testing.T) {
+	imports, transactions, auditor := newImportRepositoryMock(), &transactionRepositoryMock{}, &auditorMock{}
+	uc := newImportUseCase(imports, transactions, auditor, ImportLimits{BatchSize: 2})
+
+	body := strings.Join([]string{
+		"Account_ID,operation_type,amount,event_date",
+		"account-1,1,10.5,",
+		"account-1,9,10,",
+		"account-1,4,-1,",
+		"account-1,1,ten,",
+		"missing-account,1,10,",
+		"closed-account,1,10,",
+		"account-1,1,10,yesterday",
+		"account-2,4,99.9,2023-12-31T10:00:00Z",
+		",1,10,",
+	}, "\n")
+
+	job, err := uc.Run(context.Background(), domain.ImportCSV, strings.NewReader(body))
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ImportCompleted, job.Status)
+	assert.Equal(t, 9, job.Processed)
+	assert.Equal(t, 2, job.Imported)
+	assert.Equal(t, 7, job.Failed)
+	assert.NotNil(t, job.FinishedAt)
+
+	persisted, _ := imports.GetJob(context.Background(), job.Id)
+	assert.Equal(t, job, persisted)
+
+	if assert.Len(t, transactions.copied, 2) {
+		assert.Equal(t, "account-1", transactions.copied[0].AccountID)
+		assert.Equal(t, operation.CASH_PURCHASES, transactions.copied[0].OperationType)
+		assert.Equal(t, 10.5, transactions.copied[0].Amount)
+		assert.Equal(t, uc.now(), transactions.copied[0].EventDate, "rows without event_date are dated at import")
+		assert.Equal(t, domain.DecisionAllow, transactions.copied[0].Decision)
+		assert.Equal(t, domain.StatusApproved, transactions.copied[0].Status)
+		assert.Equal(t, time.Date(2023, 12, 31, 10, 0, 0, 0, time.UTC), transactions.copied[1].EventDate)
+	}
+
+	report := reportOf(t, uc, job.Id)
+	fields := make([]string, 0, len(report))
+	for _, rowError := range report {
+		fields = append(fields, rowError.Field)
+	}
+	assert.Equal(t, []string{"operation_type", "amount", "amount", "account_id", "account_id", "event_date", "account_id"}, fields)
+	assert.Equal(t, 2, report[0].Row)
+	assert.Equal(t, "account-1,9,10,", report[0].Raw)
+	assert.Equal(t, "must be one of 1, 2, 3, 4", report[0].Message, "rows are validated as single transactions")
+	assert.Equal(t, "account not found", report[3].Message)
+	assert.Equal(t, "account is closed", report[4].Message)
+
+	if assert.Len(t, auditor.changes, 3) {
+		assert.Equal(t, domain.AuditTransaction, auditor.changes[0].EntityType)
+		assert.Equal(t, "1", auditor.changes[0].EntityID, "imported transactions are audited with their ids")
+		assert.Equal(t, "2", auditor.changes[1].EntityID)
+		assert.Equal(t, domain.AuditImport, auditor.changes[2].EntityType)
+		assert.Equal(t, job.Id, auditor.changes[2].EntityID)
+		assert.NoError(t, auditor.errs[2])
+	}
+}
+
+func Test_ImportRunFraudRules(t *testing.T) {
+	imports, transactions, publisher := newImportRepositoryMock(), &transactionRepositoryMock{count: 1}, &publisherMock{}
+	uc := newImportUseCase(imports, transactions, &auditorMock{}, ImportLimits{BatchSize: 2})
+	uc.publisher = publisher
+
+	engine, err := NewFraudEngine([]domain.FraudRule{
+		{Name: "max-purchase", Type: domain.RuleMaxAmount, Decision: domain.DecisionDeny, MaxAmount: 1000},
+		{Name: "large-withdraw", Type: domain.RuleMaxAmount, Decision: domain.DecisionReview, OperationType: 3, MaxAmount: 500},
+	}, transactions)
+	assert.NoError(t, err)
+	uc.fraudEngine = engine
+
+	body := strings.Join([]string{
+		"account_id,operation_type,amount,merchant_name,merchant_mcc,merchant_city,merchant_country",
+		"account-1,1,10,Corner Shop,5411,Lisbon,PT",
+		"account-1,3,800,,,,",
+		"account-1,1,1500,,,,",
+	}, "\n")
+
+	job, err := uc.Run(context.Background(), domain.ImportCSV, strings.NewReader(body))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, job.Imported, "denied rows are stored as declined")
+
+	if assert.Len(t, transactions.copied, 3) {
+		assert.Equal(t, domain.StatusApproved, transactions.copied[0].Status)
+		assert.Equal(t, domain.Merchant{Name: "Corner Shop", MCC: "5411", City: "Lisbon", Country: "PT"}, transactions.copied[0].Merchant)
+		assert.Equal(t, domain.StatusPendingReview, transactions.copied[1].Status)
+		assert.Equal(t, []string{"large-withdraw"}, transactions.copied[1].Reasons)
+		assert.Equal(t, domain.DecisionDeny, transactions.copied[2].Decision)
+		assert.Equal(t, domain.StatusDeclined, transactions.copied[2].Status)
+	}
+
+	types := make([]domain.EventType, 0, len(publisher.events))
+	for _, event := range publisher.events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []domain.EventType{domain.EventTransactionCreated, domain.EventTransactionCreated, domain.EventTransactionDeclined}, types)
+	if assert.Len(t, publisher.events, 3) {
+		assert.Equal(t, "account-1", publisher.events[0].AccountID)
+	}
+}
+
+func Test_ImportRunFraudRulesWithinBatch(t *testing.T) {
+	imports, transactions := newImportRepositoryMock(), &transactionRepositoryMock{}
+	uc := newImportUseCase(imports, transactions, &auditorMock{}, ImportLimits{BatchSize: 10})
+
+	engine, err := NewFraudEngine([]domain.FraudRule{
+		{Name: "velocity", Type: domain.RuleVelocity, Decision: domain.DecisionDeny, MaxCount: 2, Window: time.Hour},
+		{Name: "first-transaction", Type: domain.RuleFirstTransaction, Decision: domain.DecisionReview, MaxAmount: 100},
+	}, transactions)
+	assert.NoError(t, err)
+	engine.(*FraudEngineImpl).now = uc.now
+	uc.fraudEngine = engine
+
+	body := strings.Join([]string{
+		"account_id,operation_type,amount",
+		"account-1,1,10",
+		"account-1,1,500",
+		"account-1,1,10",
+		"account-2,1,500",
+	}, "\n")
+
+	_, err = uc.Run(context.Background(), domain.ImportCSV, strings.NewReader(body))
+
+	assert.NoError(t, err)
+	if assert.Len(t, transactions.copied, 4) {
+		assert.Equal(t, domain.DecisionAllow, transactions.copied[1].Decision, "earlier rows of the batch are not a first transaction")
+		assert.Equal(t, []string{"velocity"}, transactions.copied[2].Reasons, "earlier rows of the batch count for velocity")
+		assert.Equal(t, []string{"first-transaction"}, transactions.copied[3].Reasons)
+	}
+}
+
+func Test_ImportRunTenantPolicy(t *testing.T) {
+	imports, transactions := newImportRepositoryMock(), &transactionRepositoryMock{}
+	uc := newImportUseCase(imports, transactions, &auditorMock{}, ImportLimits{BatchSize: 10})
+
+	body := strings.Join([]string{
+		"account_id,operation_type,amount",
+		"program-b-account,4,100",
+		"program-b-account,1,10",
+		"program-b-account,4,100.5",
+		"account-1,1,100.5",
+	}, "\n")
+
+	job, err := uc.Run(context.Background(), domain.ImportCSV, strings.NewReader(body))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, job.Imported)
+	assert.Equal(t, 2, job.Failed)
+
+	report := reportOf(t, uc, job.Id)
+	if assert.Len(t, report, 2) {
+		assert.Equal(t, "operation_type", report[0].Field)
+		assert.Equal(t, "is not enabled for the card program", report[0].Message)
+		assert.Equal(t, "amount", report[1].Field)
+		assert.Equal(t, "must be at most 100", report[1].Message)
+	}
+}
+
+func Test_ImportRunNDJSON(t *testing.T) {
+	imports, transactions := newImportRepositoryMock(), &transactionRepositoryMock{}
+	uc := newImportUseCase(imports, transactions, &auditorMock{}, ImportLimits{BatchSize: 10})
+
+	body := strings.Join([]string{
+		`{"account_id":"account-1","operation_type":2,"amount":20}`,
+		``,
+		`{"account_id":"account-1","operation_type":"two","amount":20}`,
+		`{"account_id":`,
+		`{"account_id":"account-1","operation_type":3,"amount":0}`,
+		`{"account_id":"account-1","operation_type":4,"amount":5,"event_date":"2023-12-31T10:00:00Z"}`,
+	}, "\n")
+
+	job, err := uc.Run(context.Background(), domain.ImportNDJSON, strings.NewReader(body))
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ImportCompleted, job.Status)
+	assert.Equal(t, 5, job.Processed, "blank lines are not rows")
+	assert.Equal(t, 2, job.Imported)
+	assert.Equal(t, 3, job.Failed)
+
+	report := reportOf(t, uc, job.Id)
+	if assert.Len(t, report, 3) {
+		assert.Equal(t, domain.ImportError{JobID: job.Id, Row: 3, Field: "operation_type", Message: "must be of type int", Raw: `{"account_id":"account-1","operation_type":"two","amount":20}`}, report[0])
+		assert.Equal(t, 4, report[1].Row, "rows are numbered by line")
+		assert.Equal(t, "line is not valid json", report[1].Message)
+		assert.Equal(t, "amount", report[2].Field)
+	}
+}
+
+func Test_ImportRunFailures(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		format        domain.ImportFormat
+		body          string
+		copyErr       error
+		expectedError error
+		expectedJob   string
+	}{
+		{
+			description:   "unsupported format",
+			format:        "xml",
+			body:          "<transactions/>",
+			expectedError: exceptions.UnsupportedMediaTypeError,
+		},
+		{
+			description: "csv header without amount",
+			format:      domain.ImportCSV,
+			body:        "account_id,operation_type\naccount-1,1",
+			expectedJob: "csv header misses column amount",
+		},
+		{
+			description: "empty csv",
+			format:      domain.ImportCSV,
+			expectedJob: "csv file is empty",
+		},
+		{
+			description: "batch not stored",
+			format:      domain.ImportCSV,
+			body:        "account_id,operation_type,amount\naccount-1,1,10",
+			copyErr:     exceptions.PersistenceError,
+			expectedJob: "copy import transactions",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			auditor := &auditorMock{}
+			uc := newImportUseCase(newImportRepositoryMock(), &transactionRepositoryMock{err: scenario.copyErr}, auditor, ImportLimits{BatchSize: 10})
+
+			job, err := uc.Run(context.Background(), scenario.format, strings.NewReader(scenario.body))
+
+			if scenario.expectedError != nil {
+				assert.ErrorIs(t, err, scenario.expectedError)
+				assert.Empty(t, auditor.changes)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, domain.ImportFailed, job.Status)
+			assert.Contains(t, job.Error, scenario.expectedJob)
+			assert.Zero(t, job.Imported)
+			if assert.Len(t, auditor.errs, 1) {
+				assert.Error(t, auditor.errs[0])
+			}
+		})
+	}
+}
+
+func Test_ImportStart(t *testing.T) {
+	imports, transactions := newImportRepositoryMock(), &transactionRepositoryMock{}
+	uc := newImportUseCase(imports, transactions, &auditorMock{}, ImportLimits{BatchSize: 10, MaxBytes: 64, SpoolDir: t.TempDir()})
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{ClientID: "partner"})
+
+	_, err := uc.Start(ctx, domain.ImportCSV, strings.NewReader("account_id,operation_type,amount\n"+strings.Repeat("account-1,1,10\n", 5)))
+	assert.ErrorIs(t, err, exceptions.PayloadTooLargeError)
+
+	_, err = uc.Start(ctx, "xml", strings.NewReader(""))
+	assert.ErrorIs(t, err, exceptions.UnsupportedMediaTypeError)
+
+	job, err := uc.Start(ctx, domain.ImportCSV, strings.NewReader("account_id,operation_type,amount\naccount-1,1,10\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ImportPending, job.Status)
+	assert.Equal(t, "partner", job.ClientID)
+
+	assert.Eventually(t, func() bool {
+		persisted, err := uc.Get(ctx, job.Id)
+		return err == nil && persisted.Status == domain.ImportCompleted && persisted.Imported == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_ImportOwnership(t *testing.T) {
+	job := domain.ImportJob{Id: "import-1", ClientID: "partner", Status: domain.ImportCompleted}
+	imports := newImportRepositoryMock(job)
+	imports.rowErrors = []domain.ImportError{{JobID: "import-1", Row: 1, Message: "any"}}
+	uc := newImportUseCase(imports, &transactionRepositoryMock{}, &auditorMock{}, ImportLimits{})
+
+	scenarios := []struct {
+		description   string
+		ctx           context.Context
+		expectedError error
+	}{
+		{
+			description: "owner",
+			ctx:         auth.WithPrincipal(context.Background(), auth.Principal{ClientID: "partner"}),
+		},
+		{
+			description: "admin",
+			ctx:         auth.WithPrincipal(context.Background(), auth.Principal{ClientID: "ops", Scopes: []auth.Scope{auth.ScopeAdmin}}),
+		},
+		{
+			description: "internal caller",
+			ctx:         context.Background(),
+		},
+		{
+			description:   "another client",
+			ctx:           auth.WithPrincipal(context.Background(), auth.Principal{ClientID: "other"}),
+			expectedError: exceptions.EntityNotFoundError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			_, err := uc.Get(scenario.ctx, "import-1")
+			assert.ErrorIs(t, err, scenario.expectedError)
+
+			walked := 0
+			err = uc.Errors(scenario.ctx, "import-1", func(domain.ImportError) error {
+				walked++
+				return nil
+			})
+			assert.ErrorIs(t, err, scenario.expectedError)
+			assert.Equal(t, scenario.expectedError == nil, walked == 1)
+		})
+	}
+
+	_, err := uc.Get(context.Background(), "unknown")
+	assert.True(t, errors.Is(err, exceptions.EntityNotFoundError))
+}
+
+func Test_ImportSweep(t *testing.T) {
+	imports := newImportRepositoryMock()
+	imports.staleCount = 2
+	uc := newImportUseCase(imports, &transactionRepositoryMock{}, &auditorMock{}, ImportLimits{StaleAfter: 10 * time.Minute})
+
+	failed, err := uc.Sweep(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, failed)
+	assert.Equal(t, uc.now().Add(-10*time.Minute), imports.staleFrom)
+}
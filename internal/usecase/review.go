@@ -0,0 +1,220 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/domain"
+)
+
+const expiryReason = "review expired"
+
+// ReviewUseCase is the manual review queue of transactions flagged by the
+// fraud rules, and the reversal of approved transactions by the operators.
+type ReviewUseCase interface {
+	List(ctx context.Context, limit, offset int) ([]domain.Transaction, error)
+	Approve(ctx context.Context, id int, reason string) (domain.Transaction, error)
+	Decline(ctx context.Context, id int, reason string) (domain.Transaction, error)
+	Reverse(ctx context.Context, id int, reason string) (domain.Transaction, error)
+	Expire(ctx context.Context) (int, error)
+}
+
+type ReviewUcImpl struct {
+	transactionRepository repository.Transaction
+	auditor               Auditor
+	publisher             Publisher
+	expiry                time.Duration
+	now                   func() time.Time
+}
+
+func (r ReviewUcImpl) List(ctx context.Context, limit, offset int) ([]domain.Transaction, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:review:List", trace.SpanKindInternal)
+	defer span.End()
+
+	transactions, err := r.transactionRepository.ListByStatus(ctx, domain.StatusPendingReview, limit, offset)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot list pending reviews", logger.Err(err))
+		return nil, fmt.Errorf("list pending reviews: %w", err)
+	}
+
+	return transactions, nil
+}
+
+func (r ReviewUcImpl) Approve(ctx context.Context, id int, reason string) (domain.Transaction, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:review:Approve", trace.SpanKindInternal)
+	defer span.End()
+
+	transaction, err := r.resolve(ctx, id, domain.StatusPendingReview, domain.StatusApproved, domain.AuditApprove, reason)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+	}
+
+	return transaction, err
+}
+
+func (r ReviewUcImpl) Decline(ctx context.Context, id int, reason string) (domain.Transaction, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:review:Decline", trace.SpanKindInternal)
+	defer span.End()
+
+	transaction, err := r.resolve(ctx, id, domain.StatusPendingReview, domain.StatusDeclined, domain.AuditDecline, reason)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+	}
+
+	return transaction, err
+}
+
+// Reverse takes an approved transaction back, it stops counting toward the
+// balance and the fraud rules of its account.
+func (r ReviewUcImpl) Reverse(ctx context.Context, id int, reason string) (domain.Transaction, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:review:Reverse", trace.SpanKindInternal)
+	defer span.End()
+
+	transaction, err := r.resolve(ctx, id, domain.StatusApproved, domain.StatusReversed, domain.AuditReverse, reason)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+	}
+
+	return transaction, err
+}
+
+// resolve moves a transaction from status from to status, a transaction no
+// longer in from, resolved by another operator or by expiry, answers
+// ConflictError.
+func (r ReviewUcImpl) resolve(ctx context.Context, id int, from, status domain.Status, action, reason string) (resolved domain.Transaction, err error) {
+	ctx, finish, err := r.auditor.Begin(ctx)
+	if err != nil {
+		return domain.Transaction{}, err
+	}
+	defer func() { err = finish(err) }()
+
+	change := domain.AuditChange{EntityType: domain.AuditTransaction, EntityID: strconv.Itoa(id), Action: action}
+	defer func() {
+		if err == nil {
+			change.After = resolved
+		}
+		r.auditor.Record(ctx, change, err)
+	}()
+
+	transaction, err := r.transactionRepository.Get(ctx, id)
+	if err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot get transaction to review", logger.Int("transaction_id", id), logger.Err(err))
+		return domain.Transaction{}, fmt.Errorf("get transaction %d: %w", id, err)
+	}
+
+	if transaction.Status != from {
+		return domain.Transaction{}, exceptions.ConflictError.WithDetail(
+			fmt.Sprintf("transaction %d is %s, not %s", id, transaction.Status, from),
+		)
+	}
+
+	change.Before = transaction
+
+	reviewedAt := r.now()
+	transaction.Status = status
+	transaction.ReviewedBy = auth.Actor(ctx)
+	transaction.ReviewReason = reason
+	transaction.ReviewedAt = &reviewedAt
+
+	store := r.transactionRepository.Resolve
+	if status == domain.StatusReversed {
+		store = r.transactionRepository.Reverse
+	}
+
+	err = store(ctx, transaction)
+	if errors.Is(err, exceptions.EntityNotFoundError) {
+		return domain.Transaction{}, exceptions.ConflictError.WithDetail(fmt.Sprintf("transaction %d was already resolved", id))
+	}
+	if err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot resolve transaction", logger.Int("transaction_id", id), logger.Err(err))
+		return domain.Transaction{}, fmt.Errorf("resolve transaction %d: %w", id, err)
+	}
+
+	r.publisher.Publish(ctx, domain.NewTransactionEvent(reviewEvent(status), transaction))
+
+	logger.Info(ctx, logger.FraudAlert, "transaction reviewed",
+		logger.Int("transaction_id", id),
+		logger.Str("status", string(status)),
+		logger.Str("reviewed_by", transaction.ReviewedBy),
+	)
+
+	return transaction, nil
+}
+
+// Expire declines the transactions waiting for review longer than the
+// configured expiry, it does nothing while the expiry is zero.
+func (r ReviewUcImpl) Expire(ctx context.Context) (_ int, err error) {
+	ctx, span := telemetry.Span(ctx, "useCase:review:Expire", trace.SpanKindInternal)
+	defer span.End()
+
+	if r.expiry <= 0 {
+		return 0, nil
+	}
+
+	ctx, finish, err := r.auditor.Begin(ctx)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return 0, err
+	}
+	defer func() { err = finish(err) }()
+
+	expired, err := r.transactionRepository.ExpireReviews(ctx, r.now().Add(-r.expiry), auth.SystemActor, expiryReason)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot expire pending reviews", logger.Err(err))
+		return 0, fmt.Errorf("expire pending reviews: %w", err)
+	}
+
+	for _, transaction := range expired {
+		before := transaction
+		before.Status, before.ReviewedBy, before.ReviewReason, before.ReviewedAt = domain.StatusPendingReview, "", "", nil
+
+		r.auditor.Record(ctx, domain.AuditChange{
+			EntityType: domain.AuditTransaction,
+			EntityID:   strconv.Itoa(transaction.Id),
+			Action:     domain.AuditExpire,
+			Before:     before,
+			After:      transaction,
+		}, nil)
+
+		r.publisher.Publish(ctx, domain.NewTransactionEvent(domain.EventTransactionDeclined, transaction))
+	}
+
+	if len(expired) > 0 {
+		logger.Info(ctx, logger.FraudAlert, "pending reviews expired", logger.Int("count", len(expired)))
+	}
+
+	return len(expired), nil
+}
+
+func reviewEvent(status domain.Status) domain.EventType {
+	switch status {
+	case domain.StatusApproved:
+		return domain.EventTransactionApproved
+	case domain.StatusReversed:
+		return domain.EventTransactionReversed
+	default:
+		return domain.EventTransactionDeclined
+	}
+}
+
+func NewReviewUseCase(transactionRepository repository.Transaction, auditor Auditor, publisher Publisher, expiry time.Duration) ReviewUseCase {
+	return ReviewUcImpl{
+		transactionRepository: transactionRepository,
+		auditor:               auditor,
+		publisher:             publisher,
+		expiry:                expiry,
+		now:                   time.Now,
+	}
+}
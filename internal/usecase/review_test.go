@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/domain"
+)
+
+func Test_ReviewResolveUseCase(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		description    string
+		approve        bool
+		reverse        bool
+		repository     *transactionRepositoryMock
+		expectedStatus domain.Status
+		expectedError  error
+	}{
+		{
+			description:    "approve pending transaction",
+			approve:        true,
+			repository:     &transactionRepositoryMock{Result: domain.Transaction{Id: 1, Status: domain.StatusPendingReview}},
+			expectedStatus: domain.StatusApproved,
+		},
+		{
+			description:    "decline pending transaction",
+			repository:     &transactionRepositoryMock{Result: domain.Transaction{Id: 1, Status: domain.StatusPendingReview}},
+			expectedStatus: domain.StatusDeclined,
+		},
+		{
+			description:   "transaction already approved",
+			approve:       true,
+			repository:    &transactionRepositoryMock{Result: domain.Transaction{Id: 1, Status: domain.StatusApproved}},
+			expectedError: exceptions.ConflictError,
+		},
+		{
+			description: "transaction resolved concurrently",
+			repository: &transactionRepositoryMock{
+				Result:     domain.Transaction{Id: 1, Status: domain.StatusPendingReview},
+				resolveErr: exceptions.EntityNotFoundError,
+			},
+			expectedError: exceptions.ConflictError,
+		},
+		{
+			description:    "reverse approved transaction",
+			reverse:        true,
+			repository:     &transactionRepositoryMock{Result: domain.Transaction{Id: 1, Status: domain.StatusApproved}},
+			expectedStatus: domain.StatusReversed,
+		},
+		{
+			description:   "reverse transaction pending review",
+			reverse:       true,
+			repository:    &transactionRepositoryMock{Result: domain.Transaction{Id: 1, Status: domain.StatusPendingReview}},
+			expectedError: exceptions.ConflictError,
+		},
+		{
+			description: "transaction reversed concurrently",
+			reverse:     true,
+			repository: &transactionRepositoryMock{
+				Result:     domain.Transaction{Id: 1, Status: domain.StatusApproved},
+				resolveErr: exceptions.EntityNotFoundError,
+			},
+			expectedError: exceptions.ConflictError,
+		},
+		{
+			description:   "transaction not found",
+			repository:    &transactionRepositoryMock{err: exceptions.EntityNotFoundError},
+			expectedError: exceptions.EntityNotFoundError,
+		},
+		{
+			description: "database unavailable",
+			repository: &transactionRepositoryMock{
+				Result:     domain.Transaction{Id: 1, Status: domain.StatusPendingReview},
+				resolveErr: exceptions.UnavailableError.Wrap(errors.New("connection refused")),
+			},
+			expectedError: exceptions.UnavailableError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			auditor := &auditorMock{}
+			reviewUseCase := ReviewUcImpl{transactionRepository: scenario.repository, auditor: auditor, publisher: &publisherMock{}, now: func() time.Time { return now }}
+			ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "operator-1", Operator: true})
+
+			resolve, stored := reviewUseCase.Decline, &scenario.repository.resolved
+			switch {
+			case scenario.approve:
+				resolve = reviewUseCase.Approve
+			case scenario.reverse:
+				resolve, stored = reviewUseCase.Reverse, &scenario.repository.reversed
+			}
+
+			transaction, err := resolve(ctx, 1, "checked with customer")
+
+			assert.Len(t, auditor.changes, 1, "every review attempt is audited")
+			assert.ErrorIs(t, auditor.errs[0], scenario.expectedError)
+
+			if scenario.expectedError != nil {
+				assert.ErrorIs(t, err, scenario.expectedError)
+				return
+			}
+
+			assert.Equal(t, scenario.repository.Result, auditor.changes[0].Before)
+			assert.Equal(t, transaction, auditor.changes[0].After)
+
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.expectedStatus, transaction.Status)
+			assert.Equal(t, "operator-1", transaction.ReviewedBy)
+			assert.Equal(t, "checked with customer", transaction.ReviewReason)
+			assert.Equal(t, now, *transaction.ReviewedAt)
+			assert.Equal(t, transaction, *stored)
+		})
+	}
+}
+
+func Test_ReviewExpireUseCase(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	repository := &transactionRepositoryMock{count: 2}
+	auditor := &auditorMock{}
+	reviewUseCase := ReviewUcImpl{transactionRepository: repository, auditor: auditor, publisher: &publisherMock{}, expiry: 72 * time.Hour, now: func() time.Time { return now }}
+
+	count, err := reviewUseCase.Expire(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, now.Add(-72*time.Hour), repository.expireFrom)
+	assert.Len(t, auditor.changes, 2, "one audit entry per expired transaction")
+	assert.Equal(t, domain.AuditExpire, auditor.changes[0].Action)
+
+	disabled := &transactionRepositoryMock{count: 2}
+	count, err = ReviewUcImpl{transactionRepository: disabled, auditor: &auditorMock{}, publisher: &publisherMock{}, now: time.Now}.Expire(context.Background())
+
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+	assert.True(t, disabled.expireFrom.IsZero(), "expiry disabled")
+}
@@ -0,0 +1,424 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
+)
+
+// errNothingDue skips the run of a statement balance payment of an account
+// owing nothing.
+var errNothingDue = errors.New("nothing due")
+
+type ScheduleUseCase interface {
+	Create(ctx context.Context, payment domain.ScheduledPayment) (domain.ScheduledPayment, error)
+	Get(ctx context.Context, accountID, id string) (domain.ScheduledPayment, error)
+	List(ctx context.Context, accountID string) ([]domain.ScheduledPayment, error)
+	Cancel(ctx context.Context, accountID, id string) error
+	Run(ctx context.Context) (int, error)
+}
+
+type ScheduleUcImpl struct {
+	scheduleRepository    repository.Schedule
+	accountRepository     repository.Account
+	transactionRepository repository.Transaction
+	transactions          TransactionUseCase
+	auditor               Auditor
+	publisher             Publisher
+	policy                RetryPolicy
+	now                   func() time.Time
+}
+
+// Create schedules a payment of an open account of the caller, the first run
+// of a monthly payment is the next occurrence of its day.
+func (s ScheduleUcImpl) Create(ctx context.Context, payment domain.ScheduledPayment) (created domain.ScheduledPayment, err error) {
+	ctx, span := telemetry.Span(ctx, "useCase:schedule:Create", trace.SpanKindInternal)
+	defer span.End()
+
+	ctx, finish, err := s.auditor.Begin(ctx)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.ScheduledPayment{}, err
+	}
+	defer func() { err = finish(err) }()
+
+	defer func() {
+		change := domain.AuditChange{EntityType: domain.AuditSchedule, Action: domain.AuditCreate}
+		if created.Id != "" {
+			change.EntityID, change.After = created.Id, created
+		}
+		s.auditor.Record(ctx, change, err)
+	}()
+
+	now := s.now()
+
+	if err = validateScheduledPayment(payment, now); err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.ScheduledPayment{}, err
+	}
+
+	account, err := s.account(ctx, payment.AccountID)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.ScheduledPayment{}, err
+	}
+
+	if !account.IsOpen() {
+		err = exceptions.AccountClosedError.WithDetail(fmt.Sprintf("account %s is closed", account.Id))
+		telemetry.ErrorSpan(span, err)
+		return domain.ScheduledPayment{}, err
+	}
+
+	payment.Id = uuid.New().String()
+	payment.TenantID = account.TenantID
+	payment.ClientID = auth.Actor(ctx)
+	payment.Status = domain.ScheduleActive
+	payment.CreatedAt, payment.UpdatedAt = now, now
+	if payment.Frequency == domain.ScheduleMonthly {
+		payment.NextRunAt = domain.NextMonthlyRun(payment.DayOfMonth, now)
+	}
+
+	if err = s.scheduleRepository.Push(ctx, payment); err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot create scheduled payment", logger.Err(err))
+		return domain.ScheduledPayment{}, fmt.Errorf("create scheduled payment: %w", err)
+	}
+
+	return payment, nil
+}
+
+func (s ScheduleUcImpl) Get(ctx context.Context, accountID, id string) (domain.ScheduledPayment, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:schedule:Get", trace.SpanKindInternal)
+	defer span.End()
+
+	payment, err := s.payment(ctx, accountID, id)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+	}
+
+	return payment, err
+}
+
+// List returns the scheduled payments of an account of the caller.
+func (s ScheduleUcImpl) List(ctx context.Context, accountID string) ([]domain.ScheduledPayment, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:schedule:List", trace.SpanKindInternal)
+	defer span.End()
+
+	if _, err := s.account(ctx, accountID); err != nil {
+		telemetry.ErrorSpan(span, err)
+		return nil, err
+	}
+
+	payments, err := s.scheduleRepository.ListByAccount(ctx, accountID)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot list scheduled payments", logger.Str("account_id", accountID), logger.Err(err))
+		return nil, fmt.Errorf("list scheduled payments of account %s: %w", accountID, err)
+	}
+
+	return payments, nil
+}
+
+// Cancel stops an active scheduled payment, its past runs stay posted. A
+// payment completed or failed by a run meanwhile is not cancelled.
+func (s ScheduleUcImpl) Cancel(ctx context.Context, accountID, id string) (err error) {
+	ctx, span := telemetry.Span(ctx, "useCase:schedule:Cancel", trace.SpanKindInternal)
+	defer span.End()
+
+	ctx, finish, err := s.auditor.Begin(ctx)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return err
+	}
+	defer func() { err = finish(err) }()
+
+	change := domain.AuditChange{EntityType: domain.AuditSchedule, EntityID: id, Action: domain.AuditCancel}
+	defer func() { s.auditor.Record(ctx, change, err) }()
+
+	payment, err := s.payment(ctx, accountID, id)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return err
+	}
+	change.Before = payment
+
+	if payment.Status != domain.ScheduleActive {
+		err = exceptions.InvalidStateError.WithDetail(fmt.Sprintf("scheduled payment %s is %s", id, payment.Status))
+		telemetry.ErrorSpan(span, err)
+		return err
+	}
+
+	payment.Status = domain.ScheduleCancelled
+	payment.UpdatedAt = s.now()
+
+	if err = s.scheduleRepository.Update(ctx, payment, domain.ScheduleActive); err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot cancel scheduled payment", logger.Str("scheduled_payment_id", id), logger.Err(err))
+		return fmt.Errorf("cancel scheduled payment %s: %w", id, err)
+	}
+	change.After = payment
+
+	return nil
+}
+
+// Run posts the payments due now through TransactionUseCase.Create and
+// returns how many were run. Failures the caller cannot fix by waiting are
+// final, the others are retried with the backoff of the policy; a payment
+// failing for good is notified with a scheduled_payment.failed event. A
+// payment that cannot be run does not stop the others, the first error is
+// returned once the batch is done.
+func (s ScheduleUcImpl) Run(ctx context.Context) (int, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:schedule:Run", trace.SpanKindInternal)
+	defer span.End()
+
+	now := s.now()
+
+	payments, err := s.scheduleRepository.Due(ctx, now, s.policy.BatchSize)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot list due scheduled payments", logger.Err(err))
+		return 0, fmt.Errorf("list due scheduled payments: %w", err)
+	}
+
+	ran := 0
+	var runErr error
+	for _, payment := range payments {
+		claimed, err := s.run(ctx, payment.Id, now)
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			logger.Error(ctx, logger.ServerError, "cannot run scheduled payment", logger.Str("scheduled_payment_id", payment.Id), logger.Err(err))
+			if runErr == nil {
+				runErr = err
+			}
+		}
+		if claimed {
+			ran++
+		}
+	}
+
+	return ran, runErr
+}
+
+// run claims payment id, posts its transaction and stores its next run in one
+// transaction, holding the payment locked so a concurrent cancel waits for the
+// run. The transaction is posted under a savepoint, a database failure posting
+// it is rolled back to before storing the retry. Payments cancelled, run or
+// claimed by another run since they were listed due are skipped.
+func (s ScheduleUcImpl) run(ctx context.Context, id string, due time.Time) (claimed bool, err error) {
+	ctx, finish, err := s.auditor.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { err = finish(err) }()
+
+	payment, err := s.scheduleRepository.Claim(ctx, id, due)
+	if errors.Is(err, exceptions.EntityNotFoundError) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("claim scheduled payment %s: %w", id, err)
+	}
+
+	var transaction domain.Transaction
+	err = s.auditor.Savepoint(ctx, func(ctx context.Context) (err error) {
+		transaction, err = s.post(ctx, payment)
+		return err
+	})
+
+	now := s.now()
+	payment.LastRunAt = &now
+	payment.UpdatedAt = now
+	if transaction.Id != 0 {
+		payment.LastTransactionID = transaction.Id
+	}
+
+	switch {
+	case err == nil || errors.Is(err, errNothingDue):
+		payment.Attempts = 0
+		payment.LastError = ""
+		s.advance(&payment, now)
+	case retryable(err) && payment.Attempts+1 < s.policy.MaxAttempts:
+		payment.Attempts++
+		payment.LastError = err.Error()
+		payment.NextRunAt = now.Add(s.policy.backoff(payment.Attempts))
+	default:
+		payment.Attempts++
+		payment.LastError = err.Error()
+		s.advance(&payment, now)
+		if errors.Is(err, exceptions.AccountClosedError) || errors.Is(err, exceptions.EntityNotFoundError) {
+			payment.Status = domain.ScheduleFailed
+		}
+
+		logger.Warn(ctx, logger.ScheduleFailed, "scheduled payment failed",
+			logger.Str("scheduled_payment_id", payment.Id),
+			logger.Str("account_id", payment.AccountID),
+			logger.Int("attempts", payment.Attempts),
+			logger.Str("last_error", payment.LastError),
+		)
+		s.publisher.Publish(ctx, domain.NewScheduledPaymentEvent(domain.EventScheduledPaymentFailed, payment))
+		payment.Attempts = 0
+	}
+
+	err = s.scheduleRepository.Update(ctx, payment, domain.ScheduleActive)
+	if errors.Is(err, exceptions.InvalidStateError) {
+		// cancelled meanwhile, the cancel is kept
+		logger.Warn(ctx, logger.ServerError, "scheduled payment changed while it ran", logger.Str("scheduled_payment_id", payment.Id))
+		return true, nil
+	}
+	if err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot update scheduled payment", logger.Str("scheduled_payment_id", payment.Id), logger.Err(err))
+		return true, fmt.Errorf("update scheduled payment %s: %w", payment.Id, err)
+	}
+
+	return true, nil
+}
+
+// post creates the transaction of one run of payment.
+func (s ScheduleUcImpl) post(ctx context.Context, payment domain.ScheduledPayment) (domain.Transaction, error) {
+	amount := payment.Amount
+
+	if payment.AmountType == domain.AmountStatementBalance {
+		balance, err := s.transactionRepository.Balance(ctx, payment.AccountID, s.now())
+		if err != nil {
+			return domain.Transaction{}, fmt.Errorf("compute balance of account %s: %w", payment.AccountID, err)
+		}
+
+		// a negative balance is owed, payments credit the account
+		amount = math.Round(-balance*100) / 100
+		if amount <= 0 {
+			return domain.Transaction{}, errNothingDue
+		}
+	}
+
+	return s.transactions.Create(ctx, domain.Transaction{
+		AccountID:     payment.AccountID,
+		OperationType: operation.PAYMENT,
+		Amount:        amount,
+	})
+}
+
+// advance moves payment past its current run: a one-off payment is completed
+// and a monthly one waits for the next occurrence of its day, runs missed
+// while the service was down are not caught up.
+func (s ScheduleUcImpl) advance(payment *domain.ScheduledPayment, now time.Time) {
+	if payment.Frequency == domain.ScheduleOnce {
+		payment.Status = domain.ScheduleCompleted
+		if payment.LastError != "" {
+			payment.Status = domain.ScheduleFailed
+		}
+		return
+	}
+
+	payment.NextRunAt = domain.NextMonthlyRun(payment.DayOfMonth, now)
+}
+
+// retryable reports whether err may go away by itself: the database being
+// unavailable or any error not raised by the use cases.
+func retryable(err error) bool {
+	var e *exceptions.Error
+	if !errors.As(err, &e) {
+		return true
+	}
+
+	return errors.Is(err, exceptions.UnavailableError) || errors.Is(err, exceptions.PersistenceError)
+}
+
+// account returns an account of the caller, accounts of other owners are not
+// found.
+func (s ScheduleUcImpl) account(ctx context.Context, accountID string) (domain.Account, error) {
+	account, err := s.accountRepository.Get(ctx, accountID)
+	if err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot get scheduled payment account", logger.Str("account_id", accountID), logger.Err(err))
+		return domain.Account{}, fmt.Errorf("get account %s: %w", accountID, err)
+	}
+
+	if !auth.CanAccess(ctx, account.DocumentNumber) {
+		logger.Warn(ctx, logger.ServerError, "account owned by another caller", logger.Str("account_id", accountID))
+		return domain.Account{}, exceptions.EntityNotFoundError.WithDetail(fmt.Sprintf("account %s not found", accountID))
+	}
+
+	return account, nil
+}
+
+// payment returns a scheduled payment of an account of the caller.
+func (s ScheduleUcImpl) payment(ctx context.Context, accountID, id string) (domain.ScheduledPayment, error) {
+	if _, err := s.account(ctx, accountID); err != nil {
+		return domain.ScheduledPayment{}, err
+	}
+
+	payment, err := s.scheduleRepository.Get(ctx, id)
+	if err == nil && payment.AccountID != accountID {
+		err = exceptions.EntityNotFoundError
+	}
+	if err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot get scheduled payment", logger.Str("scheduled_payment_id", id), logger.Err(err))
+		if errors.Is(err, exceptions.EntityNotFoundError) {
+			return domain.ScheduledPayment{}, exceptions.EntityNotFoundError.WithDetail(fmt.Sprintf("scheduled payment %s not found", id))
+		}
+		return domain.ScheduledPayment{}, fmt.Errorf("get scheduled payment %s: %w", id, err)
+	}
+
+	return payment, nil
+}
+
+func validateScheduledPayment(payment domain.ScheduledPayment, now time.Time) error {
+	var fields []exceptions.FieldError
+
+	switch payment.Frequency {
+	case domain.ScheduleOnce:
+		if !payment.NextRunAt.After(now) {
+			fields = append(fields, exceptions.FieldError{Field: "run_at", Message: "must be in the future"})
+		}
+	case domain.ScheduleMonthly:
+		if payment.DayOfMonth < 1 || payment.DayOfMonth > 31 {
+			fields = append(fields, exceptions.FieldError{Field: "day_of_month", Message: "must be between 1 and 31"})
+		}
+	default:
+		fields = append(fields, exceptions.FieldError{Field: "frequency", Message: "must be one of once, monthly"})
+	}
+
+	switch payment.AmountType {
+	case domain.AmountFixed:
+		if payment.Amount <= 0 {
+			fields = append(fields, exceptions.FieldError{Field: "amount", Message: "must be greater than 0"})
+		}
+	case domain.AmountStatementBalance:
+		if payment.Amount != 0 {
+			fields = append(fields, exceptions.FieldError{Field: "amount", Message: "must be empty for statement_balance"})
+		}
+	default:
+		fields = append(fields, exceptions.FieldError{Field: "amount_type", Message: "must be one of fixed, statement_balance"})
+	}
+
+	if len(fields) > 0 {
+		return exceptions.ValidationError.WithFields(fields...)
+	}
+
+	return nil
+}
+
+func NewScheduleUseCase(scheduleRepository repository.Schedule, accountRepository repository.Account, transactionRepository repository.Transaction, transactions TransactionUseCase, auditor Auditor, publisher Publisher, policy RetryPolicy) ScheduleUseCase {
+	return ScheduleUcImpl{
+		scheduleRepository:    scheduleRepository,
+		accountRepository:     accountRepository,
+		transactionRepository: transactionRepository,
+		transactions:          transactions,
+		auditor:               auditor,
+		publisher:             publisher,
+		policy:                policy,
+		now:                   time.Now,
+	}
+}
@@ -0,0 +1,539 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
+)
+
+// scheduleRepositoryMock keeps scheduled payments in memory, every active one
+// due at the given time is due. onDue and onUpdate run once, after the due
+// payments are listed and before the next update is stored, to interleave a
+// concurrent call. Updates of the payments in updateErrs fail.
+type scheduleRepositoryMock struct {
+	payments   map[string]domain.ScheduledPayment
+	err        error
+	updateErrs map[string]error
+	onDue      func()
+	onUpdate   func()
+}
+
+func newScheduleRepositoryMock(payments ...domain.ScheduledPayment) *scheduleRepositoryMock {
+	r := &scheduleRepositoryMock{payments: map[string]domain.ScheduledPayment{}}
+	for _, payment := range payments {
+		r.payments[payment.Id] = payment
+	}
+	return r
+}
+
+func (r *scheduleRepositoryMock) Push(_ context.Context, entity domain.ScheduledPayment) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.payments[entity.Id] = entity
+	return nil
+}
+
+func (r *scheduleRepositoryMock) Get(_ context.Context, id string) (domain.ScheduledPayment, error) {
+	payment, ok := r.payments[id]
+	if !ok {
+		return domain.ScheduledPayment{}, exceptions.EntityNotFoundError
+	}
+	return payment, nil
+}
+
+func (r *scheduleRepositoryMock) ListByAccount(_ context.Context, accountID string) ([]domain.ScheduledPayment, error) {
+	payments := make([]domain.ScheduledPayment, 0)
+	for _, payment := range r.payments {
+		if payment.AccountID == accountID {
+			payments = append(payments, payment)
+		}
+	}
+	return payments, r.err
+}
+
+func (r *scheduleRepositoryMock) Due(_ context.Context, now time.Time, _ int) ([]domain.ScheduledPayment, error) {
+	payments := make([]domain.ScheduledPayment, 0)
+	for _, payment := range r.payments {
+		if payment.Status == domain.ScheduleActive && !payment.NextRunAt.After(now) {
+			payments = append(payments, payment)
+		}
+	}
+	if onDue := r.onDue; onDue != nil {
+		r.onDue = nil
+		onDue()
+	}
+	return payments, r.err
+}
+
+func (r *scheduleRepositoryMock) Claim(_ context.Context, id string, now time.Time) (domain.ScheduledPayment, error) {
+	payment, ok := r.payments[id]
+	if !ok || payment.Status != domain.ScheduleActive || payment.NextRunAt.After(now) {
+		return domain.ScheduledPayment{}, exceptions.EntityNotFoundError
+	}
+	return payment, nil
+}
+
+func (r *scheduleRepositoryMock) Update(_ context.Context, entity domain.ScheduledPayment, status domain.ScheduleStatus) error {
+	if onUpdate := r.onUpdate; onUpdate != nil {
+		r.onUpdate = nil
+		onUpdate()
+	}
+	if err := r.updateErrs[entity.Id]; err != nil {
+		return err
+	}
+	if r.payments[entity.Id].Status != status {
+		return exceptions.InvalidStateError
+	}
+	r.payments[entity.Id] = entity
+	return nil
+}
+
+// transactionUseCaseMock records the transactions posted through it, calling
+// onCreate first when set.
+type transactionUseCaseMock struct {
+	created  []domain.Transaction
+	err      error
+	onCreate func()
+}
+
+func (t *transactionUseCaseMock) Create(_ context.Context, transaction domain.Transaction) (domain.Transaction, error) {
+	if t.onCreate != nil {
+		t.onCreate()
+	}
+	t.created = append(t.created, transaction)
+	if t.err != nil && !errors.Is(t.err, exceptions.TransactionDeniedError) {
+		return domain.Transaction{}, t.err
+	}
+
+	transaction.Id = len(t.created)
+	return transaction, t.err
+}
+
+func (t *transactionUseCaseMock) Get(context.Context, int) (domain.Transaction, error) {
+	return domain.Transaction{}, nil
+}
+
+var scheduleNow = time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+func newScheduleUseCase(payments *scheduleRepositoryMock, account domain.Account, transactions *transactionUseCaseMock, balance float64, publisher *publisherMock) ScheduleUcImpl {
+	return ScheduleUcImpl{
+		scheduleRepository:    payments,
+		accountRepository:     &accountRepositoryMock{Result: account},
+		transactionRepository: &transactionRepositoryMock{balances: map[time.Time]float64{scheduleNow: balance}},
+		transactions:          transactions,
+		auditor:               &auditorMock{},
+		publisher:             publisher,
+		policy:                RetryPolicy{MaxAttempts: 3, BackoffBase: 15 * time.Minute, BackoffMax: time.Hour, BatchSize: 10},
+		now:                   func() time.Time { return scheduleNow },
+	}
+}
+
+func Test_ScheduleCreateUseCase(t *testing.T) {
+	runAt := scheduleNow.Add(24 * time.Hour)
+
+	scenarios := []struct {
+		description       string
+		input             domain.ScheduledPayment
+		account           domain.Account
+		principal         *auth.Principal
+		expectedNextRunAt time.Time
+		expectedFields    []string
+		expectedError     error
+	}{
+		{
+			description:       "one-off",
+			input:             domain.ScheduledPayment{Frequency: domain.ScheduleOnce, NextRunAt: runAt, AmountType: domain.AmountFixed, Amount: 50},
+			expectedNextRunAt: runAt,
+		},
+		{
+			description:       "monthly on a day february lacks",
+			input:             domain.ScheduledPayment{Frequency: domain.ScheduleMonthly, DayOfMonth: 31, AmountType: domain.AmountStatementBalance},
+			expectedNextRunAt: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			description:       "monthly on a day already passed",
+			input:             domain.ScheduledPayment{Frequency: domain.ScheduleMonthly, DayOfMonth: 5, AmountType: domain.AmountFixed, Amount: 10},
+			expectedNextRunAt: time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			description:    "one-off in the past",
+			input:          domain.ScheduledPayment{Frequency: domain.ScheduleOnce, NextRunAt: scheduleNow, AmountType: domain.AmountFixed, Amount: 50},
+			expectedFields: []string{"run_at"},
+			expectedError:  exceptions.ValidationError,
+		},
+		{
+			description:    "monthly without day",
+			input:          domain.ScheduledPayment{Frequency: domain.ScheduleMonthly, AmountType: domain.AmountFixed},
+			expectedFields: []string{"day_of_month", "amount"},
+			expectedError:  exceptions.ValidationError,
+		},
+		{
+			description:    "statement balance with amount",
+			input:          domain.ScheduledPayment{Frequency: domain.ScheduleMonthly, DayOfMonth: 10, AmountType: domain.AmountStatementBalance, Amount: 10},
+			expectedFields: []string{"amount"},
+			expectedError:  exceptions.ValidationError,
+		},
+		{
+			description:    "unknown frequency and amount type",
+			input:          domain.ScheduledPayment{Frequency: "weekly", AmountType: "minimum"},
+			expectedFields: []string{"frequency", "amount_type"},
+			expectedError:  exceptions.ValidationError,
+		},
+		{
+			description:   "closed account",
+			input:         domain.ScheduledPayment{Frequency: domain.ScheduleOnce, NextRunAt: runAt, AmountType: domain.AmountFixed, Amount: 50},
+			account:       domain.Account{Id: "any-account", DocumentNumber: "any-document", Status: domain.AccountClosed},
+			expectedError: exceptions.AccountClosedError,
+		},
+		{
+			description:   "account of another owner",
+			input:         domain.ScheduledPayment{Frequency: domain.ScheduleOnce, NextRunAt: runAt, AmountType: domain.AmountFixed, Amount: 50},
+			principal:     &auth.Principal{Subject: "user-2", Owner: "other-document"},
+			expectedError: exceptions.EntityNotFoundError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			ctx := context.Background()
+			if scenario.principal != nil {
+				ctx = auth.WithPrincipal(ctx, *scenario.principal)
+			}
+
+			account := scenario.account
+			if account.Id == "" {
+				account = domain.Account{Id: "any-account", TenantID: "program-b", DocumentNumber: "any-document", Status: domain.AccountActive}
+			}
+
+			payments := newScheduleRepositoryMock()
+			uc := newScheduleUseCase(payments, account, &transactionUseCaseMock{}, 0, &publisherMock{})
+
+			scenario.input.AccountID = "any-account"
+			payment, err := uc.Create(ctx, scenario.input)
+
+			if scenario.expectedError != nil {
+				assert.ErrorIs(t, err, scenario.expectedError)
+				var e *exceptions.Error
+				if len(scenario.expectedFields) > 0 && assert.ErrorAs(t, err, &e) {
+					fields := make([]string, 0, len(e.Fields))
+					for _, field := range e.Fields {
+						fields = append(fields, field.Field)
+					}
+					assert.Equal(t, scenario.expectedFields, fields)
+				}
+				assert.Empty(t, payments.payments)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotEmpty(t, payment.Id)
+			assert.Equal(t, domain.ScheduleActive, payment.Status)
+			assert.Equal(t, "program-b", payment.TenantID, "payments belong to the tenant of their account")
+			assert.Equal(t, scenario.expectedNextRunAt, payment.NextRunAt)
+			assert.Equal(t, payment, payments.payments[payment.Id])
+		})
+	}
+}
+
+func Test_ScheduleRunUseCase(t *testing.T) {
+	due := scheduleNow.Add(-time.Minute)
+
+	scenarios := []struct {
+		description       string
+		payment           domain.ScheduledPayment
+		balance           float64
+		transactionErr    error
+		expectedAmount    float64
+		expectedStatus    domain.ScheduleStatus
+		expectedNextRunAt time.Time
+		expectedAttempts  int
+		expectedError     string
+		expectedNotified  bool
+	}{
+		{
+			description:    "one-off fixed amount",
+			payment:        domain.ScheduledPayment{Frequency: domain.ScheduleOnce, AmountType: domain.AmountFixed, Amount: 50},
+			expectedAmount: 50,
+			expectedStatus: domain.ScheduleCompleted,
+		},
+		{
+			description:       "monthly statement balance",
+			payment:           domain.ScheduledPayment{Frequency: domain.ScheduleMonthly, DayOfMonth: 31, AmountType: domain.AmountStatementBalance, Attempts: 1},
+			balance:           -120.456,
+			expectedAmount:    120.46,
+			expectedStatus:    domain.ScheduleActive,
+			expectedNextRunAt: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			description:       "statement balance with nothing owed",
+			payment:           domain.ScheduledPayment{Frequency: domain.ScheduleMonthly, DayOfMonth: 1, AmountType: domain.AmountStatementBalance},
+			balance:           35,
+			expectedStatus:    domain.ScheduleActive,
+			expectedNextRunAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			description:       "database unavailable",
+			payment:           domain.ScheduledPayment{Frequency: domain.ScheduleOnce, AmountType: domain.AmountFixed, Amount: 50},
+			transactionErr:    exceptions.UnavailableError.Wrap(errors.New("connection reset")),
+			expectedAmount:    50,
+			expectedStatus:    domain.ScheduleActive,
+			expectedNextRunAt: scheduleNow.Add(15 * time.Minute),
+			expectedAttempts:  1,
+			expectedError:     "service temporarily unavailable",
+		},
+		{
+			description:       "database unavailable on the last attempt",
+			payment:           domain.ScheduledPayment{Frequency: domain.ScheduleMonthly, DayOfMonth: 15, AmountType: domain.AmountFixed, Amount: 50, Attempts: 2},
+			transactionErr:    exceptions.UnavailableError,
+			expectedAmount:    50,
+			expectedStatus:    domain.ScheduleActive,
+			expectedNextRunAt: time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC),
+			expectedError:     "service temporarily unavailable",
+			expectedNotified:  true,
+		},
+		{
+			description:      "denied by fraud rules",
+			payment:          domain.ScheduledPayment{Frequency: domain.ScheduleOnce, AmountType: domain.AmountFixed, Amount: 50},
+			transactionErr:   exceptions.TransactionDeniedError,
+			expectedAmount:   50,
+			expectedStatus:   domain.ScheduleFailed,
+			expectedError:    "transaction denied by fraud rules",
+			expectedNotified: true,
+		},
+		{
+			description:       "account closed",
+			payment:           domain.ScheduledPayment{Frequency: domain.ScheduleMonthly, DayOfMonth: 15, AmountType: domain.AmountFixed, Amount: 50},
+			transactionErr:    exceptions.AccountClosedError,
+			expectedAmount:    50,
+			expectedStatus:    domain.ScheduleFailed,
+			expectedNextRunAt: time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC),
+			expectedError:     "account is closed",
+			expectedNotified:  true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			scenario.payment.Id, scenario.payment.AccountID = "any-payment", "any-account"
+			scenario.payment.Status, scenario.payment.NextRunAt = domain.ScheduleActive, due
+			if scenario.expectedNextRunAt.IsZero() {
+				scenario.expectedNextRunAt = due
+			}
+
+			payments := newScheduleRepositoryMock(scenario.payment, domain.ScheduledPayment{
+				Id: "later-payment", Status: domain.ScheduleActive, NextRunAt: scheduleNow.Add(time.Hour),
+			})
+			transactions := &transactionUseCaseMock{err: scenario.transactionErr}
+			publisher := &publisherMock{}
+			uc := newScheduleUseCase(payments, domain.Account{}, transactions, scenario.balance, publisher)
+
+			count, err := uc.Run(context.Background())
+
+			assert.NoError(t, err)
+			assert.Equal(t, 1, count)
+
+			if scenario.expectedAmount == 0 {
+				assert.Empty(t, transactions.created)
+			} else if assert.Len(t, transactions.created, 1) {
+				assert.Equal(t, domain.Transaction{AccountID: "any-account", OperationType: operation.PAYMENT, Amount: scenario.expectedAmount}, transactions.created[0])
+			}
+
+			payment := payments.payments["any-payment"]
+			assert.Equal(t, scenario.expectedStatus, payment.Status)
+			assert.Equal(t, scenario.expectedNextRunAt, payment.NextRunAt)
+			assert.Equal(t, scenario.expectedAttempts, payment.Attempts)
+			assert.Equal(t, scenario.expectedError, payment.LastError)
+			assert.Equal(t, &scheduleNow, payment.LastRunAt)
+
+			if !scenario.expectedNotified {
+				assert.Empty(t, publisher.events)
+				return
+			}
+			if assert.Len(t, publisher.events, 1) {
+				assert.Equal(t, domain.EventScheduledPaymentFailed, publisher.events[0].Type)
+				assert.Equal(t, "any-account", publisher.events[0].AccountID)
+				assert.Equal(t, scenario.expectedError, publisher.events[0].Data["error"])
+			}
+		})
+	}
+}
+
+// Test_ScheduleRunDatabaseFailure runs payments whose transactions fail in the
+// database, aborting the transaction of the run.
+func Test_ScheduleRunDatabaseFailure(t *testing.T) {
+	payments := newScheduleRepositoryMock(
+		domain.ScheduledPayment{Id: "payment-a", AccountID: "any-account", Frequency: domain.ScheduleOnce, AmountType: domain.AmountFixed, Amount: 50, Status: domain.ScheduleActive, NextRunAt: scheduleNow},
+		domain.ScheduledPayment{Id: "payment-b", AccountID: "any-account", Frequency: domain.ScheduleOnce, AmountType: domain.AmountFixed, Amount: 50, Status: domain.ScheduleActive, NextRunAt: scheduleNow},
+	)
+	audit := &auditRepositoryMock{}
+	transactions := &transactionUseCaseMock{err: exceptions.PersistenceError, onCreate: func() { audit.aborted = true }}
+	uc := newScheduleUseCase(payments, domain.Account{}, transactions, 0, &publisherMock{})
+	uc.auditor = NewAuditUseCase(audit)
+
+	abortedAtUpdate := true
+	payments.onUpdate = func() { abortedAtUpdate = audit.aborted }
+
+	count, err := uc.Run(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.False(t, abortedAtUpdate, "the failed posting is rolled back before the payment is updated")
+	assert.True(t, audit.tx.committed)
+	for _, payment := range payments.payments {
+		assert.Equal(t, domain.ScheduleActive, payment.Status, payment.Id)
+		assert.Equal(t, 1, payment.Attempts, payment.Id)
+		assert.Equal(t, scheduleNow.Add(15*time.Minute), payment.NextRunAt, payment.Id)
+	}
+}
+
+func Test_ScheduleRunKeepsGoing(t *testing.T) {
+	payments := newScheduleRepositoryMock(
+		domain.ScheduledPayment{Id: "payment-a", AccountID: "any-account", Frequency: domain.ScheduleOnce, AmountType: domain.AmountFixed, Amount: 50, Status: domain.ScheduleActive, NextRunAt: scheduleNow},
+		domain.ScheduledPayment{Id: "payment-b", AccountID: "any-account", Frequency: domain.ScheduleOnce, AmountType: domain.AmountFixed, Amount: 50, Status: domain.ScheduleActive, NextRunAt: scheduleNow},
+	)
+	payments.updateErrs = map[string]error{"payment-a": exceptions.UnavailableError}
+	transactions := &transactionUseCaseMock{}
+	uc := newScheduleUseCase(payments, domain.Account{}, transactions, 0, &publisherMock{})
+
+	count, err := uc.Run(context.Background())
+
+	assert.ErrorIs(t, err, exceptions.UnavailableError)
+	assert.Equal(t, 2, count)
+	assert.Len(t, transactions.created, 2, "a payment failing to run does not stop the batch")
+	assert.Equal(t, domain.ScheduleCompleted, payments.payments["payment-b"].Status)
+}
+
+func Test_ScheduleRunDenied(t *testing.T) {
+	payments := newScheduleRepositoryMock(domain.ScheduledPayment{
+		Id: "any-payment", AccountID: "any-account", Frequency: domain.ScheduleOnce, AmountType: domain.AmountFixed, Amount: 50,
+		Status: domain.ScheduleActive, NextRunAt: scheduleNow,
+	})
+	uc := newScheduleUseCase(payments, domain.Account{}, &transactionUseCaseMock{err: exceptions.TransactionDeniedError}, 0, &publisherMock{})
+
+	_, err := uc.Run(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, payments.payments["any-payment"].LastTransactionID, "the declined transaction stays traceable")
+}
+
+func Test_ScheduleCancelUseCase(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		accountID     string
+		status        domain.ScheduleStatus
+		expectedError error
+	}{
+		{
+			description: "active",
+			accountID:   "any-account",
+			status:      domain.ScheduleActive,
+		},
+		{
+			description:   "already completed",
+			accountID:     "any-account",
+			status:        domain.ScheduleCompleted,
+			expectedError: exceptions.InvalidStateError,
+		},
+		{
+			description:   "payment of another account",
+			accountID:     "other-account",
+			status:        domain.ScheduleActive,
+			expectedError: exceptions.EntityNotFoundError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			payments := newScheduleRepositoryMock(domain.ScheduledPayment{Id: "any-payment", AccountID: "any-account", Status: scenario.status})
+			uc := newScheduleUseCase(payments, domain.Account{Id: scenario.accountID}, &transactionUseCaseMock{}, 0, &publisherMock{})
+			auditor := &auditorMock{}
+			uc.auditor = auditor
+
+			err := uc.Cancel(context.Background(), scenario.accountID, "any-payment")
+
+			if assert.Len(t, auditor.changes, 1) {
+				assert.Equal(t, domain.AuditSchedule, auditor.changes[0].EntityType)
+				assert.Equal(t, domain.AuditCancel, auditor.changes[0].Action)
+			}
+
+			if scenario.expectedError != nil {
+				assert.ErrorIs(t, err, scenario.expectedError)
+				assert.Equal(t, scenario.status, payments.payments["any-payment"].Status)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, domain.ScheduleCancelled, payments.payments["any-payment"].Status)
+		})
+	}
+}
+
+func Test_ScheduleCancelRacingRun(t *testing.T) {
+	scenarios := []struct {
+		description         string
+		race                func(payments *scheduleRepositoryMock, transactions *transactionUseCaseMock, uc ScheduleUcImpl, cancelErr *error)
+		cancel              bool
+		expectedRuns        int
+		expectedStatus      domain.ScheduleStatus
+		expectedCancelError error
+	}{
+		{
+			description: "cancelled once listed due",
+			race: func(payments *scheduleRepositoryMock, _ *transactionUseCaseMock, uc ScheduleUcImpl, cancelErr *error) {
+				payments.onDue = func() { *cancelErr = uc.Cancel(context.Background(), "any-account", "any-payment") }
+			},
+			expectedStatus: domain.ScheduleCancelled,
+		},
+		{
+			description: "cancelled while the transaction is posted",
+			race: func(_ *scheduleRepositoryMock, transactions *transactionUseCaseMock, uc ScheduleUcImpl, cancelErr *error) {
+				transactions.onCreate = func() { *cancelErr = uc.Cancel(context.Background(), "any-account", "any-payment") }
+			},
+			expectedRuns:   1,
+			expectedStatus: domain.ScheduleCancelled,
+		},
+		{
+			description: "cancel stored after the run",
+			race: func(payments *scheduleRepositoryMock, _ *transactionUseCaseMock, uc ScheduleUcImpl, _ *error) {
+				payments.onUpdate = func() { _, _ = uc.Run(context.Background()) }
+			},
+			cancel:              true,
+			expectedRuns:        1,
+			expectedStatus:      domain.ScheduleCompleted,
+			expectedCancelError: exceptions.InvalidStateError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			payments := newScheduleRepositoryMock(domain.ScheduledPayment{
+				Id: "any-payment", AccountID: "any-account", Frequency: domain.ScheduleOnce, AmountType: domain.AmountFixed, Amount: 50,
+				Status: domain.ScheduleActive, NextRunAt: scheduleNow,
+			})
+			transactions := &transactionUseCaseMock{}
+			uc := newScheduleUseCase(payments, domain.Account{Id: "any-account"}, transactions, 0, &publisherMock{})
+
+			var cancelErr error
+			scenario.race(payments, transactions, uc, &cancelErr)
+			if scenario.cancel {
+				cancelErr = uc.Cancel(context.Background(), "any-account", "any-payment")
+			} else {
+				_, err := uc.Run(context.Background())
+				assert.NoError(t, err)
+			}
+
+			assert.ErrorIs(t, cancelErr, scenario.expectedCancelError)
+			assert.Len(t, transactions.created, scenario.expectedRuns)
+			assert.Equal(t, scenario.expectedStatus, payments.payments["any-payment"].Status)
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/mcc"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/domain"
+)
+
+// SpendingUseCase aggregates the purchases of an account by the category of
+// their merchant category code.
+type SpendingUseCase interface {
+	ByCategory(ctx context.Context, accountID string, from, to time.Time) (domain.Spending, error)
+	ByMerchant(ctx context.Context, accountID, category string, from, to time.Time) (domain.Spending, error)
+}
+
+type SpendingUcImpl struct {
+	accountRepository     repository.Account
+	transactionRepository repository.Transaction
+}
+
+// ByCategory sums the purchases of an account of the caller between from and
+// to by category.
+func (s SpendingUcImpl) ByCategory(ctx context.Context, accountID string, from, to time.Time) (domain.Spending, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:spending:ByCategory", trace.SpanKindInternal)
+	defer span.End()
+
+	merchants, err := s.merchants(ctx, accountID, from, to)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Spending{}, err
+	}
+
+	byCategory := make(map[string]*domain.SpendingItem)
+	for _, merchant := range merchants {
+		category := mcc.Category(merchant.MCC)
+		item, ok := byCategory[category]
+		if !ok {
+			item = &domain.SpendingItem{Name: category}
+			byCategory[category] = item
+		}
+		item.Amount += merchant.Amount
+		item.Count += merchant.Count
+	}
+
+	items := make([]domain.SpendingItem, 0, len(byCategory))
+	for _, item := range byCategory {
+		items = append(items, *item)
+	}
+
+	return newSpending(accountID, "", from, to, items), nil
+}
+
+// ByMerchant sums the purchases of one category of an account of the caller
+// between from and to by merchant.
+func (s SpendingUcImpl) ByMerchant(ctx context.Context, accountID, category string, from, to time.Time) (domain.Spending, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:spending:ByMerchant", trace.SpanKindInternal)
+	defer span.End()
+
+	if !mcc.IsCategory(category) {
+		err := exceptions.InvalidParameterError.WithFields(exceptions.FieldError{
+			Field:   "category",
+			Message: "must be one of " + strings.Join(mcc.Categories(), ", "),
+		})
+		telemetry.ErrorSpan(span, err)
+		return domain.Spending{}, err
+	}
+
+	merchants, err := s.merchants(ctx, accountID, from, to)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Spending{}, err
+	}
+
+	items := make([]domain.SpendingItem, 0, len(merchants))
+	for _, merchant := range merchants {
+		if mcc.Category(merchant.MCC) == category {
+			items = append(items, merchant)
+		}
+	}
+
+	return newSpending(accountID, category, from, to, items), nil
+}
+
+// merchants returns the purchases of an account of the caller between from
+// and to summed by merchant, accounts of other owners are not found.
+func (s SpendingUcImpl) merchants(ctx context.Context, accountID string, from, to time.Time) ([]domain.SpendingItem, error) {
+	if !from.Before(to) {
+		return nil, exceptions.InvalidParameterError.WithFields(exceptions.FieldError{Field: "from", Message: "must be before to"})
+	}
+
+	account, err := s.accountRepository.Get(ctx, accountID)
+	if err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot get spending account", logger.Str("account_id", accountID), logger.Err(err))
+		return nil, fmt.Errorf("get account %s: %w", accountID, err)
+	}
+
+	if !auth.CanAccess(ctx, account.DocumentNumber) {
+		logger.Warn(ctx, logger.ServerError, "account owned by another caller", logger.Str("account_id", accountID))
+		return nil, exceptions.EntityNotFoundError.WithDetail(fmt.Sprintf("account %s not found", accountID))
+	}
+
+	merchants, err := s.transactionRepository.SumByMerchant(ctx, accountID, from, to)
+	if err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot sum purchases", logger.Str("account_id", accountID), logger.Err(err))
+		return nil, fmt.Errorf("sum purchases of account %s: %w", accountID, err)
+	}
+
+	return merchants, nil
+}
+
+// newSpending rounds the amounts of items to cents and orders them by
+// amount, the largest first, then by name.
+func newSpending(accountID, category string, from, to time.Time, items []domain.SpendingItem) domain.Spending {
+	spending := domain.Spending{AccountID: accountID, From: from, To: to, Category: category, Items: items}
+
+	for i := range items {
+		spending.Total += items[i].Amount
+		spending.Count += items[i].Count
+		items[i].Amount = cents(items[i].Amount)
+	}
+	spending.Total = cents(spending.Total)
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Amount != items[j].Amount {
+			return items[i].Amount > items[j].Amount
+		}
+		return items[i].Name < items[j].Name
+	})
+
+	return spending
+}
+
+func cents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func NewSpendingUseCase(accountRepository repository.Account, transactionRepository repository.Transaction) SpendingUseCase {
+	return SpendingUcImpl{
+		accountRepository:     accountRepository,
+		transactionRepository: transactionRepository,
+	}
+}
@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/domain"
+)
+
+func Test_SpendingUseCase(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	transactions := &transactionRepositoryMock{merchants: []domain.SpendingItem{
+		{Name: "Mercado Central", MCC: "5411", Amount: 120.1, Count: 3},
+		{Name: "Padaria", MCC: "5462", Amount: 30.2, Count: 4},
+		{Name: "Cantina", MCC: "5812", Amount: 150.5, Count: 2},
+		{Name: "Unknown Code", MCC: "0001", Amount: 5, Count: 1},
+		{Amount: 8, Count: 1},
+	}}
+	spendingUseCase := NewSpendingUseCase(&accountRepositoryMock{Result: domain.Account{Id: "any-account", DocumentNumber: "any-document"}}, transactions)
+
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "user-1", Owner: "any-document"})
+
+	byCategory, err := spendingUseCase.ByCategory(ctx, "any-account", from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, 313.8, byCategory.Total)
+	assert.Equal(t, 11, byCategory.Count)
+	assert.Equal(t, []domain.SpendingItem{
+		{Name: "restaurants", Amount: 150.5, Count: 2},
+		{Name: "groceries", Amount: 150.3, Count: 7},
+		{Name: "uncategorized", Amount: 8, Count: 1},
+		{Name: "other", Amount: 5, Count: 1},
+	}, byCategory.Items)
+
+	byMerchant, err := spendingUseCase.ByMerchant(ctx, "any-account", "groceries", from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, "groceries", byMerchant.Category)
+	assert.Equal(t, 150.3, byMerchant.Total)
+	assert.Equal(t, []domain.SpendingItem{
+		{Name: "Mercado Central", MCC: "5411", Amount: 120.1, Count: 3},
+		{Name: "Padaria", MCC: "5462", Amount: 30.2, Count: 4},
+	}, byMerchant.Items)
+
+	_, err = spendingUseCase.ByMerchant(ctx, "any-account", "unknown", from, to)
+	assert.ErrorIs(t, err, exceptions.InvalidParameterError)
+
+	_, err = spendingUseCase.ByCategory(ctx, "any-account", to, from)
+	assert.ErrorIs(t, err, exceptions.InvalidParameterError)
+
+	other := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "user-2", Owner: "other-document"})
+	_, err = spendingUseCase.ByCategory(other, "any-account", from, to)
+	assert.ErrorIs(t, err, exceptions.EntityNotFoundError)
+}
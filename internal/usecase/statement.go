@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/domain"
+)
+
+// StatementUseCase builds account statements, their transactions are walked
+// separately so they can be streamed as they are read. Begin opens the
+// snapshot both read from, so the balances agree with the transactions.
+type StatementUseCase interface {
+	Begin(ctx context.Context) (context.Context, func(), error)
+	Get(ctx context.Context, accountID string, from, to time.Time) (domain.Statement, error)
+	Walk(ctx context.Context, statement domain.Statement, fn func(domain.Transaction) error) error
+}
+
+type StatementUcImpl struct {
+	accountRepository     repository.Account
+	transactionRepository repository.Transaction
+	currency              string
+	bankID                string
+	now                   func() time.Time
+}
+
+// Begin starts the read only snapshot Get and Walk join through the returned
+// context, the returned end releases it.
+func (s StatementUcImpl) Begin(ctx context.Context) (context.Context, func(), error) {
+	ctx, span := telemetry.Span(ctx, "useCase:statement:Begin", trace.SpanKindInternal)
+	defer span.End()
+
+	ctx, tx, err := s.transactionRepository.Snapshot(ctx)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return ctx, nil, fmt.Errorf("begin statement snapshot: %w", err)
+	}
+
+	return ctx, func() { _ = tx.Rollback() }, nil
+}
+
+// Get returns the statement of accountID between from and to with its
+// opening and closing balances, accounts of other owners are not found.
+func (s StatementUcImpl) Get(ctx context.Context, accountID string, from, to time.Time) (domain.Statement, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:statement:Get", trace.SpanKindInternal)
+	defer span.End()
+
+	if !from.Before(to) {
+		err := exceptions.InvalidParameterError.WithFields(exceptions.FieldError{Field: "from", Message: "must be before to"})
+		telemetry.ErrorSpan(span, err)
+		return domain.Statement{}, err
+	}
+
+	account, err := s.accountRepository.Get(ctx, accountID)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot get statement account", logger.Str("account_id", accountID), logger.Err(err))
+		return domain.Statement{}, fmt.Errorf("get account %s: %w", accountID, err)
+	}
+
+	if !auth.CanAccess(ctx, account.DocumentNumber) {
+		telemetry.ErrorSpan(span, exceptions.EntityNotFoundError)
+		logger.Warn(ctx, logger.ServerError, "account owned by another caller", logger.Str("account_id", accountID))
+		return domain.Statement{}, exceptions.EntityNotFoundError.WithDetail(fmt.Sprintf("account %s not found", accountID))
+	}
+
+	statement := domain.Statement{
+		Id:        strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Account:   account,
+		Currency:  s.currency,
+		BankID:    s.bankID,
+		From:      from,
+		To:        to,
+		CreatedAt: s.now(),
+	}
+
+	if statement.OpeningBalance, err = s.transactionRepository.Balance(ctx, accountID, from); err == nil {
+		statement.ClosingBalance, err = s.transactionRepository.Balance(ctx, accountID, to)
+	}
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot compute statement balances", logger.Str("account_id", accountID), logger.Err(err))
+		return domain.Statement{}, fmt.Errorf("compute balances of account %s: %w", accountID, err)
+	}
+
+	return statement, nil
+}
+
+// Walk streams the transactions of statement to fn in date order.
+func (s StatementUcImpl) Walk(ctx context.Context, statement domain.Statement, fn func(domain.Transaction) error) error {
+	ctx, span := telemetry.Span(ctx, "useCase:statement:Walk", trace.SpanKindInternal)
+	defer span.End()
+
+	if err := s.transactionRepository.Walk(ctx, statement.Account.Id, statement.From, statement.To, fn); err != nil {
+		telemetry.ErrorSpan(span, err)
+		return fmt.Errorf("walk transactions of account %s: %w", statement.Account.Id, err)
+	}
+
+	return nil
+}
+
+func NewStatementUseCase(accountRepository repository.Account, transactionRepository repository.Transaction, currency, bankID string) StatementUseCase {
+	return StatementUcImpl{
+		accountRepository:     accountRepository,
+		transactionRepository: transactionRepository,
+		currency:              currency,
+		bankID:                bankID,
+		now:                   time.Now,
+	}
+}
@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
+)
+
+func Test_StatementGet(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	scenarios := []struct {
+		description   string
+		ctx           context.Context
+		from          time.Time
+		account       *accountRepositoryMock
+		balanceErr    error
+		expectedError error
+	}{
+		{
+			description: "success",
+			ctx:         context.Background(),
+			from:        from,
+			account:     &accountRepositoryMock{Result: domain.Account{Id: "account-1", DocumentNumber: "owner"}},
+		},
+		{
+			description: "owner",
+			ctx:         auth.WithPrincipal(context.Background(), auth.Principal{Owner: "owner"}),
+			from:        from,
+			account:     &accountRepositoryMock{Result: domain.Account{Id: "account-1", DocumentNumber: "owner"}},
+		},
+		{
+			description:   "account of another owner",
+			ctx:           auth.WithPrincipal(context.Background(), auth.Principal{Owner: "other"}),
+			from:          from,
+			account:       &accountRepositoryMock{Result: domain.Account{Id: "account-1", DocumentNumber: "owner"}},
+			expectedError: exceptions.EntityNotFoundError,
+		},
+		{
+			description:   "account not found",
+			ctx:           context.Background(),
+			from:          from,
+			account:       &accountRepositoryMock{err: exceptions.EntityNotFoundError},
+			expectedError: exceptions.EntityNotFoundError,
+		},
+		{
+			description:   "empty period",
+			ctx:           context.Background(),
+			from:          to,
+			account:       &accountRepositoryMock{Result: domain.Account{Id: "account-1"}},
+			expectedError: exceptions.InvalidParameterError,
+		},
+		{
+			description:   "balances unavailable",
+			ctx:           context.Background(),
+			from:          from,
+			account:       &accountRepositoryMock{Result: domain.Account{Id: "account-1"}},
+			balanceErr:    exceptions.UnavailableError,
+			expectedError: exceptions.UnavailableError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			uc := StatementUcImpl{
+				accountRepository: scenario.account,
+				transactionRepository: &transactionRepositoryMock{
+					err:      scenario.balanceErr,
+					balances: map[time.Time]float64{from: 150, to: -35.25},
+				},
+				currency: "BRL",
+				bankID:   "0001",
+				now:      func() time.Time { return to.Add(time.Hour) },
+			}
+
+			statement, err := uc.Get(scenario.ctx, "account-1", scenario.from, to)
+
+			assert.ErrorIs(t, err, scenario.expectedError)
+			if scenario.expectedError != nil {
+				return
+			}
+
+			assert.Len(t, statement.Id, 32, "statement ids fit the 35 characters of camt.053")
+			assert.Equal(t, "account-1", statement.Account.Id)
+			assert.Equal(t, "BRL", statement.Currency)
+			assert.Equal(t, "0001", statement.BankID)
+			assert.Equal(t, 150.0, statement.OpeningBalance)
+			assert.Equal(t, -35.25, statement.ClosingBalance)
+			assert.Equal(t, to.Add(time.Hour), statement.CreatedAt)
+		})
+	}
+}
+
+func Test_StatementWalk(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	transactions := &transactionRepositoryMock{walked: []domain.Transaction{
+		{Id: 1, OperationType: operation.CASH_PURCHASES, Amount: 10, EventDate: from.Add(-time.Hour)},
+		{Id: 2, OperationType: operation.PAYMENT, Amount: 20, EventDate: from},
+		{Id: 3, OperationType: operation.WITHDRAW, Amount: 30, EventDate: from.AddDate(0, 1, 0)},
+	}}
+	uc := StatementUcImpl{transactionRepository: transactions}
+
+	ids := make([]int, 0)
+	err := uc.Walk(context.Background(), domain.Statement{Account: domain.Account{Id: "account-1"}, From: from, To: from.AddDate(0, 1, 0)}, func(transaction domain.Transaction) error {
+		ids = append(ids, transaction.Id)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, ids, "from is inclusive and to exclusive")
+}
+
+func Test_StatementSnapshot(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	transactions := &transactionRepositoryMock{balances: map[time.Time]float64{from: 150}}
+	uc := StatementUcImpl{
+		accountRepository:     &accountRepositoryMock{Result: domain.Account{Id: "account-1"}},
+		transactionRepository: transactions,
+		now:                   time.Now,
+	}
+
+	ctx, end, err := uc.Begin(context.Background())
+	assert.NoError(t, err)
+
+	_, err = uc.Get(context.Background(), "account-1", from, from.AddDate(0, 1, 0))
+	assert.Error(t, err, "balances are read from the snapshot")
+
+	statement, err := uc.Get(ctx, "account-1", from, from.AddDate(0, 1, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, 150.0, statement.OpeningBalance)
+	assert.False(t, transactions.snapshot.released)
+
+	end()
+	assert.True(t, transactions.snapshot.released)
+}
@@ -0,0 +1,38 @@
+package usecase
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/internal/domain"
+)
+
+// TenantPolicies holds the policy of each tenant, the transactions of tenants
+// without one are not restricted.
+type TenantPolicies map[string]domain.TenantPolicy
+
+// Check applies the policy of tenant to transaction, on top of
+// ValidateTransaction.
+func (p TenantPolicies) Check(tenant string, transaction domain.Transaction) error {
+	policy, ok := p[tenant]
+	if !ok {
+		return nil
+	}
+
+	if !policy.AllowsOperation(transaction.OperationType) {
+		return exceptions.InvalidOperationTypeError.WithFields(exceptions.FieldError{
+			Field:   "operation_type",
+			Message: "is not enabled for the card program",
+		})
+	}
+
+	if !policy.AllowsAmount(transaction.Amount) {
+		return exceptions.InvalidAmountError.WithFields(exceptions.FieldError{
+			Field:   "amount",
+			Message: fmt.Sprintf("must be at most %s", strconv.FormatFloat(policy.MaxAmount, 'f', -1, 64)),
+		})
+	}
+
+	return nil
+}
@@ -2,20 +2,32 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/payment-api/infrastructure/auth"
 	"github.com/payment-api/infrastructure/exceptions"
 	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/mcc"
 	"github.com/payment-api/infrastructure/telemetry"
 	"github.com/payment-api/internal/adapter/repository"
 	"github.com/payment-api/internal/domain"
 )
 
+const (
+	maxMerchantName = 100
+	maxMerchantCity = 60
+)
+
 type (
 	TransactionUseCase interface {
-		Create(context.Context, domain.Transaction) error
+		Create(context.Context, domain.Transaction) (domain.Transaction, error)
+		Get(context.Context, int) (domain.Transaction, error)
 	}
 )
 
@@ -23,32 +35,242 @@ type (
 	TransactionUcImpl struct {
 		accountRepository     repository.Account
 		transactionRepository repository.Transaction
+		cardRepository        repository.Card
+		fraudEngine           FraudEngine
+		auditor               Auditor
+		publisher             Publisher
+		policies              TenantPolicies
+		now                   func() time.Time
 	}
 )
 
-func (t TransactionUcImpl) Create(ctx context.Context, transaction domain.Transaction) error {
+// Create posts a transaction once the fraud rules assessed it. Transactions
+// to review are queued as pending_review, denied ones are recorded as declined,
+// so the decision stays traceable, and answered with TransactionDeniedError.
+// Transactions requested with a card token are posted to the account of the
+// card, once the card is checked to be usable.
+func (t TransactionUcImpl) Create(ctx context.Context, transaction domain.Transaction) (created domain.Transaction, err error) {
 	ctx, span := telemetry.Span(ctx, "useCase:transaction:Create", trace.SpanKindInternal)
 	defer span.End()
 
-	_, err := t.accountRepository.Get(ctx, transaction.AccountID)
+	ctx, finish, err := t.auditor.Begin(ctx)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Transaction{}, err
+	}
+	defer func() { err = finish(err) }()
+
+	defer func() {
+		change := domain.AuditChange{EntityType: domain.AuditTransaction, Action: domain.AuditCreate}
+		if created.Id != 0 {
+			change.EntityID, change.After = strconv.Itoa(created.Id), created
+		}
+		t.auditor.Record(ctx, change, err)
+	}()
+
+	if transaction.CardToken != "" {
+		if transaction, err = t.resolveCard(ctx, transaction); err != nil {
+			telemetry.ErrorSpan(span, err)
+			return domain.Transaction{}, err
+		}
+	}
+
+	account, err := t.accountRepository.Get(ctx, transaction.AccountID)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot get transaction account", logger.Str("account_id", transaction.AccountID), logger.Err(err))
+		return domain.Transaction{}, fmt.Errorf("get account %s: %w", transaction.AccountID, err)
+	}
+
+	if !auth.CanAccess(ctx, account.DocumentNumber) {
+		telemetry.ErrorSpan(span, exceptions.EntityNotFoundError)
+		logger.Warn(ctx, logger.ServerError, "account owned by another caller", logger.Str("account_id", transaction.AccountID))
+		return domain.Transaction{}, exceptions.EntityNotFoundError.WithDetail(fmt.Sprintf("account %s not found", transaction.AccountID))
+	}
+
+	if !account.IsOpen() {
+		telemetry.ErrorSpan(span, exceptions.AccountClosedError)
+		return domain.Transaction{}, exceptions.AccountClosedError.WithDetail(fmt.Sprintf("account %s is closed", transaction.AccountID))
+	}
+
+	if err = t.policies.Check(account.TenantID, transaction); err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Transaction{}, err
+	}
+
+	assessment, err := t.fraudEngine.Evaluate(ctx, transaction)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot assess transaction", logger.Err(err))
+		return domain.Transaction{}, fmt.Errorf("assess transaction: %w", err)
+	}
+
+	transaction.Decision = assessment.Decision
+	transaction.Reasons = assessment.Reasons
+	transaction.Status = domain.StatusFor(assessment.Decision)
+
+	transaction, err = t.transactionRepository.Push(ctx, transaction)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot create transaction", logger.Err(err))
+		return domain.Transaction{}, fmt.Errorf("create transaction: %w", err)
+	}
+
+	eventType := domain.EventTransactionCreated
+	if transaction.Status == domain.StatusDeclined {
+		eventType = domain.EventTransactionDeclined
+	}
+	t.publisher.Publish(ctx, domain.NewTransactionEvent(eventType, transaction))
+
+	if transaction.Decision != domain.DecisionAllow {
+		logger.Warn(ctx, logger.FraudAlert, "transaction flagged by fraud rules",
+			logger.Int("transaction_id", transaction.Id),
+			logger.Str("account_id", transaction.AccountID),
+			logger.Str("decision", string(transaction.Decision)),
+			logger.Str("reasons", strings.Join(transaction.Reasons, ",")),
+		)
+	}
+
+	if transaction.Decision == domain.DecisionDeny {
+		telemetry.ErrorSpan(span, exceptions.TransactionDeniedError)
+		return transaction, exceptions.TransactionDeniedError.WithDetail(
+			fmt.Sprintf("transaction %d denied by rules: %s", transaction.Id, strings.Join(transaction.Reasons, ", ")),
+		)
+	}
+
+	return transaction, nil
+}
+
+func (t TransactionUcImpl) Get(ctx context.Context, id int) (domain.Transaction, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:transaction:Get", trace.SpanKindInternal)
+	defer span.End()
+
+	transaction, err := t.transactionRepository.Get(ctx, id)
 	if err != nil {
 		telemetry.ErrorSpan(span, err)
-		logger.Error(logger.ServerError, "account not found", err.Error())
-		return exceptions.EntityNotFoundError
+		logger.Error(ctx, logger.ServerError, "cannot get transaction", logger.Int("transaction_id", id), logger.Err(err))
+		return domain.Transaction{}, fmt.Errorf("get transaction %d: %w", id, err)
 	}
 
-	if err := t.transactionRepository.Push(ctx, transaction); err != nil {
+	account, err := t.accountRepository.Get(ctx, transaction.AccountID)
+	if err != nil {
 		telemetry.ErrorSpan(span, err)
-		logger.Error(logger.ServerError, fmt.Sprintf("cannot create transaction error: %v", err.Error()))
-		return exceptions.PersistenceError
+		return domain.Transaction{}, fmt.Errorf("get account %s: %w", transaction.AccountID, err)
+	}
+
+	if !auth.CanAccess(ctx, account.DocumentNumber) {
+		telemetry.ErrorSpan(span, exceptions.EntityNotFoundError)
+		return domain.Transaction{}, exceptions.EntityNotFoundError.WithDetail(fmt.Sprintf("transaction %d not found", id))
+	}
+
+	return transaction, nil
+}
+
+// resolveCard sets the account and card of a transaction requested with a card
+// token. Cards of other tenants, or of another account than the one requested,
+// are not found; blocked, replaced and expired cards are rejected.
+func (t TransactionUcImpl) resolveCard(ctx context.Context, transaction domain.Transaction) (domain.Transaction, error) {
+	card, err := t.cardRepository.GetByToken(ctx, transaction.CardToken)
+	if err == nil && transaction.AccountID != "" && card.AccountID != transaction.AccountID {
+		err = exceptions.EntityNotFoundError
+	}
+	if err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot get transaction card", logger.Err(err))
+		if errors.Is(err, exceptions.EntityNotFoundError) {
+			return domain.Transaction{}, exceptions.EntityNotFoundError.WithDetail("card not found")
+		}
+		return domain.Transaction{}, fmt.Errorf("get card: %w", err)
+	}
+
+	if !card.IsUsable(t.now()) {
+		status := card.Status
+		if status == domain.CardActive {
+			status = domain.CardExpired
+		}
+		return domain.Transaction{}, exceptions.CardInactiveError.WithDetail(fmt.Sprintf("card %s is %s", card.Id, status))
+	}
+
+	transaction.AccountID, transaction.CardID, transaction.CardToken = card.AccountID, card.Id, ""
+	return transaction, nil
+}
+
+// ValidateTransaction applies the request rules shared by every way of
+// creating a transaction: REST, gRPC and bulk imports.
+func ValidateTransaction(transaction domain.Transaction) error {
+	if transaction.AccountID == "" && transaction.CardToken == "" {
+		return exceptions.ValidationError.WithFields(exceptions.FieldError{Field: "account_id", Message: "is required without card_token"})
+	}
+
+	if !transaction.OperationType.IsValid() {
+		return exceptions.InvalidOperationTypeError.WithFields(exceptions.FieldError{
+			Field:   "operation_type",
+			Message: "must be one of 1, 2, 3, 4",
+		})
+	}
+
+	if transaction.Amount < 0 {
+		return exceptions.InvalidAmountError.WithFields(exceptions.FieldError{
+			Field:   "amount",
+			Message: "must be greater than or equal to 0",
+		})
+	}
+
+	if transaction.Amount == 0 {
+		return exceptions.ValidationError.WithFields(exceptions.FieldError{Field: "amount", Message: "is required"})
+	}
+
+	if !transaction.Merchant.IsZero() {
+		return validateMerchant(transaction)
+	}
+
+	return nil
+}
+
+// validateMerchant accepts merchants of purchases only, with a name and a
+// merchant category code.
+func validateMerchant(transaction domain.Transaction) error {
+	if !transaction.OperationType.IsPurchase() {
+		return exceptions.ValidationError.WithFields(exceptions.FieldError{Field: "merchant", Message: "is only allowed on purchases"})
+	}
+
+	merchant := transaction.Merchant
+	var fields []exceptions.FieldError
+
+	switch {
+	case merchant.Name == "":
+		fields = append(fields, exceptions.FieldError{Field: "merchant.name", Message: "is required"})
+	case len(merchant.Name) > maxMerchantName:
+		fields = append(fields, exceptions.FieldError{Field: "merchant.name", Message: fmt.Sprintf("must be at most %d characters", maxMerchantName)})
+	}
+
+	if !mcc.Valid(merchant.MCC) {
+		fields = append(fields, exceptions.FieldError{Field: "merchant.mcc", Message: "must be 4 digits"})
+	}
+
+	if len(merchant.City) > maxMerchantCity {
+		fields = append(fields, exceptions.FieldError{Field: "merchant.city", Message: fmt.Sprintf("must be at most %d characters", maxMerchantCity)})
+	}
+
+	if merchant.Country != "" && !countryPattern.MatchString(merchant.Country) {
+		fields = append(fields, exceptions.FieldError{Field: "merchant.country", Message: "must be an ISO 3166-1 alpha-2 code"})
+	}
+
+	if len(fields) > 0 {
+		return exceptions.ValidationError.WithFields(fields...)
 	}
 
 	return nil
 }
 
-func NewTransactionUseCase(accountRepository repository.Account, transactionRepository repository.Transaction) TransactionUseCase {
+func NewTransactionUseCase(accountRepository repository.Account, transactionRepository repository.Transaction, cardRepository repository.Card, fraudEngine FraudEngine, auditor Auditor, publisher Publisher, policies TenantPolicies) TransactionUseCase {
 	return TransactionUcImpl{
 		accountRepository:     accountRepository,
 		transactionRepository: transactionRepository,
+		cardRepository:        cardRepository,
+		fraudEngine:           fraudEngine,
+		auditor:               auditor,
+		publisher:             publisher,
+		policies:              policies,
+		now:                   time.Now,
 	}
 }
@@ -4,30 +4,176 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.opentelemetry.io/otel/sdk/trace"
 
+	"github.com/payment-api/infrastructure/auth"
 	"github.com/payment-api/infrastructure/exceptions"
 	"github.com/payment-api/internal/adapter/repository"
 	"github.com/payment-api/internal/domain"
+	operation "github.com/payment-api/internal/enum"
 )
 
 type transactionRepositoryMock struct {
-	Result domain.Transaction
-	err    error
+	Result     domain.Transaction
+	count      int
+	err        error
+	resolveErr error
+	resolved   domain.Transaction
+	reversed   domain.Transaction
+	expireFrom time.Time
+	copied     []domain.Transaction
+	walked     []domain.Transaction
+	balances   map[time.Time]float64
+	merchants  []domain.SpendingItem
+	snapshot   *snapshotTxMock
 }
 
-func (r *transactionRepositoryMock) Push(_ context.Context, _ domain.Transaction) error {
+type snapshotTxMock struct {
+	released bool
+}
+
+func (t *snapshotTxMock) Commit() error {
+	t.released = true
+	return nil
+}
+
+func (t *snapshotTxMock) Rollback() error {
+	t.released = true
+	return nil
+}
+
+type snapshotKey struct{}
+
+func (r *transactionRepositoryMock) Push(_ context.Context, entity domain.Transaction) (domain.Transaction, error) {
+	entity.Id = 1
+	return entity, r.err
+}
+
+func (r *transactionRepositoryMock) Get(_ context.Context, _ int) (domain.Transaction, error) {
+	return r.Result, r.err
+}
+
+func (r *transactionRepositoryMock) Count(_ context.Context, _ string, _ time.Time) (int, error) {
+	return r.count, r.err
+}
+
+func (r *transactionRepositoryMock) ListByStatus(_ context.Context, _ domain.Status, _, _ int) ([]domain.Transaction, error) {
+	return []domain.Transaction{r.Result}, r.err
+}
+
+func (r *transactionRepositoryMock) Resolve(_ context.Context, entity domain.Transaction) error {
+	r.resolved = entity
+	return r.resolveErr
+}
+
+func (r *transactionRepositoryMock) Reverse(_ context.Context, entity domain.Transaction) error {
+	r.reversed = entity
+	return r.resolveErr
+}
+
+func (r *transactionRepositoryMock) ExpireReviews(_ context.Context, before time.Time, _, _ string) ([]domain.Transaction, error) {
+	r.expireFrom = before
+
+	expired := make([]domain.Transaction, r.count)
+	for i := range expired {
+		expired[i] = domain.Transaction{Id: i + 1, Status: domain.StatusDeclined}
+	}
+	return expired, r.err
+}
+
+func (r *transactionRepositoryMock) CopyIn(_ context.Context, entities []domain.Transaction) ([]domain.Transaction, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	stored := make([]domain.Transaction, 0, len(entities))
+	for _, entity := range entities {
+		entity.Id = len(r.copied) + 1
+		r.copied = append(r.copied, entity)
+		stored = append(stored, entity)
+	}
+	return stored, nil
+}
+
+func (r *transactionRepositoryMock) Walk(_ context.Context, _ string, from, to time.Time, fn func(domain.Transaction) error) error {
+	for _, transaction := range r.walked {
+		if transaction.EventDate.Before(from) || !transaction.EventDate.Before(to) {
+			continue
+		}
+		if err := fn(transaction); err != nil {
+			return err
+		}
+	}
 	return r.err
 }
 
+func (r *transactionRepositoryMock) Balance(ctx context.Context, _ string, before time.Time) (float64, error) {
+	if r.snapshot != nil && ctx.Value(snapshotKey{}) != r.snapshot {
+		return 0, errors.New("balance read outside of the snapshot")
+	}
+	return r.balances[before], r.err
+}
+
+func (r *transactionRepositoryMock) Snapshot(ctx context.Context) (context.Context, repository.Tx, error) {
+	r.snapshot = &snapshotTxMock{}
+	return context.WithValue(ctx, snapshotKey{}, r.snapshot), r.snapshot, r.err
+}
+
+func (r *transactionRepositoryMock) SumByMerchant(context.Context, string, time.Time, time.Time) ([]domain.SpendingItem, error) {
+	return r.merchants, r.err
+}
+
+type auditorMock struct {
+	changes []domain.AuditChange
+	errs    []error
+}
+
+func (a *auditorMock) Begin(ctx context.Context) (context.Context, func(error) error, error) {
+	return ctx, func(err error) error { return err }, nil
+}
+
+func (a *auditorMock) Savepoint(ctx context.Context, fn func(context.Context) error) error {
+	return fn(ctx)
+}
+
+func (a *auditorMock) Record(_ context.Context, change domain.AuditChange, err error) {
+	a.changes = append(a.changes, change)
+	a.errs = append(a.errs, err)
+}
+
+type publisherMock struct {
+	events []domain.Event
+}
+
+func (p *publisherMock) Publish(_ context.Context, event domain.Event) {
+	p.events = append(p.events, event)
+}
+
+type fraudEngineMock struct {
+	assessment domain.Assessment
+	err        error
+}
+
+func (f fraudEngineMock) Evaluate(context.Context, domain.Transaction, ...domain.Transaction) (domain.Assessment, error) {
+	if f.assessment.Decision == "" {
+		return domain.NewAssessment(), f.err
+	}
+	return f.assessment, f.err
+}
+
 func Test_TransactionCreateUseCase(t *testing.T) {
 	scenarios := []struct {
 		description           string
 		input                 domain.Transaction
 		accountRepository     repository.Account
 		transactionRepository repository.Transaction
+		fraudEngine           FraudEngine
+		policies              TenantPolicies
+		expectedDecision      domain.Decision
+		expectedStatus        domain.Status
 		expectedError         error
 	}{
 		{
@@ -52,6 +198,110 @@ func Test_TransactionCreateUseCase(t *testing.T) {
 			},
 			expectedError: exceptions.EntityNotFoundError,
 		},
+		{
+			description: "account-database-unavailable",
+			input:       domain.Transaction{},
+			accountRepository: &accountRepositoryMock{
+				err: exceptions.UnavailableError.Wrap(errors.New("connection refused")),
+			},
+			transactionRepository: &transactionRepositoryMock{
+				err: nil,
+			},
+			expectedError: exceptions.UnavailableError,
+		},
+		{
+			description: "account-closed",
+			input:       domain.Transaction{},
+			accountRepository: &accountRepositoryMock{
+				Result: domain.Account{Status: domain.AccountClosed},
+			},
+			transactionRepository: &transactionRepositoryMock{
+				err: nil,
+			},
+			expectedError: exceptions.AccountClosedError,
+		},
+		{
+			description: "operation-not-enabled-for-tenant",
+			input:       domain.Transaction{OperationType: operation.WITHDRAW, Amount: 10},
+			accountRepository: &accountRepositoryMock{
+				Result: domain.Account{TenantID: "program-b"},
+			},
+			transactionRepository: &transactionRepositoryMock{
+				err: nil,
+			},
+			policies:      TenantPolicies{"program-b": {OperationTypes: []operation.Type{operation.CASH_PURCHASES, operation.PAYMENT}}},
+			expectedError: exceptions.InvalidOperationTypeError,
+		},
+		{
+			description: "amount-above-tenant-maximum",
+			input:       domain.Transaction{OperationType: operation.CASH_PURCHASES, Amount: 500.01},
+			accountRepository: &accountRepositoryMock{
+				Result: domain.Account{TenantID: "program-b"},
+			},
+			transactionRepository: &transactionRepositoryMock{
+				err: nil,
+			},
+			policies:      TenantPolicies{"program-b": {MaxAmount: 500}},
+			expectedError: exceptions.InvalidAmountError,
+		},
+		{
+			description: "policy-of-another-tenant",
+			input:       domain.Transaction{OperationType: operation.WITHDRAW, Amount: 500.01},
+			accountRepository: &accountRepositoryMock{
+				Result: domain.Account{TenantID: "program-a"},
+			},
+			transactionRepository: &transactionRepositoryMock{
+				err: nil,
+			},
+			policies:      TenantPolicies{"program-b": {OperationTypes: []operation.Type{operation.PAYMENT}, MaxAmount: 500}},
+			expectedError: nil,
+		},
+		{
+			description: "review-decision",
+			input:       domain.Transaction{},
+			accountRepository: &accountRepositoryMock{
+				err: nil,
+			},
+			transactionRepository: &transactionRepositoryMock{
+				err: nil,
+			},
+			fraudEngine: fraudEngineMock{
+				assessment: domain.Assessment{Decision: domain.DecisionReview, Reasons: []string{"large-withdraw"}},
+			},
+			expectedDecision: domain.DecisionReview,
+			expectedStatus:   domain.StatusPendingReview,
+			expectedError:    nil,
+		},
+		{
+			description: "deny-decision",
+			input:       domain.Transaction{},
+			accountRepository: &accountRepositoryMock{
+				err: nil,
+			},
+			transactionRepository: &transactionRepositoryMock{
+				err: nil,
+			},
+			fraudEngine: fraudEngineMock{
+				assessment: domain.Assessment{Decision: domain.DecisionDeny, Reasons: []string{"velocity"}},
+			},
+			expectedDecision: domain.DecisionDeny,
+			expectedStatus:   domain.StatusDeclined,
+			expectedError:    exceptions.TransactionDeniedError,
+		},
+		{
+			description: "fraud-engine-unavailable",
+			input:       domain.Transaction{},
+			accountRepository: &accountRepositoryMock{
+				err: nil,
+			},
+			transactionRepository: &transactionRepositoryMock{
+				err: nil,
+			},
+			fraudEngine: fraudEngineMock{
+				err: exceptions.UnavailableError,
+			},
+			expectedError: exceptions.UnavailableError,
+		},
 		{
 			description: "any-persist-error",
 			input:       domain.Transaction{},
@@ -59,10 +309,21 @@ func Test_TransactionCreateUseCase(t *testing.T) {
 				err: nil,
 			},
 			transactionRepository: &transactionRepositoryMock{
-				err: errors.New("persist error"),
+				err: exceptions.PersistenceError.Wrap(errors.New("persist error")),
 			},
 			expectedError: exceptions.PersistenceError,
 		},
+		{
+			description: "transaction-database-unavailable",
+			input:       domain.Transaction{},
+			accountRepository: &accountRepositoryMock{
+				err: nil,
+			},
+			transactionRepository: &transactionRepositoryMock{
+				err: exceptions.UnavailableError.Wrap(errors.New("connection reset")),
+			},
+			expectedError: exceptions.UnavailableError,
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -73,11 +334,169 @@ func Test_TransactionCreateUseCase(t *testing.T) {
 			traceProvider := trace.NewTracerProvider(trace.WithSampler(trace.AlwaysSample()))
 			traceProvider.Tracer(ctx.Value("service-name").(string))
 
-			TransactionUseCase := NewTransactionUseCase(scenario.accountRepository, scenario.transactionRepository)
+			fraudEngine := scenario.fraudEngine
+			if fraudEngine == nil {
+				fraudEngine = fraudEngineMock{}
+			}
+
+			TransactionUseCase := NewTransactionUseCase(scenario.accountRepository, scenario.transactionRepository, &cardRepositoryMock{}, fraudEngine, &auditorMock{}, &publisherMock{}, scenario.policies)
+
+			output, err := TransactionUseCase.Create(ctx, scenario.input)
+
+			if scenario.expectedDecision != "" {
+				assert.Equal(t, scenario.expectedDecision, output.Decision)
+			}
+			if scenario.expectedStatus != "" {
+				assert.Equal(t, scenario.expectedStatus, output.Status)
+			}
+			if scenario.expectedError == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, scenario.expectedError)
+		})
+	}
+}
+
+func Test_TransactionCreateOwnershipUseCase(t *testing.T) {
+	accountRepository := &accountRepositoryMock{
+		Result: domain.Account{Id: "any-account-id", DocumentNumber: "any-document"},
+	}
+
+	transactionUseCase := NewTransactionUseCase(accountRepository, &transactionRepositoryMock{}, &cardRepositoryMock{}, fraudEngineMock{}, &auditorMock{}, &publisherMock{}, TenantPolicies{})
+
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "user-1", Owner: "any-document"})
+	_, err := transactionUseCase.Create(ctx, domain.Transaction{AccountID: "any-account-id"})
+	assert.NoError(t, err)
+
+	ctx = auth.WithPrincipal(context.Background(), auth.Principal{Subject: "user-2", Owner: "other-document"})
+	_, err = transactionUseCase.Create(ctx, domain.Transaction{AccountID: "any-account-id"})
+	assert.ErrorIs(t, err, exceptions.EntityNotFoundError)
+}
+
+func Test_TransactionCreateCardUseCase(t *testing.T) {
+	valid := domain.Card{Id: "any-card-id", AccountID: "any-account-id", Token: "tok_valid", Status: domain.CardActive, ExpiryMonth: 12, ExpiryYear: 2099}
+	blocked := domain.Card{Id: "blocked-card-id", AccountID: "any-account-id", Token: "tok_blocked", Status: domain.CardBlocked, ExpiryMonth: 12, ExpiryYear: 2099}
+	lapsed := domain.Card{Id: "lapsed-card-id", AccountID: "any-account-id", Token: "tok_lapsed", Status: domain.CardActive, ExpiryMonth: 1, ExpiryYear: 2020}
+	cards := &cardRepositoryMock{cards: map[string]domain.Card{valid.Id: valid, blocked.Id: blocked, lapsed.Id: lapsed}}
+
+	scenarios := []struct {
+		description       string
+		input             domain.Transaction
+		expectedAccountID string
+		expectedCardID    string
+		expectedError     error
+	}{
+		{
+			description:       "card token alone",
+			input:             domain.Transaction{CardToken: "tok_valid", OperationType: operation.PAYMENT, Amount: 10},
+			expectedAccountID: "any-account-id",
+			expectedCardID:    "any-card-id",
+		},
+		{
+			description:       "card token of the account",
+			input:             domain.Transaction{AccountID: "any-account-id", CardToken: "tok_valid", OperationType: operation.PAYMENT, Amount: 10},
+			expectedAccountID: "any-account-id",
+			expectedCardID:    "any-card-id",
+		},
+		{
+			description:   "card token of another account",
+			input:         domain.Transaction{AccountID: "other-account-id", CardToken: "tok_valid", OperationType: operation.PAYMENT, Amount: 10},
+			expectedError: exceptions.EntityNotFoundError,
+		},
+		{
+			description:   "unknown card token",
+			input:         domain.Transaction{CardToken: "tok_unknown", OperationType: operation.PAYMENT, Amount: 10},
+			expectedError: exceptions.EntityNotFoundError,
+		},
+		{
+			description:   "blocked card",
+			input:         domain.Transaction{CardToken: "tok_blocked", OperationType: operation.PAYMENT, Amount: 10},
+			expectedError: exceptions.CardInactiveError,
+		},
+		{
+			description:   "card past its expiry not yet marked expired",
+			input:         domain.Transaction{CardToken: "tok_lapsed", OperationType: operation.PAYMENT, Amount: 10},
+			expectedError: exceptions.CardInactiveError,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			transactions := &transactionRepositoryMock{}
+			accountRepository := &accountRepositoryMock{Result: domain.Account{Id: "any-account-id"}}
+
+			transactionUseCase := NewTransactionUseCase(accountRepository, transactions, cards, fraudEngineMock{}, &auditorMock{}, &publisherMock{}, TenantPolicies{})
+
+			assert.NoError(t, ValidateTransaction(scenario.input))
+			created, err := transactionUseCase.Create(context.Background(), scenario.input)
+
+			if scenario.expectedError != nil {
+				assert.ErrorIs(t, err, scenario.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.expectedAccountID, created.AccountID)
+			assert.Equal(t, scenario.expectedCardID, created.CardID)
+			assert.Empty(t, created.CardToken)
+		})
+	}
+}
+
+func Test_ValidateTransactionMerchant(t *testing.T) {
+	scenarios := []struct {
+		description    string
+		input          domain.Transaction
+		expectedFields []string
+	}{
+		{
+			description: "purchase with merchant",
+			input: domain.Transaction{AccountID: "any-account-id", OperationType: operation.CASH_PURCHASES, Amount: 10,
+				Merchant: domain.Merchant{Name: "Mercado Central", MCC: "5411", City: "São Paulo", Country: "BR"}},
+		},
+		{
+			description: "purchase without merchant",
+			input:       domain.Transaction{AccountID: "any-account-id", OperationType: operation.INSTALLMENT_PURCHASES, Amount: 10},
+		},
+		{
+			description: "merchant on a payment",
+			input: domain.Transaction{AccountID: "any-account-id", OperationType: operation.PAYMENT, Amount: 10,
+				Merchant: domain.Merchant{Name: "Mercado Central", MCC: "5411"}},
+			expectedFields: []string{"merchant"},
+		},
+		{
+			description: "merchant without name and code",
+			input: domain.Transaction{AccountID: "any-account-id", OperationType: operation.CASH_PURCHASES, Amount: 10,
+				Merchant: domain.Merchant{City: "São Paulo"}},
+			expectedFields: []string{"merchant.name", "merchant.mcc"},
+		},
+		{
+			description: "invalid code and country",
+			input: domain.Transaction{AccountID: "any-account-id", OperationType: operation.CASH_PURCHASES, Amount: 10,
+				Merchant: domain.Merchant{Name: "Mercado Central", MCC: "54a1", Country: "Brazil"}},
+			expectedFields: []string{"merchant.mcc", "merchant.country"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			err := ValidateTransaction(scenario.input)
+
+			if scenario.expectedFields == nil {
+				assert.NoError(t, err)
+				return
+			}
 
-			err := TransactionUseCase.Create(ctx, scenario.input)
+			var validation *exceptions.Error
+			assert.ErrorAs(t, err, &validation)
+			assert.ErrorIs(t, err, exceptions.ValidationError)
 
-			assert.Equal(t, scenario.expectedError, err)
+			fields := make([]string, 0, len(validation.Fields))
+			for _, field := range validation.Fields {
+				fields = append(fields, field.Field)
+			}
+			assert.Equal(t, scenario.expectedFields, fields)
 		})
 	}
 }
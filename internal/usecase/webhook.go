@@ -0,0 +1,463 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/logger"
+	"github.com/payment-api/infrastructure/telemetry"
+	"github.com/payment-api/infrastructure/webhook"
+	"github.com/payment-api/internal/adapter/repository"
+	"github.com/payment-api/internal/domain"
+)
+
+// Publisher notifies the events of the use cases, within the transaction of
+// the change they describe when it has one, or once it is committed. A
+// failure to publish is logged, not returned, rolling back the transaction it
+// fails in.
+type Publisher interface {
+	Publish(ctx context.Context, event domain.Event)
+}
+
+// Sender posts a signed payload to a webhook endpoint, ValidateURL telling
+// the endpoints it refuses to post to.
+type Sender interface {
+	Send(ctx context.Context, endpoint, secret, deliveryID, eventType string, payload []byte) (int, error)
+	ValidateURL(endpoint string) error
+}
+
+// RetryPolicy bounds the attempts of a delivery, the wait after the nth
+// failed attempt is BackoffBase * 2^(n-1), at most BackoffMax.
+type RetryPolicy struct {
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	Lease       time.Duration
+	BatchSize   int
+}
+
+func (p RetryPolicy) backoff(attempts int) time.Duration {
+	wait := p.BackoffBase
+	for i := 1; i < attempts && wait < p.BackoffMax; i++ {
+		wait *= 2
+	}
+
+	if wait > p.BackoffMax {
+		return p.BackoffMax
+	}
+
+	return wait
+}
+
+type WebhookUseCase interface {
+	Publisher
+	Create(ctx context.Context, subscription domain.Subscription) (domain.Subscription, error)
+	Get(ctx context.Context, id string) (domain.Subscription, error)
+	List(ctx context.Context) ([]domain.Subscription, error)
+	Update(ctx context.Context, subscription domain.Subscription) (domain.Subscription, error)
+	Delete(ctx context.Context, id string) error
+	Deliveries(ctx context.Context, subscriptionID string, limit, offset int) ([]domain.Delivery, error)
+	Redeliver(ctx context.Context, subscriptionID, deliveryID string) (domain.Delivery, error)
+	Dispatch(ctx context.Context) (int, error)
+}
+
+type WebhookUcImpl struct {
+	webhookRepository repository.Webhook
+	accountRepository repository.Account
+	sender            Sender
+	policy            RetryPolicy
+	now               func() time.Time
+}
+
+// Create registers a subscription of the caller to the events of one of its
+// accounts, or of every account of the tenant for admins and operators,
+// generating its signing secret unless one is given. The secret is only
+// returned here.
+func (w WebhookUcImpl) Create(ctx context.Context, subscription domain.Subscription) (domain.Subscription, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:webhook:Create", trace.SpanKindInternal)
+	defer span.End()
+
+	if err := w.validateSubscription(ctx, subscription); err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Subscription{}, err
+	}
+
+	if subscription.Secret == "" {
+		secret, err := webhook.GenerateSecret()
+		if err != nil {
+			telemetry.ErrorSpan(span, err)
+			return domain.Subscription{}, fmt.Errorf("generate webhook secret: %w", err)
+		}
+		subscription.Secret = secret
+	}
+
+	now := w.now()
+	subscription.Id = uuid.New().String()
+	subscription.ClientID = auth.Actor(ctx)
+	subscription.TenantID = auth.OwnTenant(ctx)
+	subscription.Active = true
+	subscription.CreatedAt, subscription.UpdatedAt = now, now
+
+	if err := w.webhookRepository.PushSubscription(ctx, subscription); err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot create webhook subscription", logger.Err(err))
+		return domain.Subscription{}, fmt.Errorf("create webhook subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+func (w WebhookUcImpl) Get(ctx context.Context, id string) (domain.Subscription, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:webhook:Get", trace.SpanKindInternal)
+	defer span.End()
+
+	subscription, err := w.subscription(ctx, id)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+	}
+
+	return subscription, err
+}
+
+// List returns the subscriptions of the caller, every subscription for admins.
+func (w WebhookUcImpl) List(ctx context.Context) ([]domain.Subscription, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:webhook:List", trace.SpanKindInternal)
+	defer span.End()
+
+	clientID := auth.Actor(ctx)
+	if principal, ok := auth.PrincipalFrom(ctx); !ok || principal.HasScope(auth.ScopeAdmin) {
+		clientID = ""
+	}
+
+	subscriptions, err := w.webhookRepository.ListSubscriptions(ctx, clientID)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot list webhook subscriptions", logger.Err(err))
+		return nil, fmt.Errorf("list webhook subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// Update replaces the url, event types, account and active flag of a
+// subscription, its secret is kept.
+func (w WebhookUcImpl) Update(ctx context.Context, subscription domain.Subscription) (domain.Subscription, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:webhook:Update", trace.SpanKindInternal)
+	defer span.End()
+
+	if err := w.validateSubscription(ctx, subscription); err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Subscription{}, err
+	}
+
+	persisted, err := w.subscription(ctx, subscription.Id)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Subscription{}, err
+	}
+
+	persisted.URL = subscription.URL
+	persisted.EventTypes = subscription.EventTypes
+	persisted.AccountID = subscription.AccountID
+	persisted.Active = subscription.Active
+	persisted.UpdatedAt = w.now()
+
+	if err := w.webhookRepository.UpdateSubscription(ctx, persisted); err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot update webhook subscription", logger.Err(err))
+		return domain.Subscription{}, fmt.Errorf("update webhook subscription %s: %w", subscription.Id, err)
+	}
+
+	return persisted, nil
+}
+
+// Delete removes a subscription together with its deliveries.
+func (w WebhookUcImpl) Delete(ctx context.Context, id string) error {
+	ctx, span := telemetry.Span(ctx, "useCase:webhook:Delete", trace.SpanKindInternal)
+	defer span.End()
+
+	if _, err := w.subscription(ctx, id); err != nil {
+		telemetry.ErrorSpan(span, err)
+		return err
+	}
+
+	if err := w.webhookRepository.DeleteSubscription(ctx, id); err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot delete webhook subscription", logger.Err(err))
+		return fmt.Errorf("delete webhook subscription %s: %w", id, err)
+	}
+
+	return nil
+}
+
+func (w WebhookUcImpl) Deliveries(ctx context.Context, subscriptionID string, limit, offset int) ([]domain.Delivery, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:webhook:Deliveries", trace.SpanKindInternal)
+	defer span.End()
+
+	if _, err := w.subscription(ctx, subscriptionID); err != nil {
+		telemetry.ErrorSpan(span, err)
+		return nil, err
+	}
+
+	deliveries, err := w.webhookRepository.ListDeliveries(ctx, subscriptionID, limit, offset)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot list webhook deliveries", logger.Err(err))
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver enqueues the event of a delivery again as a new delivery, keeping
+// the log of the previous one. Receivers deduplicate by the event id.
+func (w WebhookUcImpl) Redeliver(ctx context.Context, subscriptionID, deliveryID string) (domain.Delivery, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:webhook:Redeliver", trace.SpanKindInternal)
+	defer span.End()
+
+	if _, err := w.subscription(ctx, subscriptionID); err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Delivery{}, err
+	}
+
+	previous, err := w.webhookRepository.GetDelivery(ctx, deliveryID)
+	if err == nil && previous.SubscriptionID != subscriptionID {
+		err = exceptions.EntityNotFoundError.WithDetail(fmt.Sprintf("delivery %s not found", deliveryID))
+	}
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		return domain.Delivery{}, fmt.Errorf("get webhook delivery %s: %w", deliveryID, err)
+	}
+
+	delivery := w.newDelivery(subscriptionID, previous.EventID, previous.EventType, previous.Payload)
+
+	if err := w.webhookRepository.PushDeliveries(ctx, []domain.Delivery{delivery}); err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot redeliver webhook", logger.Err(err))
+		return domain.Delivery{}, fmt.Errorf("redeliver webhook %s: %w", deliveryID, err)
+	}
+
+	return delivery, nil
+}
+
+// Publish enqueues a delivery of event for every matching subscription, the
+// dispatcher sends them in the background.
+func (w WebhookUcImpl) Publish(ctx context.Context, event domain.Event) {
+	ctx, span := telemetry.Span(ctx, "useCase:webhook:Publish", trace.SpanKindInternal)
+	defer span.End()
+
+	err := w.publish(ctx, event)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot publish event to webhooks",
+			logger.Str("event_id", event.Id),
+			logger.Str("event_type", string(event.Type)),
+			logger.Err(err),
+		)
+	}
+}
+
+func (w WebhookUcImpl) publish(ctx context.Context, event domain.Event) error {
+	subscriptions, err := w.webhookRepository.MatchSubscriptions(ctx, event)
+	if err != nil || len(subscriptions) == 0 {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]domain.Delivery, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		deliveries = append(deliveries, w.newDelivery(subscription.Id, event.Id, event.Type, payload))
+	}
+
+	return w.webhookRepository.PushDeliveries(ctx, deliveries)
+}
+
+// Dispatch attempts the deliveries due now and returns how many were attempted.
+func (w WebhookUcImpl) Dispatch(ctx context.Context) (int, error) {
+	ctx, span := telemetry.Span(ctx, "useCase:webhook:Dispatch", trace.SpanKindInternal)
+	defer span.End()
+
+	now := w.now()
+
+	deliveries, err := w.webhookRepository.ClaimDeliveries(ctx, now, now.Add(w.policy.Lease), w.policy.BatchSize)
+	if err != nil {
+		telemetry.ErrorSpan(span, err)
+		logger.Error(ctx, logger.ServerError, "cannot claim webhook deliveries", logger.Err(err))
+		return 0, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+
+	subscriptions := make(map[string]domain.Subscription)
+
+	for _, delivery := range deliveries {
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok {
+			subscription, err = w.webhookRepository.GetSubscription(ctx, delivery.SubscriptionID)
+			if errors.Is(err, exceptions.EntityNotFoundError) {
+				// deleted meanwhile, its deliveries went with it
+				continue
+			}
+			if err != nil {
+				telemetry.ErrorSpan(span, err)
+				return 0, fmt.Errorf("get webhook subscription %s: %w", delivery.SubscriptionID, err)
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+
+		if err := w.attempt(ctx, subscription, delivery); err != nil {
+			telemetry.ErrorSpan(span, err)
+			return 0, err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+func (w WebhookUcImpl) attempt(ctx context.Context, subscription domain.Subscription, delivery domain.Delivery) error {
+	delivery.Attempts++
+	delivery.LastError = ""
+
+	var status int
+	var err error
+
+	if subscription.Active {
+		status, err = w.sender.Send(ctx, subscription.URL, subscription.Secret, delivery.Id, string(delivery.EventType), delivery.Payload)
+	} else {
+		// an inactive subscription is never attempted again
+		delivery.Attempts = w.policy.MaxAttempts
+		err = errors.New("subscription inactive")
+	}
+
+	now := w.now()
+	delivery.LastStatusCode = status
+
+	switch {
+	case err == nil && status >= 200 && status < 300:
+		delivery.Status = domain.DeliveryDelivered
+		delivery.DeliveredAt = &now
+	default:
+		delivery.LastError = fmt.Sprintf("unexpected status %d", status)
+		if err != nil {
+			delivery.LastError = err.Error()
+		}
+
+		if delivery.Attempts >= w.policy.MaxAttempts {
+			delivery.Status = domain.DeliveryDead
+			logger.Warn(ctx, logger.WebhookDead, "webhook delivery dead-lettered",
+				logger.Str("delivery_id", delivery.Id),
+				logger.Str("subscription_id", subscription.Id),
+				logger.Int("attempts", delivery.Attempts),
+				logger.Str("last_error", delivery.LastError),
+			)
+		} else {
+			delivery.NextAttemptAt = now.Add(w.policy.backoff(delivery.Attempts))
+		}
+	}
+
+	err = w.webhookRepository.UpdateDelivery(ctx, delivery)
+	if err != nil && !errors.Is(err, exceptions.EntityNotFoundError) {
+		logger.Error(ctx, logger.ServerError, "cannot update webhook delivery", logger.Str("delivery_id", delivery.Id), logger.Err(err))
+		return fmt.Errorf("update webhook delivery %s: %w", delivery.Id, err)
+	}
+
+	return nil
+}
+
+// subscription returns a subscription visible to the caller, subscriptions
+// of other clients answer EntityNotFoundError unless the caller is admin.
+func (w WebhookUcImpl) subscription(ctx context.Context, id string) (domain.Subscription, error) {
+	subscription, err := w.webhookRepository.GetSubscription(ctx, id)
+	if err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot get webhook subscription", logger.Str("subscription_id", id), logger.Err(err))
+		return domain.Subscription{}, fmt.Errorf("get webhook subscription %s: %w", id, err)
+	}
+
+	principal, ok := auth.PrincipalFrom(ctx)
+	if ok && !principal.HasScope(auth.ScopeAdmin) && auth.Actor(ctx) != subscription.ClientID {
+		return domain.Subscription{}, exceptions.EntityNotFoundError.WithDetail(fmt.Sprintf("webhook %s not found", id))
+	}
+
+	return subscription, nil
+}
+
+func (w WebhookUcImpl) newDelivery(subscriptionID, eventID string, eventType domain.EventType, payload []byte) domain.Delivery {
+	now := w.now()
+
+	return domain.Delivery{
+		Id:             uuid.New().String(),
+		SubscriptionID: subscriptionID,
+		EventID:        eventID,
+		EventType:      eventType,
+		Payload:        payload,
+		Status:         domain.DeliveryPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+	}
+}
+
+// validateSubscription checks the subscription fields and that the caller may
+// receive the events of its account, accounts of other owners are not found.
+func (w WebhookUcImpl) validateSubscription(ctx context.Context, subscription domain.Subscription) error {
+	var fields []exceptions.FieldError
+
+	if err := w.sender.ValidateURL(subscription.URL); err != nil {
+		fields = append(fields, exceptions.FieldError{Field: "url", Message: err.Error()})
+	}
+
+	if len(subscription.EventTypes) == 0 {
+		fields = append(fields, exceptions.FieldError{Field: "event_types", Message: "must not be empty"})
+	}
+
+	for _, eventType := range subscription.EventTypes {
+		if !eventType.IsValid() {
+			fields = append(fields, exceptions.FieldError{Field: "event_types", Message: fmt.Sprintf("unknown event type %q", eventType)})
+		}
+	}
+
+	principal, ok := auth.PrincipalFrom(ctx)
+	if subscription.AccountID == "" && ok && !principal.Operator && !principal.HasScope(auth.ScopeAdmin) {
+		fields = append(fields, exceptions.FieldError{Field: "account_id", Message: "is required unless the caller is admin or operator"})
+	}
+
+	if len(fields) > 0 {
+		return exceptions.ValidationError.WithFields(fields...)
+	}
+
+	if subscription.AccountID == "" {
+		return nil
+	}
+
+	account, err := w.accountRepository.Get(ctx, subscription.AccountID)
+	if err != nil {
+		logger.Error(ctx, logger.ServerError, "cannot get webhook account", logger.Str("account_id", subscription.AccountID), logger.Err(err))
+		return fmt.Errorf("get account %s: %w", subscription.AccountID, err)
+	}
+
+	if !auth.CanAccess(ctx, account.DocumentNumber) {
+		logger.Warn(ctx, logger.ServerError, "account owned by another caller", logger.Str("account_id", subscription.AccountID))
+		return exceptions.EntityNotFoundError.WithDetail(fmt.Sprintf("account %s not found", subscription.AccountID))
+	}
+
+	return nil
+}
+
+func NewWebhookUseCase(webhookRepository repository.Webhook, accountRepository repository.Account, sender Sender, policy RetryPolicy) WebhookUseCase {
+	return WebhookUcImpl{
+		webhookRepository: webhookRepository,
+		accountRepository: accountRepository,
+		sender:            sender,
+		policy:            policy,
+		now:               time.Now,
+	}
+}
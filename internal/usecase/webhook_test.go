@@ -0,0 +1,373 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/payment-api/infrastructure/auth"
+	"github.com/payment-api/infrastructure/exceptions"
+	"github.com/payment-api/infrastructure/signature"
+	"github.com/payment-api/infrastructure/webhook"
+	"github.com/payment-api/internal/domain"
+)
+
+// webhookRepositoryMock keeps subscriptions and deliveries in memory,
+// matching and claiming them as the postgres repository does.
+type webhookRepositoryMock struct {
+	subscriptions map[string]domain.Subscription
+	deliveries    map[string]domain.Delivery
+}
+
+func newWebhookRepositoryMock(subscriptions ...domain.Subscription) *webhookRepositoryMock {
+	r := &webhookRepositoryMock{subscriptions: map[string]domain.Subscription{}, deliveries: map[string]domain.Delivery{}}
+	for _, subscription := range subscriptions {
+		r.subscriptions[subscription.Id] = subscription
+	}
+	return r
+}
+
+func (r *webhookRepositoryMock) PushSubscription(_ context.Context, entity domain.Subscription) error {
+	r.subscriptions[entity.Id] = entity
+	return nil
+}
+
+func (r *webhookRepositoryMock) GetSubscription(_ context.Context, id string) (domain.Subscription, error) {
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return domain.Subscription{}, exceptions.EntityNotFoundError
+	}
+	return subscription, nil
+}
+
+func (r *webhookRepositoryMock) ListSubscriptions(_ context.Context, clientID string) ([]domain.Subscription, error) {
+	subscriptions := make([]domain.Subscription, 0)
+	for _, subscription := range r.subscriptions {
+		if clientID == "" || subscription.ClientID == clientID {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (r *webhookRepositoryMock) UpdateSubscription(_ context.Context, entity domain.Subscription) error {
+	r.subscriptions[entity.Id] = entity
+	return nil
+}
+
+func (r *webhookRepositoryMock) DeleteSubscription(_ context.Context, id string) error {
+	delete(r.subscriptions, id)
+	return nil
+}
+
+func (r *webhookRepositoryMock) MatchSubscriptions(_ context.Context, event domain.Event) ([]domain.Subscription, error) {
+	subscriptions := make([]domain.Subscription, 0)
+	for _, subscription := range r.subscriptions {
+		if !subscription.Active || (subscription.AccountID != "" && subscription.AccountID != event.AccountID) {
+			continue
+		}
+		for _, eventType := range subscription.EventTypes {
+			if eventType == event.Type {
+				subscriptions = append(subscriptions, subscription)
+			}
+		}
+	}
+	return subscriptions, nil
+}
+
+func (r *webhookRepositoryMock) PushDeliveries(_ context.Context, deliveries []domain.Delivery) error {
+	for _, delivery := range deliveries {
+		r.deliveries[delivery.Id] = delivery
+	}
+	return nil
+}
+
+func (r *webhookRepositoryMock) ClaimDeliveries(_ context.Context, now, until time.Time, limit int) ([]domain.Delivery, error) {
+	claimed := make([]domain.Delivery, 0)
+	for id, delivery := range r.deliveries {
+		if len(claimed) < limit && delivery.Status == domain.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			delivery.NextAttemptAt = until
+			r.deliveries[id] = delivery
+			claimed = append(claimed, delivery)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *webhookRepositoryMock) UpdateDelivery(_ context.Context, entity domain.Delivery) error {
+	r.deliveries[entity.Id] = entity
+	return nil
+}
+
+func (r *webhookRepositoryMock) GetDelivery(_ context.Context, id string) (domain.Delivery, error) {
+	delivery, ok := r.deliveries[id]
+	if !ok {
+		return domain.Delivery{}, exceptions.EntityNotFoundError
+	}
+	return delivery, nil
+}
+
+func (r *webhookRepositoryMock) ListDeliveries(_ context.Context, subscriptionID string, _, _ int) ([]domain.Delivery, error) {
+	deliveries := make([]domain.Delivery, 0)
+	for _, delivery := range r.deliveries {
+		if delivery.SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt) })
+	return deliveries, nil
+}
+
+// receiver is a merchant endpoint answering status and verifying signatures.
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	events   []domain.Event
+	verifier *signature.Verifier
+	errs     []error
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+
+	var event domain.Event
+	_ = json.Unmarshal(body, &event)
+	rc.events = append(rc.events, event)
+	rc.errs = append(rc.errs, rc.verifier.Verify(r.Context(), "payment-api", r.Method, r.URL.Path,
+		r.Header.Get(signature.TimestampHeader), r.Header.Get(signature.NonceHeader), r.Header.Get(signature.Header), body))
+
+	w.WriteHeader(rc.status)
+}
+
+func Test_WebhookPublishAndDispatch(t *testing.T) {
+	const secret = "whsec_test"
+
+	rc := &receiver{
+		status:   http.StatusOK,
+		verifier: signature.NewVerifier(map[string]string{"payment-api": secret}, time.Hour, signature.NewMemoryNonceStore()),
+	}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	repository := newWebhookRepositoryMock(
+		domain.Subscription{Id: "all-accounts", URL: server.URL + "/hooks", Secret: secret, Active: true,
+			EventTypes: []domain.EventType{domain.EventAccountCreated, domain.EventTransactionCreated}},
+		domain.Subscription{Id: "other-account", URL: server.URL + "/hooks", Secret: secret, Active: true, AccountID: "other-account-id",
+			EventTypes: []domain.EventType{domain.EventTransactionCreated}},
+		domain.Subscription{Id: "inactive", URL: server.URL + "/hooks", Secret: secret, Active: false,
+			EventTypes: []domain.EventType{domain.EventTransactionCreated}},
+	)
+
+	useCase := NewWebhookUseCase(repository, &accountRepositoryMock{}, webhook.NewSender(time.Second, true), RetryPolicy{MaxAttempts: 3, BackoffBase: time.Minute, BackoffMax: time.Hour, Lease: time.Minute, BatchSize: 10})
+
+	event := domain.NewTransactionEvent(domain.EventTransactionCreated, domain.Transaction{Id: 1, AccountID: "any-account-id", Amount: 10})
+	useCase.Publish(context.Background(), event)
+
+	assert.Len(t, repository.deliveries, 1, "only the matching active subscription is notified")
+
+	attempted, err := useCase.Dispatch(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+	assert.Len(t, rc.events, 1)
+	assert.Equal(t, event.Id, rc.events[0].Id)
+	assert.NoError(t, rc.errs[0], "payload is signed with the subscription secret")
+
+	for _, delivery := range repository.deliveries {
+		assert.Equal(t, domain.DeliveryDelivered, delivery.Status)
+		assert.Equal(t, 1, delivery.Attempts)
+		assert.Equal(t, http.StatusOK, delivery.LastStatusCode)
+		assert.NotNil(t, delivery.DeliveredAt)
+	}
+
+	attempted, err = useCase.Dispatch(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, attempted, "delivered deliveries are not attempted again")
+}
+
+func Test_WebhookRetriesAndDeadLetters(t *testing.T) {
+	rc := &receiver{
+		status:   http.StatusInternalServerError,
+		verifier: signature.NewVerifier(map[string]string{"payment-api": "whsec_test"}, 24*time.Hour, signature.NewMemoryNonceStore()),
+	}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	repository := newWebhookRepositoryMock(domain.Subscription{
+		Id: "any-subscription-id", URL: server.URL, Secret: "whsec_test", Active: true,
+		EventTypes: []domain.EventType{domain.EventAccountCreated},
+	})
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	useCase := WebhookUcImpl{
+		webhookRepository: repository,
+		sender:            webhook.NewSender(time.Second, true),
+		policy:            RetryPolicy{MaxAttempts: 3, BackoffBase: time.Minute, BackoffMax: 90 * time.Second, Lease: time.Minute, BatchSize: 10},
+		now:               func() time.Time { return now },
+	}
+
+	useCase.Publish(context.Background(), domain.NewAccountEvent(domain.EventAccountCreated, domain.NewAccount("any-account-id", "any-document")))
+
+	delivery := func() domain.Delivery {
+		for _, d := range repository.deliveries {
+			return d
+		}
+		return domain.Delivery{}
+	}
+
+	_, err := useCase.Dispatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, domain.DeliveryPending, delivery().Status)
+	assert.Equal(t, 1, delivery().Attempts)
+	assert.Equal(t, "unexpected status 500", delivery().LastError)
+	assert.Equal(t, now.Add(time.Minute), delivery().NextAttemptAt)
+
+	attempted, _ := useCase.Dispatch(context.Background())
+	assert.Zero(t, attempted, "not due before the backoff")
+
+	now = now.Add(time.Minute)
+	_, _ = useCase.Dispatch(context.Background())
+	assert.Equal(t, 2, delivery().Attempts)
+	assert.Equal(t, now.Add(90*time.Second), delivery().NextAttemptAt, "backoff is capped")
+
+	now = now.Add(90 * time.Second)
+	_, _ = useCase.Dispatch(context.Background())
+	assert.Equal(t, domain.DeliveryDead, delivery().Status)
+	assert.Equal(t, 3, delivery().Attempts)
+	assert.Len(t, rc.events, 3)
+
+	dead := delivery()
+	redelivered, err := useCase.Redeliver(context.Background(), "any-subscription-id", dead.Id)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, dead.Id, redelivered.Id)
+	assert.Equal(t, dead.EventID, redelivered.EventID)
+	assert.Equal(t, domain.DeliveryPending, redelivered.Status)
+	assert.Equal(t, domain.DeliveryDead, repository.deliveries[dead.Id].Status, "the dead delivery stays in the log")
+
+	rc.status = http.StatusAccepted
+	_, _ = useCase.Dispatch(context.Background())
+	assert.Equal(t, domain.DeliveryDelivered, repository.deliveries[redelivered.Id].Status)
+}
+
+func Test_WebhookUnreachableEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	repository := newWebhookRepositoryMock(domain.Subscription{
+		Id: "any-subscription-id", URL: server.URL, Secret: "whsec_test", Active: true,
+		EventTypes: []domain.EventType{domain.EventAccountCreated},
+	})
+	useCase := NewWebhookUseCase(repository, &accountRepositoryMock{}, webhook.NewSender(time.Second, true), RetryPolicy{MaxAttempts: 1, BackoffBase: time.Minute, BackoffMax: time.Hour, BatchSize: 10})
+
+	useCase.Publish(context.Background(), domain.NewAccountEvent(domain.EventAccountCreated, domain.NewAccount("any-account-id", "any-document")))
+	_, err := useCase.Dispatch(context.Background())
+
+	assert.NoError(t, err)
+	for _, delivery := range repository.deliveries {
+		assert.Equal(t, domain.DeliveryDead, delivery.Status)
+		assert.NotEmpty(t, delivery.LastError)
+		assert.Zero(t, delivery.LastStatusCode)
+	}
+}
+
+func Test_WebhookSubscriptionUseCase(t *testing.T) {
+	repository := newWebhookRepositoryMock()
+	accounts := &accountRepositoryMock{Result: domain.Account{Id: "any-account-id", DocumentNumber: "any-document"}}
+	useCase := NewWebhookUseCase(repository, accounts, webhook.NewSender(time.Second, false), RetryPolicy{})
+
+	merchant := auth.WithPrincipal(context.Background(), auth.Principal{ClientID: "merchant", Scopes: []auth.Scope{auth.ScopeWebhooksManage}})
+	other := auth.WithPrincipal(context.Background(), auth.Principal{ClientID: "other-merchant", Scopes: []auth.Scope{auth.ScopeWebhooksManage}})
+	admin := auth.WithPrincipal(context.Background(), auth.Principal{ClientID: "back-office", Scopes: []auth.Scope{auth.ScopeAdmin}})
+
+	_, err := useCase.Create(merchant, domain.Subscription{URL: "ftp://merchant.test", EventTypes: []domain.EventType{"account.deleted"}})
+	assert.ErrorIs(t, err, exceptions.ValidationError)
+
+	_, err = useCase.Create(merchant, domain.Subscription{URL: "https://169.254.169.254/latest/meta-data", AccountID: "any-account-id", EventTypes: []domain.EventType{domain.EventAccountCreated}})
+	assert.ErrorIs(t, err, exceptions.ValidationError, "internal addresses are refused")
+
+	_, err = useCase.Create(merchant, domain.Subscription{URL: "https://merchant.test/hooks", EventTypes: []domain.EventType{domain.EventAccountCreated}})
+	assert.ErrorIs(t, err, exceptions.ValidationError, "only admins and operators subscribe to every account")
+
+	everyAccount, err := useCase.Create(admin, domain.Subscription{URL: "https://merchant.test/hooks", EventTypes: []domain.EventType{domain.EventAccountCreated}})
+	assert.NoError(t, err)
+	assert.Empty(t, everyAccount.AccountID)
+	assert.NoError(t, useCase.Delete(admin, everyAccount.Id))
+
+	created, err := useCase.Create(merchant, domain.Subscription{URL: "https://merchant.test/hooks", AccountID: "any-account-id", EventTypes: []domain.EventType{domain.EventAccountCreated}})
+	assert.NoError(t, err)
+	assert.Equal(t, "merchant", created.ClientID)
+	assert.NotEmpty(t, created.Secret)
+	assert.True(t, created.Active)
+
+	_, err = useCase.Get(other, created.Id)
+	assert.ErrorIs(t, err, exceptions.EntityNotFoundError, "subscriptions of other clients are hidden")
+
+	_, err = useCase.Get(admin, created.Id)
+	assert.NoError(t, err)
+
+	listed, _ := useCase.List(other)
+	assert.Empty(t, listed)
+
+	updated, err := useCase.Update(merchant, domain.Subscription{Id: created.Id, URL: "https://merchant.test/v2", AccountID: "any-account-id", EventTypes: []domain.EventType{domain.EventTransactionCreated}})
+	assert.NoError(t, err)
+	assert.Equal(t, created.Secret, updated.Secret, "the secret is kept")
+	assert.False(t, updated.Active)
+
+	assert.ErrorIs(t, useCase.Delete(other, created.Id), exceptions.EntityNotFoundError)
+	assert.NoError(t, useCase.Delete(merchant, created.Id))
+	assert.Empty(t, repository.subscriptions)
+}
+
+func Test_WebhookSubscriptionOtherOwner(t *testing.T) {
+	repository := newWebhookRepositoryMock()
+	accounts := &accountRepositoryMock{Result: domain.Account{Id: "account-b", DocumentNumber: "document-b"}}
+	useCase := NewWebhookUseCase(repository, accounts, webhook.NewSender(time.Second, false), RetryPolicy{})
+
+	scopes := []auth.Scope{auth.ScopeWebhooksManage}
+	userA := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "user-a", Owner: "document-a", Scopes: scopes})
+	userB := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "user-b", Owner: "document-b", Scopes: scopes})
+	operator := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "operator", Owner: "document-o", Operator: true, Scopes: scopes})
+
+	subscription := domain.Subscription{URL: "https://merchant.test/hooks", AccountID: "account-b", EventTypes: []domain.EventType{domain.EventTransactionCreated}}
+
+	_, err := useCase.Create(userA, subscription)
+	assert.ErrorIs(t, err, exceptions.EntityNotFoundError)
+	assert.Empty(t, repository.subscriptions)
+
+	owned, err := useCase.Create(userB, subscription)
+	assert.NoError(t, err)
+
+	owned.AccountID = ""
+	_, err = useCase.Update(userB, owned)
+	assert.ErrorIs(t, err, exceptions.ValidationError, "end users cannot widen a subscription to every account")
+
+	_, err = useCase.Create(operator, domain.Subscription{URL: subscription.URL, EventTypes: subscription.EventTypes})
+	assert.NoError(t, err)
+
+	accounts.Result = domain.Account{Id: "account-a", DocumentNumber: "document-a"}
+	owned.AccountID = "account-a"
+	_, err = useCase.Update(userB, owned)
+	assert.ErrorIs(t, err, exceptions.EntityNotFoundError, "nor move it to an account of another owner")
+}
+
+func Test_RetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BackoffBase: 30 * time.Second, BackoffMax: 5 * time.Minute}
+
+	assert.Equal(t, 30*time.Second, policy.backoff(1))
+	assert.Equal(t, time.Minute, policy.backoff(2))
+	assert.Equal(t, 4*time.Minute, policy.backoff(4))
+	assert.Equal(t, 5*time.Minute, policy.backoff(5))
+	assert.Equal(t, 5*time.Minute, policy.backoff(40))
+}
@@ -0,0 +1,10 @@
+CREATE TABLE schema_migrations
+(
+    version    INT       NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (version)
+);
+
+INSERT INTO schema_migrations (version)
+VALUES (1),
+       (2);
@@ -0,0 +1,4 @@
+CREATE UNIQUE INDEX accounts_document_number_uindex ON accounts (document_number);
+
+INSERT INTO schema_migrations (version)
+VALUES (3);
@@ -0,0 +1,17 @@
+CREATE TABLE api_keys
+(
+    id         VARCHAR(50)  NOT NULL,
+    client_id  VARCHAR(100) NOT NULL,
+    key_hash   VARCHAR(64)  NOT NULL,
+    scopes     TEXT[]       NOT NULL,
+    created_at TIMESTAMP    NOT NULL,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX api_keys_key_hash_uindex ON api_keys (key_hash);
+CREATE INDEX api_keys_client_id_index ON api_keys (client_id);
+
+INSERT INTO schema_migrations (version)
+VALUES (4);
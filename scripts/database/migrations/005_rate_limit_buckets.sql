@@ -0,0 +1,10 @@
+CREATE UNLOGGED TABLE rate_limit_buckets
+(
+    key        VARCHAR(150)     NOT NULL,
+    tokens     DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP        NOT NULL,
+    PRIMARY KEY (key)
+);
+
+INSERT INTO schema_migrations (version)
+VALUES (5);
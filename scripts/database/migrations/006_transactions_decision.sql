@@ -0,0 +1,8 @@
+ALTER TABLE transactions
+    ADD COLUMN decision         VARCHAR(10) NOT NULL DEFAULT 'allow',
+    ADD COLUMN decision_reasons TEXT[]      NOT NULL DEFAULT '{}';
+
+CREATE INDEX transactions_account_id_event_date_index ON transactions (account_id, event_date);
+
+INSERT INTO schema_migrations (version)
+VALUES (6);
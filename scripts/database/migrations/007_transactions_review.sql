@@ -0,0 +1,14 @@
+ALTER TABLE transactions
+    ADD COLUMN status        VARCHAR(20)  NOT NULL DEFAULT 'approved',
+    ADD COLUMN reviewed_by   VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN review_reason TEXT         NOT NULL DEFAULT '',
+    ADD COLUMN reviewed_at   TIMESTAMP;
+
+UPDATE transactions SET status = 'declined' WHERE decision = 'deny';
+UPDATE transactions SET status = 'pending_review' WHERE decision = 'review';
+
+CREATE INDEX transactions_pending_review_index ON transactions (event_date)
+    WHERE status = 'pending_review';
+
+INSERT INTO schema_migrations (version)
+VALUES (7);
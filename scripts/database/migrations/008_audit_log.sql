@@ -0,0 +1,39 @@
+CREATE TABLE audit_log
+(
+    id              BIGINT GENERATED ALWAYS AS IDENTITY,
+    actor           VARCHAR(100) NOT NULL,
+    route           VARCHAR(200) NOT NULL,
+    request_id      VARCHAR(100) NOT NULL,
+    entity_type     VARCHAR(30)  NOT NULL,
+    entity_id       VARCHAR(50)  NOT NULL,
+    action          VARCHAR(30)  NOT NULL,
+    before_snapshot JSONB,
+    after_snapshot  JSONB,
+    outcome         VARCHAR(10)  NOT NULL,
+    error_code      VARCHAR(50)  NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ  NOT NULL,
+    prev_hash       VARCHAR(64)  NOT NULL,
+    hash            VARCHAR(64)  NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT audit_log_hash_unique UNIQUE (hash)
+);
+
+CREATE INDEX audit_log_entity_index ON audit_log (entity_type, entity_id, id);
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+INSERT INTO schema_migrations (version)
+VALUES (8);
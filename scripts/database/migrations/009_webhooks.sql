@@ -0,0 +1,43 @@
+CREATE TABLE webhook_subscriptions
+(
+    id          VARCHAR(50)  NOT NULL,
+    client_id   VARCHAR(100) NOT NULL,
+    url         TEXT         NOT NULL,
+    event_types TEXT[]       NOT NULL,
+    account_id  VARCHAR(50)  NOT NULL DEFAULT '',
+    secret      VARCHAR(100) NOT NULL,
+    active      BOOLEAN      NOT NULL DEFAULT TRUE,
+    created_at  TIMESTAMP    NOT NULL,
+    updated_at  TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX webhook_subscriptions_client_id_index ON webhook_subscriptions (client_id);
+
+CREATE TABLE webhook_deliveries
+(
+    id               VARCHAR(50) NOT NULL,
+    subscription_id  VARCHAR(50) NOT NULL,
+    event_id         VARCHAR(50) NOT NULL,
+    event_type       VARCHAR(50) NOT NULL,
+    payload          TEXT        NOT NULL,
+    status           VARCHAR(20) NOT NULL,
+    attempts         INT         NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMP   NOT NULL,
+    last_status_code INT         NOT NULL DEFAULT 0,
+    last_error       TEXT        NOT NULL DEFAULT '',
+    created_at       TIMESTAMP   NOT NULL,
+    delivered_at     TIMESTAMP,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_delivery_subscription
+        FOREIGN KEY (subscription_id)
+            REFERENCES webhook_subscriptions (id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_deliveries_due_index ON webhook_deliveries (next_attempt_at)
+    WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_subscription_id_index ON webhook_deliveries (subscription_id, created_at);
+
+INSERT INTO schema_migrations (version)
+VALUES (9);
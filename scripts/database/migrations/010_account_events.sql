@@ -0,0 +1,16 @@
+CREATE TABLE account_events
+(
+    seq         BIGINT GENERATED ALWAYS AS IDENTITY,
+    id          VARCHAR(50) NOT NULL,
+    account_id  VARCHAR(50) NOT NULL,
+    type        VARCHAR(50) NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    data        JSONB       NOT NULL,
+    PRIMARY KEY (seq),
+    CONSTRAINT account_events_id_unique UNIQUE (id)
+);
+
+CREATE INDEX account_events_account_id_index ON account_events (account_id, seq);
+
+INSERT INTO schema_migrations (version)
+VALUES (10);
@@ -0,0 +1,35 @@
+CREATE TABLE import_jobs
+(
+    id          VARCHAR(50)  NOT NULL,
+    client_id   VARCHAR(100) NOT NULL,
+    format      VARCHAR(10)  NOT NULL,
+    status      VARCHAR(20)  NOT NULL,
+    processed   INT          NOT NULL DEFAULT 0,
+    imported    INT          NOT NULL DEFAULT 0,
+    failed      INT          NOT NULL DEFAULT 0,
+    error       TEXT         NOT NULL DEFAULT '',
+    created_at  TIMESTAMP    NOT NULL,
+    updated_at  TIMESTAMP    NOT NULL,
+    finished_at TIMESTAMP,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX import_jobs_unfinished_index ON import_jobs (updated_at)
+    WHERE status IN ('pending', 'running');
+
+CREATE TABLE import_errors
+(
+    job_id  VARCHAR(50)  NOT NULL,
+    row     INT          NOT NULL,
+    field   VARCHAR(50)  NOT NULL DEFAULT '',
+    message TEXT         NOT NULL,
+    raw     TEXT         NOT NULL DEFAULT '',
+    PRIMARY KEY (job_id, row),
+    CONSTRAINT fk_import_error_job
+        FOREIGN KEY (job_id)
+            REFERENCES import_jobs (id)
+            ON DELETE CASCADE
+);
+
+INSERT INTO schema_migrations (version)
+VALUES (11);
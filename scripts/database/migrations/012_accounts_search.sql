@@ -0,0 +1,11 @@
+ALTER TABLE accounts
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active';
+
+CREATE INDEX accounts_document_number_prefix_index ON accounts (document_number varchar_pattern_ops);
+
+CREATE INDEX accounts_created_at_index ON accounts (created_at DESC, id DESC);
+
+CREATE INDEX accounts_status_created_at_index ON accounts (status, created_at DESC, id DESC);
+
+INSERT INTO schema_migrations (version)
+VALUES (12);
@@ -0,0 +1,16 @@
+ALTER TABLE accounts
+    ADD COLUMN holder_name VARCHAR(200) NOT NULL DEFAULT '',
+    ADD COLUMN email       VARCHAR(254) NOT NULL DEFAULT '',
+    ADD COLUMN phone       VARCHAR(16)  NOT NULL DEFAULT '',
+    ADD COLUMN address     JSONB,
+    ADD COLUMN metadata    JSONB        NOT NULL DEFAULT '{}',
+    ADD COLUMN updated_at  TIMESTAMP;
+
+UPDATE accounts
+SET updated_at = created_at;
+
+ALTER TABLE accounts
+    ALTER COLUMN updated_at SET NOT NULL;
+
+INSERT INTO schema_migrations (version)
+VALUES (13);
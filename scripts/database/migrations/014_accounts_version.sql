@@ -0,0 +1,5 @@
+ALTER TABLE accounts
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+INSERT INTO schema_migrations (version)
+VALUES (14);
@@ -0,0 +1,31 @@
+ALTER TABLE accounts
+    ADD COLUMN closed_at  TIMESTAMP,
+    ADD COLUMN erased_at  TIMESTAMP,
+    ADD COLUMN deleted_at TIMESTAMP;
+
+-- accounts are soft deleted, so the ledger of an account outlives it
+ALTER TABLE transactions
+    DROP CONSTRAINT fk_transaction_account,
+    ADD CONSTRAINT fk_transaction_account
+        FOREIGN KEY (account_id)
+            REFERENCES accounts (id)
+            ON DELETE RESTRICT;
+
+CREATE FUNCTION accounts_soft_delete_only() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'accounts are soft deleted, set deleted_at instead';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER accounts_soft_delete_only
+    BEFORE DELETE ON accounts
+    FOR EACH ROW EXECUTE FUNCTION accounts_soft_delete_only();
+
+-- a deleted account frees its document number for a new account
+DROP INDEX accounts_document_number_uindex;
+CREATE UNIQUE INDEX accounts_document_number_uindex ON accounts (document_number)
+    WHERE deleted_at IS NULL;
+
+INSERT INTO schema_migrations (version)
+VALUES (15);
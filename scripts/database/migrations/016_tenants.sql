@@ -0,0 +1,40 @@
+ALTER TABLE accounts
+    ADD COLUMN tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+
+ALTER TABLE transactions
+    ADD COLUMN tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+
+ALTER TABLE webhook_subscriptions
+    ADD COLUMN tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+
+-- document numbers are unique within a card program
+DROP INDEX accounts_document_number_uindex;
+CREATE UNIQUE INDEX accounts_document_number_uindex ON accounts (tenant_id, document_number)
+    WHERE deleted_at IS NULL;
+
+-- a transaction belongs to the tenant of its account, whatever the insert says
+CREATE FUNCTION transactions_tenant() RETURNS trigger AS
+$$
+BEGIN
+    NEW.tenant_id := COALESCE((SELECT tenant_id FROM accounts WHERE id = NEW.account_id), NEW.tenant_id);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER transactions_tenant
+    BEFORE INSERT ON transactions
+    FOR EACH ROW EXECUTE FUNCTION transactions_tenant();
+
+-- row level security only binds roles that do not own the tables, and only
+-- once postgres.row_level_security makes the service set app.tenant_id;
+-- statements without it, from workers and commands, see every tenant
+ALTER TABLE accounts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE transactions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY accounts_tenant ON accounts
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+CREATE POLICY transactions_tenant ON transactions
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+INSERT INTO schema_migrations (version)
+VALUES (16);
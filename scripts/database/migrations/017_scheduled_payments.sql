@@ -0,0 +1,35 @@
+CREATE TABLE scheduled_payments
+(
+    id                  VARCHAR(50)  NOT NULL,
+    account_id          VARCHAR(50)  NOT NULL,
+    tenant_id           VARCHAR(50)  NOT NULL DEFAULT 'default',
+    client_id           VARCHAR(100) NOT NULL,
+    frequency           VARCHAR(20)  NOT NULL,
+    day_of_month        INT          NOT NULL DEFAULT 0,
+    amount_type         VARCHAR(30)  NOT NULL,
+    amount              FLOAT        NOT NULL DEFAULT 0,
+    status              VARCHAR(20)  NOT NULL,
+    next_run_at         TIMESTAMP    NOT NULL,
+    attempts            INT          NOT NULL DEFAULT 0,
+    last_run_at         TIMESTAMP,
+    last_transaction_id INT          NOT NULL DEFAULT 0,
+    last_error          TEXT         NOT NULL DEFAULT '',
+    created_at          TIMESTAMP    NOT NULL,
+    updated_at          TIMESTAMP    NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_scheduled_payment_account
+        FOREIGN KEY (account_id)
+            REFERENCES accounts (id)
+);
+
+CREATE INDEX scheduled_payments_due_index ON scheduled_payments (next_run_at)
+    WHERE status = 'active';
+CREATE INDEX scheduled_payments_account_id_index ON scheduled_payments (account_id, created_at);
+
+ALTER TABLE scheduled_payments ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY scheduled_payments_tenant ON scheduled_payments
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+INSERT INTO schema_migrations (version)
+VALUES (17);
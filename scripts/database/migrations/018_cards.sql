@@ -0,0 +1,33 @@
+CREATE TABLE cards
+(
+    id           VARCHAR(50) NOT NULL,
+    account_id   VARCHAR(50) NOT NULL,
+    tenant_id    VARCHAR(50) NOT NULL DEFAULT 'default',
+    token        VARCHAR(50) NOT NULL,
+    last_four    CHAR(4)     NOT NULL,
+    expiry_month INT         NOT NULL,
+    expiry_year  INT         NOT NULL,
+    status       VARCHAR(20) NOT NULL,
+    replaced_by  VARCHAR(50) NOT NULL DEFAULT '',
+    created_at   TIMESTAMP   NOT NULL,
+    updated_at   TIMESTAMP   NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT cards_token_unique UNIQUE (token),
+    CONSTRAINT fk_card_account
+        FOREIGN KEY (account_id)
+            REFERENCES accounts (id)
+);
+
+CREATE INDEX cards_account_id_index ON cards (account_id, created_at);
+CREATE INDEX cards_expiry_index ON cards (expiry_year, expiry_month)
+    WHERE status IN ('active', 'blocked');
+
+ALTER TABLE cards ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY cards_tenant ON cards
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE transactions ADD COLUMN card_id VARCHAR(50) NOT NULL DEFAULT '';
+
+INSERT INTO schema_migrations (version)
+VALUES (18);
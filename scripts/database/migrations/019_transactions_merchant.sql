@@ -0,0 +1,8 @@
+ALTER TABLE transactions
+    ADD COLUMN merchant_name    VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN merchant_mcc     VARCHAR(4)   NOT NULL DEFAULT '',
+    ADD COLUMN merchant_city    VARCHAR(60)  NOT NULL DEFAULT '',
+    ADD COLUMN merchant_country VARCHAR(2)   NOT NULL DEFAULT '';
+
+INSERT INTO schema_migrations (version)
+VALUES (19);
@@ -0,0 +1,91 @@
+-- the history of every account, the accounts and transactions tables are its
+-- projections and can be rebuilt from it with the history command
+CREATE TABLE history_events
+(
+    seq         BIGINT GENERATED ALWAYS AS IDENTITY,
+    account_id  VARCHAR(50) NOT NULL,
+    tenant_id   VARCHAR(50) NOT NULL DEFAULT 'default',
+    type        VARCHAR(50) NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL,
+    data        JSONB       NOT NULL,
+    PRIMARY KEY (seq)
+);
+
+CREATE INDEX history_events_account_id_index ON history_events (account_id, seq);
+
+-- events are never removed nor edited, but for the erasure of an account,
+-- which rewrites the data of its own events once app.erasure names it
+CREATE FUNCTION history_events_append_only() RETURNS trigger AS
+$$
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        IF OLD.account_id = COALESCE(current_setting('app.erasure', true), '')
+            AND NEW.seq = OLD.seq AND NEW.account_id = OLD.account_id AND NEW.tenant_id = OLD.tenant_id
+            AND NEW.type = OLD.type AND NEW.recorded_at = OLD.recorded_at THEN
+            RETURN NEW;
+        END IF;
+    END IF;
+
+    RAISE EXCEPTION 'history_events is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER history_events_append_only
+    BEFORE UPDATE OR DELETE ON history_events
+    FOR EACH ROW EXECUTE FUNCTION history_events_append_only();
+
+CREATE TRIGGER history_events_no_truncate
+    BEFORE TRUNCATE ON history_events
+    FOR EACH STATEMENT EXECUTE FUNCTION history_events_append_only();
+
+ALTER TABLE history_events ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY history_events_tenant ON history_events
+    USING (COALESCE(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+-- existing rows enter the history as their current state, timestamps keep
+-- the wall clock the columns store
+INSERT INTO history_events (account_id, tenant_id, type, recorded_at, data)
+SELECT id, tenant_id, 'account.opened', now(), jsonb_build_object(
+        'id', id,
+        'tenant_id', tenant_id,
+        'document_number', document_number,
+        'status', status,
+        'holder_name', holder_name,
+        'email', email,
+        'phone', phone,
+        'address', address,
+        'metadata', metadata,
+        'version', version,
+        'created_at', to_char(created_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+        'updated_at', to_char(updated_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+        'closed_at', to_char(closed_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+        'erased_at', to_char(erased_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+        'deleted_at', to_char(deleted_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'))
+FROM accounts
+ORDER BY created_at, id;
+
+INSERT INTO history_events (account_id, tenant_id, type, recorded_at, data)
+SELECT account_id, tenant_id, 'transaction.posted', now(), jsonb_build_object(
+        'id', id,
+        'account_id', account_id,
+        'operation_type', operation_type_id,
+        'amount', amount,
+        'event_date', to_char(event_date, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+        'decision', decision,
+        'reasons', to_jsonb(decision_reasons),
+        'status', status,
+        'reviewed_by', reviewed_by,
+        'review_reason', review_reason,
+        'reviewed_at', to_char(reviewed_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+        'card_id', card_id,
+        'merchant', jsonb_build_object(
+                'name', merchant_name,
+                'mcc', merchant_mcc,
+                'city', merchant_city,
+                'country', merchant_country))
+FROM transactions
+ORDER BY id;
+
+INSERT INTO schema_migrations (version)
+VALUES (20);
@@ -0,0 +1,11 @@
+-- the validity a key was minted with, carried over to the keys rotating it;
+-- zero never expires
+ALTER TABLE api_keys
+    ADD COLUMN ttl_seconds BIGINT NOT NULL DEFAULT 0;
+
+UPDATE api_keys
+SET ttl_seconds = EXTRACT(EPOCH FROM expires_at - created_at)
+WHERE expires_at IS NOT NULL;
+
+INSERT INTO schema_migrations (version)
+VALUES (21);
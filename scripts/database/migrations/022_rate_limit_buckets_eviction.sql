@@ -0,0 +1,12 @@
+-- buckets are keyed by client and account, and evicted once refilled; the
+-- buckets in flight are dropped, which refills them
+TRUNCATE rate_limit_buckets;
+
+ALTER TABLE rate_limit_buckets
+    ALTER COLUMN key TYPE TEXT,
+    ADD COLUMN full_at TIMESTAMP NOT NULL;
+
+CREATE INDEX rate_limit_buckets_full_at_index ON rate_limit_buckets (full_at);
+
+INSERT INTO schema_migrations (version)
+VALUES (22);
@@ -0,0 +1,26 @@
+-- an unset app.tenant_id matches no tenant; internal callers, as workers and
+-- commands, see every tenant only once they set app.tenant_bypass
+DROP POLICY accounts_tenant ON accounts;
+DROP POLICY transactions_tenant ON transactions;
+DROP POLICY scheduled_payments_tenant ON scheduled_payments;
+DROP POLICY cards_tenant ON cards;
+DROP POLICY history_events_tenant ON history_events;
+
+CREATE POLICY accounts_tenant ON accounts
+    USING (current_setting('app.tenant_bypass', true) = 'on'
+        OR tenant_id = current_setting('app.tenant_id', true));
+CREATE POLICY transactions_tenant ON transactions
+    USING (current_setting('app.tenant_bypass', true) = 'on'
+        OR tenant_id = current_setting('app.tenant_id', true));
+CREATE POLICY scheduled_payments_tenant ON scheduled_payments
+    USING (current_setting('app.tenant_bypass', true) = 'on'
+        OR tenant_id = current_setting('app.tenant_id', true));
+CREATE POLICY cards_tenant ON cards
+    USING (current_setting('app.tenant_bypass', true) = 'on'
+        OR tenant_id = current_setting('app.tenant_id', true));
+CREATE POLICY history_events_tenant ON history_events
+    USING (current_setting('app.tenant_bypass', true) = 'on'
+        OR tenant_id = current_setting('app.tenant_id', true));
+
+INSERT INTO schema_migrations (version)
+VALUES (23);
@@ -0,0 +1,57 @@
+-- every table read on behalf of a caller belongs to a tenant
+ALTER TABLE import_jobs
+    ADD COLUMN tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+
+ALTER TABLE api_keys
+    ADD COLUMN tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+
+ALTER TABLE account_events
+    ADD COLUMN tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+
+UPDATE account_events
+SET tenant_id = accounts.tenant_id
+FROM accounts
+WHERE accounts.id = account_events.account_id;
+
+-- the tenant of the entity an audit entry is about, null when it is not
+-- visible to the caller or does not exist, as for failed creations
+CREATE FUNCTION audit_entity_tenant(entity_type TEXT, entity_id TEXT) RETURNS VARCHAR AS
+$$
+BEGIN
+    CASE entity_type
+        WHEN 'account' THEN
+            RETURN (SELECT tenant_id FROM accounts WHERE id = entity_id);
+        WHEN 'transaction' THEN
+            IF entity_id ~ '^[0-9]{1,9}$' THEN
+                RETURN (SELECT tenant_id FROM transactions WHERE id = entity_id::int);
+            END IF;
+        WHEN 'card' THEN
+            RETURN (SELECT tenant_id FROM cards WHERE id = entity_id);
+        WHEN 'scheduled_payment' THEN
+            RETURN (SELECT tenant_id FROM scheduled_payments WHERE id = entity_id);
+        WHEN 'import' THEN
+            RETURN (SELECT tenant_id FROM import_jobs WHERE id = entity_id);
+        ELSE
+            NULL;
+    END CASE;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+-- the tenant is not part of the hash, so existing entries keep verifying
+ALTER TABLE audit_log
+    ADD COLUMN tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+
+ALTER TABLE audit_log DISABLE TRIGGER audit_log_append_only;
+
+UPDATE audit_log
+SET tenant_id = audit_entity_tenant(entity_type, entity_id)
+WHERE audit_entity_tenant(entity_type, entity_id) IS NOT NULL;
+
+ALTER TABLE audit_log ENABLE TRIGGER audit_log_append_only;
+
+CREATE INDEX audit_log_tenant_id_index ON audit_log (tenant_id, id);
+
+INSERT INTO schema_migrations (version)
+VALUES (24);